@@ -62,6 +62,7 @@ type agentConfig struct {
 	LogFile                       string    `hcl:"log_file"`
 	LogFormat                     string    `hcl:"log_format"`
 	LogLevel                      string    `hcl:"log_level"`
+	ReattestToRenew               bool      `hcl:"reattest_to_renew"`
 	SDS                           sdsConfig `hcl:"sds"`
 	ServerAddress                 string    `hcl:"server_address"`
 	ServerPort                    int       `hcl:"server_port"`
@@ -71,6 +72,7 @@ type agentConfig struct {
 	TrustDomain                   string    `hcl:"trust_domain"`
 	AllowUnauthenticatedVerifiers bool      `hcl:"allow_unauthenticated_verifiers"`
 	AllowedForeignJWTClaims       []string  `hcl:"allowed_foreign_jwt_claims"`
+	WorkloadAttestationCacheTTL   string    `hcl:"workload_attestation_cache_ttl"`
 
 	AuthorizedDelegates []string `hcl:"authorized_delegates"`
 
@@ -357,6 +359,17 @@ func NewAgentConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool)
 		}
 	}
 
+	if c.Agent.WorkloadAttestationCacheTTL != "" {
+		var err error
+		ac.WorkloadAttestationCacheTTL, err = time.ParseDuration(c.Agent.WorkloadAttestationCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse workload attestation cache TTL: %w", err)
+		}
+		if ac.WorkloadAttestationCacheTTL < 0 {
+			return nil, errors.New("workload attestation cache TTL cannot be negative")
+		}
+	}
+
 	serverHostPort := net.JoinHostPort(c.Agent.ServerAddress, strconv.Itoa(c.Agent.ServerPort))
 	ac.ServerAddress = fmt.Sprintf("dns:///%s", serverHostPort)
 
@@ -402,6 +415,7 @@ func NewAgentConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool)
 		}
 	}
 	ac.JoinToken = c.Agent.JoinToken
+	ac.ReattestToRenew = c.Agent.ReattestToRenew
 	ac.DataDir = c.Agent.DataDir
 	ac.DefaultSVIDName = c.Agent.SDS.DefaultSVIDName
 	ac.DefaultBundleName = c.Agent.SDS.DefaultBundleName
@@ -488,6 +502,10 @@ func validateConfig(c *Config) error {
 		return errors.New("plugins section must be configured")
 	}
 
+	if c.Agent.ReattestToRenew && c.Agent.JoinToken != "" {
+		return errors.New("reattest_to_renew cannot be used with join_token since join tokens are single use")
+	}
+
 	return nil
 }
 
@@ -700,6 +700,26 @@ func TestNewAgentConfig(t *testing.T) {
 				require.True(t, c.InsecureBootstrap)
 			},
 		},
+		{
+			msg: "reattest_to_renew should be correctly configured",
+			input: func(c *Config) {
+				c.Agent.ReattestToRenew = true
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.True(t, c.ReattestToRenew)
+			},
+		},
+		{
+			msg:         "reattest_to_renew cannot be used with join_token",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.ReattestToRenew = true
+				c.Agent.JoinToken = "foo"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg: "join_token should be correctly configured",
 			input: func(c *Config) {
@@ -797,6 +817,35 @@ func TestNewAgentConfig(t *testing.T) {
 				require.EqualValues(t, 2045000000, c.SyncInterval)
 			},
 		},
+		{
+			msg: "workload_attestation_cache_ttl parses a duration",
+			input: func(c *Config) {
+				c.Agent.WorkloadAttestationCacheTTL = "30s"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.EqualValues(t, 30000000000, c.WorkloadAttestationCacheTTL)
+			},
+		},
+		{
+			msg:         "invalid workload_attestation_cache_ttl returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.WorkloadAttestationCacheTTL = "moo"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
+		{
+			msg:         "negative workload_attestation_cache_ttl returns an error",
+			expectError: true,
+			input: func(c *Config) {
+				c.Agent.WorkloadAttestationCacheTTL = "-1s"
+			},
+			test: func(t *testing.T, c *agent.Config) {
+				require.Nil(t, c)
+			},
+		},
 		{
 			msg:         "invalid sync_interval returns an error",
 			expectError: true,
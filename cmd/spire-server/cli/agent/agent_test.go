@@ -14,10 +14,12 @@ import (
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/cmd/spire-server/cli/agent"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	agentbanv1 "github.com/spiffe/spire/proto/private/server/agentban/v1"
 	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 var (
@@ -47,8 +49,9 @@ type agentTest struct {
 	stdout *bytes.Buffer
 	stderr *bytes.Buffer
 
-	args   []string
-	server *fakeAgentServer
+	args      []string
+	server    *fakeAgentServer
+	banServer *fakeAgentBanServer
 
 	client cli.Command
 }
@@ -119,6 +122,63 @@ func TestBan(t *testing.T) {
 	}
 }
 
+func TestUnbanHelp(t *testing.T) {
+	test := setupTest(t, agent.NewUnbanCommandWithEnv)
+
+	test.client.Help()
+	require.Equal(t, `Usage of agent unban:
+  -socketPath string
+    	Path to the SPIRE Server API socket (default "/tmp/spire-server/private/api.sock")
+  -spiffeID string
+    	The SPIFFE ID of the agent to unban (agent identity)
+`, test.stderr.String())
+}
+
+func TestUnban(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		args             []string
+		expectReturnCode int
+		expectStdout     string
+		expectStderr     string
+		expectID         *types.SPIFFEID
+		serverErr        error
+	}{
+		{
+			name:             "success",
+			args:             []string{"-spiffeID", "spiffe://example.org/spire/agent/agent1"},
+			expectReturnCode: 0,
+			expectStdout:     "Agent unbanned successfully\n",
+			expectID:         &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/agent1"},
+		},
+		{
+			name:             "no spiffe id",
+			expectReturnCode: 1,
+			expectStderr:     "Error: a SPIFFE ID is required\n",
+		},
+		{
+			name:             "server error",
+			args:             []string{"-spiffeID", "spiffe://example.org/spire/agent/foo"},
+			serverErr:        status.Error(codes.FailedPrecondition, "agent is not banned"),
+			expectReturnCode: 1,
+			expectStderr:     "Error: rpc error: code = FailedPrecondition desc = agent is not banned\n",
+			expectID:         &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/foo"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test := setupTest(t, agent.NewUnbanCommandWithEnv)
+			test.banServer.err = tt.serverErr
+
+			returnCode := test.client.Run(append(test.args, tt.args...))
+			require.Equal(t, tt.expectStdout, test.stdout.String())
+			require.Equal(t, tt.expectStderr, test.stderr.String())
+			require.Equal(t, tt.expectReturnCode, returnCode)
+			spiretest.RequireProtoEqual(t, tt.expectID, test.banServer.gotID)
+		})
+	}
+}
+
 func TestEvictHelp(t *testing.T) {
 	test := setupTest(t, agent.NewEvictCommandWithEnv)
 
@@ -240,6 +300,8 @@ func TestListHelp(t *testing.T) {
 
 	test.client.Help()
 	require.Equal(t, `Usage of agent list:
+  -banned
+    	Only list banned agents
   -matchSelectorsOn string
     	The match mode used when filtering by selectors. Options: exact, any, superset and subset (default "superset")
   -selector value
@@ -273,11 +335,37 @@ func TestList(t *testing.T) {
 		{
 			name:               "no agents",
 			expectedReturnCode: 0,
+			expectedStdout:     "No attested agents found\n",
 			expectReq: &agentv1.ListAgentsRequest{
 				Filter:   &agentv1.ListAgentsRequest_Filter{},
 				PageSize: 1000,
 			},
 		},
+		{
+			name:               "banned agents",
+			args:               []string{"-banned"},
+			expectedReturnCode: 0,
+			existentAgents:     testAgentsWithBanned,
+			expectedStdout:     "Found 1 banned agent:\n\nSPIFFE ID         : spiffe://example.org/spire/agent/banned",
+			expectReq: &agentv1.ListAgentsRequest{
+				Filter: &agentv1.ListAgentsRequest_Filter{
+					ByBanned: wrapperspb.Bool(true),
+				},
+				PageSize: 1000,
+			},
+		},
+		{
+			name:               "no banned agents",
+			args:               []string{"-banned"},
+			expectedReturnCode: 0,
+			expectedStdout:     "No banned agents found\n",
+			expectReq: &agentv1.ListAgentsRequest{
+				Filter: &agentv1.ListAgentsRequest_Filter{
+					ByBanned: wrapperspb.Bool(true),
+				},
+				PageSize: 1000,
+			},
+		},
 		{
 			name:               "server error",
 			expectedReturnCode: 1,
@@ -491,9 +579,11 @@ func TestShow(t *testing.T) {
 
 func setupTest(t *testing.T, newClient func(*common_cli.Env) cli.Command) *agentTest {
 	server := &fakeAgentServer{}
+	banServer := &fakeAgentBanServer{}
 
 	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
 		agentv1.RegisterAgentServer(s, server)
+		agentbanv1.RegisterAgentBanServer(s, banServer)
 	})
 
 	stdin := new(bytes.Buffer)
@@ -507,12 +597,13 @@ func setupTest(t *testing.T, newClient func(*common_cli.Env) cli.Command) *agent
 	})
 
 	test := &agentTest{
-		stdin:  stdin,
-		stdout: stdout,
-		stderr: stderr,
-		args:   []string{"-socketPath", socketPath},
-		server: server,
-		client: client,
+		stdin:     stdin,
+		stdout:    stdout,
+		stderr:    stderr,
+		args:      []string{"-socketPath", socketPath},
+		server:    server,
+		banServer: banServer,
+		client:    client,
 	}
 
 	t.Cleanup(func() {
@@ -558,3 +649,15 @@ func (s *fakeAgentServer) GetAgent(ctx context.Context, req *agentv1.GetAgentReq
 
 	return nil, s.err
 }
+
+type fakeAgentBanServer struct {
+	agentbanv1.UnimplementedAgentBanServer
+
+	gotID *types.SPIFFEID
+	err   error
+}
+
+func (s *fakeAgentBanServer) UnbanAgent(ctx context.Context, req *agentbanv1.UnbanAgentRequest) (*emptypb.Empty, error) {
+	s.gotID = req.Id
+	return &emptypb.Empty{}, s.err
+}
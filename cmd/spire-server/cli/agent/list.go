@@ -15,6 +15,7 @@ import (
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 
 	"golang.org/x/net/context"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type listCommand struct {
@@ -24,6 +25,9 @@ type listCommand struct {
 
 	// Match used when filtering agents by selectors
 	matchSelectorsOn string
+
+	// Only list banned agents
+	banned bool
 }
 
 // NewListCommand creates a new "list" subcommand for "agent" command.
@@ -67,6 +71,9 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 			Match:     matchBehavior,
 		}
 	}
+	if c.banned {
+		filter.ByBanned = wrapperspb.Bool(true)
+	}
 
 	agentClient := serverClient.NewAgentClient()
 
@@ -87,11 +94,16 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 		}
 	}
 
+	kind := "attested"
+	if c.banned {
+		kind = "banned"
+	}
+
 	if len(agents) == 0 {
-		return env.Printf("No attested agents found\n")
+		return env.Printf("No %s agents found\n", kind)
 	}
 
-	msg := fmt.Sprintf("Found %d attested ", len(agents))
+	msg := fmt.Sprintf("Found %d %s ", len(agents), kind)
 	msg = util.Pluralizer(msg, "agent", "agents", len(agents))
 	env.Printf(msg + ":\n\n")
 
@@ -99,6 +111,7 @@ func (c *listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient
 }
 
 func (c *listCommand) AppendFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.banned, "banned", false, "Only list banned agents")
 	fs.StringVar(&c.matchSelectorsOn, "matchSelectorsOn", "superset", "The match mode used when filtering by selectors. Options: exact, any, superset and subset")
 	fs.Var(&c.selectors, "selector", "A colon-delimited type:value selector. Can be used more than once")
 }
@@ -119,7 +132,7 @@ func printAgents(env *common_cli.Env, agents ...*types.Agent) error {
 		if err := env.Printf("Expiration time   : %s\n", time.Unix(agent.X509SvidExpiresAt, 0)); err != nil {
 			return err
 		}
-		// Banned agents will have an empty serial number
+		// Banned agents have their serial number cleared
 		if agent.Banned {
 			if err := env.Printf("Banned            : %t\n", agent.Banned); err != nil {
 				return err
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/mitchellh/cli"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/cmd/spire-server/util"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	"github.com/spiffe/spire/pkg/server/api"
+	agentbanv1 "github.com/spiffe/spire/proto/private/server/agentban/v1"
+)
+
+type unbanCommand struct {
+	// SPIFFE ID of agent being unbanned
+	spiffeID string
+}
+
+// NewUnbanCommand creates a new "unban" subcommand for "agent" command.
+func NewUnbanCommand() cli.Command {
+	return NewUnbanCommandWithEnv(common_cli.DefaultEnv)
+}
+
+// NewUnbanCommandWithEnv creates a new "unban" subcommand for "agent" command
+// using the environment specified
+func NewUnbanCommandWithEnv(env *common_cli.Env) cli.Command {
+	return util.AdaptCommand(env, new(unbanCommand))
+}
+
+func (*unbanCommand) Name() string {
+	return "agent unban"
+}
+
+func (*unbanCommand) Synopsis() string {
+	return "Unban a banned agent given its SPIFFE ID, allowing it to attest again"
+}
+
+// Run unbans an agent given its SPIFFE ID
+func (c *unbanCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	if c.spiffeID == "" {
+		return errors.New("a SPIFFE ID is required")
+	}
+
+	id, err := spiffeid.FromString(c.spiffeID)
+	if err != nil {
+		return err
+	}
+
+	agentBanClient := serverClient.NewAgentBanClient()
+	if _, err := agentBanClient.UnbanAgent(ctx, &agentbanv1.UnbanAgentRequest{
+		Id: api.ProtoFromID(id),
+	}); err != nil {
+		return err
+	}
+
+	return env.Println("Agent unbanned successfully")
+}
+
+func (c *unbanCommand) AppendFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.spiffeID, "spiffeID", "", "The SPIFFE ID of the agent to unban (agent identity)")
+}
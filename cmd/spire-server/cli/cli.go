@@ -44,6 +44,9 @@ func (cc *CLI) Run(args []string) int {
 		"agent show": func() (cli.Command, error) {
 			return agent.NewShowCommand(), nil
 		},
+		"agent unban": func() (cli.Command, error) {
+			return agent.NewUnbanCommand(), nil
+		},
 		"bundle count": func() (cli.Command, error) {
 			return bundle.NewCountCommand(), nil
 		},
@@ -98,6 +101,9 @@ func (cc *CLI) Run(args []string) int {
 		"token generate": func() (cli.Command, error) {
 			return token.NewGenerateCommand(), nil
 		},
+		"token list": func() (cli.Command, error) {
+			return token.NewListCommand(), nil
+		},
 		"healthcheck": func() (cli.Command, error) {
 			return healthcheck.NewHealthCheckCommand(), nil
 		},
@@ -30,11 +30,13 @@ import (
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/pkg/server"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
 	bundleClient "github.com/spiffe/spire/pkg/server/bundle/client"
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/endpoints/bundle"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
 )
 
 const (
@@ -64,25 +66,26 @@ type Config struct {
 }
 
 type serverConfig struct {
-	AgentTTL        string             `hcl:"agent_ttl"`
-	AuditLogEnabled bool               `hcl:"audit_log_enabled"`
-	BindAddress     string             `hcl:"bind_address"`
-	BindPort        int                `hcl:"bind_port"`
-	CAKeyType       string             `hcl:"ca_key_type"`
-	CASubject       *caSubjectConfig   `hcl:"ca_subject"`
-	CATTL           string             `hcl:"ca_ttl"`
-	DataDir         string             `hcl:"data_dir"`
-	DefaultSVIDTTL  string             `hcl:"default_svid_ttl"`
-	Experimental    experimentalConfig `hcl:"experimental"`
-	Federation      *federationConfig  `hcl:"federation"`
-	JWTIssuer       string             `hcl:"jwt_issuer"`
-	JWTKeyType      string             `hcl:"jwt_key_type"`
-	LogFile         string             `hcl:"log_file"`
-	LogLevel        string             `hcl:"log_level"`
-	LogFormat       string             `hcl:"log_format"`
-	RateLimit       rateLimitConfig    `hcl:"ratelimit"`
-	SocketPath      string             `hcl:"socket_path"`
-	TrustDomain     string             `hcl:"trust_domain"`
+	AgentTTL                  string             `hcl:"agent_ttl"`
+	AuditLogEnabled           bool               `hcl:"audit_log_enabled"`
+	BindAddress               string             `hcl:"bind_address"`
+	BindPort                  int                `hcl:"bind_port"`
+	CAKeyType                 string             `hcl:"ca_key_type"`
+	CASubject                 *caSubjectConfig   `hcl:"ca_subject"`
+	CATTL                     string             `hcl:"ca_ttl"`
+	DataDir                   string             `hcl:"data_dir"`
+	DefaultSVIDTTL            string             `hcl:"default_svid_ttl"`
+	Experimental              experimentalConfig `hcl:"experimental"`
+	Federation                *federationConfig  `hcl:"federation"`
+	JWTIssuer                 string             `hcl:"jwt_issuer"`
+	JWTKeyType                string             `hcl:"jwt_key_type"`
+	LogFile                   string             `hcl:"log_file"`
+	LogLevel                  string             `hcl:"log_level"`
+	LogFormat                 string             `hcl:"log_format"`
+	RateLimit                 rateLimitConfig    `hcl:"ratelimit"`
+	ReattestableNodeAttestors []string           `hcl:"reattestable_node_attestors"`
+	SocketPath                string             `hcl:"socket_path"`
+	TrustDomain               string             `hcl:"trust_domain"`
 
 	ConfigPath string
 	ExpandEnv  bool
@@ -105,6 +108,8 @@ type experimentalConfig struct {
 	UnusedKeys []string `hcl:",unusedKeys"`
 
 	AuthOpaPolicyEngine *authpolicy.OpaEngineConfig `hcl:"auth_opa_policy_engine"`
+
+	AttestationOpaPolicyEngine *attestpolicy.OpaEngineConfig `hcl:"attestation_opa_policy_engine"`
 }
 
 type caSubjectConfig struct {
@@ -449,6 +454,8 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 		sc.AgentTTL = ttl
 	}
 
+	sc.ReattestableNodeAttestors = c.Server.ReattestableNodeAttestors
+
 	if c.Server.DefaultSVIDTTL != "" {
 		ttl, err := time.ParseDuration(c.Server.DefaultSVIDTTL)
 		if err != nil {
@@ -562,6 +569,7 @@ func NewServerConfig(c *Config, logOptions []log.Option, allowUnknownConfig bool
 	}
 
 	sc.AuthOpaPolicyEngineConfig = c.Server.Experimental.AuthOpaPolicyEngine
+	sc.AttestationOpaPolicyEngineConfig = c.Server.Experimental.AttestationOpaPolicyEngine
 
 	return sc, nil
 }
@@ -628,6 +636,12 @@ func validateConfig(c *Config) error {
 		return errors.New("plugins section must be configured")
 	}
 
+	for _, nodeAttestorType := range c.Server.ReattestableNodeAttestors {
+		if !nodeattestor.SupportsReattestation(nodeAttestorType) {
+			return fmt.Errorf("reattestable_node_attestors cannot include %q since it does not support re-attestation", nodeAttestorType)
+		}
+	}
+
 	if c.Server.Federation != nil {
 		if c.Server.Federation.BundleEndpoint != nil &&
 			c.Server.Federation.BundleEndpoint.ACME != nil {
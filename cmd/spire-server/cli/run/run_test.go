@@ -18,6 +18,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/log"
 	"github.com/spiffe/spire/pkg/server"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
 	bundleClient "github.com/spiffe/spire/pkg/server/bundle/client"
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
@@ -920,6 +921,23 @@ func TestNewServerConfig(t *testing.T) {
 				require.Nil(t, c)
 			},
 		},
+		{
+			msg: "attestation_opa_policy_engine is passed through",
+			input: func(c *Config) {
+				c.Server.Experimental.AttestationOpaPolicyEngine = &attestpolicy.OpaEngineConfig{
+					LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+						RegoPath: "attestation.rego",
+					},
+				}
+			},
+			test: func(t *testing.T, c *server.Config) {
+				require.Equal(t, &attestpolicy.OpaEngineConfig{
+					LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+						RegoPath: "attestation.rego",
+					},
+				}, c.AttestationOpaPolicyEngineConfig)
+			},
+		},
 		{
 			msg: "audit_log_enabled is enabled",
 			input: func(c *Config) {
@@ -1014,6 +1032,21 @@ func TestValidateConfig(t *testing.T) {
 			applyConf:   func(c *Config) { c.Plugins = nil },
 			expectedErr: "plugins section must be configured",
 		},
+		{
+			name:        "reattestable_node_attestors cannot include join_token",
+			applyConf:   func(c *Config) { c.Server.ReattestableNodeAttestors = []string{"x509pop", "join_token"} },
+			expectedErr: `reattestable_node_attestors cannot include "join_token" since it does not support re-attestation`,
+		},
+		{
+			name:        "reattestable_node_attestors cannot include attest once node attestors",
+			applyConf:   func(c *Config) { c.Server.ReattestableNodeAttestors = []string{"aws_iid"} },
+			expectedErr: `reattestable_node_attestors cannot include "aws_iid" since it does not support re-attestation`,
+		},
+		{
+			name:        "reattestable_node_attestors cannot include trust on first use node attestors",
+			applyConf:   func(c *Config) { c.Server.ReattestableNodeAttestors = []string{"http_challenge"} },
+			expectedErr: `reattestable_node_attestors cannot include "http_challenge" since it does not support re-attestation`,
+		},
 		{
 			name: "if ACME is used, federation.bundle_endpoint.acme.domain_name must be configured",
 			applyConf: func(c *Config) {
@@ -1531,6 +1564,14 @@ func TestAgentTTL(t *testing.T) {
 	}
 }
 
+func TestReattestableNodeAttestors(t *testing.T) {
+	config := defaultValidConfig()
+	config.Server.ReattestableNodeAttestors = []string{"x509pop", "sshpop"}
+	sconfig, err := NewServerConfig(config, []log.Option{}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x509pop", "sshpop"}, sconfig.ReattestableNodeAttestors)
+}
+
 func httpsSPIFFEConfigTest(t *testing.T) federatesWithConfig {
 	configString := `bundle_endpoint_url = "https://192.168.1.1:1337"
 	bundle_endpoint_profile "https_spiffe" {
@@ -1,7 +1,9 @@
 package token
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 
 	"github.com/mitchellh/cli"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
@@ -9,6 +11,7 @@ import (
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/cmd/spire-server/util"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
 
 	"golang.org/x/net/context"
 )
@@ -27,6 +30,16 @@ type generateCommand struct {
 
 	// Token TTL in seconds
 	TTL int
+
+	// How many agents can attest with the token
+	MaxUses int
+
+	// Selectors assigned to the agents that attest with the token. Type and
+	// value are delimited by a colon (:)
+	Selectors common_cli.StringsFlag
+
+	// Optional template for the path of the agent IDs
+	AgentIDTemplate string
 }
 
 func (g *generateCommand) Name() string {
@@ -38,25 +51,55 @@ func (g *generateCommand) Synopsis() string {
 }
 
 func (g *generateCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	if g.MaxUses < 1 {
+		return errors.New("maxUses must be greater than zero")
+	}
+
 	id, err := getID(g.SpiffeID)
 	if err != nil {
 		return err
 	}
 
-	c := serverClient.NewAgentClient()
-	resp, err := c.CreateJoinToken(ctx, &agentv1.CreateJoinTokenRequest{
-		AgentId: id,
-		Ttl:     int32(g.TTL),
-	})
-	if err != nil {
-		return err
+	var selectors []*types.Selector
+	for _, s := range g.Selectors {
+		selector, err := util.ParseSelector(s)
+		if err != nil {
+			return fmt.Errorf("error parsing selector %q: %w", s, err)
+		}
+		selectors = append(selectors, selector)
+	}
+
+	var token string
+	if g.MaxUses == 1 && len(selectors) == 0 && g.AgentIDTemplate == "" {
+		c := serverClient.NewAgentClient()
+		resp, err := c.CreateJoinToken(ctx, &agentv1.CreateJoinTokenRequest{
+			AgentId: id,
+			Ttl:     int32(g.TTL),
+		})
+		if err != nil {
+			return err
+		}
+		token = resp.Value
+	} else {
+		c := serverClient.NewJoinTokenClient()
+		resp, err := c.CreateJoinToken(ctx, &jointokenv1.CreateJoinTokenRequest{
+			AgentId:         id,
+			Ttl:             int32(g.TTL),
+			MaxUses:         int32(g.MaxUses),
+			Selectors:       selectors,
+			AgentIdTemplate: g.AgentIDTemplate,
+		})
+		if err != nil {
+			return err
+		}
+		token = resp.Value
 	}
 
-	if err := env.Printf("Token: %s\n", resp.Value); err != nil {
+	if err := env.Printf("Token: %s\n", token); err != nil {
 		return err
 	}
 
-	if g.SpiffeID == "" {
+	if g.SpiffeID == "" && g.MaxUses == 1 {
 		env.Printf("Warning: Missing SPIFFE ID.\n")
 		return nil
 	}
@@ -81,5 +124,8 @@ func getID(spiffeID string) (*types.SPIFFEID, error) {
 
 func (g *generateCommand) AppendFlags(fs *flag.FlagSet) {
 	fs.IntVar(&g.TTL, "ttl", 600, "Token TTL in seconds")
-	fs.StringVar(&g.SpiffeID, "spiffeID", "", "Additional SPIFFE ID to assign the token owner (optional)")
+	fs.StringVar(&g.SpiffeID, "spiffeID", "", "Additional SPIFFE ID to assign the token owner (optional). Only supported by single-use tokens")
+	fs.IntVar(&g.MaxUses, "maxUses", 1, "How many agents can attest with the token")
+	fs.Var(&g.Selectors, "selector", "A colon-delimited type:value selector assigned to the agents that attest with the token. Can be used more than once")
+	fs.StringVar(&g.AgentIDTemplate, "agentIDTemplate", "", "Template for the path of the agent IDs, relative to /spire/agent. Can reference {{ .Token }} and {{ .Use }} (optional)")
 }
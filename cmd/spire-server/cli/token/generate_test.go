@@ -8,6 +8,7 @@ import (
 	agentv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/agent/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
 	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
@@ -29,6 +30,7 @@ func TestCreateToken(t *testing.T) {
 		expectedStderr string
 		expectedStdout string
 		expectedReq    *agentv1.CreateJoinTokenRequest
+		expectedJTReq  *jointokenv1.CreateJoinTokenRequest
 		serverErr      error
 	}{
 		{
@@ -52,6 +54,55 @@ func TestCreateToken(t *testing.T) {
 			},
 			token: "token",
 		},
+		{
+			name: "multi-use token with selectors and template",
+			args: []string{
+				"-maxUses", "3",
+				"-selector", "rack:1",
+				"-selector", "env:prod",
+				"-agentIDTemplate", "rack-1/{{ .Use }}",
+			},
+			expectedJTReq: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:     600,
+				MaxUses: 3,
+				Selectors: []*types.Selector{
+					{Type: "rack", Value: "1"},
+					{Type: "env", Value: "prod"},
+				},
+				AgentIdTemplate: "rack-1/{{ .Use }}",
+			},
+			expectedStdout: "Token: token\n",
+			token:          "token",
+		},
+		{
+			name: "single-use token with selectors",
+			args: []string{
+				"-spiffeID", "spiffe://example.org/agent",
+				"-selector", "rack:1",
+			},
+			expectedJTReq: &jointokenv1.CreateJoinTokenRequest{
+				AgentId:   &types.SPIFFEID{TrustDomain: "example.org", Path: "/agent"},
+				Ttl:       600,
+				MaxUses:   1,
+				Selectors: []*types.Selector{{Type: "rack", Value: "1"}},
+			},
+			expectedStdout: "Token: token\n",
+			token:          "token",
+		},
+		{
+			name: "invalid max uses",
+			args: []string{
+				"-maxUses", "0",
+			},
+			expectedStderr: "Error: maxUses must be greater than zero\n",
+		},
+		{
+			name: "malformed selector",
+			args: []string{
+				"-selector", "rack",
+			},
+			expectedStderr: "Error: error parsing selector \"rack\": selector \"rack\" must be formatted as type:value\n",
+		},
 		{
 			name: "malformed spiffe ID",
 			args: []string{
@@ -79,6 +130,8 @@ func TestCreateToken(t *testing.T) {
 			args := append(test.args, tt.args...)
 			test.server.token = tt.token
 			test.server.expectReq = tt.expectedReq
+			test.jtServer.token = tt.token
+			test.jtServer.expectReq = tt.expectedJTReq
 			test.server.err = tt.serverErr
 
 			rc := test.client.Run(args)
@@ -100,36 +153,44 @@ type tokenTest struct {
 	stdout *bytes.Buffer
 	stderr *bytes.Buffer
 
-	args   []string
-	server *fakeAgentServer
+	args     []string
+	server   *fakeAgentServer
+	jtServer *fakeJoinTokenServer
 
 	client cli.Command
 }
 
 func setupTest(t *testing.T) *tokenTest {
+	return setupTestWithCommand(t, newGenerateCommand)
+}
+
+func setupTestWithCommand(t *testing.T, newCommand func(*common_cli.Env) cli.Command) *tokenTest {
 	server := &fakeAgentServer{t: t}
+	jtServer := &fakeJoinTokenServer{t: t}
 
 	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
 		agentv1.RegisterAgentServer(s, server)
+		jointokenv1.RegisterJoinTokenServer(s, jtServer)
 	})
 
 	stdin := new(bytes.Buffer)
 	stdout := new(bytes.Buffer)
 	stderr := new(bytes.Buffer)
 
-	client := newGenerateCommand(&common_cli.Env{
+	client := newCommand(&common_cli.Env{
 		Stdin:  stdin,
 		Stdout: stdout,
 		Stderr: stderr,
 	})
 
 	return &tokenTest{
-		stderr: stderr,
-		stdin:  stdin,
-		stdout: stdout,
-		args:   []string{"-socketPath", socketPath},
-		server: server,
-		client: client,
+		stderr:   stderr,
+		stdin:    stdin,
+		stdout:   stdout,
+		args:     []string{"-socketPath", socketPath},
+		server:   server,
+		jtServer: jtServer,
+		client:   client,
 	}
 }
 
@@ -152,3 +213,31 @@ func (f *fakeAgentServer) CreateJoinToken(ctx context.Context, req *agentv1.Crea
 		Value: f.token,
 	}, nil
 }
+
+type fakeJoinTokenServer struct {
+	jointokenv1.UnimplementedJoinTokenServer
+
+	t         testing.TB
+	expectReq *jointokenv1.CreateJoinTokenRequest
+	err       error
+	token     string
+	tokens    []*jointokenv1.Token
+}
+
+func (f *fakeJoinTokenServer) CreateJoinToken(ctx context.Context, req *jointokenv1.CreateJoinTokenRequest) (*jointokenv1.Token, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	spiretest.AssertProtoEqual(f.t, f.expectReq, req)
+
+	return &jointokenv1.Token{
+		Value: f.token,
+	}, nil
+}
+
+func (f *fakeJoinTokenServer) ListJoinTokens(ctx context.Context, req *jointokenv1.ListJoinTokensRequest) (*jointokenv1.ListJoinTokensResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &jointokenv1.ListJoinTokensResponse{Tokens: f.tokens}, nil
+}
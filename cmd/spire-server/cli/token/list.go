@@ -0,0 +1,76 @@
+package token
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/spiffe/spire/cmd/spire-server/util"
+	common_cli "github.com/spiffe/spire/pkg/common/cli"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
+
+	"golang.org/x/net/context"
+)
+
+// NewListCommand creates a new "list" subcommand for "token" command.
+func NewListCommand() cli.Command {
+	return newListCommand(common_cli.DefaultEnv)
+}
+
+func newListCommand(env *common_cli.Env) cli.Command {
+	return util.AdaptCommand(env, new(listCommand))
+}
+
+type listCommand struct{}
+
+func (*listCommand) Name() string {
+	return "token list"
+}
+
+func (*listCommand) Synopsis() string {
+	return "Lists join tokens that have not been used up"
+}
+
+func (*listCommand) Run(ctx context.Context, env *common_cli.Env, serverClient util.ServerClient) error {
+	c := serverClient.NewJoinTokenClient()
+	resp, err := c.ListJoinTokens(ctx, &jointokenv1.ListJoinTokensRequest{})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Tokens) == 0 {
+		return env.Printf("No join tokens found\n")
+	}
+
+	msg := fmt.Sprintf("Found %d join ", len(resp.Tokens))
+	msg = util.Pluralizer(msg, "token", "tokens", len(resp.Tokens))
+	if err := env.Printf(msg + ":\n\n"); err != nil {
+		return err
+	}
+
+	for _, token := range resp.Tokens {
+		printToken(token, env.Printf)
+	}
+	return nil
+}
+
+func (*listCommand) AppendFlags(*flag.FlagSet) {}
+
+func printToken(token *jointokenv1.Token, printf func(string, ...interface{}) error) {
+	maxUses := token.MaxUses
+	if maxUses < 1 {
+		maxUses = 1
+	}
+
+	_ = printf("Token            : %s\n", token.Value)
+	_ = printf("Expiration time  : %s\n", time.Unix(token.ExpiresAt, 0).UTC())
+	_ = printf("Uses             : %d/%d\n", token.Uses, maxUses)
+	for _, s := range token.Selectors {
+		_ = printf("Selector         : %s:%s\n", s.Type, s.Value)
+	}
+	if token.AgentIdTemplate != "" {
+		_ = printf("Agent ID template: %s\n", token.AgentIdTemplate)
+	}
+	_ = printf("\n")
+}
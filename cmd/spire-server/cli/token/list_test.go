@@ -0,0 +1,92 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListSynopsis(t *testing.T) {
+	require.Equal(t, "Lists join tokens that have not been used up", NewListCommand().Synopsis())
+}
+
+func TestListTokens(t *testing.T) {
+	expiresAt := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name string
+
+		tokens         []*jointokenv1.Token
+		serverErr      error
+		expectedStderr string
+		expectedStdout string
+	}{
+		{
+			name:           "no tokens",
+			expectedStdout: "No join tokens found\n",
+		},
+		{
+			name: "tokens",
+			tokens: []*jointokenv1.Token{
+				{
+					Value:     "token1",
+					ExpiresAt: expiresAt.Unix(),
+					MaxUses:   1,
+				},
+				{
+					Value:     "token2",
+					ExpiresAt: expiresAt.Unix(),
+					MaxUses:   3,
+					Uses:      1,
+					Selectors: []*types.Selector{
+						{Type: "rack", Value: "1"},
+						{Type: "env", Value: "prod"},
+					},
+					AgentIdTemplate: "rack-1/{{ .Use }}",
+				},
+			},
+			expectedStdout: `Found 2 join tokens:
+
+Token            : token1
+Expiration time  : 2021-10-01 12:00:00 +0000 UTC
+Uses             : 0/1
+
+Token            : token2
+Expiration time  : 2021-10-01 12:00:00 +0000 UTC
+Uses             : 1/3
+Selector         : rack:1
+Selector         : env:prod
+Agent ID template: rack-1/{{ .Use }}
+
+`,
+		},
+		{
+			name:           "server fails to list tokens",
+			serverErr:      status.New(codes.Internal, "server error").Err(),
+			expectedStderr: "Error: rpc error: code = Internal desc = server error\n",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test := setupTestWithCommand(t, newListCommand)
+			test.jtServer.tokens = tt.tokens
+			test.jtServer.err = tt.serverErr
+
+			rc := test.client.Run(test.args)
+			if tt.expectedStderr != "" {
+				require.Equal(t, tt.expectedStderr, test.stderr.String())
+				require.Equal(t, 1, rc)
+				return
+			}
+
+			require.Empty(t, test.stderr.String())
+			require.Equal(t, 0, rc)
+			require.Equal(t, tt.expectedStdout, test.stdout.String())
+		})
+	}
+}
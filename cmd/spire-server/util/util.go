@@ -19,6 +19,8 @@ import (
 	api_types "github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	common_cli "github.com/spiffe/spire/pkg/common/cli"
 	"github.com/spiffe/spire/pkg/common/pemutil"
+	agentbanv1 "github.com/spiffe/spire/proto/private/server/agentban/v1"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health/grpc_health_v1"
 )
@@ -48,8 +50,10 @@ func dialer(ctx context.Context, addr string) (net.Conn, error) {
 type ServerClient interface {
 	Release()
 	NewAgentClient() agentv1.AgentClient
+	NewAgentBanClient() agentbanv1.AgentBanClient
 	NewBundleClient() bundlev1.BundleClient
 	NewEntryClient() entryv1.EntryClient
+	NewJoinTokenClient() jointokenv1.JoinTokenClient
 	NewSVIDClient() svidv1.SVIDClient
 	NewTrustDomainClient() trustdomainv1.TrustDomainClient
 	NewHealthClient() grpc_health_v1.HealthClient
@@ -75,6 +79,10 @@ func (c *serverClient) NewAgentClient() agentv1.AgentClient {
 	return agentv1.NewAgentClient(c.conn)
 }
 
+func (c *serverClient) NewAgentBanClient() agentbanv1.AgentBanClient {
+	return agentbanv1.NewAgentBanClient(c.conn)
+}
+
 func (c *serverClient) NewBundleClient() bundlev1.BundleClient {
 	return bundlev1.NewBundleClient(c.conn)
 }
@@ -83,6 +91,10 @@ func (c *serverClient) NewEntryClient() entryv1.EntryClient {
 	return entryv1.NewEntryClient(c.conn)
 }
 
+func (c *serverClient) NewJoinTokenClient() jointokenv1.JoinTokenClient {
+	return jointokenv1.NewJoinTokenClient(c.conn)
+}
+
 func (c *serverClient) NewSVIDClient() svidv1.SVIDClient {
 	return svidv1.NewSVIDClient(c.conn)
 }
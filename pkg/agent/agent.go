@@ -91,9 +91,10 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	storeService := a.newSVIDStoreService(svidStoreCache, cat, metrics)
 	workloadAttestor := workload_attestor.New(&workload_attestor.Config{
-		Catalog: cat,
-		Log:     a.c.Log.WithField(telemetry.SubsystemName, telemetry.WorkloadAttestor),
-		Metrics: metrics,
+		Catalog:  cat,
+		Log:      a.c.Log.WithField(telemetry.SubsystemName, telemetry.WorkloadAttestor),
+		Metrics:  metrics,
+		CacheTTL: a.c.WorkloadAttestationCacheTTL,
 	})
 
 	endpoints := a.newEndpoints(metrics, manager, workloadAttestor)
@@ -208,6 +209,7 @@ func (a *Agent) newManager(ctx context.Context, cat catalog.Catalog, metrics tel
 		SVIDCachePath:   a.agentSVIDPath(),
 		SyncInterval:    a.c.SyncInterval,
 		SVIDStoreCache:  cache,
+		ReattestToRenew: a.c.ReattestToRenew,
 	}
 
 	mgr := manager.New(config)
@@ -0,0 +1,141 @@
+package attestor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	telemetry_workload "github.com/spiffe/spire/pkg/common/telemetry/agent/workloadapi"
+	"github.com/spiffe/spire/proto/spire/common"
+)
+
+const (
+	evictReasonExpired = "expired"
+	evictReasonExited  = "exited"
+)
+
+// processKey identifies a process. PIDs can be reused once a process exits,
+// so the start time of the process is included to make sure that a new
+// process is never handed the selectors of a previous one. A process keeps its
+// PID and start time across execve and credential changes, so its identity
+// is included as well.
+type processKey struct {
+	pid       int
+	startTime int64
+	identity  processIdentity
+}
+
+// processIdentity holds the attributes of a process that change when it
+// executes a new binary or changes its credentials.
+type processIdentity struct {
+	// exeDev and exeIno identify the executable of the process
+	exeDev uint64
+	exeIno uint64
+	uid    uint32
+	gid    uint32
+}
+
+type cacheEntry struct {
+	selectors []*common.Selector
+	expiresAt time.Time
+}
+
+// attestationCache caches the selectors of attested processes for a fixed
+// TTL. Entries for processes that have exited are purged periodically, when
+// new entries are added.
+type attestationCache struct {
+	ttl             time.Duration
+	identifyProcess func(pid int) (processKey, error)
+
+	mu        sync.Mutex
+	entries   map[processKey]cacheEntry
+	lastPurge time.Time
+}
+
+func newAttestationCache(ttl time.Duration) *attestationCache {
+	return &attestationCache{
+		ttl:             ttl,
+		identifyProcess: getProcessKey,
+		entries:         make(map[processKey]cacheEntry),
+	}
+}
+
+// processKey returns the key identifying the process with the given PID. It
+// fails if the process no longer exists.
+func (c *attestationCache) processKey(pid int) (processKey, error) {
+	return c.identifyProcess(pid)
+}
+
+func (c *attestationCache) get(key processKey, now time.Time, metrics telemetry.Metrics) ([]*common.Selector, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	switch {
+	case !ok:
+		return nil, false
+	case !now.Before(entry.expiresAt):
+		c.evict(key, evictReasonExpired, metrics)
+		telemetry_workload.SetAttestationCacheSizeGauge(metrics, len(c.entries))
+		return nil, false
+	}
+
+	// Hand out a copy so callers can't alter the cached slice
+	return append([]*common.Selector(nil), entry.selectors...), true
+}
+
+func (c *attestationCache) set(key processKey, selectors []*common.Selector, now time.Time, metrics telemetry.Metrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPurge) >= c.ttl {
+		c.purge(now, metrics)
+		c.lastPurge = now
+	}
+
+	c.entries[key] = cacheEntry{
+		selectors: append([]*common.Selector(nil), selectors...),
+		expiresAt: now.Add(c.ttl),
+	}
+	telemetry_workload.SetAttestationCacheSizeGauge(metrics, len(c.entries))
+}
+
+// purge removes expired entries and entries for processes that have exited.
+// It must be called with the lock held.
+func (c *attestationCache) purge(now time.Time, metrics telemetry.Metrics) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			c.evict(key, evictReasonExpired, metrics)
+			continue
+		}
+		if current, err := c.processKey(key.pid); err != nil || current != key {
+			c.evict(key, evictReasonExited, metrics)
+		}
+	}
+}
+
+func (c *attestationCache) evict(key processKey, reason string, metrics telemetry.Metrics) {
+	delete(c.entries, key)
+	telemetry_workload.IncrAttestationCacheEvictionCounter(metrics, reason)
+}
+
+func getProcessKey(pid int) (processKey, error) {
+	startTime, err := processStartTime(pid)
+	if err != nil {
+		return processKey{}, err
+	}
+	identity, err := getProcessIdentity(pid)
+	if err != nil {
+		return processKey{}, err
+	}
+	return processKey{pid: pid, startTime: startTime, identity: identity}, nil
+}
+
+func processStartTime(pid int) (int64, error) {
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return 0, err
+	}
+	return p.CreateTime()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package attestor
+
+// getProcessIdentity is not supported on this platform, so processes are only
+// identified by their PID and start time.
+func getProcessIdentity(pid int) (processIdentity, error) {
+	return processIdentity{}, nil
+}
@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package attestor
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// getProcessIdentity reads the executable and the credentials of a process
+// from procfs. The owner of the proc directory is the effective user and
+// group of the process.
+func getProcessIdentity(pid int) (processIdentity, error) {
+	var procStat syscall.Stat_t
+	if err := syscall.Stat(fmt.Sprintf("/proc/%d", pid), &procStat); err != nil {
+		return processIdentity{}, err
+	}
+
+	var exeStat syscall.Stat_t
+	if err := syscall.Stat(fmt.Sprintf("/proc/%d/exe", pid), &exeStat); err != nil {
+		return processIdentity{}, err
+	}
+
+	return processIdentity{
+		exeDev: uint64(exeStat.Dev), //nolint: unconvert // Dev is not a uint64 on every architecture
+		exeIno: exeStat.Ino,
+		uid:    procStat.Uid,
+		gid:    procStat.Gid,
+	}, nil
+}
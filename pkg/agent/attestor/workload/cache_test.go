@@ -0,0 +1,127 @@
+package attestor
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/fakes/fakemetrics"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStartTime(t *testing.T) {
+	startTime, err := processStartTime(os.Getpid())
+	require.NoError(t, err)
+	require.NotZero(t, startTime)
+
+	// the start time of a process doesn't change
+	again, err := processStartTime(os.Getpid())
+	require.NoError(t, err)
+	require.Equal(t, startTime, again)
+
+	_, err = processStartTime(-1)
+	require.Error(t, err)
+}
+
+func TestGetProcessKey(t *testing.T) {
+	key, err := getProcessKey(os.Getpid())
+	require.NoError(t, err)
+	require.Equal(t, os.Getpid(), key.pid)
+	require.NotZero(t, key.startTime)
+
+	// the key of a process doesn't change until it executes another binary
+	// or changes its credentials
+	again, err := getProcessKey(os.Getpid())
+	require.NoError(t, err)
+	require.Equal(t, key, again)
+
+	_, err = getProcessKey(-1)
+	require.Error(t, err)
+}
+
+func TestAttestationCacheGetSet(t *testing.T) {
+	now := time.Now()
+	metrics := fakemetrics.New()
+	cache := newAttestationCache(time.Minute)
+
+	key := processKey{pid: 1, startTime: 100, identity: processIdentity{exeDev: 1, exeIno: 2, uid: 1000, gid: 1000}}
+	selectors := []*common.Selector{{Type: "unix", Value: "uid:1000"}}
+
+	_, ok := cache.get(key, now, metrics)
+	require.False(t, ok)
+
+	cache.set(key, selectors, now, metrics)
+	got, ok := cache.get(key, now, metrics)
+	require.True(t, ok)
+	spiretest.RequireProtoListEqual(t, selectors, got)
+
+	// the cached selectors can't be altered through the returned slice
+	got[0] = &common.Selector{Type: "unix", Value: "uid:0"}
+	got, ok = cache.get(key, now, metrics)
+	require.True(t, ok)
+	spiretest.RequireProtoListEqual(t, selectors, got)
+
+	// a process that executed another binary or changed its credentials
+	// doesn't get the selectors of the original process
+	execed := key
+	execed.identity.exeIno = 3
+	_, ok = cache.get(execed, now, metrics)
+	require.False(t, ok)
+
+	setuid := key
+	setuid.identity.uid = 0
+	_, ok = cache.get(setuid, now, metrics)
+	require.False(t, ok)
+
+	setgid := key
+	setgid.identity.gid = 0
+	_, ok = cache.get(setgid, now, metrics)
+	require.False(t, ok)
+
+	// entries expire after the TTL
+	_, ok = cache.get(key, now.Add(time.Minute), metrics)
+	require.False(t, ok)
+	require.Empty(t, cache.entries)
+}
+
+func TestAttestationCachePurge(t *testing.T) {
+	now := time.Now()
+	metrics := fakemetrics.New()
+	cache := newAttestationCache(time.Minute)
+
+	current := map[int]processKey{}
+	cache.identifyProcess = func(pid int) (processKey, error) {
+		key, ok := current[pid]
+		if !ok {
+			return processKey{}, errors.New("no such process")
+		}
+		return key, nil
+	}
+
+	exited := processKey{pid: 1, startTime: 100}
+	execed := processKey{pid: 2, startTime: 100}
+	running := processKey{pid: 3, startTime: 100}
+	expired := processKey{pid: 4, startTime: 100}
+	current[execed.pid] = processKey{pid: 2, startTime: 100, identity: processIdentity{exeIno: 1}}
+	current[running.pid] = running
+	current[expired.pid] = expired
+
+	cache.set(expired, nil, now.Add(-30*time.Second), metrics)
+	cache.set(exited, nil, now, metrics)
+	cache.set(execed, nil, now, metrics)
+	cache.set(running, nil, now, metrics)
+	require.Len(t, cache.entries, 4)
+
+	// no purge happens until the TTL has elapsed since the last one
+	cache.set(running, nil, now.Add(20*time.Second), metrics)
+	require.Len(t, cache.entries, 4)
+
+	// expired entries and entries of processes that exited or executed
+	// another binary are purged
+	cache.set(running, nil, now.Add(30*time.Second), metrics)
+	require.Len(t, cache.entries, 1)
+	require.Contains(t, cache.entries, running)
+}
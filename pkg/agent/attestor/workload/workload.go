@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/andres-erbsen/clock"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/agent/catalog"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
@@ -14,7 +16,9 @@ import (
 )
 
 type attestor struct {
-	c *Config
+	c     *Config
+	clk   clock.Clock
+	cache *attestationCache
 }
 
 type Attestor interface {
@@ -26,18 +30,74 @@ func New(config *Config) Attestor {
 }
 
 func newAttestor(config *Config) *attestor {
-	return &attestor{c: config}
+	wla := &attestor{
+		c:   config,
+		clk: config.Clock,
+	}
+	if wla.clk == nil {
+		wla.clk = clock.New()
+	}
+	if config.CacheTTL > 0 {
+		wla.cache = newAttestationCache(config.CacheTTL)
+	}
+	return wla
 }
 
 type Config struct {
 	Catalog catalog.Catalog
 	Log     logrus.FieldLogger
 	Metrics telemetry.Metrics
+
+	// CacheTTL is how long the selectors of an attested process are cached.
+	// If zero, attestation results are not cached.
+	CacheTTL time.Duration
+
+	// Clock is used to expire cached attestation results. Defaults to the
+	// system clock.
+	Clock clock.Clock
 }
 
 // Attest invokes all workload attestor plugins against the provided PID. If an error
 // is encountered, it is logged and selectors from the failing plugin are discarded.
+// When caching is enabled, the selectors of a process are served from the cache
+// until they expire, as long as every plugin succeeded when they were obtained.
 func (wla *attestor) Attest(ctx context.Context, pid int) []*common.Selector {
+	if wla.cache == nil {
+		selectors, _ := wla.attest(ctx, pid)
+		return selectors
+	}
+
+	key, err := wla.cache.processKey(pid)
+	if err != nil {
+		// The process can't be identified (e.g. it has already exited) so
+		// the result can't be cached either.
+		wla.c.Log.WithError(err).WithField(telemetry.PID, pid).Debug("Unable to identify process; not caching attestation result")
+		selectors, _ := wla.attest(ctx, pid)
+		return selectors
+	}
+
+	if selectors, ok := wla.cache.get(key, wla.clk.Now(), wla.c.Metrics); ok {
+		telemetry_workload.IncrAttestationCacheHitCounter(wla.c.Metrics)
+		return selectors
+	}
+	telemetry_workload.IncrAttestationCacheMissCounter(wla.c.Metrics)
+
+	selectors, complete := wla.attest(ctx, pid)
+	if !complete {
+		return selectors
+	}
+
+	// Only cache the result if the attested process is still the one that
+	// was identified before attestation.
+	if current, err := wla.cache.processKey(pid); err == nil && current == key {
+		wla.cache.set(key, selectors, wla.clk.Now(), wla.c.Metrics)
+	}
+	return selectors
+}
+
+// attest invokes all workload attestor plugins against the provided PID. It
+// returns the collected selectors and whether or not all plugins succeeded.
+func (wla *attestor) attest(ctx context.Context, pid int) ([]*common.Selector, bool) {
 	counter := telemetry_workload.StartAttestationCall(wla.c.Metrics)
 	defer counter.Done(nil)
 
@@ -59,12 +119,14 @@ func (wla *attestor) Attest(ctx context.Context, pid int) []*common.Selector {
 
 	// Collect the results
 	selectors := []*common.Selector{}
+	complete := true
 	for i := 0; i < len(plugins); i++ {
 		select {
 		case s := <-sChan:
 			selectors = append(selectors, s...)
 		case err := <-errChan:
 			log.WithError(err).Error("Failed to collect all selectors for PID")
+			complete = false
 		}
 	}
 
@@ -76,7 +138,7 @@ func (wla *attestor) Attest(ctx context.Context, pid int) []*common.Selector {
 	if pid != os.Getpid() {
 		log.WithField(telemetry.Selectors, selectors).Debug("PID attested to have selectors")
 	}
-	return selectors
+	return selectors, complete
 }
 
 // invokeAttestor invokes attestation against the supplied plugin. Should be called from a goroutine.
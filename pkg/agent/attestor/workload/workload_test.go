@@ -3,13 +3,16 @@ package attestor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	telemetry_workload "github.com/spiffe/spire/pkg/common/telemetry/agent/workloadapi"
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/fakes/fakeagentcatalog"
 	"github.com/spiffe/spire/test/fakes/fakemetrics"
 	"github.com/spiffe/spire/test/fakes/fakeworkloadattestor"
@@ -126,3 +129,135 @@ func (s *WorkloadAttestorTestSuite) TestAttestWorkloadMetrics() {
 
 	s.Require().Equal(expected.AllMetrics(), metrics.AllMetrics())
 }
+
+func (s *WorkloadAttestorTestSuite) TestAttestWorkloadCache() {
+	clk := clock.NewMock(s.T())
+	metrics := fakemetrics.New()
+	log, _ := test.NewNullLogger()
+	wla := newAttestor(&Config{
+		Catalog:  s.catalog,
+		Log:      log,
+		Metrics:  metrics,
+		CacheTTL: time.Minute,
+		Clock:    clk,
+	})
+	startTimes := map[int]int64{2: 100, 3: 100, 4: 100}
+	wla.cache.identifyProcess = func(pid int) (processKey, error) {
+		startTime, ok := startTimes[pid]
+		if !ok {
+			return processKey{}, fmt.Errorf("no process with PID %d", pid)
+		}
+		return processKey{pid: pid, startTime: startTime}, nil
+	}
+
+	setAttestorSelectors := func(value string) {
+		s.catalog.SetWorkloadAttestors(
+			fakeworkloadattestor.New(s.T(), "fake1", map[int32][]string{
+				2: {value},
+				4: {value},
+				5: {value},
+			}),
+		)
+	}
+	requireSelectors := func(pid int, value string) {
+		spiretest.AssertProtoListEqual(s.T(), []*common.Selector{{Type: "fake1", Value: value}}, wla.Attest(ctx, pid))
+	}
+
+	// the first attestation populates the cache
+	setAttestorSelectors("first")
+	requireSelectors(2, "first")
+
+	// the cached selectors are returned until they expire
+	setAttestorSelectors("second")
+	requireSelectors(2, "first")
+	clk.Add(time.Minute)
+	requireSelectors(2, "second")
+
+	// a new process reusing the PID is attested again
+	startTimes[2] = 200
+	setAttestorSelectors("third")
+	requireSelectors(2, "third")
+
+	// incomplete results are not cached
+	s.catalog.SetWorkloadAttestors(
+		fakeworkloadattestor.New(s.T(), "fake1", attestor1Pids),
+		fakeworkloadattestor.New(s.T(), "fake2", attestor2Pids),
+	)
+	spiretest.AssertProtoListEqual(s.T(), selectors2, wla.Attest(ctx, 3))
+	s.catalog.SetWorkloadAttestors(
+		fakeworkloadattestor.New(s.T(), "fake1", map[int32][]string{3: {"bar"}}),
+	)
+	spiretest.AssertProtoListEqual(s.T(), selectors1, wla.Attest(ctx, 3))
+
+	// processes that can't be identified are never cached
+	setAttestorSelectors("fourth")
+	requireSelectors(5, "fourth")
+	setAttestorSelectors("fifth")
+	requireSelectors(5, "fifth")
+
+	// entries of processes that have exited are purged when new entries are
+	// added once the TTL has elapsed since the last purge
+	s.Require().Len(wla.cache.entries, 3)
+	startTimes[6] = 100
+	startTimes[7] = 100
+	s.catalog.SetWorkloadAttestors(
+		fakeworkloadattestor.New(s.T(), "fake1", map[int32][]string{4: {"sixth"}, 6: {"sixth"}, 7: {"sixth"}}),
+	)
+	clk.Add(30 * time.Second)
+	requireSelectors(4, "sixth")
+	requireSelectors(6, "sixth")
+	s.Require().Len(wla.cache.entries, 5)
+
+	delete(startTimes, 4)
+	clk.Add(30 * time.Second)
+	requireSelectors(7, "sixth")
+	s.Require().Len(wla.cache.entries, 2)
+	s.Require().Contains(wla.cache.entries, processKey{pid: 6, startTime: 100})
+	s.Require().Contains(wla.cache.entries, processKey{pid: 7, startTime: 100})
+}
+
+func (s *WorkloadAttestorTestSuite) TestAttestWorkloadCacheMetrics() {
+	metrics := fakemetrics.New()
+	log, _ := test.NewNullLogger()
+	clk := clock.NewMock(s.T())
+	wla := newAttestor(&Config{
+		Catalog:  s.catalog,
+		Log:      log,
+		Metrics:  metrics,
+		CacheTTL: time.Minute,
+		Clock:    clk,
+	})
+	wla.cache.identifyProcess = func(pid int) (processKey, error) {
+		return processKey{pid: pid, startTime: 100}, nil
+	}
+	s.catalog.SetWorkloadAttestors(
+		fakeworkloadattestor.New(s.T(), "fake1", attestor1Pids),
+	)
+
+	// miss, hit, then miss after expiration
+	wla.Attest(ctx, 2)
+	wla.Attest(ctx, 2)
+	clk.Add(time.Minute)
+	wla.Attest(ctx, 2)
+
+	expected := fakemetrics.New()
+	telemetry_workload.IncrAttestationCacheMissCounter(expected)
+	attestorCounter := telemetry_workload.StartAttestorCall(expected, "fake1")
+	attestorCounter.Done(nil)
+	telemetry_workload.AddDiscoveredSelectorsSample(expected, 1)
+	attestationCounter := telemetry_workload.StartAttestationCall(expected)
+	attestationCounter.Done(nil)
+	telemetry_workload.SetAttestationCacheSizeGauge(expected, 1)
+	telemetry_workload.IncrAttestationCacheHitCounter(expected)
+	telemetry_workload.IncrAttestationCacheEvictionCounter(expected, evictReasonExpired)
+	telemetry_workload.SetAttestationCacheSizeGauge(expected, 0)
+	telemetry_workload.IncrAttestationCacheMissCounter(expected)
+	attestorCounter = telemetry_workload.StartAttestorCall(expected, "fake1")
+	attestorCounter.Done(nil)
+	telemetry_workload.AddDiscoveredSelectorsSample(expected, 1)
+	attestationCounter = telemetry_workload.StartAttestationCall(expected)
+	attestationCounter.Done(nil)
+	telemetry_workload.SetAttestationCacheSizeGauge(expected, 1)
+
+	s.Require().Equal(expected.AllMetrics(), metrics.AllMetrics())
+}
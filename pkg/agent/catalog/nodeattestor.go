@@ -5,11 +5,14 @@ import (
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/aws"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/azure"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/gcp"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/httpchallenge"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/jointoken"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/jwtoidc"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/k8s/psat"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/k8s/sat"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/sshpop"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmdevid"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmek"
 	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/x509pop"
 	"github.com/spiffe/spire/pkg/common/catalog"
 )
@@ -37,11 +40,14 @@ func (repo *nodeAttestorRepository) BuiltIns() []catalog.BuiltIn {
 		aws.BuiltIn(),
 		azure.BuiltIn(),
 		gcp.BuiltIn(),
+		httpchallenge.BuiltIn(),
 		jointoken.BuiltIn(),
+		jwtoidc.BuiltIn(),
 		psat.BuiltIn(),
 		sat.BuiltIn(),
 		sshpop.BuiltIn(),
 		tpmdevid.BuiltIn(),
+		tpmek.BuiltIn(),
 		x509pop.BuiltIn(),
 	}
 }
@@ -2,8 +2,11 @@ package catalog
 
 import (
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/cri"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/docker"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/k8s"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/podman"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/systemd"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/unix"
 	"github.com/spiffe/spire/pkg/common/catalog"
 )
@@ -26,8 +29,11 @@ func (repo *workloadAttestorRepository) Versions() []catalog.Version {
 
 func (repo *workloadAttestorRepository) BuiltIns() []catalog.BuiltIn {
 	return []catalog.BuiltIn{
+		cri.BuiltIn(),
 		docker.BuiltIn(),
 		k8s.BuiltIn(),
+		podman.BuiltIn(),
+		systemd.BuiltIn(),
 		unix.BuiltIn(),
 	}
 }
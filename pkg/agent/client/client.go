@@ -17,6 +17,7 @@ import (
 	entryv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/entry/v1"
 	svidv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/svid/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
 	"github.com/spiffe/spire/pkg/common/bundleutil"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/proto/spire/common"
@@ -45,6 +46,7 @@ type JWTSVID struct {
 type Client interface {
 	FetchUpdates(ctx context.Context) (*Update, error)
 	RenewSVID(ctx context.Context, csr []byte) (*X509SVID, error)
+	ReattestSVID(ctx context.Context, csr []byte, attestor nodeattestor.NodeAttestor) (*X509SVID, error)
 	NewX509SVIDs(ctx context.Context, csrs map[string][]byte) (map[string]*X509SVID, error)
 	NewJWTSVID(ctx context.Context, entryID string, audience []string) (*JWTSVID, error)
 
@@ -185,6 +187,41 @@ func (c *client) RenewSVID(ctx context.Context, csr []byte) (*X509SVID, error) {
 	}, nil
 }
 
+// ReattestSVID obtains a new agent SVID by running node attestation again
+// over a connection authenticated with the current agent SVID. The gRPC status
+// code returned by the server is preserved so callers can tell when the
+// server does not allow the agent to re-attest.
+func (c *client) ReattestSVID(ctx context.Context, csr []byte, attestor nodeattestor.NodeAttestor) (*X509SVID, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	agentClient, connection, err := c.newAgentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Release()
+
+	stream := &reattestStream{client: agentClient, csr: csr, log: c.c.Log}
+	if err := attestor.Attest(ctx, stream); err != nil {
+		c.release(connection)
+		c.c.Log.WithError(err).Error("Failed to re-attest agent")
+		st := status.Convert(err)
+		return nil, status.Errorf(st.Code(), "failed to re-attest agent: %s", st.Message())
+	}
+	if stream.svid == nil {
+		return nil, errors.New("failed to re-attest agent: attestation completed without an SVID")
+	}
+
+	var certChain []byte
+	for _, cert := range stream.svid.CertChain {
+		certChain = append(certChain, cert...)
+	}
+	return &X509SVID{
+		CertChain: certChain,
+		ExpiresAt: stream.svid.ExpiresAt,
+	}, nil
+}
+
 func (c *client) NewX509SVIDs(ctx context.Context, csrs map[string][]byte) (map[string]*X509SVID, error) {
 	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
 	defer cancel()
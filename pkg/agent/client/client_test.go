@@ -17,10 +17,13 @@ import (
 	svidv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/svid/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/fakes/fakeagentnodeattestor"
+	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -256,6 +259,77 @@ func TestRenewSVID(t *testing.T) {
 	}
 }
 
+func TestReattestSVID(t *testing.T) {
+	client, tc := createClient()
+
+	agentSVID := &types.X509SVID{
+		Id: &types.SPIFFEID{
+			TrustDomain: "example.org",
+			Path:        "/agent1",
+		},
+		CertChain: [][]byte{{1, 2, 3}},
+		ExpiresAt: 12345,
+	}
+
+	for _, tt := range []struct {
+		name       string
+		agentErr   error
+		challenges [][]byte
+		code       codes.Code
+		err        string
+		expectSVID *X509SVID
+		csr        []byte
+	}{
+		{
+			name:       "success",
+			csr:        []byte{0, 1, 2},
+			challenges: [][]byte{[]byte("challenge")},
+			expectSVID: &X509SVID{
+				CertChain: []byte{1, 2, 3},
+				ExpiresAt: 12345,
+			},
+		},
+		{
+			name: "no csr",
+			csr:  []byte(nil),
+			code: codes.Unknown,
+			err:  "failed to re-attest agent: malformed param",
+		},
+		{
+			name:     "re-attestation not allowed",
+			csr:      []byte{0, 1, 2},
+			agentErr: status.Error(codes.FailedPrecondition, "re-attestation is not allowed"),
+			code:     codes.FailedPrecondition,
+			err:      "failed to re-attest agent: re-attestation is not allowed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tc.agentClient.err = tt.agentErr
+			tc.agentClient.svid = agentSVID
+			tc.agentClient.challenges = tt.challenges
+
+			var responses []string
+			for _, challenge := range tt.challenges {
+				responses = append(responses, string(challenge))
+			}
+			attestor := fakeagentnodeattestor.New(t, fakeagentnodeattestor.Config{Responses: responses})
+
+			svid, err := client.ReattestSVID(context.Background(), tt.csr, attestor)
+			if tt.err != "" {
+				spiretest.RequireGRPCStatus(t, err, tt.code, tt.err)
+				require.Nil(t, svid)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectSVID, svid)
+
+			assertConnectionIsNotNil(t, client)
+		})
+	}
+}
+
 func TestNewX509SVIDs(t *testing.T) {
 	client, tc := createClient()
 
@@ -864,8 +938,52 @@ func (c *fakeSVIDClient) NewJWTSVID(ctx context.Context, in *svidv1.NewJWTSVIDRe
 
 type fakeAgentClient struct {
 	agentv1.AgentClient
-	err  error
-	svid *types.X509SVID
+	err        error
+	svid       *types.X509SVID
+	challenges [][]byte
+}
+
+func (c *fakeAgentClient) AttestAgent(ctx context.Context, opts ...grpc.CallOption) (agentv1.Agent_AttestAgentClient, error) {
+	return &fakeAttestAgentClient{client: c, challenges: c.challenges}, nil
+}
+
+type fakeAttestAgentClient struct {
+	agentv1.Agent_AttestAgentClient
+	client     *fakeAgentClient
+	challenges [][]byte
+	params     *agentv1.AttestAgentRequest_Params
+}
+
+func (s *fakeAttestAgentClient) Send(req *agentv1.AttestAgentRequest) error {
+	if params := req.GetParams(); params != nil {
+		s.params = params
+	}
+	return nil
+}
+
+func (s *fakeAttestAgentClient) Recv() (*agentv1.AttestAgentResponse, error) {
+	if s.client.err != nil {
+		return nil, s.client.err
+	}
+	if s.params == nil || len(s.params.Params.GetCsr()) == 0 {
+		return nil, errors.New("malformed param")
+	}
+	if len(s.challenges) > 0 {
+		challenge := s.challenges[0]
+		s.challenges = s.challenges[1:]
+		return &agentv1.AttestAgentResponse{
+			Step: &agentv1.AttestAgentResponse_Challenge{Challenge: challenge},
+		}, nil
+	}
+	return &agentv1.AttestAgentResponse{
+		Step: &agentv1.AttestAgentResponse_Result_{
+			Result: &agentv1.AttestAgentResponse_Result{Svid: s.client.svid},
+		},
+	}, nil
+}
+
+func (s *fakeAttestAgentClient) CloseSend() error {
+	return nil
 }
 
 func (c *fakeAgentClient) RenewAgent(ctx context.Context, in *agentv1.RenewAgentRequest, opts ...grpc.CallOption) (*agentv1.RenewAgentResponse, error) {
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	agentv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/agent/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
+)
+
+// reattestStream implements nodeattestor.ServerStream on top of the
+// AttestAgent RPC. Errors returned by the server are passed through unchanged
+// so that their status code is not lost.
+type reattestStream struct {
+	log    logrus.FieldLogger
+	client agentv1.AgentClient
+	csr    []byte
+	stream agentv1.Agent_AttestAgentClient
+	svid   *types.X509SVID
+}
+
+func (rs *reattestStream) SendAttestationData(ctx context.Context, attestationData nodeattestor.AttestationData) ([]byte, error) {
+	return rs.sendRequest(ctx, &agentv1.AttestAgentRequest{
+		Step: &agentv1.AttestAgentRequest_Params_{
+			Params: &agentv1.AttestAgentRequest_Params{
+				Data: &types.AttestationData{
+					Type:    attestationData.Type,
+					Payload: attestationData.Payload,
+				},
+				Params: &agentv1.AgentX509SVIDParams{
+					Csr: rs.csr,
+				},
+			},
+		},
+	})
+}
+
+func (rs *reattestStream) SendChallengeResponse(ctx context.Context, response []byte) ([]byte, error) {
+	return rs.sendRequest(ctx, &agentv1.AttestAgentRequest{
+		Step: &agentv1.AttestAgentRequest_ChallengeResponse{
+			ChallengeResponse: response,
+		},
+	})
+}
+
+func (rs *reattestStream) sendRequest(ctx context.Context, req *agentv1.AttestAgentRequest) ([]byte, error) {
+	if rs.stream == nil {
+		stream, err := rs.client.AttestAgent(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rs.stream = stream
+	}
+
+	if err := rs.stream.Send(req); err != nil {
+		if errors.Is(err, io.EOF) {
+			// The server closed the stream; the actual error is returned by Recv
+			_, err = rs.stream.Recv()
+		}
+		return nil, err
+	}
+
+	resp, err := rs.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge := resp.GetChallenge(); challenge != nil {
+		return challenge, nil
+	}
+
+	svid := resp.GetResult().GetSvid()
+	if svid == nil || len(svid.CertChain) == 0 {
+		return nil, errors.New("attest response is missing SVID")
+	}
+
+	if err := rs.stream.CloseSend(); err != nil {
+		rs.log.WithError(err).Warn("Failed to close stream send side")
+	}
+
+	rs.svid = svid
+	return nil, nil
+}
@@ -0,0 +1,356 @@
+// Package sigstore verifies cosign-style container image signatures against a
+// set of trusted public keys. Signatures are fetched from the registry that
+// hosts the image, following the cosign tag convention, and verified offline;
+// no transparency log is consulted.
+package sigstore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// SelectorSignedBy is the selector prefix for the ID of a trusted key that
+	// signed the image.
+	SelectorSignedBy = "image-signed-by"
+
+	// SelectorSignatureSubject is the selector prefix for the ID of a trusted
+	// key and the image reference vouched for by a signature verified with
+	// that key.
+	SelectorSignatureSubject = "image-signature-subject"
+
+	// signatureAnnotation is the layer annotation holding the base64 encoded
+	// signature of the layer contents.
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// signatureType is the expected type of the signed payload.
+	signatureType = "cosign container image signature"
+
+	// maxPayloadSize caps the size of a signed payload read from the registry.
+	maxPayloadSize = 1 << 20
+
+	// cacheTTL is how long the verification results for an image digest are
+	// cached. Signatures are rarely added or removed, while every workload
+	// attestation of a container verifies its image.
+	cacheTTL = 5 * time.Minute
+
+	// errorCacheTTL is how long a failure to fetch the signatures of an image
+	// digest is cached, so an unavailable registry does not stall every
+	// workload attestation of the image.
+	errorCacheTTL = 30 * time.Second
+
+	// fetchTimeout bounds the time spent fetching the signatures of an image.
+	// Attestation of the workload waits on the fetch, so it is kept short.
+	fetchTimeout = 5 * time.Second
+)
+
+// HCLConfig is the image signature verification configuration shared by the
+// workload attestors.
+type HCLConfig struct {
+	// PublicKeys maps a key ID, used as the value of the image-signed-by
+	// selector, to the path of a PEM encoded public key.
+	PublicKeys map[string]string `hcl:"public_keys"`
+}
+
+// Verifier verifies image signatures against a set of trusted keys.
+type Verifier struct {
+	keys       []trustedKey
+	remoteOpts []remote.Option
+	clock      clock.Clock
+
+	mtx     sync.Mutex
+	cache   map[string]cacheEntry
+	fetches singleflight.Group
+}
+
+type cacheEntry struct {
+	selectorValues []string
+	err            error
+	expiresAt      time.Time
+}
+
+type trustedKey struct {
+	id  string
+	key crypto.PublicKey
+}
+
+type signature struct {
+	payload []byte
+	sig     []byte
+}
+
+// simpleSigning is the subset of the payload signed by cosign that is
+// relevant for verification.
+type simpleSigning struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// NewVerifier loads the trusted keys in the configuration and returns a
+// verifier that uses the default keychain (i.e. the docker configuration) to
+// authenticate with registries.
+func NewVerifier(config *HCLConfig) (*Verifier, error) {
+	if len(config.PublicKeys) == 0 {
+		return nil, errors.New("at least one public key is required")
+	}
+
+	var keys []trustedKey
+	for id, path := range config.PublicKeys {
+		key, err := pemutil.LoadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load public key %q: %w", id, err)
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, fmt.Errorf("unsupported public key %q type %T", id, key)
+		}
+		keys = append(keys, trustedKey{id: id, key: key})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].id < keys[j].id
+	})
+
+	return &Verifier{
+		keys: keys,
+		remoteOpts: []remote.Option{
+			remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		},
+		clock: clock.New(),
+		cache: make(map[string]cacheEntry),
+	}, nil
+}
+
+// Verify fetches the signatures for the image with the given digest reference
+// (e.g. "registry.example.org/app@sha256:...") and returns selector values for
+// those that verify against a trusted key and were issued for the image
+// digest. An image without signatures yields no selector values. Results are
+// cached per image digest, and failures are cached for a shorter time.
+// Concurrent verifications of the same digest share a single fetch.
+func (v *Verifier) Verify(ctx context.Context, imageRef string) ([]string, error) {
+	digest, err := name.NewDigest(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %w", imageRef, err)
+	}
+
+	if entry, ok := v.getCached(digest.Name()); ok {
+		return entry.selectorValues, entry.err
+	}
+
+	result, err, _ := v.fetches.Do(digest.Name(), func() (interface{}, error) {
+		selectorValues, err := v.verify(ctx, digest)
+		v.setCached(digest.Name(), selectorValues, err)
+		return selectorValues, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), result.([]string)...), nil
+}
+
+func (v *Verifier) verify(ctx context.Context, digest name.Digest) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	signatures, err := v.fetchSignatures(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	signedBy := make(map[string]bool)
+	subjects := make(map[string]bool)
+	for _, signature := range signatures {
+		var keyIDs []string
+		for _, key := range v.keys {
+			if verifySignature(key.key, signature.payload, signature.sig) {
+				keyIDs = append(keyIDs, key.id)
+			}
+		}
+		if len(keyIDs) == 0 {
+			continue
+		}
+
+		subject, ok := checkPayload(signature.payload, digest.DigestStr())
+		if !ok {
+			continue
+		}
+
+		// The subject is only vouched for by the keys that verified the
+		// signature, so it is qualified with their IDs
+		for _, keyID := range keyIDs {
+			signedBy[keyID] = true
+			if subject != "" {
+				subjects[keyID+":"+subject] = true
+			}
+		}
+	}
+
+	var selectorValues []string
+	for _, keyID := range sortedKeys(signedBy) {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", SelectorSignedBy, keyID))
+	}
+	for _, subject := range sortedKeys(subjects) {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", SelectorSignatureSubject, subject))
+	}
+
+	return selectorValues, nil
+}
+
+func (v *Verifier) getCached(digest string) (cacheEntry, bool) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	entry, ok := v.cache[digest]
+	if !ok || !v.clock.Now().Before(entry.expiresAt) {
+		return cacheEntry{}, false
+	}
+	entry.selectorValues = append([]string(nil), entry.selectorValues...)
+	return entry, true
+}
+
+func (v *Verifier) setCached(digest string, selectorValues []string, err error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	now := v.clock.Now()
+	for key, entry := range v.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(v.cache, key)
+		}
+	}
+	ttl := cacheTTL
+	if err != nil {
+		ttl = errorCacheTTL
+	}
+	v.cache[digest] = cacheEntry{
+		selectorValues: append([]string(nil), selectorValues...),
+		err:            err,
+		expiresAt:      now.Add(ttl),
+	}
+}
+
+// fetchSignatures fetches the signatures for the image digest. Cosign stores
+// the signatures in the repository of the image, under a tag derived from the
+// image digest (i.e. sha256-<hex>.sig), with one layer per signature.
+func (v *Verifier) fetchSignatures(ctx context.Context, digest name.Digest) ([]signature, error) {
+	tag := digest.Context().Tag(strings.Replace(digest.DigestStr(), ":", "-", 1) + ".sig")
+
+	opts := append([]remote.Option{remote.WithContext(ctx)}, v.remoteOpts...)
+	img, err := remote.Image(tag, opts...)
+	var transportErr *transport.Error
+	switch {
+	case errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("unable to fetch signatures for %s: %w", digest, err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch signature manifest for %s: %w", digest, err)
+	}
+
+	var signatures []signature
+	for _, desc := range manifest.Layers {
+		encodedSig, ok := desc.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(encodedSig)
+		if err != nil {
+			// Malformed signatures can never verify; skip them
+			continue
+		}
+		if desc.Size > maxPayloadSize {
+			continue
+		}
+
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch signature payload for %s: %w", digest, err)
+		}
+		payload, err := readPayload(layer.Compressed)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch signature payload for %s: %w", digest, err)
+		}
+		signatures = append(signatures, signature{payload: payload, sig: sig})
+	}
+	return signatures, nil
+}
+
+func readPayload(open func() (io.ReadCloser, error)) ([]byte, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// The reader verifies the layer digest once fully consumed
+	return io.ReadAll(io.LimitReader(rc, maxPayloadSize))
+}
+
+func verifySignature(key crypto.PublicKey, payload, sig []byte) bool {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha256.Sum256(payload)
+		return ecdsa.VerifyASN1(key, hash[:], sig)
+	case *rsa.PublicKey:
+		hash := sha256.Sum256(payload)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, sig)
+	default:
+		return false
+	}
+}
+
+// checkPayload returns the signed image reference if the payload is a cosign
+// signature for the given digest.
+func checkPayload(payload []byte, digest string) (string, bool) {
+	var ss simpleSigning
+	if err := json.Unmarshal(payload, &ss); err != nil {
+		return "", false
+	}
+	if ss.Critical.Type != signatureType || ss.Critical.Image.DockerManifestDigest != digest {
+		return "", false
+	}
+	return ss.Critical.Identity.DockerReference, true
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,208 @@
+package sigstore
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/fakes/fakeregistry"
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVerifier(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := fakeregistry.WritePublicKey(t, dir, "key", testkey.NewEC256(t))
+
+	_, err := NewVerifier(&HCLConfig{})
+	require.EqualError(t, err, "at least one public key is required")
+
+	_, err = NewVerifier(&HCLConfig{PublicKeys: map[string]string{"missing": filepath.Join(dir, "missing.pem")}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unable to load public key "missing"`)
+
+	v, err := NewVerifier(&HCLConfig{PublicKeys: map[string]string{"b": keyPath, "a": keyPath}})
+	require.NoError(t, err)
+	require.Len(t, v.keys, 2)
+	require.Equal(t, "a", v.keys[0].id)
+	require.Equal(t, "b", v.keys[1].id)
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	ecKey := testkey.NewEC256(t)
+	rsaKey := testkey.NewRSA2048(t)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	untrustedKey := testkey.NewEC384(t)
+
+	v, err := NewVerifier(&HCLConfig{
+		PublicKeys: map[string]string{
+			"ec":  fakeregistry.WritePublicKey(t, dir, "ec", ecKey),
+			"rsa": fakeregistry.WritePublicKey(t, dir, "rsa", rsaKey),
+			"ed":  fakeregistry.WritePublicKey(t, dir, "ed", edKey),
+		},
+	})
+	require.NoError(t, err)
+
+	registry := fakeregistry.New(t)
+	unsigned := registry.PushImage(t, "unsigned")
+
+	signed := registry.PushImage(t, "signed")
+	registry.Sign(t, signed, ecKey, "example.org/signed:v1")
+	registry.Sign(t, signed, edKey, "example.org/signed:v1")
+	registry.Sign(t, signed, rsaKey, "example.org/signed:latest")
+
+	untrusted := registry.PushImage(t, "untrusted")
+	registry.Sign(t, untrusted, untrustedKey, "example.org/untrusted:v1")
+
+	// a valid signature that was issued for another image
+	replayed := registry.PushImage(t, "replayed")
+	payload := fakeregistry.Payload(t, signed, "example.org/signed:v1")
+	registry.AddSignature(t, replayed, payload, fakeregistry.SignPayload(t, ecKey, payload))
+
+	// a signature over a payload that is not a cosign signature
+	malformed := registry.PushImage(t, "malformed")
+	payload = []byte("not json")
+	registry.AddSignature(t, malformed, payload, fakeregistry.SignPayload(t, ecKey, payload))
+
+	for _, tt := range []struct {
+		name      string
+		imageRef  string
+		expected  []string
+		expectErr string
+	}{
+		{
+			name:     "unsigned",
+			imageRef: unsigned,
+		},
+		{
+			name:     "signed",
+			imageRef: signed,
+			expected: []string{
+				"image-signed-by:ec",
+				"image-signed-by:ed",
+				"image-signed-by:rsa",
+				"image-signature-subject:ec:example.org/signed:v1",
+				"image-signature-subject:ed:example.org/signed:v1",
+				"image-signature-subject:rsa:example.org/signed:latest",
+			},
+		},
+		{
+			name:     "signed by untrusted key",
+			imageRef: untrusted,
+		},
+		{
+			name:     "signature for another image",
+			imageRef: replayed,
+		},
+		{
+			name:     "malformed payload",
+			imageRef: malformed,
+		},
+		{
+			name:      "not a digest reference",
+			imageRef:  registry.Host() + "/signed:latest",
+			expectErr: `invalid image reference "` + registry.Host() + `/signed:latest"`,
+		},
+		{
+			name:      "registry unavailable",
+			imageRef:  "localhost:1/app@sha256:0123456789012345678901234567890123456789012345678901234567890123",
+			expectErr: "unable to fetch signatures for localhost:1/app@sha256:0123456789012345678901234567890123456789012345678901234567890123",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			selectorValues, err := v.Verify(context.Background(), tt.imageRef)
+			if tt.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, selectorValues)
+		})
+	}
+}
+
+func TestVerifyCache(t *testing.T) {
+	dir := t.TempDir()
+	key := testkey.NewEC256(t)
+	clk := clock.NewMock(t)
+
+	v, err := NewVerifier(&HCLConfig{
+		PublicKeys: map[string]string{
+			"key": fakeregistry.WritePublicKey(t, dir, "key", key),
+		},
+	})
+	require.NoError(t, err)
+	v.clock = clk
+
+	registry := fakeregistry.New(t)
+	image := registry.PushImage(t, "app")
+
+	selectorValues, err := v.Verify(context.Background(), image)
+	require.NoError(t, err)
+	require.Empty(t, selectorValues)
+
+	// The result is cached, so a new signature is not seen until the cached
+	// result expires
+	registry.Sign(t, image, key, "example.org/app:v1")
+	selectorValues, err = v.Verify(context.Background(), image)
+	require.NoError(t, err)
+	require.Empty(t, selectorValues)
+
+	clk.Add(cacheTTL)
+	selectorValues, err = v.Verify(context.Background(), image)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"image-signed-by:key",
+		"image-signature-subject:key:example.org/app:v1",
+	}, selectorValues)
+}
+
+func TestVerifyCachesFailures(t *testing.T) {
+	dir := t.TempDir()
+	clk := clock.NewMock(t)
+
+	v, err := NewVerifier(&HCLConfig{
+		PublicKeys: map[string]string{
+			"key": fakeregistry.WritePublicKey(t, dir, "key", testkey.NewEC256(t)),
+		},
+	})
+	require.NoError(t, err)
+	v.clock = clk
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app@sha256:0123456789012345678901234567890123456789012345678901234567890123"
+
+	_, err = v.Verify(context.Background(), image)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to fetch signatures")
+	fetched := atomic.LoadInt32(&requests)
+	require.NotZero(t, fetched)
+
+	// The failure is cached, so the registry is not contacted again
+	_, err = v.Verify(context.Background(), image)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to fetch signatures")
+	require.Equal(t, fetched, atomic.LoadInt32(&requests))
+
+	// Failures are cached for a shorter time than results
+	clk.Add(errorCacheTTL)
+	_, err = v.Verify(context.Background(), image)
+	require.Error(t, err)
+	require.Greater(t, atomic.LoadInt32(&requests), fetched)
+}
@@ -48,6 +48,10 @@ type Config struct {
 	// SyncInterval controls how often the agent sync synchronizer waits
 	SyncInterval time.Duration
 
+	// WorkloadAttestationCacheTTL controls how long workload attestation
+	// results are cached. If zero, results are not cached.
+	WorkloadAttestationCacheTTL time.Duration
+
 	// Trust domain and associated CA bundle
 	TrustDomain spiffeid.TrustDomain
 	TrustBundle []*x509.Certificate
@@ -55,6 +59,10 @@ type Config struct {
 	// Join token to use for attestation, if needed
 	JoinToken string
 
+	// ReattestToRenew makes the agent re-attest when its SVID is due for
+	// rotation instead of renewing it
+	ReattestToRenew bool
+
 	// If true enables profiling.
 	ProfilingEnabled bool
 
@@ -33,6 +33,10 @@ type Config struct {
 	RotationInterval time.Duration
 	SVIDStoreCache   *storecache.Cache
 
+	// ReattestToRenew makes the agent re-attest using its node attestor
+	// instead of renewing its SVID
+	ReattestToRenew bool
+
 	// Clk is the clock the manager will use to get time
 	Clk clock.Clock
 }
@@ -69,6 +73,9 @@ func newManager(c *Config) *manager {
 		Interval:       c.RotationInterval,
 		Clk:            c.Clk,
 	}
+	if c.ReattestToRenew {
+		rotCfg.NodeAttestor = c.Catalog.GetNodeAttestor()
+	}
 	svidRotator, client := svid.NewRotator(rotCfg)
 
 	m := &manager{
@@ -0,0 +1,248 @@
+package httpchallenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/plugin/httpchallenge"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultAgentName = "default"
+	defaultPort      = 80
+
+	shutdownTimeout = 5 * time.Second
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(httpchallenge.PluginName,
+		nodeattestorv1.NodeAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// Config is the configuration of the http_challenge agent node attestor.
+type Config struct {
+	// HostName is the DNS name the server fetches the challenge from.
+	// Defaults to the hostname of the machine.
+	HostName string `hcl:"hostname"`
+	// AgentName distinguishes multiple agents on the same host. Defaults to
+	// "default".
+	AgentName string `hcl:"agentname"`
+	// Port is the port the challenge is served on. If 0, a random port is
+	// used. Defaults to 80.
+	Port *int `hcl:"port"`
+	// AdvertisedPort is the port the server connects to, for when the agent
+	// is behind a port mapping. Defaults to the port the agent listens on.
+	AdvertisedPort int `hcl:"advertised_port"`
+}
+
+type configuration struct {
+	attestationData httpchallenge.AttestationData
+	port            int
+}
+
+// Plugin proves control of a DNS name by serving a challenge issued by the
+// server over HTTP.
+type Plugin struct {
+	nodeattestorv1.UnsafeNodeAttestorServer
+	configv1.UnsafeConfigServer
+
+	log hclog.Logger
+
+	mu     sync.RWMutex
+	config *configuration
+
+	hooks struct {
+		hostname func() (string, error)
+	}
+}
+
+func New() *Plugin {
+	p := &Plugin{}
+	p.hooks.hostname = os.Hostname
+	return p
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) AidAttestation(stream nodeattestorv1.NodeAttestor_AidAttestationServer) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	// The listener is opened before sending the attestation data so that
+	// the port is known (and reserved) when a random port is configured.
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.port))
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to listen for the challenge: %v", err)
+	}
+	defer listener.Close()
+
+	attestationData := config.attestationData
+	if attestationData.Port == 0 {
+		attestationData.Port = listener.Addr().(*net.TCPAddr).Port
+	}
+
+	payload, err := json.Marshal(attestationData)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal attestation data: %v", err)
+	}
+	if err := stream.Send(&nodeattestorv1.PayloadOrChallengeResponse{
+		Data: &nodeattestorv1.PayloadOrChallengeResponse_Payload{
+			Payload: payload,
+		},
+	}); err != nil {
+		return err
+	}
+
+	challengeReq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	challenge := new(httpchallenge.Challenge)
+	if err := json.Unmarshal(challengeReq.Challenge, challenge); err != nil {
+		return status.Errorf(codes.Internal, "unable to unmarshal challenge: %v", err)
+	}
+
+	server := &http.Server{
+		Handler:           challengeHandler(attestationData.AgentName, challenge),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			p.log.Warn("Failed to shut down challenge server", telemetry.Error, err)
+		}
+		if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.log.Warn("Challenge server failed", telemetry.Error, err)
+		}
+	}()
+
+	response, err := json.Marshal(httpchallenge.Response{Ready: true})
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal challenge response: %v", err)
+	}
+	if err := stream.Send(&nodeattestorv1.PayloadOrChallengeResponse{
+		Data: &nodeattestorv1.PayloadOrChallengeResponse_ChallengeResponse{
+			ChallengeResponse: response,
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Keep serving the challenge until the server has verified it. The
+	// stream is closed once attestation completes.
+	_, _ = stream.Recv()
+	return nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	hclConfig := new(Config)
+	if err := hcl.Decode(hclConfig, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	hostName := hclConfig.HostName
+	if hostName == "" {
+		var err error
+		hostName, err = p.hooks.hostname()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to get hostname: %v", err)
+		}
+	}
+	agentName := hclConfig.AgentName
+	if agentName == "" {
+		agentName = defaultAgentName
+	}
+	port := defaultPort
+	if hclConfig.Port != nil {
+		port = *hclConfig.Port
+	}
+	if port < 0 || port > 65535 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid port %d", port)
+	}
+	if hclConfig.AdvertisedPort < 0 || hclConfig.AdvertisedPort > 65535 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid advertised_port %d", hclConfig.AdvertisedPort)
+	}
+
+	config := &configuration{
+		attestationData: httpchallenge.AttestationData{
+			HostName:  hostName,
+			AgentName: agentName,
+			Port:      port,
+		},
+		port: port,
+	}
+	if hclConfig.AdvertisedPort != 0 {
+		config.attestationData.Port = hclConfig.AdvertisedPort
+	}
+
+	// Validate with a placeholder port when a random port is used since the
+	// port is only known at attestation time
+	validationData := config.attestationData
+	if validationData.Port == 0 {
+		validationData.Port = 1
+	}
+	if err := httpchallenge.ValidateAttestationData(&validationData); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid configuration: %v", err)
+	}
+
+	p.setConfig(config)
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfig() (*configuration, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+func (p *Plugin) setConfig(config *configuration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func challengeHandler(agentName string, challenge *httpchallenge.Challenge) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(httpchallenge.ChallengePath(agentName), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(challenge.Nonce))
+	})
+	return mux
+}
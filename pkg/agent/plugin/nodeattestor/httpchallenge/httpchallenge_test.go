@@ -0,0 +1,127 @@
+package httpchallenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
+	nodeattestortest "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/test"
+	"github.com/spiffe/spire/pkg/common/plugin/httpchallenge"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	streamBuilder = nodeattestortest.ServerStream(httpchallenge.PluginName)
+)
+
+func TestAttestNotConfigured(t *testing.T) {
+	na := loadPlugin(t)
+	err := na.Attest(context.Background(), streamBuilder.Build())
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "nodeattestor(http_challenge): not configured")
+}
+
+func TestAttest(t *testing.T) {
+	na := loadPlugin(t, plugintest.Configure(`
+		hostname = "localhost"
+		agentname = "agent-1"
+		port = 0
+	`))
+
+	challenge := &httpchallenge.Challenge{Nonce: "NONCE"}
+	challengeBytes, err := json.Marshal(challenge)
+	require.NoError(t, err)
+
+	var data httpchallenge.AttestationData
+	stream := streamBuilder.
+		Handle(func(payload []byte) ([]byte, error) {
+			if err := json.Unmarshal(payload, &data); err != nil {
+				return nil, err
+			}
+			return challengeBytes, nil
+		}).
+		Handle(func(response []byte) ([]byte, error) {
+			if string(response) != `{"ready":true}` {
+				return nil, errors.New("unexpected challenge response")
+			}
+			// Another agent name does not serve the challenge
+			otherData := data
+			otherData.AgentName = "agent-2"
+			if err := httpchallenge.VerifyChallenge(context.Background(), http.DefaultClient, &otherData, challenge); err == nil {
+				return nil, errors.New("challenge was served for another agent name")
+			}
+			return nil, httpchallenge.VerifyChallenge(context.Background(), http.DefaultClient, &data, challenge)
+		}).Build()
+
+	require.NoError(t, na.Attest(context.Background(), stream))
+	assert.Equal(t, "localhost", data.HostName)
+	assert.Equal(t, "agent-1", data.AgentName)
+	assert.NotZero(t, data.Port)
+
+	// The challenge is no longer served once attestation completes
+	require.Error(t, httpchallenge.VerifyChallenge(context.Background(), http.DefaultClient, &data, challenge))
+}
+
+func TestAttestAdvertisedPort(t *testing.T) {
+	na := loadPlugin(t, plugintest.Configure(`
+		hostname = "localhost"
+		port = 0
+		advertised_port = 8443
+	`))
+
+	stream := streamBuilder.Handle(func(payload []byte) ([]byte, error) {
+		return nil, errors.New(string(payload))
+	}).Build()
+	err := na.Attest(context.Background(), stream)
+	require.Error(t, err)
+	assert.Equal(t, `{"hostname":"localhost","agentname":"default","port":8443}`, err.Error())
+}
+
+func TestConfigure(t *testing.T) {
+	var err error
+
+	// malformed configuration
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure("malformed"))
+	spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, "unable to decode configuration")
+
+	// invalid hostname
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure(`hostname = "local_host"`))
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, `invalid configuration: invalid hostname "local_host"`)
+
+	// invalid agent name
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure(`hostname = "localhost" agentname = "a/b"`))
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, `invalid configuration: invalid agent name "a/b"`)
+
+	// invalid port
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure(`hostname = "localhost" port = 70000`))
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, "invalid port 70000")
+
+	// invalid advertised port
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure(`hostname = "localhost" advertised_port = -1`))
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, "invalid advertised_port -1")
+
+	// hostname defaults to the machine hostname
+	p := New()
+	p.hooks.hostname = func() (string, error) { return "node1.example.org", nil }
+	plugintest.Load(t, builtin(p), nil, plugintest.CaptureConfigureError(&err), plugintest.Configure(""))
+	require.NoError(t, err)
+	config, err := p.getConfig()
+	require.NoError(t, err)
+	assert.Equal(t, httpchallenge.AttestationData{
+		HostName:  "node1.example.org",
+		AgentName: "default",
+		Port:      80,
+	}, config.attestationData)
+}
+
+func loadPlugin(t *testing.T, options ...plugintest.Option) nodeattestor.NodeAttestor {
+	na := new(nodeattestor.V1)
+	plugintest.Load(t, BuiltIn(), na, options...)
+	return na
+}
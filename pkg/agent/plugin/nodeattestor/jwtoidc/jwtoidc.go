@@ -0,0 +1,141 @@
+package jwtoidc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/plugin/jwtoidc"
+	"github.com/zeebo/errs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *AttestorPlugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(jwtoidc.PluginName,
+		nodeattestorv1.NodeAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// New creates a new jwt_oidc attestor plugin
+func New() *AttestorPlugin {
+	return &AttestorPlugin{}
+}
+
+// AttestorPlugin sends a JWT issued by an OpenID Connect provider, e.g. a CI
+// system, to the server.
+type AttestorPlugin struct {
+	nodeattestorv1.UnsafeNodeAttestorServer
+	configv1.UnsafeConfigServer
+
+	mu     sync.RWMutex
+	config *attestorConfig
+}
+
+// AttestorConfig holds configuration for AttestorPlugin
+type AttestorConfig struct {
+	// File path of the token
+	TokenPath string `hcl:"token_path"`
+	// Name of the environment variable holding the token
+	TokenEnv string `hcl:"token_env"`
+}
+
+type attestorConfig struct {
+	tokenPath string
+	tokenEnv  string
+}
+
+// AidAttestation loads the token from the configured source. The token is
+// loaded on every attestation since these tokens are usually short-lived and
+// rotated by the issuer.
+func (p *AttestorPlugin) AidAttestation(stream nodeattestorv1.NodeAttestor_AidAttestationServer) error {
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	var token string
+	if config.tokenPath != "" {
+		token, err = loadTokenFromFile(config.tokenPath)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "unable to load token from %s: %v", config.tokenPath, err)
+		}
+	} else {
+		token = strings.TrimSpace(os.Getenv(config.tokenEnv))
+		if token == "" {
+			return status.Errorf(codes.InvalidArgument, "environment variable %s is not set", config.tokenEnv)
+		}
+	}
+
+	payload, err := json.Marshal(jwtoidc.AttestationData{
+		Token: token,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal attestation data: %v", err)
+	}
+
+	return stream.Send(&nodeattestorv1.PayloadOrChallengeResponse{
+		Data: &nodeattestorv1.PayloadOrChallengeResponse_Payload{
+			Payload: payload,
+		},
+	})
+}
+
+// Configure decodes JSON config from request and populates AttestorPlugin with it
+func (p *AttestorPlugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	hclConfig := new(AttestorConfig)
+	if err := hcl.Decode(hclConfig, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	switch {
+	case hclConfig.TokenPath == "" && hclConfig.TokenEnv == "":
+		return nil, status.Error(codes.InvalidArgument, "one of token_path or token_env must be set")
+	case hclConfig.TokenPath != "" && hclConfig.TokenEnv != "":
+		return nil, status.Error(codes.InvalidArgument, "token_path and token_env are mutually exclusive")
+	}
+
+	p.setConfig(&attestorConfig{
+		tokenPath: hclConfig.TokenPath,
+		tokenEnv:  hclConfig.TokenEnv,
+	})
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *AttestorPlugin) getConfig() (*attestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+func (p *AttestorPlugin) setConfig(config *attestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func loadTokenFromFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errs.New("%q is empty", path)
+	}
+	return token, nil
+}
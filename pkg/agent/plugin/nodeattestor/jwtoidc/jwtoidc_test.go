@@ -0,0 +1,87 @@
+package jwtoidc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
+	nodeattestortest "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/test"
+	"github.com/spiffe/spire/pkg/common/plugin/jwtoidc"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	streamBuilder = nodeattestortest.ServerStream(jwtoidc.PluginName)
+)
+
+func TestAttestNotConfigured(t *testing.T) {
+	na := loadPlugin(t)
+	err := na.Attest(context.Background(), streamBuilder.Build())
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "nodeattestor(jwt_oidc): not configured")
+}
+
+func TestAttestFromFile(t *testing.T) {
+	tokenPath := filepath.Join(spiretest.TempDir(t), "token")
+	na := loadPlugin(t, plugintest.Configuref(`token_path = %q`, tokenPath))
+
+	// missing token file
+	err := na.Attest(context.Background(), streamBuilder.Build())
+	spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, "nodeattestor(jwt_oidc): unable to load token from")
+
+	// empty token file
+	require.NoError(t, os.WriteFile(tokenPath, []byte("\n"), 0600))
+	err = na.Attest(context.Background(), streamBuilder.Build())
+	spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, "is empty")
+
+	// the token is reloaded on every attestation
+	require.NoError(t, os.WriteFile(tokenPath, []byte("TOKEN1\n"), 0600))
+	err = na.Attest(context.Background(), streamBuilder.ExpectAndBuild([]byte(`{"token":"TOKEN1"}`)))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(tokenPath, []byte("TOKEN2"), 0600))
+	err = na.Attest(context.Background(), streamBuilder.ExpectAndBuild([]byte(`{"token":"TOKEN2"}`)))
+	require.NoError(t, err)
+}
+
+func TestAttestFromEnv(t *testing.T) {
+	na := loadPlugin(t, plugintest.Configure(`token_env = "SPIRE_TEST_OIDC_TOKEN"`))
+
+	t.Setenv("SPIRE_TEST_OIDC_TOKEN", "")
+	err := na.Attest(context.Background(), streamBuilder.Build())
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, "nodeattestor(jwt_oidc): environment variable SPIRE_TEST_OIDC_TOKEN is not set")
+
+	t.Setenv("SPIRE_TEST_OIDC_TOKEN", "TOKEN")
+	err = na.Attest(context.Background(), streamBuilder.ExpectAndBuild([]byte(`{"token":"TOKEN"}`)))
+	require.NoError(t, err)
+}
+
+func TestConfigure(t *testing.T) {
+	var err error
+
+	// malformed configuration
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure("malformed"))
+	spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, "unable to decode configuration")
+
+	// no token source
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure(""))
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, "one of token_path or token_env must be set")
+
+	// both token sources
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure(`token_path = "/token" token_env = "TOKEN"`))
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, "token_path and token_env are mutually exclusive")
+
+	// success
+	loadPlugin(t, plugintest.CaptureConfigureError(&err), plugintest.Configure(`token_path = "/token"`))
+	require.NoError(t, err)
+}
+
+func loadPlugin(t *testing.T, options ...plugintest.Option) nodeattestor.NodeAttestor {
+	na := new(nodeattestor.V1)
+	plugintest.Load(t, BuiltIn(), na, options...)
+	return na
+}
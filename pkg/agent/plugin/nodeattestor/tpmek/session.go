@@ -0,0 +1,284 @@
+package tpmek
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm-tools/client"
+	"github.com/google/go-tpm/tpm2"
+	tpm2util "github.com/google/go-tpm/tpmutil"
+	"github.com/hashicorp/go-hclog"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmdevid/tpmutil"
+	"github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+	common_ek "github.com/spiffe/spire/pkg/common/plugin/tpmek"
+)
+
+// randomPasswordSize is the number of bytes of generated random passwords
+const randomPasswordSize = 32
+
+// session represents a TPM with a loaded attestation key and the regenerated
+// endorsement key.
+type session struct {
+	akHandle   tpm2util.Handle
+	akPassword string
+	akPub      []byte
+	ekHandle   tpm2util.Handle
+
+	endorsementHierarchyPassword string
+
+	rwc io.ReadWriteCloser
+	log hclog.Logger
+}
+
+// newSession opens a connection to the TPM, creates an attestation key and
+// regenerates the endorsement key using the default RSA template.
+func newSession(devicePath string, passwords tpmutil.TPMPasswords, log hclog.Logger) (_ *session, err error) {
+	rwc, err := tpmutil.OpenTPM(devicePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open TPM at %q: %w", devicePath, err)
+	}
+
+	s := &session{
+		rwc:                          rwc,
+		log:                          log,
+		endorsementHierarchyPassword: passwords.EndorsementHierarchy,
+	}
+
+	// Close session in case of error
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	srkPassword, err := newRandomPassword()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate random password for storage root key: %w", err)
+	}
+
+	s.akPassword, err = newRandomPassword()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate random password for attestation key: %w", err)
+	}
+
+	if err := s.loadAttestationKey(passwords.OwnerHierarchy, srkPassword); err != nil {
+		return nil, err
+	}
+
+	s.ekHandle, _, _, _, _, _, err = tpm2.CreatePrimaryEx(rwc, tpm2.HandleEndorsement,
+		tpm2.PCRSelection{},
+		passwords.EndorsementHierarchy,
+		"",
+		client.DefaultEKTemplateRSA())
+	if err != nil {
+		return nil, fmt.Errorf("cannot create endorsement key: %w", err)
+	}
+
+	return s, nil
+}
+
+// Close unloads TPM loaded objects and closes the connection to the TPM.
+func (s *session) Close() {
+	if s.akHandle != 0 {
+		s.flushContext(s.akHandle)
+	}
+
+	if s.ekHandle != 0 {
+		s.flushContext(s.ekHandle)
+	}
+
+	if err := s.rwc.Close(); err != nil {
+		s.log.Warn(fmt.Sprintf("Failed to close TPM: %v", err))
+	}
+}
+
+// GetEKCert returns the TPM endorsement certificate, or nil if the TPM does
+// not have one provisioned.
+func (s *session) GetEKCert() ([]byte, error) {
+	// NVRead does not preserve the TPM error, so the index is looked up first
+	// to tell a missing certificate apart from a failure
+	if _, err := tpm2.NVReadPublic(s.rwc, tpmutil.EKCertificateHandleRSA); err != nil {
+		var handleErr tpm2.HandleError
+		if errors.As(err, &handleErr) && handleErr.Code == tpm2.RCHandle {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read public area of NV index %08x: %w", tpmutil.EKCertificateHandleRSA, err)
+	}
+
+	ekCertAndTrailingBytes, err := tpm2.NVRead(s.rwc, tpmutil.EKCertificateHandleRSA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NV index %08x: %w", tpmutil.EKCertificateHandleRSA, err)
+	}
+
+	// Some TPMs return the DER encoded certificate followed by trailing data
+	var ekCert asn1.RawValue
+	if _, err := asn1.Unmarshal(ekCertAndTrailingBytes, &ekCert); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall certificate read from %08x: %w", tpmutil.EKCertificateHandleRSA, err)
+	}
+
+	return ekCert.FullBytes, nil
+}
+
+// GetEKPublic returns the public part of the endorsement key encoded in TPM
+// wire format.
+func (s *session) GetEKPublic() ([]byte, error) {
+	publicEK, _, _, err := tpm2.ReadPublic(s.rwc, s.ekHandle)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read EK from handle: %w", err)
+	}
+
+	encodedPublicEK, err := publicEK.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encode failed: %w", err)
+	}
+
+	return encodedPublicEK, nil
+}
+
+// GetAKPublic returns the public part of the attestation key encoded in TPM
+// wire format.
+func (s *session) GetAKPublic() []byte {
+	return s.akPub
+}
+
+// SolveCredActivationChallenge runs credential activation on the TPM. It
+// proves that the attestation key resides on the same TPM as the endorsement
+// key.
+func (s *session) SolveCredActivationChallenge(credentialBlob, secret []byte) ([]byte, error) {
+	hSession, err := s.createPolicySessionForEK()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tpm2.ActivateCredentialUsingAuth(
+		s.rwc,
+		[]tpm2.AuthCommand{
+			{Session: tpm2.HandlePasswordSession, Auth: []byte(s.akPassword)},
+			{Session: hSession},
+		},
+		s.akHandle,
+		s.ekHandle,
+		credentialBlob,
+		secret,
+	)
+	if err != nil {
+		// Flush only in case of error. If the command executes successfully it
+		// closes the session.
+		s.flushContext(hSession)
+		return nil, fmt.Errorf("failed to activate credential: %w", err)
+	}
+
+	return b, nil
+}
+
+// QuotePCRs signs the given PCRs of the selector bank with the attestation
+// key and returns the quote, its signature and the current PCR values.
+func (s *session) QuotePCRs(pcrs []int, nonce []byte) ([]byte, []byte, map[int][]byte, error) {
+	sel := tpm2.PCRSelection{Hash: common_ek.PCRHashAlg, PCRs: pcrs}
+
+	quote, sig, err := tpm2.Quote(s.rwc, s.akHandle, s.akPassword, "", nonce, sel, tpm2.AlgNull)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to quote PCRs: %w", err)
+	}
+	if sig.RSA == nil {
+		return nil, nil, nil, errors.New("unexpected quote signature type")
+	}
+
+	values, err := client.ReadPCRs(s.rwc, sel)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read PCRs: %w", err)
+	}
+
+	pcrValues := make(map[int][]byte, len(values.Pcrs))
+	for pcr, value := range values.Pcrs {
+		pcrValues[int(pcr)] = value
+	}
+
+	return quote, sig.RSA.Signature, pcrValues, nil
+}
+
+func (s *session) loadAttestationKey(ownerHierarchyPassword, srkPassword string) error {
+	srkHandle, _, _, _, _, _, err := tpm2.CreatePrimaryEx(s.rwc,
+		tpm2.HandleOwner,
+		tpm2.PCRSelection{},
+		ownerHierarchyPassword,
+		srkPassword,
+		tpmutil.SRKTemplateHighRSA())
+	if err != nil {
+		return fmt.Errorf("failed to create SRK: %w", err)
+	}
+	defer s.flushContext(srkHandle)
+
+	akPriv, akPub, _, _, _, err := tpm2.CreateKey(s.rwc,
+		srkHandle,
+		tpm2.PCRSelection{},
+		srkPassword,
+		s.akPassword,
+		client.AKTemplateRSA())
+	if err != nil {
+		return fmt.Errorf("failed to create AK: %w", err)
+	}
+
+	s.akHandle, _, err = tpm2.Load(s.rwc, srkHandle, srkPassword, akPub, akPriv)
+	if err != nil {
+		return fmt.Errorf("failed to load AK: %w", err)
+	}
+	s.akPub = akPub
+
+	return nil
+}
+
+// createPolicySessionForEK creates a session-based authorization to access
+// the EK, which is required to run the activate credential command because
+// of the attributes of the EK template.
+func (s *session) createPolicySessionForEK() (tpm2util.Handle, error) {
+	hSession, _, err := tpm2.StartAuthSession(
+		s.rwc,
+		tpm2.HandleNull,
+		tpm2.HandleNull,
+		make([]byte, 16),
+		nil,
+		tpm2.SessionPolicy,
+		tpm2.AlgNull,
+		tpm2.AlgSHA256,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tpm2.PolicySecret(
+		s.rwc,
+		tpm2.HandleEndorsement,
+		tpm2.AuthCommand{
+			Session: tpm2.HandlePasswordSession,
+			Auth:    []byte(s.endorsementHierarchyPassword),
+		},
+		hSession,
+		nil,
+		nil,
+		nil,
+		0,
+	)
+	if err != nil {
+		s.flushContext(hSession)
+		return 0, err
+	}
+
+	return hSession, nil
+}
+
+func (s *session) flushContext(handle tpm2util.Handle) {
+	if err := tpm2.FlushContext(s.rwc, handle); err != nil {
+		s.log.Warn(fmt.Sprintf("Failed to flush handle %v: %v", handle, err))
+	}
+}
+
+func newRandomPassword() (string, error) {
+	rndBytes, err := tpmdevid.GetRandomBytes(randomPasswordSize)
+	if err != nil {
+		return "", err
+	}
+	return string(rndBytes), nil
+}
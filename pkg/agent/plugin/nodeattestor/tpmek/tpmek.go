@@ -0,0 +1,235 @@
+package tpmek
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmdevid/tpmutil"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	common_ek "github.com/spiffe/spire/pkg/common/plugin/tpmek"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const BaseTPMDir = "/dev"
+
+// maxPCRIndex is the highest PCR index defined by the TCG PC Client Platform
+// TPM Profile
+const maxPCRIndex = 23
+
+// Functions defined here are overridden in test files to facilitate unit testing
+var (
+	AutoDetectTPMPath func(string) (string, error) = tpmutil.AutoDetectTPMPath
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(common_ek.PluginName,
+		nodeattestorv1.NodeAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p))
+}
+
+type Config struct {
+	OwnerHierarchyPassword       string `hcl:"owner_hierarchy_password"`
+	EndorsementHierarchyPassword string `hcl:"endorsement_hierarchy_password"`
+
+	DevicePath string `hcl:"tpm_device_path"`
+	PCRs       []int  `hcl:"pcrs"`
+}
+
+type config struct {
+	devicePath string
+	passwords  tpmutil.TPMPasswords
+	pcrs       []int
+}
+
+type Plugin struct {
+	nodeattestorv1.UnsafeNodeAttestorServer
+	configv1.UnsafeConfigServer
+	log hclog.Logger
+
+	m sync.Mutex
+	c *config
+}
+
+func New() *Plugin {
+	return &Plugin{}
+}
+
+func (p *Plugin) AidAttestation(stream nodeattestorv1.NodeAttestor_AidAttestationServer) error {
+	conf := p.getConfig()
+	if conf == nil {
+		return status.Error(codes.FailedPrecondition, "not configured")
+	}
+
+	// Open TPM connection, create the AK and regenerate the EK
+	tpm, err := newSession(conf.devicePath, conf.passwords, p.log)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to start a new TPM session: %v", err)
+	}
+	defer tpm.Close()
+
+	// Get endorsement certificate from TPM NV index, if provisioned
+	ekCert, err := tpm.GetEKCert()
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to get endorsement certificate: %v", err)
+	}
+
+	// Get regenerated endorsement public key
+	ekPub, err := tpm.GetEKPublic()
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to get endorsement public key: %v", err)
+	}
+
+	// Marshal attestation data
+	marshaledAttData, err := json.Marshal(common_ek.AttestationRequest{
+		EKCert: ekCert,
+		EKPub:  ekPub,
+		AKPub:  tpm.GetAKPublic(),
+		PCRs:   conf.pcrs,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal attestation data: %v", err)
+	}
+
+	// Send attestation request
+	err = stream.Send(&nodeattestorv1.PayloadOrChallengeResponse{
+		Data: &nodeattestorv1.PayloadOrChallengeResponse_Payload{
+			Payload: marshaledAttData,
+		},
+	})
+	if err != nil {
+		st := status.Convert(err)
+		return status.Errorf(st.Code(), "unable to send attestation data: %s", st.Message())
+	}
+
+	// Receive challenges
+	marshalledChallenges, err := stream.Recv()
+	if err != nil {
+		st := status.Convert(err)
+		return status.Errorf(st.Code(), "unable to receive challenges: %s", st.Message())
+	}
+
+	challenges := &common_ek.ChallengeRequest{}
+	if err = json.Unmarshal(marshalledChallenges.Challenge, challenges); err != nil {
+		return status.Errorf(codes.InvalidArgument, "unable to unmarshall challenges: %v", err)
+	}
+
+	// Solve Credential Activation challenge
+	if challenges.CredActivation == nil {
+		return status.Error(codes.Internal, "received empty credential activation challenge from server")
+	}
+
+	resp := common_ek.ChallengeResponse{}
+	resp.CredActivation, err = tpm.SolveCredActivationChallenge(
+		challenges.CredActivation.Credential,
+		challenges.CredActivation.Secret)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to solve proof of residency challenge: %v", err)
+	}
+
+	// Quote the configured PCRs
+	if len(conf.pcrs) > 0 {
+		if len(challenges.QuoteNonce) == 0 {
+			return status.Error(codes.Internal, "received empty quote nonce from server")
+		}
+
+		resp.Quote, resp.QuoteSignature, resp.PCRValues, err = tpm.QuotePCRs(conf.pcrs, challenges.QuoteNonce)
+		if err != nil {
+			return status.Errorf(codes.Internal, "unable to quote PCRs: %v", err)
+		}
+	}
+
+	// Marshal challenges responses
+	marshalledChallengeResp, err := json.Marshal(resp)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal challenge response: %v", err)
+	}
+
+	// Send challenge response back to the server
+	err = stream.Send(&nodeattestorv1.PayloadOrChallengeResponse{
+		Data: &nodeattestorv1.PayloadOrChallengeResponse_ChallengeResponse{
+			ChallengeResponse: marshalledChallengeResp,
+		},
+	})
+	if err != nil {
+		st := status.Convert(err)
+		return status.Errorf(st.Code(), "unable to send challenge response: %s", st.Message())
+	}
+
+	return nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	extConf := new(Config)
+	if err := hcl.Decode(extConf, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if err := validatePCRs(extConf.PCRs); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid configuration: %v", err)
+	}
+
+	c := &config{
+		pcrs: extConf.PCRs,
+		passwords: tpmutil.TPMPasswords{
+			OwnerHierarchy:       extConf.OwnerHierarchyPassword,
+			EndorsementHierarchy: extConf.EndorsementHierarchyPassword,
+		},
+	}
+
+	switch {
+	case runtime.GOOS == "windows" && extConf.DevicePath == "":
+		// OK
+	case runtime.GOOS == "windows" && extConf.DevicePath != "":
+		return nil, status.Error(codes.InvalidArgument, "device path is not allowed on windows")
+	case runtime.GOOS != "windows" && extConf.DevicePath != "":
+		c.devicePath = extConf.DevicePath
+	case runtime.GOOS != "windows" && extConf.DevicePath == "":
+		tpmPath, err := AutoDetectTPMPath(BaseTPMDir)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "tpm autodetection failed: %v", err)
+		}
+		c.devicePath = tpmPath
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.c = c
+
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) getConfig() *config {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.c
+}
+
+func validatePCRs(pcrs []int) error {
+	seen := make(map[int]bool, len(pcrs))
+	for _, pcr := range pcrs {
+		if pcr < 0 || pcr > maxPCRIndex {
+			return fmt.Errorf("PCR index %d is out of range", pcr)
+		}
+		if seen[pcr] {
+			return fmt.Errorf("PCR index %d is duplicated", pcr)
+		}
+		seen[pcr] = true
+	}
+	return nil
+}
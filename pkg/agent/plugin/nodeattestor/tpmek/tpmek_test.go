@@ -0,0 +1,332 @@
+package tpmek_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
+	nodeattestortest "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/test"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmdevid/tpmutil"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmek"
+	common_devid "github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+	common_ek "github.com/spiffe/spire/pkg/common/plugin/tpmek"
+	server_devid "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmdevid"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/tpmsimulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	tpmDevicePath = "/dev/tpmrm0"
+
+	tpmPasswords = tpmutil.TPMPasswords{
+		EndorsementHierarchy: "endorsement-hierarchy-pass",
+		OwnerHierarchy:       "owner-hierarchy-pass",
+	}
+
+	streamBuilder = nodeattestortest.ServerStream("tpm_ek")
+	isWindows     = runtime.GOOS == "windows"
+)
+
+func setupSimulator(t *testing.T) *tpmsimulator.TPMSimulator {
+	sim, err := tpmsimulator.New(tpmPasswords.EndorsementHierarchy, tpmPasswords.OwnerHierarchy)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, sim.Close(), "unexpected error encountered closing simulator")
+	})
+
+	// Override OpenTPM fuction to use a simulator instead of a physical TPM
+	tpmutil.OpenTPM = sim.OpenTPM
+
+	if isWindows {
+		tpmDevicePath = ""
+	}
+	tpmek.AutoDetectTPMPath = func(string) (string, error) {
+		if isWindows {
+			return "", errors.New("autodetect is not supported on windows")
+		}
+		return tpmDevicePath, nil
+	}
+	return sim
+}
+
+func TestConfigure(t *testing.T) {
+	setupSimulator(t)
+
+	tests := []struct {
+		name    string
+		hclConf string
+		expErr  string
+		posix   bool
+	}{
+		{
+			name:    "Configure fails if receives wrong HCL configuration",
+			hclConf: "not HCL conf",
+			expErr:  "rpc error: code = InvalidArgument desc = unable to decode configuration",
+		},
+		{
+			name:    "Configure fails if a PCR index is out of range",
+			hclConf: "pcrs = [0, 24]",
+			expErr:  "rpc error: code = InvalidArgument desc = invalid configuration: PCR index 24 is out of range",
+		},
+		{
+			name:    "Configure fails if a PCR index is duplicated",
+			hclConf: "pcrs = [7, 7]",
+			expErr:  "rpc error: code = InvalidArgument desc = invalid configuration: PCR index 7 is duplicated",
+		},
+		{
+			name:    "Configure succeeds auto detecting the TPM path",
+			hclConf: "pcrs = [0, 7]",
+		},
+		{
+			name:    "Configure succeeds with a TPM path",
+			hclConf: `tpm_device_path = "/dev/tpm0"`,
+			posix:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.posix && isWindows {
+				t.Skip()
+			}
+
+			resp, err := tpmek.New().Configure(context.Background(), &configv1.ConfigureRequest{HclConfiguration: tt.hclConf})
+			if tt.expErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expErr)
+				require.Nil(t, resp)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, resp)
+		})
+	}
+}
+
+func TestAidAttestationFailures(t *testing.T) {
+	tests := []struct {
+		name                              string
+		pcrs                              string
+		wrongOwnerHierarchyPassword       bool
+		wrongEndorsementHierarchyPassword bool
+		expErr                            string
+		serverStream                      nodeattestor.ServerStream
+	}{
+		{
+			name:                              "AidAttestation fails if a wrong endorsement hierarchy password is provided",
+			expErr:                            "rpc error: code = Internal desc = nodeattestor(tpm_ek): unable to start a new TPM session: cannot create endorsement key",
+			wrongEndorsementHierarchyPassword: true,
+			serverStream:                      streamBuilder.Build(),
+		},
+		{
+			name:                        "AidAttestation fails if a wrong owner hierarchy password is provided",
+			expErr:                      "rpc error: code = Internal desc = nodeattestor(tpm_ek): unable to start a new TPM session: failed to create SRK",
+			wrongOwnerHierarchyPassword: true,
+			serverStream:                streamBuilder.Build(),
+		},
+		{
+			name:         "AidAttestation fails if server does not sends a challenge",
+			expErr:       "the error",
+			serverStream: streamBuilder.FailAndBuild(errors.New("the error")),
+		},
+		{
+			name:         "AidAttestation fails if agent cannot unmarshall server challenge",
+			expErr:       "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): unable to unmarshall challenges",
+			serverStream: streamBuilder.IgnoreThenChallenge([]byte("not-a-challenge")).Build(),
+		},
+		{
+			name:   "AidAttestation fails if server does not send a proof of residency challenge",
+			expErr: "rpc error: code = Internal desc = nodeattestor(tpm_ek): received empty credential activation challenge from server",
+			serverStream: func() nodeattestor.ServerStream {
+				challenges, err := json.Marshal(common_ek.ChallengeRequest{})
+				require.NoError(t, err)
+				return streamBuilder.IgnoreThenChallenge(challenges).Build()
+			}(),
+		},
+		{
+			name:   "AidAttestation fails if agent fails to solve proof of residency challenge",
+			expErr: "rpc error: code = Internal desc = nodeattestor(tpm_ek): unable to solve proof of residency challenge",
+			serverStream: func() nodeattestor.ServerStream {
+				challenges, err := json.Marshal(common_ek.ChallengeRequest{
+					CredActivation: &common_devid.CredActivation{
+						Credential: []byte("wrong formatted credential"),
+						Secret:     []byte("wrong formatted secret"),
+					},
+				})
+				require.NoError(t, err)
+				return streamBuilder.IgnoreThenChallenge(challenges).Build()
+			}(),
+		},
+		{
+			name:   "AidAttestation fails if server does not send a quote nonce",
+			pcrs:   "pcrs = [0]",
+			expErr: "rpc error: code = Internal desc = nodeattestor(tpm_ek): received empty quote nonce from server",
+			serverStream: streamBuilder.Handle(func(payload []byte) ([]byte, error) {
+				challenge, _, err := newChallenge(payload, nil)
+				return challenge, err
+			}).Build(),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			setupSimulator(t)
+
+			passwords := tpmPasswords
+			if tt.wrongEndorsementHierarchyPassword {
+				passwords.EndorsementHierarchy = "wrong-password"
+			}
+			if tt.wrongOwnerHierarchyPassword {
+				passwords.OwnerHierarchy = "wrong-password"
+			}
+
+			p := loadAndConfigurePlugin(t, passwords, tt.pcrs)
+			err := p.Attest(context.Background(), tt.serverStream)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expErr)
+		})
+	}
+}
+
+func TestAidAttestationSucceeds(t *testing.T) {
+	tests := []struct {
+		name        string
+		pcrs        string
+		removeEK    bool
+		expectPCRs  []int
+		expectEKCrt bool
+	}{
+		{
+			name:        "AidAttestation succeeds",
+			expectEKCrt: true,
+		},
+		{
+			name:        "AidAttestation succeeds quoting PCRs",
+			pcrs:        "pcrs = [0, 7]",
+			expectPCRs:  []int{0, 7},
+			expectEKCrt: true,
+		},
+		{
+			name:     "AidAttestation succeeds without an EK certificate",
+			removeEK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sim := setupSimulator(t)
+			if tt.removeEK {
+				require.NoError(t, tpm2.NVUndefineSpace(sim, "", tpm2.HandlePlatform, tpmutil.EKCertificateHandleRSA))
+			}
+
+			var expectedNonce []byte
+			quoteNonce := []byte("quote-nonce")
+			ss := streamBuilder.Handle(func(payload []byte) ([]byte, error) {
+				attReq := new(common_ek.AttestationRequest)
+				if err := json.Unmarshal(payload, attReq); err != nil {
+					return nil, err
+				}
+				if tt.expectEKCrt != (len(attReq.EKCert) > 0) {
+					return nil, fmt.Errorf("unexpected EK certificate presence: %t", len(attReq.EKCert) > 0)
+				}
+				if !assert.Equal(t, tt.expectPCRs, attReq.PCRs) {
+					return nil, errors.New("unexpected PCR selection")
+				}
+
+				var nonce []byte
+				if len(attReq.PCRs) > 0 {
+					nonce = quoteNonce
+				}
+
+				challenge, credActivationNonce, err := newChallenge(payload, nonce)
+				expectedNonce = credActivationNonce
+				return challenge, err
+			}).Handle(func(challengeResponse []byte) ([]byte, error) {
+				response := new(common_ek.ChallengeResponse)
+				if err := json.Unmarshal(challengeResponse, response); err != nil {
+					return nil, err
+				}
+
+				if err := server_devid.VerifyCredActivationChallenge(expectedNonce, response.CredActivation); err != nil {
+					return nil, err
+				}
+
+				if len(tt.expectPCRs) == 0 {
+					if len(response.Quote) != 0 {
+						return nil, errors.New("unexpected quote")
+					}
+					return nil, nil
+				}
+
+				data, err := tpm2.DecodeAttestationData(response.Quote)
+				if err != nil {
+					return nil, err
+				}
+				if string(data.ExtraData) != string(quoteNonce) {
+					return nil, errors.New("unexpected quote nonce")
+				}
+				if len(response.PCRValues) != len(tt.expectPCRs) {
+					return nil, errors.New("unexpected number of PCR values")
+				}
+				return nil, nil
+			}).Build()
+
+			p := loadAndConfigurePlugin(t, tpmPasswords, tt.pcrs)
+			err := p.Attest(context.Background(), ss)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func newChallenge(payload, quoteNonce []byte) ([]byte, []byte, error) {
+	attReq := new(common_ek.AttestationRequest)
+	if err := json.Unmarshal(payload, attReq); err != nil {
+		return nil, nil, err
+	}
+
+	akPub, err := tpm2.DecodePublic(attReq.AKPub)
+	if err != nil {
+		return nil, nil, err
+	}
+	ekPub, err := tpm2.DecodePublic(attReq.EKPub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credActivation, credActivationNonce, err := server_devid.NewCredActivationChallenge(akPub, ekPub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	challenge, err := json.Marshal(common_ek.ChallengeRequest{
+		CredActivation: credActivation,
+		QuoteNonce:     quoteNonce,
+	})
+	return challenge, credActivationNonce, err
+}
+
+func loadAndConfigurePlugin(t *testing.T, passwords tpmutil.TPMPasswords, extraConfig string) nodeattestor.NodeAttestor {
+	config := fmt.Sprintf(`
+		endorsement_hierarchy_password = %q
+		owner_hierarchy_password = %q
+		%s`,
+		passwords.EndorsementHierarchy,
+		passwords.OwnerHierarchy,
+		extraConfig)
+
+	p := new(nodeattestor.V1)
+	plugintest.Load(t, tpmek.BuiltIn(), p, plugintest.Configure(config))
+	return p
+}
@@ -0,0 +1,424 @@
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/plugin/svidstore"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	svidstorev1 "github.com/spiffe/spire/proto/spire/plugin/agent/svidstore/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "disk"
+
+	svidFileName   = "svid.pem"
+	keyFileName    = "svid_key.pem"
+	bundleFileName = "bundle.pem"
+
+	// federatedBundleFilePrefix and federatedBundleFileSuffix surround the
+	// name of the federated trust domain in the name of the files holding
+	// federated bundles.
+	federatedBundleFilePrefix = "federated_bundle."
+	federatedBundleFileSuffix = ".pem"
+
+	defaultDirectoryMode = 0755
+	defaultCertFileMode  = 0644
+	defaultKeyFileMode   = 0600
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *DiskPlugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		svidstorev1.SVIDStorePluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+func New() *DiskPlugin {
+	p := &DiskPlugin{}
+	p.hooks.chown = func(f *os.File, uid, gid int) error {
+		return f.Chown(uid, gid)
+	}
+
+	return p
+}
+
+type Configuration struct {
+	Directory     string   `hcl:"directory" json:"directory"`
+	DirectoryMode string   `hcl:"directory_mode" json:"directory_mode"`
+	CertFileMode  string   `hcl:"cert_file_mode" json:"cert_file_mode"`
+	KeyFileMode   string   `hcl:"key_file_mode" json:"key_file_mode"`
+	UnusedKeys    []string `hcl:",unusedKeys" json:",omitempty"`
+}
+
+type diskConfig struct {
+	directory     string
+	directoryMode os.FileMode
+	certFileMode  os.FileMode
+	keyFileMode   os.FileMode
+}
+
+type DiskPlugin struct {
+	svidstorev1.UnsafeSVIDStoreServer
+	configv1.UnsafeConfigServer
+
+	log    hclog.Logger
+	mtx    sync.RWMutex
+	config *diskConfig
+
+	hooks struct {
+		chown func(f *os.File, uid, gid int) error
+	}
+}
+
+func (p *DiskPlugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+// Configure configures the DiskPlugin.
+func (p *DiskPlugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	// Parse HCL config payload into config struct
+	config := &Configuration{}
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if len(config.UnusedKeys) != 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown configurations detected: %s", strings.Join(config.UnusedKeys, ","))
+	}
+
+	if config.Directory == "" {
+		return nil, status.Error(codes.InvalidArgument, "directory is required")
+	}
+
+	directoryMode, err := parseFileMode(config.DirectoryMode, defaultDirectoryMode)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid directory_mode: %v", err)
+	}
+	// Only the agent may create files in the directory of an entry,
+	// otherwise the files it writes could be swapped while being written
+	if directoryMode&0022 != 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid directory_mode: %q must not be writable by group or others", config.DirectoryMode)
+	}
+	certFileMode, err := parseFileMode(config.CertFileMode, defaultCertFileMode)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid cert_file_mode: %v", err)
+	}
+	keyFileMode, err := parseFileMode(config.KeyFileMode, defaultKeyFileMode)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid key_file_mode: %v", err)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.config = &diskConfig{
+		directory:     config.Directory,
+		directoryMode: directoryMode,
+		certFileMode:  certFileMode,
+		keyFileMode:   keyFileMode,
+	}
+
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// PutX509SVID writes the specified X509-SVID into the directory of the entry.
+// Every file is written to a temporary file first and then renamed, so
+// readers never observe a partially written file.
+func (p *DiskPlugin) PutX509SVID(ctx context.Context, req *svidstorev1.PutX509SVIDRequest) (*svidstorev1.PutX509SVIDResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opt, err := optionsFromSecretData(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	secretData, err := svidstore.SecretFromProto(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse request: %v", err)
+	}
+
+	federatedBundleFiles := make(map[string]string, len(secretData.FederatedBundles))
+	for id, bundle := range secretData.FederatedBundles {
+		td, err := spiffeid.TrustDomainFromString(id)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to parse request: invalid federated trust domain %q: %v", id, err)
+		}
+		federatedBundleFiles[federatedBundleFilePrefix+td.String()+federatedBundleFileSuffix] = bundle
+	}
+
+	dir := filepath.Join(config.directory, opt.name)
+	if err := os.MkdirAll(dir, config.directoryMode); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create directory: %v", err)
+	}
+	if err := p.setupDirectory(dir, config.directoryMode, opt); err != nil {
+		return nil, err
+	}
+
+	// The key is written before the certificates to keep the time in which
+	// the files do not match short.
+	files := []svidFile{
+		{name: keyFileName, data: secretData.X509SVIDKey, mode: config.keyFileMode},
+		{name: svidFileName, data: secretData.X509SVID, mode: config.certFileMode},
+		{name: bundleFileName, data: secretData.Bundle, mode: config.certFileMode},
+	}
+	for name, bundle := range federatedBundleFiles {
+		files = append(files, svidFile{name: name, data: bundle, mode: config.certFileMode})
+	}
+
+	for _, file := range files {
+		if err := p.writeFile(filepath.Join(dir, file.name), []byte(file.data), file.mode, opt); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to write %s: %v", file.name, err)
+		}
+	}
+
+	// Remove the bundles of trust domains that are no longer federated with
+	if err := removeFederatedBundles(dir, func(name string) bool {
+		_, ok := federatedBundleFiles[name]
+		return !ok
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove stale federated bundle: %v", err)
+	}
+
+	p.log.With("directory", dir).Debug("SVID written to disk")
+	return &svidstorev1.PutX509SVIDResponse{}, nil
+}
+
+// DeleteX509SVID removes the files written for the entry. The directory of
+// the entry is only removed when no other files are left in it.
+func (p *DiskPlugin) DeleteX509SVID(ctx context.Context, req *svidstorev1.DeleteX509SVIDRequest) (*svidstorev1.DeleteX509SVIDResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opt, err := optionsFromSecretData(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(config.directory, opt.name)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		p.log.With("directory", dir).Debug("SVID directory to delete not found")
+		return &svidstorev1.DeleteX509SVIDResponse{}, nil
+	}
+
+	for _, name := range []string{svidFileName, keyFileName, bundleFileName} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, status.Errorf(codes.Internal, "failed to remove %s: %v", name, err)
+		}
+	}
+	if err := removeFederatedBundles(dir, func(string) bool { return true }); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove federated bundle: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	switch {
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to read directory: %v", err)
+	case len(entries) > 0:
+		p.log.With("directory", dir).Warn("SVID files deleted but directory is not empty; leaving it in place")
+		return &svidstorev1.DeleteX509SVIDResponse{}, nil
+	}
+
+	if err := os.Remove(dir); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove directory: %v", err)
+	}
+
+	p.log.With("directory", dir).Debug("SVID deleted from disk")
+	return &svidstorev1.DeleteX509SVIDResponse{}, nil
+}
+
+func (p *DiskPlugin) getConfig() (*diskConfig, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+// setupDirectory sets the mode and group of the directory of an entry. The
+// directory stays owned by the agent, so the workload can read the files in
+// it but not replace them; access is granted through the group and the mode.
+func (p *DiskPlugin) setupDirectory(dir string, mode os.FileMode, opt *secretOptions) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to open directory: %v", err)
+	}
+	defer f.Close()
+
+	// MkdirAll is subject to the umask and leaves existing directories as is
+	if err := f.Chmod(mode); err != nil {
+		return status.Errorf(codes.Internal, "failed to set directory mode: %v", err)
+	}
+	// The directory is owned by the agent even if it already existed, so
+	// workloads can't replace the files renamed into it. An agent that is
+	// not allowed to take ownership of the directory fails here instead.
+	if err := p.setOwnership(f, os.Geteuid(), opt.gid); err != nil {
+		return status.Errorf(codes.Internal, "failed to set directory ownership: %v", err)
+	}
+	return nil
+}
+
+// writeFile writes the data into a temporary file in the same directory,
+// sets its mode and ownership, and renames it to the final path. The mode and
+// ownership are set through the open file, so they can't be redirected to
+// another file by replacing the temporary file.
+func (p *DiskPlugin) writeFile(path string, data []byte, mode os.FileMode, opt *secretOptions) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := p.fillTempFile(tmp, data, mode, opt); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// fillTempFile writes the data into the temporary file and sets its mode and
+// ownership.
+func (p *DiskPlugin) fillTempFile(tmp *os.File, data []byte, mode os.FileMode, opt *secretOptions) error {
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	// Chmod is used since the mode of CreateTemp is subject to the umask
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	return p.setOwnership(tmp, opt.uid, opt.gid)
+}
+
+func (p *DiskPlugin) setOwnership(f *os.File, uid, gid int) error {
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return p.hooks.chown(f, uid, gid)
+}
+
+// removeFederatedBundles removes the federated bundle files in the directory
+// for which shouldRemove returns true.
+func removeFederatedBundles(dir string, shouldRemove func(name string) bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !isFederatedBundleFile(name) || !shouldRemove(name) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func isFederatedBundleFile(name string) bool {
+	return strings.HasPrefix(name, federatedBundleFilePrefix) && strings.HasSuffix(name, federatedBundleFileSuffix)
+}
+
+type svidFile struct {
+	name string
+	data string
+	mode os.FileMode
+}
+
+type secretOptions struct {
+	name string
+	uid  int
+	gid  int
+}
+
+func optionsFromSecretData(selectorData []string) (*secretOptions, error) {
+	data, err := svidstore.ParseMetadata(selectorData)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid metadata: %v", err)
+	}
+
+	name, ok := data["name"]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	// The name is a directory under the configured directory. Anything that
+	// could escape it is rejected.
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid name %q: must be a single path element", name)
+	}
+
+	uid, err := parseID(data, "uid")
+	if err != nil {
+		return nil, err
+	}
+	gid, err := parseID(data, "gid")
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretOptions{
+		name: name,
+		uid:  uid,
+		gid:  gid,
+	}, nil
+}
+
+// parseID parses a user or group ID from the metadata. -1 is returned when it
+// is not set, which leaves it unchanged when passed to chown.
+func parseID(data map[string]string, key string) (int, error) {
+	value, ok := data[key]
+	if !ok {
+		return -1, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 31)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s %q", key, value)
+	}
+	return int(id), nil
+}
+
+// parseFileMode parses an octal file mode, like "0640".
+func parseFileMode(s string, defaultMode os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return defaultMode, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not an octal file mode", s)
+	}
+	return os.FileMode(mode), nil
+}
@@ -0,0 +1,352 @@
+package k8ssecret
+
+import (
+	"context"
+	"crypto/sha1" //nolint: gosec // We use sha1 to hash trust domain names in 128 bytes to avoid secret label restrictions
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/plugin/svidstore"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	svidstorev1 "github.com/spiffe/spire/proto/spire/plugin/agent/svidstore/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	pluginName = "k8s_secret"
+
+	// spireSVIDLabel marks the secrets managed by SPIRE. Its value is the
+	// hash of the trust domain of the SPIRE deployment.
+	spireSVIDLabel = "spire-svid"
+
+	// spiffeIDAnnotation holds the SPIFFE ID of the stored SVID.
+	spiffeIDAnnotation = "spiffe.io/spiffe-id"
+
+	// bundleKey holds the bundle of the trust domain, next to the tls.crt
+	// and tls.key entries of a TLS secret.
+	bundleKey = "ca.crt"
+
+	// federatedBundleKeyPrefix prefixes the keys holding federated bundles,
+	// which are named after the federated trust domain.
+	federatedBundleKeyPrefix = "federated-"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *SecretPlugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		svidstorev1.SVIDStorePluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+func New() *SecretPlugin {
+	return newPlugin(newKubeClient)
+}
+
+func newPlugin(newClient func(kubeConfigFilePath string) (kubernetes.Interface, error)) *SecretPlugin {
+	p := &SecretPlugin{}
+	p.hooks.newClient = newClient
+
+	return p
+}
+
+type Configuration struct {
+	KubeConfigFilePath string   `hcl:"kube_config_file_path" json:"kube_config_file_path"`
+	Namespaces         []string `hcl:"namespaces" json:"namespaces,omitempty"`
+	UnusedKeys         []string `hcl:",unusedKeys" json:",omitempty"`
+}
+
+type SecretPlugin struct {
+	svidstorev1.UnsafeSVIDStoreServer
+	configv1.UnsafeConfigServer
+
+	log        hclog.Logger
+	mtx        sync.RWMutex
+	client     kubernetes.Interface
+	namespaces map[string]bool
+	tdHash     string
+
+	hooks struct {
+		newClient func(kubeConfigFilePath string) (kubernetes.Interface, error)
+	}
+}
+
+func (p *SecretPlugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+// Configure configures the SecretPlugin.
+func (p *SecretPlugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	// Parse HCL config payload into config struct
+	config := &Configuration{}
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if len(config.UnusedKeys) != 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown configurations detected: %s", strings.Join(config.UnusedKeys, ","))
+	}
+
+	var namespaces map[string]bool
+	if len(config.Namespaces) > 0 {
+		namespaces = make(map[string]bool, len(config.Namespaces))
+		for _, namespace := range config.Namespaces {
+			if namespace == "" {
+				return nil, status.Error(codes.InvalidArgument, "namespaces cannot contain an empty namespace")
+			}
+			namespaces[namespace] = true
+		}
+	}
+
+	client, err := p.hooks.newClient(config.KubeConfigFilePath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create kubernetes client: %v", err)
+	}
+
+	// labels are limited to 63 characters, hash td as label value
+	tdHash := sha1.Sum([]byte(req.CoreConfiguration.TrustDomain)) //nolint: gosec // We use sha1 to hash trust domain names in 128 bytes to avoid secret label restrictions
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.client = client
+	p.namespaces = namespaces
+	p.tdHash = hex.EncodeToString(tdHash[:])
+
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// PutX509SVID puts the specified X509-SVID in a Kubernetes TLS secret
+func (p *SecretPlugin) PutX509SVID(ctx context.Context, req *svidstorev1.PutX509SVIDRequest) (*svidstorev1.PutX509SVIDResponse, error) {
+	client, tdHash, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	opt, err := p.optionsFromSecretData(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	secretData, err := svidstore.SecretFromProto(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse request: %v", err)
+	}
+
+	data, err := dataFromSecret(secretData)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse request: %v", err)
+	}
+
+	secrets := client.CoreV1().Secrets(opt.namespace)
+
+	// Get secret, if it does not exist, a secret is created
+	secret, secretFound, err := getSecret(ctx, client, opt, tdHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Secret not found, create it
+	if !secretFound {
+		secret, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      opt.name,
+				Namespace: opt.namespace,
+				Labels: map[string]string{
+					spireSVIDLabel: tdHash,
+				},
+				Annotations: map[string]string{
+					spiffeIDAnnotation: secretData.SPIFFEID,
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: data,
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create secret: %v", err)
+		}
+		p.log.With("secret_name", opt.name).With("namespace", opt.namespace).With("uid", secret.UID).Debug("Secret created")
+		return &svidstorev1.PutX509SVIDResponse{}, nil
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[spiffeIDAnnotation] = secretData.SPIFFEID
+	secret.Data = data
+
+	secret, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update secret: %v", err)
+	}
+
+	p.log.With("secret_name", opt.name).With("namespace", opt.namespace).With("resource_version", secret.ResourceVersion).Debug("Secret updated")
+	return &svidstorev1.PutX509SVIDResponse{}, nil
+}
+
+// DeleteX509SVID deletes a secret managed by SPIRE from Kubernetes
+func (p *SecretPlugin) DeleteX509SVID(ctx context.Context, req *svidstorev1.DeleteX509SVIDRequest) (*svidstorev1.DeleteX509SVIDResponse, error) {
+	client, tdHash, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	opt, err := p.optionsFromSecretData(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, ok, err := getSecret(ctx, client, opt, tdHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		p.log.With("secret_name", opt.name).With("namespace", opt.namespace).Debug("Secret to delete not found")
+		return &svidstorev1.DeleteX509SVIDResponse{}, nil
+	}
+
+	// Make sure the secret that was validated is the one being deleted
+	err = client.CoreV1().Secrets(opt.namespace).Delete(ctx, opt.name, metav1.DeleteOptions{
+		Preconditions: metav1.NewUIDPreconditions(string(secret.UID)),
+	})
+	switch {
+	case k8serrors.IsNotFound(err):
+		p.log.With("secret_name", opt.name).With("namespace", opt.namespace).Debug("Secret to delete not found")
+		return &svidstorev1.DeleteX509SVIDResponse{}, nil
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to delete secret: %v", err)
+	}
+
+	p.log.With("secret_name", opt.name).With("namespace", opt.namespace).Debug("Secret deleted")
+	return &svidstorev1.DeleteX509SVIDResponse{}, nil
+}
+
+func (p *SecretPlugin) getClient() (kubernetes.Interface, string, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.client == nil {
+		return nil, "", status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.client, p.tdHash, nil
+}
+
+// getSecret gets the secret from Kubernetes and validates that it has the `spire-svid` label with the hashed trust
+// domain as value, false if not found
+func getSecret(ctx context.Context, client kubernetes.Interface, opt *secretOptions, tdHash string) (*corev1.Secret, bool, error) {
+	secret, err := client.CoreV1().Secrets(opt.namespace).Get(ctx, opt.name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		// Verify that secret contains "spire-svid" label with the expected value
+		if ok := validateLabels(secret.Labels, tdHash); !ok {
+			return nil, false, status.Error(codes.InvalidArgument, "secret is not managed by this SPIRE deployment")
+		}
+	case k8serrors.IsNotFound(err):
+		return nil, false, nil
+	default:
+		return nil, false, status.Errorf(codes.Internal, "failed to get secret: %v", err)
+	}
+
+	return secret, true, nil
+}
+
+type secretOptions struct {
+	name      string
+	namespace string
+}
+
+func (p *SecretPlugin) optionsFromSecretData(selectorData []string) (*secretOptions, error) {
+	data, err := svidstore.ParseMetadata(selectorData)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid metadata: %v", err)
+	}
+
+	// Getting secret name and namespace, both are required.
+	name, ok := data["name"]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	namespace, ok := data["namespace"]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "namespace is required")
+	}
+
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	if p.namespaces != nil && !p.namespaces[namespace] {
+		return nil, status.Errorf(codes.PermissionDenied, "namespace %q is not allowed", namespace)
+	}
+
+	return &secretOptions{
+		name:      name,
+		namespace: namespace,
+	}, nil
+}
+
+// dataFromSecret lays out the SVID as the data of a TLS secret. Federated
+// bundles are stored under a key per trust domain.
+func dataFromSecret(secretData *svidstore.Data) (map[string][]byte, error) {
+	data := map[string][]byte{
+		corev1.TLSCertKey:       []byte(secretData.X509SVID),
+		corev1.TLSPrivateKeyKey: []byte(secretData.X509SVIDKey),
+		bundleKey:               []byte(secretData.Bundle),
+	}
+
+	federatedIDs := make([]string, 0, len(secretData.FederatedBundles))
+	for id := range secretData.FederatedBundles {
+		federatedIDs = append(federatedIDs, id)
+	}
+	sort.Strings(federatedIDs)
+
+	for _, id := range federatedIDs {
+		td, err := spiffeid.TrustDomainFromString(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid federated trust domain %q: %w", id, err)
+		}
+		key := federatedBundleKeyPrefix + td.String() + ".crt"
+		if _, ok := data[key]; ok {
+			return nil, fmt.Errorf("duplicated federated trust domain %q", td)
+		}
+		data[key] = []byte(secretData.FederatedBundles[id])
+	}
+
+	return data, nil
+}
+
+func validateLabels(labels map[string]string, tdHash string) bool {
+	spireLabel, ok := labels[spireSVIDLabel]
+	return ok && spireLabel == tdHash
+}
+
+func newKubeClient(kubeConfigFilePath string) (kubernetes.Interface, error) {
+	var config *rest.Config
+	var err error
+	if kubeConfigFilePath != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeConfigFilePath)
+	} else {
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
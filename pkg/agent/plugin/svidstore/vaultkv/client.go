@@ -0,0 +1,84 @@
+package vaultkv
+
+import (
+	"context"
+	"fmt"
+
+	vapi "github.com/hashicorp/vault/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type client struct {
+	vaultClient *vapi.Client
+	// stopRenew stops renewing the token of the client
+	stopRenew context.CancelFunc
+}
+
+// readCustomMetadata returns the custom metadata of the secret at the given
+// path of a KV v2 secrets engine. It returns false if the secret does not
+// exist.
+func (c *client) readCustomMetadata(mountPoint, path string) (map[string]string, bool, error) {
+	sec, err := c.vaultClient.Logical().Read(metadataPath(mountPoint, path))
+	if err != nil {
+		return nil, false, status.Errorf(codes.Internal, "failed to read secret metadata: %v", err)
+	}
+	if sec == nil || sec.Data == nil {
+		return nil, false, nil
+	}
+
+	customMetadata := make(map[string]string)
+	if raw, ok := sec.Data["custom_metadata"].(map[string]interface{}); ok {
+		for k, v := range raw {
+			if s, ok := v.(string); ok {
+				customMetadata[k] = s
+			}
+		}
+	}
+	return customMetadata, true, nil
+}
+
+// writeCustomMetadata sets the custom metadata of the secret at the given
+// path, creating the secret metadata if it does not exist.
+func (c *client) writeCustomMetadata(mountPoint, path string, customMetadata map[string]string) error {
+	if _, err := c.vaultClient.Logical().Write(metadataPath(mountPoint, path), map[string]interface{}{
+		"custom_metadata": customMetadata,
+	}); err != nil {
+		return status.Errorf(codes.Internal, "failed to write secret metadata: %v", err)
+	}
+	return nil
+}
+
+// writeData writes a new version of the secret at the given path and returns
+// the version number.
+func (c *client) writeData(mountPoint, path string, data map[string]interface{}) (string, error) {
+	sec, err := c.vaultClient.Logical().Write(dataPath(mountPoint, path), map[string]interface{}{
+		"data": data,
+	})
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to write secret: %v", err)
+	}
+
+	var version string
+	if sec != nil && sec.Data != nil {
+		version = fmt.Sprint(sec.Data["version"])
+	}
+	return version, nil
+}
+
+// destroy permanently deletes every version of the secret at the given path,
+// along with its metadata.
+func (c *client) destroy(mountPoint, path string) error {
+	if _, err := c.vaultClient.Logical().Delete(metadataPath(mountPoint, path)); err != nil {
+		return status.Errorf(codes.Internal, "failed to delete secret: %v", err)
+	}
+	return nil
+}
+
+func dataPath(mountPoint, path string) string {
+	return fmt.Sprintf("%s/data/%s", mountPoint, path)
+}
+
+func metadataPath(mountPoint, path string) string {
+	return fmt.Sprintf("%s/metadata/%s", mountPoint, path)
+}
@@ -0,0 +1,301 @@
+package vaultkv
+
+import (
+	"context"
+	"crypto/sha1" //nolint: gosec // We use sha1 to hash trust domain names in 128 bytes to keep the metadata value short
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/plugin/svidstore"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	cvault "github.com/spiffe/spire/pkg/common/plugin/vault"
+	svidstorev1 "github.com/spiffe/spire/proto/spire/plugin/agent/svidstore/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "vault_kv"
+
+	// spireSVIDKey is the custom metadata key that marks the secrets managed
+	// by this plugin. Its value is the hashed trust domain.
+	spireSVIDKey = "spire-svid"
+
+	defaultKVMountPoint = "secret"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *KVPlugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		svidstorev1.SVIDStorePluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+func New() *KVPlugin {
+	p := &KVPlugin{}
+	p.hooks.lookupEnv = os.LookupEnv
+
+	return p
+}
+
+type Configuration struct {
+	cvault.Config `hcl:",squash"`
+
+	// Name of the mount point where the KV v2 secrets engine is mounted.
+	KVMountPoint string `hcl:"kv_mount_point" json:"kv_mount_point"`
+
+	UnusedKeys []string `hcl:",unusedKeys" json:",omitempty"`
+}
+
+type kvConfig struct {
+	kvMountPoint string
+	tdHash       string
+	authMethod   cvault.AuthMethod
+	clientParams *cvault.ClientParams
+}
+
+type KVPlugin struct {
+	svidstorev1.UnsafeSVIDStoreServer
+	configv1.UnsafeConfigServer
+
+	log    hclog.Logger
+	mtx    sync.Mutex
+	config *kvConfig
+	client *client
+
+	hooks struct {
+		lookupEnv func(string) (string, bool)
+	}
+}
+
+func (p *KVPlugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+// Configure configures the KVPlugin.
+func (p *KVPlugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	// Parse HCL config payload into config struct
+	config := &Configuration{}
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if len(config.UnusedKeys) != 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown configurations detected: %s", strings.Join(config.UnusedKeys, ","))
+	}
+
+	am, err := cvault.ParseAuthMethod(&config.Config)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := cvault.GenClientParams(am, &config.Config, p.hooks.lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	if cp.VaultAddr == "" {
+		return nil, status.Error(codes.InvalidArgument, "vault_addr is required")
+	}
+
+	kvMountPoint := strings.Trim(config.KVMountPoint, "/")
+	if kvMountPoint == "" {
+		kvMountPoint = defaultKVMountPoint
+	}
+
+	tdHash := sha1.Sum([]byte(req.CoreConfiguration.TrustDomain)) //nolint: gosec // We use sha1 to hash trust domain names in 128 bytes to keep the metadata value short
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.config = &kvConfig{
+		kvMountPoint: kvMountPoint,
+		tdHash:       hex.EncodeToString(tdHash[:]),
+		authMethod:   am,
+		clientParams: cp,
+	}
+	// Authenticate again with the new configuration on the next request
+	if p.client != nil {
+		p.client.stopRenew()
+		p.client = nil
+	}
+
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// PutX509SVID writes the specified X509-SVID as a new version of the secret
+// at the path of the entry. The secret is created if it does not exist.
+func (p *KVPlugin) PutX509SVID(ctx context.Context, req *svidstorev1.PutX509SVIDRequest) (*svidstorev1.PutX509SVIDResponse, error) {
+	path, err := pathFromMetadata(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	secretData, err := svidstore.SecretFromProto(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse request: %v", err)
+	}
+	data, err := secretDataToMap(secretData)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal payload: %v", err)
+	}
+
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := getSecret(client, config, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Secret not found, mark it as managed by this plugin before writing the
+	// SVID, so it can be recognized even if writing the data fails
+	if !found {
+		if err := client.writeCustomMetadata(config.kvMountPoint, path, map[string]string{
+			spireSVIDKey: config.tdHash,
+		}); err != nil {
+			return nil, err
+		}
+		p.log.With("path", path).Debug("Secret created")
+	}
+
+	version, err := client.writeData(config.kvMountPoint, path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	p.log.With("path", path).With("version", version).Debug("Secret payload updated")
+	return &svidstorev1.PutX509SVIDResponse{}, nil
+}
+
+// DeleteX509SVID destroys every version of the secret at the path of the
+// entry, along with its metadata.
+func (p *KVPlugin) DeleteX509SVID(ctx context.Context, req *svidstorev1.DeleteX509SVIDRequest) (*svidstorev1.DeleteX509SVIDResponse, error) {
+	path, err := pathFromMetadata(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	config, client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := getSecret(client, config, path)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		p.log.With("path", path).Debug("Secret to delete not found")
+		return &svidstorev1.DeleteX509SVIDResponse{}, nil
+	}
+
+	if err := client.destroy(config.kvMountPoint, path); err != nil {
+		return nil, err
+	}
+
+	p.log.With("path", path).Debug("Secret deleted")
+	return &svidstorev1.DeleteX509SVIDResponse{}, nil
+}
+
+// getClient returns the current configuration and an authenticated client,
+// authenticating to Vault if there is no client or its token could not be
+// renewed.
+func (p *KVPlugin) getClient() (*kvConfig, *client, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.config == nil {
+		return nil, nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	if p.client != nil {
+		return p.config, p.client, nil
+	}
+
+	renewCtx, stopRenew := context.WithCancel(context.Background())
+	renewCh := make(chan struct{})
+	vc, err := cvault.NewAuthenticatedClient(renewCtx, p.config.clientParams, p.config.authMethod, renewCh, p.log)
+	if err != nil {
+		stopRenew()
+		return nil, nil, status.Errorf(codes.Internal, "failed to prepare authenticated client: %v", err)
+	}
+	c := &client{vaultClient: vc, stopRenew: stopRenew}
+	p.client = c
+
+	// if renewCh has been closed, the token can not be renewed and may expire,
+	// it needs to re-authenticate to the Vault.
+	go func() {
+		<-renewCh
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+		if p.client == c {
+			p.client = nil
+			p.log.Debug("Going to re-authenticate to the Vault on the next request")
+		}
+	}()
+
+	return p.config, c, nil
+}
+
+// getSecret looks up the secret at the given path and validates that it has
+// the `spire-svid` custom metadata with the hashed trust domain as value.
+// It returns false if the secret does not exist.
+func getSecret(client *client, config *kvConfig, path string) (bool, error) {
+	customMetadata, found, err := client.readCustomMetadata(config.kvMountPoint, path)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+	if customMetadata[spireSVIDKey] != config.tdHash {
+		return false, status.Error(codes.InvalidArgument, "secret is not managed by this SPIRE deployment")
+	}
+	return true, nil
+}
+
+// pathFromMetadata gets the path of the secret, relative to the mount point
+// of the KV secrets engine, from the metadata.
+func pathFromMetadata(selectorData []string) (string, error) {
+	data, err := svidstore.ParseMetadata(selectorData)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid metadata: %v", err)
+	}
+
+	path, ok := data["path"]
+	if !ok {
+		return "", status.Error(codes.InvalidArgument, "path is required")
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", status.Errorf(codes.InvalidArgument, "invalid path %q", path)
+		}
+	}
+
+	return path, nil
+}
+
+// secretDataToMap converts the secret data into the key/value pairs stored in
+// the secret, using the same keys other SVIDStore plugins use in their JSON
+// payloads.
+func secretDataToMap(secretData *svidstore.Data) (map[string]interface{}, error) {
+	b, err := json.Marshal(secretData)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,233 @@
+package vaultkv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault is a stand-in for a Vault server with the token, login and KV v2
+// endpoints used by the plugin. Secrets are kept in memory.
+type fakeVault struct {
+	// mountPoint is the mount point of the KV v2 secrets engine
+	mountPoint string
+	// token is the token returned on login and required on KV requests
+	token string
+	// leaseDuration and renewable are returned for the token
+	leaseDuration int
+	renewable     bool
+	// failures maps "<method> <data|metadata>" to the status code returned
+	// for KV requests of that kind
+	failures map[string]int
+
+	mtx        sync.Mutex
+	secrets    map[string]*fakeSecret
+	logins     []fakeLogin
+	namespaces []string
+}
+
+type fakeSecret struct {
+	customMetadata map[string]string
+	versions       []map[string]interface{}
+}
+
+type fakeLogin struct {
+	mountPoint string
+	body       map[string]interface{}
+	tls        bool
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{
+		mountPoint: "secret",
+		token:      "test-token",
+		secrets:    make(map[string]*fakeSecret),
+	}
+}
+
+func (v *fakeVault) start(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(v)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (v *fakeVault) setSecret(path string, secret *fakeSecret) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.secrets[path] = secret
+}
+
+func (v *fakeVault) getSecrets() map[string]*fakeSecret {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	secrets := make(map[string]*fakeSecret, len(v.secrets))
+	for path, secret := range v.secrets {
+		secrets[path] = secret
+	}
+	return secrets
+}
+
+func (v *fakeVault) getLogins() []fakeLogin {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return append([]fakeLogin(nil), v.logins...)
+}
+
+func (v *fakeVault) getNamespaces() []string {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return append([]string(nil), v.namespaces...)
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	v.namespaces = append(v.namespaces, r.Header.Get("X-Vault-Namespace"))
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case path == "auth/token/lookup-self" && r.Method == http.MethodGet:
+		v.lookupSelf(w, r)
+	case strings.HasPrefix(path, "auth/") && strings.HasSuffix(path, "/login") && r.Method == http.MethodPut:
+		v.login(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "auth/"), "/login"))
+	case strings.HasPrefix(path, v.mountPoint+"/"):
+		if r.Header.Get("X-Vault-Token") != v.token {
+			writeErrors(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		parts := strings.SplitN(strings.TrimPrefix(path, v.mountPoint+"/"), "/", 2)
+		if len(parts) != 2 {
+			writeErrors(w, http.StatusNotFound)
+			return
+		}
+		kind, secretPath := parts[0], parts[1]
+		if code, ok := v.failures[r.Method+" "+kind]; ok {
+			writeErrors(w, code, "oh! no")
+			return
+		}
+		switch kind {
+		case "data":
+			v.kvData(w, r, secretPath)
+		case "metadata":
+			v.kvMetadata(w, r, secretPath)
+		default:
+			writeErrors(w, http.StatusNotFound)
+		}
+	default:
+		writeErrors(w, http.StatusNotFound)
+	}
+}
+
+func (v *fakeVault) lookupSelf(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Vault-Token")
+	if token != v.token {
+		writeErrors(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"id":        token,
+			"ttl":       v.leaseDuration,
+			"renewable": v.renewable,
+		},
+	})
+}
+
+func (v *fakeVault) login(w http.ResponseWriter, r *http.Request, mountPoint string) {
+	body := make(map[string]interface{})
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	v.logins = append(v.logins, fakeLogin{
+		mountPoint: mountPoint,
+		body:       body,
+		tls:        r.TLS != nil && len(r.TLS.PeerCertificates) > 0,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"auth": map[string]interface{}{
+			"client_token":   v.token,
+			"lease_duration": v.leaseDuration,
+			"renewable":      v.renewable,
+		},
+	})
+}
+
+func (v *fakeVault) kvData(w http.ResponseWriter, r *http.Request, path string) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		writeErrors(w, http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, ok := v.secrets[path]
+	if !ok {
+		secret = &fakeSecret{}
+		v.secrets[path] = secret
+	}
+	secret.versions = append(secret.versions, body.Data)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"version": len(secret.versions),
+		},
+	})
+}
+
+func (v *fakeVault) kvMetadata(w http.ResponseWriter, r *http.Request, path string) {
+	secret, ok := v.secrets[path]
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			writeErrors(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"custom_metadata": secret.customMetadata,
+				"current_version": len(secret.versions),
+			},
+		})
+	case http.MethodPut, http.MethodPost:
+		var body struct {
+			CustomMetadata map[string]string `json:"custom_metadata"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !ok {
+			secret = &fakeSecret{}
+			v.secrets[path] = secret
+		}
+		secret.customMetadata = body.CustomMetadata
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		delete(v.secrets, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeErrors(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeErrors(w http.ResponseWriter, code int, errs ...string) {
+	if errs == nil {
+		errs = []string{}
+	}
+	writeJSON(w, code, map[string]interface{}{"errors": errs})
+}
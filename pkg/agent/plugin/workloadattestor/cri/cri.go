@@ -0,0 +1,300 @@
+package cri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	workloadattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/workloadattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/docker/cgroup"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "cri"
+
+	runtimeAPICRI        = "cri"
+	runtimeAPIContainerd = "containerd"
+
+	defaultRuntimeSocketPath = "/run/containerd/containerd.sock"
+
+	subselectorImage               = "image"
+	subselectorImageDigest         = "image_digest"
+	subselectorLabel               = "label"
+	subselectorNamespace           = "namespace"
+	subselectorContainerdNamespace = "containerd_namespace"
+	subselectorContainerName       = "container_name"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		workloadattestorv1.WorkloadAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// containerInfo describes a container as reported by the container runtime.
+type containerInfo struct {
+	Name string
+	// Namespace is the Kubernetes namespace of the pod sandbox, as reported
+	// by the CRI runtime service
+	Namespace string
+	// ContainerdNamespace is the containerd namespace of the container, as
+	// reported by the containerd API
+	ContainerdNamespace string
+	Image               string
+	ImageDigest         string
+	Labels              map[string]string
+}
+
+// runtimeClient looks up containers in the container runtime.
+type runtimeClient interface {
+	// GetContainer returns the container with the given ID, or nil if the
+	// runtime does not know about the container.
+	GetContainer(ctx context.Context, containerID string) (*containerInfo, error)
+}
+
+type Plugin struct {
+	workloadattestorv1.UnsafeWorkloadAttestorServer
+	configv1.UnsafeConfigServer
+
+	log hclog.Logger
+	fs  cgroups.FileSystem
+
+	// hook for tests
+	dial func(socketPath string) (*grpc.ClientConn, error)
+
+	mtx               sync.RWMutex
+	conn              *grpc.ClientConn
+	runtime           runtimeClient
+	containerIDFinder cgroup.ContainerIDFinder
+}
+
+func New() *Plugin {
+	return &Plugin{
+		fs:   cgroups.OSFileSystem{},
+		dial: dialRuntime,
+	}
+}
+
+type criPluginConfig struct {
+	// RuntimeSocketPath is the location of the container runtime socket
+	// (default: "/run/containerd/containerd.sock").
+	RuntimeSocketPath string `hcl:"runtime_socket_path"`
+	// RuntimeAPI is the API used to query the runtime, either "cri" (the
+	// default) or "containerd".
+	RuntimeAPI string `hcl:"runtime_api"`
+	// ContainerIDCGroupMatchers is a list of patterns used to discover container IDs from cgroup entries.
+	// See the documentation for cgroup.NewContainerIDFinder in the docker cgroup subpackage for more information.
+	ContainerIDCGroupMatchers []string `hcl:"container_id_cgroup_matchers"`
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestRequest) (*workloadattestorv1.AttestResponse, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.runtime == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+
+	cgroupList, err := cgroups.GetCgroups(req.Pid, p.fs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read cgroups: %v", err)
+	}
+
+	containerID, err := getContainerIDFromCGroups(p.containerIDFinder, cgroupList)
+	switch {
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	case containerID == "":
+		// Not a container workload. Nothing more to do.
+		return &workloadattestorv1.AttestResponse{}, nil
+	}
+
+	container, err := p.runtime.GetContainer(ctx, containerID)
+	switch {
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to get container %q from runtime: %v", containerID, err)
+	case container == nil:
+		// The container is managed by another runtime (e.g. docker), which
+		// is handled by other attestors.
+		p.log.Debug("Container not found in runtime", "container_id", containerID)
+		return &workloadattestorv1.AttestResponse{}, nil
+	}
+
+	return &workloadattestorv1.AttestResponse{
+		SelectorValues: getSelectorValues(container),
+	}, nil
+}
+
+func getSelectorValues(container *containerInfo) []string {
+	var selectorValues []string
+	if container.Image != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorImage, container.Image))
+	}
+	if container.ImageDigest != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorImageDigest, container.ImageDigest))
+	}
+	if container.Namespace != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorNamespace, container.Namespace))
+	}
+	if container.ContainerdNamespace != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorContainerdNamespace, container.ContainerdNamespace))
+	}
+	if container.Name != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorContainerName, container.Name))
+	}
+
+	labels := make([]string, 0, len(container.Labels))
+	for label, value := range container.Labels {
+		labels = append(labels, fmt.Sprintf("%s:%s:%s", subselectorLabel, label, value))
+	}
+	sort.Strings(labels)
+	return append(selectorValues, labels...)
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := &criPluginConfig{}
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode configuration: %v", err)
+	}
+
+	if config.RuntimeSocketPath == "" {
+		config.RuntimeSocketPath = defaultRuntimeSocketPath
+	}
+
+	var newRuntime func(*grpc.ClientConn) runtimeClient
+	switch config.RuntimeAPI {
+	case "", runtimeAPICRI:
+		newRuntime = newCRIClient
+	case runtimeAPIContainerd:
+		newRuntime = newContainerdClient
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported runtime_api %q: expected %q or %q", config.RuntimeAPI, runtimeAPICRI, runtimeAPIContainerd)
+	}
+
+	var containerIDFinder cgroup.ContainerIDFinder = &defaultContainerIDFinder{}
+	if len(config.ContainerIDCGroupMatchers) > 0 {
+		var err error
+		containerIDFinder, err = cgroup.NewContainerIDFinder(config.ContainerIDCGroupMatchers)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid container_id_cgroup_matchers: %v", err)
+		}
+	}
+
+	conn, err := p.dial(config.RuntimeSocketPath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to dial runtime socket: %v", err)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn = conn
+	p.runtime = newRuntime(conn)
+	p.containerIDFinder = containerIDFinder
+	return &configv1.ConfigureResponse{}, nil
+}
+
+// dialRuntime creates a connection to the runtime socket. The connection is
+// established lazily so the agent can start before the runtime.
+func dialRuntime(socketPath string) (*grpc.ClientConn, error) {
+	return grpc.Dial("unix:"+socketPath, grpc.WithInsecure())
+}
+
+// getContainerIDFromCGroups returns the container ID from a set of cgroups
+// using the given finder. The container ID found on each cgroup path (if any)
+// must be consistent. If no container ID is found among the cgroups, i.e.,
+// this isn't a container workload, the function returns an empty string. If
+// more than one container ID is found, or the "found" container ID is blank,
+// the function will fail.
+func getContainerIDFromCGroups(finder cgroup.ContainerIDFinder, cgroups []cgroups.Cgroup) (string, error) {
+	var hasContainerEntries bool
+	var containerID string
+	for _, cgroup := range cgroups {
+		candidate, ok := finder.FindContainerID(cgroup.GroupPath)
+		if !ok {
+			continue
+		}
+
+		hasContainerEntries = true
+
+		switch {
+		case containerID == "":
+			// This is the first container ID found so far.
+			containerID = candidate
+		case containerID != candidate:
+			// More than one container ID found in the cgroups.
+			return "", fmt.Errorf("multiple container IDs found in cgroups (%s, %s)", containerID, candidate)
+		}
+	}
+
+	switch {
+	case !hasContainerEntries:
+		return "", nil
+	case containerID == "":
+		// The "finder" found a container ID, but it was blank. This is a
+		// defensive measure against bad matcher patterns and shouldn't
+		// be possible with the default finder.
+		return "", errors.New("a pattern matched, but no container id was found")
+	default:
+		return containerID, nil
+	}
+}
+
+// criCGroupREs match the cgroup paths of containers created by CRI runtimes:
+// 1) `cri-containerd-<id>.scope` and `crio-<id>.scope` for the systemd cgroup driver
+// 2) `/kubepods/.../<id>` for the cgroupfs cgroup driver
+// where <id> is a 64 hex-character container id.
+var criCGroupREs = []*regexp.Regexp{
+	regexp.MustCompile(`/(?:cri-containerd|crio)-([[:xdigit:]]{64})\.scope$`),
+	regexp.MustCompile(`^/kubepods(?:/[^/]+)*/([[:xdigit:]]{64})$`),
+}
+
+type defaultContainerIDFinder struct{}
+
+// FindContainerID returns the container ID in the given cgroup path if it
+// matches one of the layouts used by CRI runtimes. Containers created with
+// the containerd API directly are placed in runtime specific paths, so
+// container_id_cgroup_matchers must be configured to attest them.
+func (f *defaultContainerIDFinder) FindContainerID(cgroupPath string) (string, bool) {
+	for _, re := range criCGroupREs {
+		if m := re.FindStringSubmatch(cgroupPath); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// imageDigestFromRef returns the digest of an image reference in the
+// "name@digest" form, or the reference itself if it is a bare digest.
+func imageDigestFromRef(ref string) string {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		return ref[i+1:]
+	}
+	if strings.HasPrefix(ref, "sha256:") {
+		return ref
+	}
+	return ""
+}
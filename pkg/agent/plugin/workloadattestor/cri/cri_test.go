@@ -0,0 +1,452 @@
+package cri
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	"github.com/containerd/containerd/api/types"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	criv1 "k8s.io/cri-api/pkg/apis/runtime/v1"
+	criv1alpha2 "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+const (
+	testPID         = 123
+	testContainerID = "6469646e742064657465637420746869732073656372657420636f6e7461696e"
+	testDigest      = "sha256:8a4d0d1f5d6f5b0e6b4b2c1c2e6c9d7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e"
+)
+
+var (
+	criCgroups        = fmt.Sprintf("0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-podfoo.slice/cri-containerd-%s.scope\n", testContainerID)
+	cgroupfsCgroups   = fmt.Sprintf("11:memory:/kubepods/besteffort/podfoo/%s\n", testContainerID)
+	containerdCgroups = fmt.Sprintf("0::/default/%s\n", testContainerID)
+	dockerCgroups     = fmt.Sprintf("0::/system.slice/docker-%s.scope\n", testContainerID)
+)
+
+func TestAttestCRI(t *testing.T) {
+	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		criv1.RegisterRuntimeServiceServer(s, &fakeCRIServer{})
+	})
+
+	for _, tt := range []struct {
+		name            string
+		cgroups         string
+		config          string
+		expectSelectors []string
+		expectCode      codes.Code
+		expectMsg       string
+	}{
+		{
+			name:    "systemd cgroup driver",
+			cgroups: criCgroups,
+			expectSelectors: []string{
+				"image:docker.io/library/nginx:1.21",
+				"image_digest:" + testDigest,
+				"namespace:prod",
+				"container_name:nginx",
+				"label:io.kubernetes.container.name:nginx",
+				"label:io.kubernetes.pod.namespace:prod",
+			},
+		},
+		{
+			name:    "cgroupfs cgroup driver",
+			cgroups: cgroupfsCgroups,
+			expectSelectors: []string{
+				"image:docker.io/library/nginx:1.21",
+				"image_digest:" + testDigest,
+				"namespace:prod",
+				"container_name:nginx",
+				"label:io.kubernetes.container.name:nginx",
+				"label:io.kubernetes.pod.namespace:prod",
+			},
+		},
+		{
+			name:    "not a CRI container",
+			cgroups: dockerCgroups,
+		},
+		{
+			name:    "not a container",
+			cgroups: "0::/system.slice/nginx.service\n",
+		},
+		{
+			name:    "container unknown to runtime",
+			cgroups: strings.ReplaceAll(criCgroups, testContainerID, strings.Repeat("a", 64)),
+		},
+		{
+			name:       "runtime error",
+			cgroups:    strings.ReplaceAll(criCgroups, testContainerID, strings.Repeat("b", 64)),
+			expectCode: codes.Internal,
+			expectMsg:  fmt.Sprintf(`workloadattestor(cri): failed to get container %q from runtime: rpc error: code = Unavailable desc = runtime error`, strings.Repeat("b", 64)),
+		},
+		{
+			name:       "multiple container IDs",
+			cgroups:    criCgroups + strings.ReplaceAll(cgroupfsCgroups, testContainerID, strings.Repeat("a", 64)),
+			expectCode: codes.Internal,
+			expectMsg:  fmt.Sprintf("workloadattestor(cri): multiple container IDs found in cgroups (%s, %s)", testContainerID, strings.Repeat("a", 64)),
+		},
+		{
+			name:       "malformed cgroups",
+			cgroups:    "malformed\n",
+			expectCode: codes.Internal,
+			expectMsg:  `workloadattestor(cri): failed to read cgroups: cgroup entry contains 1 colons, but expected at least 2 colons: "malformed"`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := fmt.Sprintf("runtime_socket_path = %q\n%s", socketPath, tt.config)
+			selectors, err := doAttest(t, config, tt.cgroups)
+			spiretest.RequireGRPCStatus(t, err, tt.expectCode, tt.expectMsg)
+			require.Equal(t, tt.expectSelectors, selectors)
+		})
+	}
+}
+
+func TestAttestCRIV1alpha2(t *testing.T) {
+	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		criv1alpha2.RegisterRuntimeServiceServer(s, &fakeCRIV1alpha2Server{})
+	})
+
+	p := New()
+	p.fs = fakeFileSystem{fmt.Sprintf("/proc/%d/cgroup", testPID): criCgroups}
+	attestor := new(workloadattestor.V1)
+	plugintest.Load(t, builtin(p), attestor, plugintest.Configure(fmt.Sprintf("runtime_socket_path = %q", socketPath)))
+
+	for i := 0; i < 2; i++ {
+		selectors, err := attestor.Attest(context.Background(), testPID)
+		require.NoError(t, err)
+		require.Len(t, selectors, 6)
+		require.Equal(t, "namespace:prod", selectors[2].Value)
+	}
+
+	// The v1alpha2 API keeps being used once the runtime reports that it
+	// does not implement v1
+	require.Equal(t, int32(1), atomic.LoadInt32(&p.runtime.(*criClient).v1alpha2Only))
+}
+
+func TestAttestContainerd(t *testing.T) {
+	socketPath := spiretest.StartGRPCSocketServerOnTempSocket(t, func(s *grpc.Server) {
+		namespacesapi.RegisterNamespacesServer(s, &fakeNamespacesServer{})
+		containersapi.RegisterContainersServer(s, &fakeContainersServer{})
+		imagesapi.RegisterImagesServer(s, &fakeImagesServer{})
+	})
+	config := fmt.Sprintf(`
+		runtime_socket_path = %q
+		runtime_api = "containerd"
+		container_id_cgroup_matchers = ["/*/<id>"]
+	`, socketPath)
+
+	for _, tt := range []struct {
+		name            string
+		cgroups         string
+		expectSelectors []string
+		expectCode      codes.Code
+		expectMsg       string
+	}{
+		{
+			name:    "container",
+			cgroups: containerdCgroups,
+			expectSelectors: []string{
+				"image:docker.io/library/nginx:1.21",
+				"image_digest:" + testDigest,
+				"containerd_namespace:default",
+				"container_name:" + testContainerID,
+				"label:app:web",
+			},
+		},
+		{
+			name:    "container with removed image",
+			cgroups: strings.ReplaceAll(containerdCgroups, testContainerID, "noimage"),
+			expectSelectors: []string{
+				"image:docker.io/library/removed:1.0",
+				"containerd_namespace:default",
+				"container_name:noimage",
+			},
+		},
+		{
+			name:    "container unknown to runtime",
+			cgroups: strings.ReplaceAll(containerdCgroups, testContainerID, "unknown"),
+		},
+		{
+			name:       "runtime error",
+			cgroups:    strings.ReplaceAll(containerdCgroups, testContainerID, "error"),
+			expectCode: codes.Internal,
+			expectMsg:  `workloadattestor(cri): failed to get container "error" from runtime: rpc error: code = Unavailable desc = runtime error`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			selectors, err := doAttest(t, config, tt.cgroups)
+			spiretest.RequireGRPCStatus(t, err, tt.expectCode, tt.expectMsg)
+			require.Equal(t, tt.expectSelectors, selectors)
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		config    string
+		expectMsg string
+	}{
+		{
+			name:      "malformed configuration",
+			config:    "runtime_api = [",
+			expectMsg: "failed to decode configuration",
+		},
+		{
+			name:      "unsupported runtime API",
+			config:    `runtime_api = "docker"`,
+			expectMsg: `unsupported runtime_api "docker": expected "cri" or "containerd"`,
+		},
+		{
+			name:      "invalid cgroup matcher",
+			config:    `container_id_cgroup_matchers = ["/docker/"]`,
+			expectMsg: "invalid container_id_cgroup_matchers",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, BuiltIn(), new(workloadattestor.V1),
+				plugintest.Configure(tt.config),
+				plugintest.CaptureConfigureError(&err))
+			spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, tt.expectMsg)
+		})
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	attestor := new(workloadattestor.V1)
+	plugintest.Load(t, BuiltIn(), attestor)
+
+	_, err := attestor.Attest(context.Background(), testPID)
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "workloadattestor(cri): not configured")
+}
+
+func TestDefaultContainerIDFinder(t *testing.T) {
+	finder := &defaultContainerIDFinder{}
+	for _, cgroupPath := range []string{
+		"/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + testContainerID + ".scope",
+		"/kubepods.slice/kubepods-pod1.slice/crio-" + testContainerID + ".scope",
+		"/kubepods/pod1/" + testContainerID,
+		"/kubepods/burstable/pod1/" + testContainerID,
+	} {
+		id, ok := finder.FindContainerID(cgroupPath)
+		require.True(t, ok, cgroupPath)
+		require.Equal(t, testContainerID, id, cgroupPath)
+	}
+
+	for _, cgroupPath := range []string{
+		"/system.slice/docker-" + testContainerID + ".scope",
+		"/docker/" + testContainerID,
+		"/kubepods/pod1",
+		"/kubepods/pod1/" + testContainerID + "/sub",
+		"/system.slice/containerd.service",
+	} {
+		_, ok := finder.FindContainerID(cgroupPath)
+		require.False(t, ok, cgroupPath)
+	}
+}
+
+func TestImageDigestFromRef(t *testing.T) {
+	require.Equal(t, testDigest, imageDigestFromRef("docker.io/library/nginx@"+testDigest))
+	require.Equal(t, testDigest, imageDigestFromRef(testDigest))
+	require.Empty(t, imageDigestFromRef("docker.io/library/nginx:1.21"))
+}
+
+func doAttest(t *testing.T, config, cgroups string) ([]string, error) {
+	p := New()
+	p.fs = fakeFileSystem{fmt.Sprintf("/proc/%d/cgroup", testPID): cgroups}
+
+	attestor := new(workloadattestor.V1)
+	plugintest.Load(t, builtin(p), attestor, plugintest.Configure(config))
+
+	selectors, err := attestor.Attest(context.Background(), testPID)
+	if err != nil {
+		return nil, err
+	}
+	var selectorValues []string
+	for _, selector := range selectors {
+		require.Equal(t, pluginName, selector.Type)
+		selectorValues = append(selectorValues, selector.Value)
+	}
+	return selectorValues, nil
+}
+
+type fakeFileSystem map[string]string
+
+func (fs fakeFileSystem) Open(path string) (io.ReadCloser, error) {
+	data, ok := fs[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+type fakeCRIServer struct {
+	criv1.UnimplementedRuntimeServiceServer
+}
+
+func (s *fakeCRIServer) ListContainers(ctx context.Context, req *criv1.ListContainersRequest) (*criv1.ListContainersResponse, error) {
+	switch req.GetFilter().GetId() {
+	case testContainerID:
+		return &criv1.ListContainersResponse{
+			Containers: []*criv1.Container{
+				{
+					Id:           testContainerID,
+					PodSandboxId: "sandbox",
+					Metadata:     &criv1.ContainerMetadata{Name: "nginx"},
+					Image:        &criv1.ImageSpec{Image: "docker.io/library/nginx:1.21"},
+					ImageRef:     "docker.io/library/nginx@" + testDigest,
+					Labels: map[string]string{
+						"io.kubernetes.pod.namespace":  "prod",
+						"io.kubernetes.container.name": "nginx",
+					},
+				},
+			},
+		}, nil
+	case strings.Repeat("b", 64):
+		return nil, status.Error(codes.Unavailable, "runtime error")
+	default:
+		return &criv1.ListContainersResponse{}, nil
+	}
+}
+
+func (s *fakeCRIServer) PodSandboxStatus(ctx context.Context, req *criv1.PodSandboxStatusRequest) (*criv1.PodSandboxStatusResponse, error) {
+	if req.PodSandboxId != "sandbox" {
+		return nil, status.Error(codes.NotFound, "sandbox not found")
+	}
+	return &criv1.PodSandboxStatusResponse{
+		Status: &criv1.PodSandboxStatus{
+			Id:       "sandbox",
+			Metadata: &criv1.PodSandboxMetadata{Name: "web", Namespace: "prod"},
+		},
+	}, nil
+}
+
+// fakeCRIV1alpha2Server implements the runtime service of runtimes that
+// predate the v1 CRI API
+type fakeCRIV1alpha2Server struct {
+	criv1alpha2.UnimplementedRuntimeServiceServer
+}
+
+func (s *fakeCRIV1alpha2Server) ListContainers(ctx context.Context, req *criv1alpha2.ListContainersRequest) (*criv1alpha2.ListContainersResponse, error) {
+	switch req.GetFilter().GetId() {
+	case testContainerID:
+		return &criv1alpha2.ListContainersResponse{
+			Containers: []*criv1alpha2.Container{
+				{
+					Id:           testContainerID,
+					PodSandboxId: "sandbox",
+					Metadata:     &criv1alpha2.ContainerMetadata{Name: "nginx"},
+					Image:        &criv1alpha2.ImageSpec{Image: "docker.io/library/nginx:1.21"},
+					ImageRef:     "docker.io/library/nginx@" + testDigest,
+					Labels: map[string]string{
+						"io.kubernetes.pod.namespace":  "prod",
+						"io.kubernetes.container.name": "nginx",
+					},
+				},
+			},
+		}, nil
+	case strings.Repeat("b", 64):
+		return nil, status.Error(codes.Unavailable, "runtime error")
+	default:
+		return &criv1alpha2.ListContainersResponse{}, nil
+	}
+}
+
+func (s *fakeCRIV1alpha2Server) PodSandboxStatus(ctx context.Context, req *criv1alpha2.PodSandboxStatusRequest) (*criv1alpha2.PodSandboxStatusResponse, error) {
+	if req.PodSandboxId != "sandbox" {
+		return nil, status.Error(codes.NotFound, "sandbox not found")
+	}
+	return &criv1alpha2.PodSandboxStatusResponse{
+		Status: &criv1alpha2.PodSandboxStatus{
+			Id:       "sandbox",
+			Metadata: &criv1alpha2.PodSandboxMetadata{Name: "web", Namespace: "prod"},
+		},
+	}, nil
+}
+
+type fakeNamespacesServer struct {
+	namespacesapi.NamespacesServer
+}
+
+func (s *fakeNamespacesServer) List(ctx context.Context, req *namespacesapi.ListNamespacesRequest) (*namespacesapi.ListNamespacesResponse, error) {
+	return &namespacesapi.ListNamespacesResponse{
+		Namespaces: []namespacesapi.Namespace{
+			{Name: "k8s.io"},
+			{Name: "default"},
+		},
+	}, nil
+}
+
+type fakeContainersServer struct {
+	containersapi.ContainersServer
+}
+
+func (s *fakeContainersServer) Get(ctx context.Context, req *containersapi.GetContainerRequest) (*containersapi.GetContainerResponse, error) {
+	if req.ID == "error" {
+		return nil, status.Error(codes.Unavailable, "runtime error")
+	}
+	if namespaceFromContext(ctx) != "default" {
+		return nil, status.Error(codes.NotFound, "container not found")
+	}
+	switch req.ID {
+	case testContainerID:
+		return &containersapi.GetContainerResponse{
+			Container: containersapi.Container{
+				ID:     testContainerID,
+				Image:  "docker.io/library/nginx:1.21",
+				Labels: map[string]string{"app": "web"},
+			},
+		}, nil
+	case "noimage":
+		return &containersapi.GetContainerResponse{
+			Container: containersapi.Container{
+				ID:    "noimage",
+				Image: "docker.io/library/removed:1.0",
+			},
+		}, nil
+	default:
+		return nil, status.Error(codes.NotFound, "container not found")
+	}
+}
+
+type fakeImagesServer struct {
+	imagesapi.ImagesServer
+}
+
+func (s *fakeImagesServer) Get(ctx context.Context, req *imagesapi.GetImageRequest) (*imagesapi.GetImageResponse, error) {
+	if namespaceFromContext(ctx) != "default" || req.Name != "docker.io/library/nginx:1.21" {
+		return nil, status.Error(codes.NotFound, "image not found")
+	}
+	return &imagesapi.GetImageResponse{
+		Image: &imagesapi.Image{
+			Name:   req.Name,
+			Target: types.Descriptor{Digest: testDigest},
+		},
+	}, nil
+}
+
+func namespaceFromContext(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(containerdNamespaceHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package cri
+
+import (
+	"context"
+	"sync/atomic"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	criv1 "k8s.io/cri-api/pkg/apis/runtime/v1"
+	criv1alpha2 "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// containerdNamespaceHeader is the gRPC header used by containerd to scope
+// requests to a namespace.
+const containerdNamespaceHeader = "containerd-namespace"
+
+// criClient looks up containers using the CRI runtime service. The v1 API is
+// used unless the runtime only implements v1alpha2.
+type criClient struct {
+	v1       criv1.RuntimeServiceClient
+	v1alpha2 criv1alpha2.RuntimeServiceClient
+
+	// v1alpha2Only is set when the runtime reports that it does not
+	// implement the v1 API
+	v1alpha2Only int32
+}
+
+func newCRIClient(conn *grpc.ClientConn) runtimeClient {
+	return &criClient{
+		v1:       criv1.NewRuntimeServiceClient(conn),
+		v1alpha2: criv1alpha2.NewRuntimeServiceClient(conn),
+	}
+}
+
+func (c *criClient) GetContainer(ctx context.Context, containerID string) (*containerInfo, error) {
+	if atomic.LoadInt32(&c.v1alpha2Only) == 0 {
+		info, err := c.getContainerV1(ctx, containerID)
+		if status.Code(err) != codes.Unimplemented {
+			return info, err
+		}
+		atomic.StoreInt32(&c.v1alpha2Only, 1)
+	}
+	return c.getContainerV1alpha2(ctx, containerID)
+}
+
+func (c *criClient) getContainerV1(ctx context.Context, containerID string) (*containerInfo, error) {
+	resp, err := c.v1.ListContainers(ctx, &criv1.ListContainersRequest{
+		Filter: &criv1.ContainerFilter{Id: containerID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Containers) == 0 {
+		return nil, nil
+	}
+	container := resp.Containers[0]
+
+	info := &containerInfo{
+		Name:        container.GetMetadata().GetName(),
+		Image:       container.GetImage().GetImage(),
+		ImageDigest: imageDigestFromRef(container.ImageRef),
+		Labels:      container.Labels,
+	}
+
+	if container.PodSandboxId != "" {
+		sandbox, err := c.v1.PodSandboxStatus(ctx, &criv1.PodSandboxStatusRequest{
+			PodSandboxId: container.PodSandboxId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		info.Namespace = sandbox.GetStatus().GetMetadata().GetNamespace()
+	}
+	return info, nil
+}
+
+func (c *criClient) getContainerV1alpha2(ctx context.Context, containerID string) (*containerInfo, error) {
+	resp, err := c.v1alpha2.ListContainers(ctx, &criv1alpha2.ListContainersRequest{
+		Filter: &criv1alpha2.ContainerFilter{Id: containerID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Containers) == 0 {
+		return nil, nil
+	}
+	container := resp.Containers[0]
+
+	info := &containerInfo{
+		Name:        container.GetMetadata().GetName(),
+		Image:       container.GetImage().GetImage(),
+		ImageDigest: imageDigestFromRef(container.ImageRef),
+		Labels:      container.Labels,
+	}
+
+	if container.PodSandboxId != "" {
+		sandbox, err := c.v1alpha2.PodSandboxStatus(ctx, &criv1alpha2.PodSandboxStatusRequest{
+			PodSandboxId: container.PodSandboxId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		info.Namespace = sandbox.GetStatus().GetMetadata().GetNamespace()
+	}
+	return info, nil
+}
+
+// containerdClient looks up containers using the containerd API. Containers
+// are searched for in every containerd namespace.
+type containerdClient struct {
+	namespaces namespacesapi.NamespacesClient
+	containers containersapi.ContainersClient
+	images     imagesapi.ImagesClient
+}
+
+func newContainerdClient(conn *grpc.ClientConn) runtimeClient {
+	return containerdClient{
+		namespaces: namespacesapi.NewNamespacesClient(conn),
+		containers: containersapi.NewContainersClient(conn),
+		images:     imagesapi.NewImagesClient(conn),
+	}
+}
+
+func (c containerdClient) GetContainer(ctx context.Context, containerID string) (*containerInfo, error) {
+	namespaces, err := c.namespaces.List(ctx, &namespacesapi.ListNamespacesRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, namespace := range namespaces.Namespaces {
+		nsCtx := metadata.AppendToOutgoingContext(ctx, containerdNamespaceHeader, namespace.Name)
+
+		resp, err := c.containers.Get(nsCtx, &containersapi.GetContainerRequest{ID: containerID})
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.NotFound:
+			continue
+		default:
+			return nil, err
+		}
+
+		info := &containerInfo{
+			Name:                resp.Container.ID,
+			ContainerdNamespace: namespace.Name,
+			Image:               resp.Container.Image,
+			Labels:              resp.Container.Labels,
+		}
+
+		if resp.Container.Image != "" {
+			image, err := c.images.Get(nsCtx, &imagesapi.GetImageRequest{Name: resp.Container.Image})
+			switch status.Code(err) {
+			case codes.OK:
+				info.ImageDigest = image.Image.Target.Digest.String()
+			case codes.NotFound:
+				// The image was removed after the container was created
+			default:
+				return nil, err
+			}
+		}
+		return info, nil
+	}
+	return nil, nil
+}
@@ -15,8 +15,10 @@ import (
 	workloadattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/workloadattestor/v1"
 	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
 	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/common/sigstore"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/docker/cgroup"
 	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/telemetry"
 )
 
 const (
@@ -40,6 +42,7 @@ func builtin(p *Plugin) catalog.BuiltIn {
 // Docker is a subset of the docker client functionality, useful for mocking.
 type Docker interface {
 	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
 }
 
 type Plugin struct {
@@ -53,6 +56,7 @@ type Plugin struct {
 	mtx               sync.RWMutex
 	containerIDFinder cgroup.ContainerIDFinder
 	docker            Docker
+	sigVerifier       *sigstore.Verifier
 }
 
 func New() *Plugin {
@@ -70,6 +74,9 @@ type dockerPluginConfig struct {
 	// ContainerIDCGroupMatchers is a list of patterns used to discover container IDs from cgroup entries.
 	// See the documentation for cgroup.NewContainerIDFinder in the cgroup subpackage for more information.
 	ContainerIDCGroupMatchers []string `hcl:"container_id_cgroup_matchers"`
+	// ImageSignatureVerification, if set, enables the verification of the
+	// container image signatures against the configured public keys.
+	ImageSignatureVerification *sigstore.HCLConfig `hcl:"image_signature_verification"`
 }
 
 func (p *Plugin) SetLogger(log hclog.Logger) {
@@ -106,11 +113,53 @@ func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestReque
 		return nil, err
 	}
 
+	selectorValues := getSelectorValuesFromConfig(container.Config)
+	if p.sigVerifier != nil {
+		selectorValues = append(selectorValues, p.getImageSignatureSelectorValues(ctx, container)...)
+	}
+
 	return &workloadattestorv1.AttestResponse{
-		SelectorValues: getSelectorValuesFromConfig(container.Config),
+		SelectorValues: selectorValues,
 	}, nil
 }
 
+// getImageSignatureSelectorValues verifies the signatures of the container
+// image for each of its repository digests. Failures to verify are logged and
+// only result in missing signature selectors, so that workloads that do not
+// rely on them can still be attested while the registry is unavailable.
+func (p *Plugin) getImageSignatureSelectorValues(ctx context.Context, container types.ContainerJSON) []string {
+	imageID := container.Config.Image
+	if container.ContainerJSONBase != nil && container.Image != "" {
+		imageID = container.Image
+	}
+	if imageID == "" {
+		return nil
+	}
+
+	image, _, err := p.docker.ImageInspectWithRaw(ctx, imageID)
+	if err != nil {
+		p.log.Warn("Unable to inspect container image; skipping signature verification", telemetry.ImageRef, imageID, telemetry.Error, err)
+		return nil
+	}
+
+	var selectorValues []string
+	seen := make(map[string]bool)
+	for _, repoDigest := range image.RepoDigests {
+		values, err := p.sigVerifier.Verify(ctx, repoDigest)
+		if err != nil {
+			p.log.Warn("Unable to verify container image signatures", telemetry.ImageRef, repoDigest, telemetry.Error, err)
+			continue
+		}
+		for _, value := range values {
+			if !seen[value] {
+				seen[value] = true
+				selectorValues = append(selectorValues, value)
+			}
+		}
+	}
+	return selectorValues
+}
+
 func getSelectorValuesFromConfig(cfg *container.Config) []string {
 	var selectorValues []string
 	for label, value := range cfg.Labels {
@@ -156,10 +205,19 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 		}
 	}
 
+	var sigVerifier *sigstore.Verifier
+	if config.ImageSignatureVerification != nil {
+		sigVerifier, err = sigstore.NewVerifier(config.ImageSignatureVerification)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image signature verification configuration: %w", err)
+		}
+	}
+
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 	p.docker = docker
 	p.containerIDFinder = containerIDFinder
+	p.sigVerifier = sigVerifier
 	return &configv1.ConfigureResponse{}, nil
 }
 
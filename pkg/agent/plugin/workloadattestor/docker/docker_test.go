@@ -21,7 +21,9 @@ import (
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/docker/cgroup"
 	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/fakes/fakeregistry"
 	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/testkey"
 	"github.com/stretchr/testify/require"
 )
 
@@ -96,6 +98,86 @@ func TestDockerSelectors(t *testing.T) {
 	}
 }
 
+func TestDockerImageSignatureSelectors(t *testing.T) {
+	key := testkey.NewEC256(t)
+	keyPath := fakeregistry.WritePublicKey(t, t.TempDir(), "key", key)
+
+	registry := fakeregistry.New(t)
+	signed := registry.PushImage(t, "signed")
+	registry.Sign(t, signed, key, "example.org/signed:v1")
+	unsigned := registry.PushImage(t, "unsigned")
+
+	config := `
+image_signature_verification {
+	public_keys = {
+		"release" = "` + keyPath + `"
+	}
+}`
+
+	tests := []struct {
+		desc                 string
+		repoDigests          []string
+		inspectErr           error
+		expectSelectorValues []string
+	}{
+		{
+			desc:        "signed image",
+			repoDigests: []string{signed},
+			expectSelectorValues: []string{
+				"image-signature-subject:release:example.org/signed:v1",
+				"image-signed-by:release",
+				"image_id:my-docker-image",
+			},
+		},
+		{
+			desc:        "unsigned image",
+			repoDigests: []string{unsigned},
+			expectSelectorValues: []string{
+				"image_id:my-docker-image",
+			},
+		},
+		{
+			desc: "signature verification failure is not fatal",
+			repoDigests: []string{
+				"localhost:1/app@sha256:0123456789012345678901234567890123456789012345678901234567890123",
+				signed,
+			},
+			expectSelectorValues: []string{
+				"image-signature-subject:release:example.org/signed:v1",
+				"image-signed-by:release",
+				"image_id:my-docker-image",
+			},
+		},
+		{
+			desc:       "image inspection failure is not fatal",
+			inspectErr: errors.New("oh no"),
+			expectSelectorValues: []string{
+				"image_id:my-docker-image",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			d := fakeImageContainer{
+				imageID:     "sha256:6e9d4e8d7a9b",
+				repoDigests: tt.repoDigests,
+				inspectErr:  tt.inspectErr,
+			}
+
+			p := newTestPlugin(t,
+				withConfig(t, config),
+				withDocker(d),
+				withFileSystem(newFakeFileSystem(testCgroupEntries)))
+
+			selectorValues, err := doAttest(t, p)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectSelectorValues, selectorValues)
+		})
+	}
+}
+
 func TestContainerExtraction(t *testing.T) {
 	tests := []struct {
 		desc      string
@@ -291,6 +373,16 @@ container_id_cgroup_matchers = [
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `must contain the container id token "<id>" exactly once`)
 	})
+	t.Run("bad image signature verification", func(t *testing.T) {
+		p := New()
+		cfg := `
+image_signature_verification {
+	public_keys = {}
+}`
+		err := doConfigure(t, p, cfg)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid image signature verification configuration: at least one public key is required")
+	})
 	t.Run("bad hcl", func(t *testing.T) {
 		p := New()
 		cfg := `
@@ -391,6 +483,10 @@ func (dockerError) ContainerInspect(ctx context.Context, containerID string) (ty
 	return types.ContainerJSON{}, errors.New("docker error")
 }
 
+func (dockerError) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	return types.ImageInspect{}, nil, errors.New("docker error")
+}
+
 type fakeContainer container.Config
 
 func (f fakeContainer) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
@@ -403,6 +499,40 @@ func (f fakeContainer) ContainerInspect(ctx context.Context, containerID string)
 	}, nil
 }
 
+func (f fakeContainer) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	return types.ImageInspect{}, nil, errors.New("unexpected image inspection")
+}
+
+type fakeImageContainer struct {
+	imageID     string
+	repoDigests []string
+	inspectErr  error
+}
+
+func (f fakeImageContainer) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			Image: f.imageID,
+		},
+		Config: &container.Config{
+			Image: "my-docker-image",
+		},
+	}, nil
+}
+
+func (f fakeImageContainer) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	if f.inspectErr != nil {
+		return types.ImageInspect{}, nil, f.inspectErr
+	}
+	if imageID != f.imageID {
+		return types.ImageInspect{}, nil, errors.New("expected test image ID")
+	}
+	return types.ImageInspect{
+		ID:          f.imageID,
+		RepoDigests: f.repoDigests,
+	}, nil, nil
+}
+
 func newFakeFileSystem(cgroups string) FakeFileSystem {
 	return FakeFileSystem{
 		Files: map[string]string{
@@ -0,0 +1,156 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	podUIDIndex = "uid"
+
+	// serviceAccountCacheTTL is how long a service account fetched from the
+	// API server is cached.
+	serviceAccountCacheTTL = time.Minute
+)
+
+// podWatcher maintains a cache of the pods scheduled on a node by watching the
+// API server. It is used instead of the kubelet when the plugin is configured
+// to use the API server. Service accounts are fetched on demand, and cached
+// for a short time, so the agent does not need to watch every service account
+// in the cluster.
+type podWatcher struct {
+	client kubernetes.Interface
+	clock  clock.Clock
+	pods   cache.SharedIndexInformer
+	cancel context.CancelFunc
+
+	mtx             sync.Mutex
+	serviceAccounts map[string]serviceAccountCacheEntry
+}
+
+type serviceAccountCacheEntry struct {
+	// serviceAccount is nil if the service account does not exist
+	serviceAccount *corev1.ServiceAccount
+	expiresAt      time.Time
+}
+
+func newKubeClient(kubeConfigPath string) (kubernetes.Interface, error) {
+	var config *rest.Config
+	var err error
+	if kubeConfigPath != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+	} else {
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// newPodWatcher starts watching the pods scheduled on the given node. The
+// cache is populated in the background; until it has synced, lookups report
+// that the pod is not found.
+func newPodWatcher(client kubernetes.Interface, nodeName string, clk clock.Clock) (*podWatcher, error) {
+	podFactory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}),
+	)
+	pods := podFactory.Core().V1().Pods().Informer()
+	if err := pods.AddIndexers(cache.Indexers{podUIDIndex: indexPodByUID}); err != nil {
+		return nil, fmt.Errorf("unable to index pods: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	podFactory.Start(ctx.Done())
+
+	return &podWatcher{
+		client:          client,
+		clock:           clk,
+		pods:            pods,
+		cancel:          cancel,
+		serviceAccounts: make(map[string]serviceAccountCacheEntry),
+	}, nil
+}
+
+// Stop stops watching the API server.
+func (w *podWatcher) Stop() {
+	w.cancel()
+}
+
+// GetPod returns the pod with the given UID, if it is scheduled on the node.
+func (w *podWatcher) GetPod(uid types.UID) (*corev1.Pod, bool, error) {
+	if !w.pods.HasSynced() {
+		return nil, false, nil
+	}
+
+	objs, err := w.pods.GetIndexer().ByIndex(podUIDIndex, string(uid))
+	if err != nil {
+		return nil, false, err
+	}
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			return pod, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// GetServiceAccount returns the service account with the given namespace and
+// name. It is fetched from the API server unless it was recently cached.
+func (w *podWatcher) GetServiceAccount(ctx context.Context, namespace, name string) (*corev1.ServiceAccount, bool, error) {
+	key := namespace + "/" + name
+
+	w.mtx.Lock()
+	entry, ok := w.serviceAccounts[key]
+	w.mtx.Unlock()
+	if ok && w.clock.Now().Before(entry.expiresAt) {
+		return entry.serviceAccount, entry.serviceAccount != nil, nil
+	}
+
+	serviceAccount, err := w.client.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		serviceAccount = nil
+	case err != nil:
+		return nil, false, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	now := w.clock.Now()
+	for key, entry := range w.serviceAccounts {
+		if !now.Before(entry.expiresAt) {
+			delete(w.serviceAccounts, key)
+		}
+	}
+	w.serviceAccounts[key] = serviceAccountCacheEntry{
+		serviceAccount: serviceAccount,
+		expiresAt:      now.Add(serviceAccountCacheTTL),
+	}
+	return serviceAccount, serviceAccount != nil, nil
+}
+
+func indexPodByUID(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, nil
+	}
+	return []string{string(pod.UID)}, nil
+}
@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,6 +26,7 @@ import (
 	workloadattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/workloadattestor/v1"
 	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
 	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/common/sigstore"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/pemutil"
 	"github.com/spiffe/spire/pkg/common/telemetry"
@@ -32,6 +34,7 @@ import (
 	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 )
 
 const (
@@ -116,6 +119,20 @@ type HCLConfig struct {
 	// ReloadInterval controls how often TLS and token configuration is loaded
 	// from the disk.
 	ReloadInterval string `hcl:"reload_interval"`
+
+	// ImageSignatureVerification, if set, enables the verification of the
+	// container image signatures against the configured public keys.
+	ImageSignatureVerification *sigstore.HCLConfig `hcl:"image_signature_verification"`
+
+	// UseAPIServer, if true, causes the plugin to look up pods in a cache
+	// of the pods scheduled on the node, kept up to date by watching the
+	// Kubernetes API server, instead of querying the kubelet.
+	UseAPIServer bool `hcl:"use_api_server"`
+
+	// KubeConfigFilePath is the path to the kubeconfig file used to contact
+	// the API server when UseAPIServer is set. If unset, the in-cluster
+	// configuration is used.
+	KubeConfigFilePath string `hcl:"kube_config_file_path"`
 }
 
 // k8sConfig holds the configuration distilled from HCL
@@ -131,6 +148,8 @@ type k8sConfig struct {
 	KubeletCAPath           string
 	NodeName                string
 	ReloadInterval          time.Duration
+	SigVerifier             *sigstore.Verifier
+	PodWatcher              *podWatcher
 
 	Client     *kubeletClient
 	LastReload time.Time
@@ -145,6 +164,8 @@ type Plugin struct {
 	clock  clock.Clock
 	getenv func(string) string
 
+	newKubeClient func(kubeConfigPath string) (kubernetes.Interface, error)
+
 	mu     sync.RWMutex
 	config *k8sConfig
 }
@@ -154,6 +175,8 @@ func New() *Plugin {
 		fs:     cgroups.OSFileSystem{},
 		clock:  clock.New(),
 		getenv: os.Getenv,
+
+		newKubeClient: newKubeClient,
 	}
 }
 
@@ -187,12 +210,12 @@ func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestReque
 	for attempt := 1; ; attempt++ {
 		log = log.With(telemetry.Attempt, attempt)
 
-		list, err := config.Client.GetPodList()
+		pods, err := p.getPods(config, podUID)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, item := range list.Items {
+		for _, item := range pods {
 			item := item
 			if item.UID != podUID {
 				continue
@@ -201,8 +224,19 @@ func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestReque
 			status, lookup := lookUpContainerInPod(containerID, item.Status)
 			switch lookup {
 			case containerInPod:
+				selectorValues := getSelectorValuesFromPodInfo(&item, status)
+				if config.PodWatcher != nil {
+					saSelectorValues, err := getServiceAccountSelectorValues(ctx, config.PodWatcher, &item)
+					if err != nil {
+						return nil, err
+					}
+					selectorValues = append(selectorValues, saSelectorValues...)
+				}
+				if config.SigVerifier != nil {
+					selectorValues = append(selectorValues, getImageSignatureSelectorValues(ctx, log, config.SigVerifier, status)...)
+				}
 				return &workloadattestorv1.AttestResponse{
-					SelectorValues: getSelectorValuesFromPodInfo(&item, status),
+					SelectorValues: selectorValues,
 				}, nil
 			case containerNotInPod:
 			}
@@ -283,7 +317,15 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 	// Determine the node name
 	nodeName := p.getNodeName(config.NodeName, config.NodeNameEnv)
 
-	// Configure the kubelet client
+	var sigVerifier *sigstore.Verifier
+	if config.ImageSignatureVerification != nil {
+		sigVerifier, err = sigstore.NewVerifier(config.ImageSignatureVerification)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid image signature verification configuration: %v", err)
+		}
+	}
+
+	// Configure the kubelet client, or the API server watch that replaces it
 	c := &k8sConfig{
 		Secure:                  secure,
 		Port:                    port,
@@ -296,8 +338,21 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 		KubeletCAPath:           config.KubeletCAPath,
 		NodeName:                nodeName,
 		ReloadInterval:          reloadInterval,
+		SigVerifier:             sigVerifier,
 	}
-	if err := p.reloadKubeletClient(c); err != nil {
+	if config.UseAPIServer {
+		if nodeName == "" {
+			return nil, status.Error(codes.InvalidArgument, "node name is required when using the API server")
+		}
+		client, err := p.newKubeClient(config.KubeConfigFilePath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to create API server client: %v", err)
+		}
+		c.PodWatcher, err = newPodWatcher(client, nodeName, p.clock)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to watch pods: %v", err)
+		}
+	} else if err := p.reloadKubeletClient(c); err != nil {
 		return nil, err
 	}
 
@@ -309,9 +364,22 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 func (p *Plugin) setConfig(config *k8sConfig) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.config != nil && p.config.PodWatcher != nil {
+		p.config.PodWatcher.Stop()
+	}
 	p.config = config
 }
 
+// Close stops watching the API server when the plugin is unloaded.
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config != nil && p.config.PodWatcher != nil {
+		p.config.PodWatcher.Stop()
+	}
+	return nil
+}
+
 func (p *Plugin) getConfig() (*k8sConfig, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -319,12 +387,35 @@ func (p *Plugin) getConfig() (*k8sConfig, error) {
 	if p.config == nil {
 		return nil, status.Error(codes.FailedPrecondition, "not configured")
 	}
-	if err := p.reloadKubeletClient(p.config); err != nil {
-		p.log.Warn("Unable to load kubelet client", "err", err)
+	if p.config.PodWatcher == nil {
+		if err := p.reloadKubeletClient(p.config); err != nil {
+			p.log.Warn("Unable to load kubelet client", "err", err)
+		}
 	}
 	return p.config, nil
 }
 
+// getPods returns the pods that might host the pod with the given UID. When
+// watching the API server only the matching pod, if known, is returned.
+func (p *Plugin) getPods(config *k8sConfig, podUID types.UID) ([]corev1.Pod, error) {
+	if config.PodWatcher == nil {
+		list, err := config.Client.GetPodList()
+		if err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+
+	pod, ok, err := config.PodWatcher.GetPod(podUID)
+	switch {
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to look up pod: %v", err)
+	case !ok:
+		return nil, nil
+	}
+	return []corev1.Pod{*pod}, nil
+}
+
 func (p *Plugin) getPodUIDAndContainerIDFromCGroups(pid int32) (types.UID, string, error) {
 	cgroups, err := cgroups.GetCgroups(pid, p.fs)
 	if err != nil {
@@ -711,6 +802,57 @@ func getSelectorValuesFromPodInfo(pod *corev1.Pod, status *corev1.ContainerStatu
 	return selectorValues
 }
 
+// getServiceAccountSelectorValues returns the selectors for the annotations
+// of the service account the pod runs as.
+func getServiceAccountSelectorValues(ctx context.Context, watcher *podWatcher, pod *corev1.Pod) ([]string, error) {
+	serviceAccount, ok, err := watcher.GetServiceAccount(ctx, pod.Namespace, pod.Spec.ServiceAccountName)
+	switch {
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to look up service account: %v", err)
+	case !ok:
+		return nil, nil
+	}
+
+	var selectorValues []string
+	for k, v := range serviceAccount.Annotations {
+		selectorValues = append(selectorValues, fmt.Sprintf("sa-annotation:%s:%s", k, v))
+	}
+	sort.Strings(selectorValues)
+	return selectorValues, nil
+}
+
+// getImageSignatureSelectorValues verifies the signatures of the image the
+// container is running. Failures to verify are logged and only result in
+// missing signature selectors, so that workloads that do not rely on them can
+// still be attested while the registry is unavailable.
+func getImageSignatureSelectorValues(ctx context.Context, log hclog.Logger, verifier *sigstore.Verifier, containerStatus *corev1.ContainerStatus) []string {
+	imageRef, ok := imageDigestRef(containerStatus.ImageID)
+	if !ok {
+		log.Debug("Container image ID is not a digest reference; skipping signature verification", telemetry.ImageRef, containerStatus.ImageID)
+		return nil
+	}
+
+	selectorValues, err := verifier.Verify(ctx, imageRef)
+	if err != nil {
+		log.Warn("Unable to verify container image signatures", telemetry.ImageRef, imageRef, telemetry.Error, err)
+		return nil
+	}
+	return selectorValues
+}
+
+// imageDigestRef returns the digest reference (i.e. repository@digest) in the
+// image ID reported by the container runtime, which may be prefixed with a
+// runtime specific scheme (e.g. docker-pullable://).
+func imageDigestRef(imageID string) (string, bool) {
+	if i := strings.Index(imageID, "://"); i >= 0 {
+		imageID = imageID[i+3:]
+	}
+	if !strings.Contains(imageID, "@") {
+		return "", false
+	}
+	return imageID, true
+}
+
 func tryRead(r io.Reader) string {
 	buf := make([]byte, 1024)
 	n, _ := r.Read(buf)
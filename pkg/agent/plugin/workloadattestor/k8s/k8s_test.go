@@ -11,6 +11,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -19,6 +21,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,12 +31,20 @@ import (
 	"github.com/spiffe/spire/pkg/common/util"
 	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/fakes/fakeregistry"
 	"github.com/spiffe/spire/test/plugintest"
 	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 const (
@@ -347,6 +358,220 @@ func (s *Suite) TestAttestAgainstNodeOverride() {
 	s.Require().Empty(selectors)
 }
 
+func (s *Suite) TestAttestWithImageSignatures() {
+	key := testkey.NewEC256(s.T())
+	keyPath := fakeregistry.WritePublicKey(s.T(), s.dir, "release", key)
+
+	registry := fakeregistry.New(s.T())
+	signed := registry.PushImage(s.T(), "spiffe/blog")
+	registry.Sign(s.T(), signed, key, "localhost/spiffe/blog:latest")
+
+	// Point the image of the container at the fake registry
+	const fixtureImageID = "localhost/spiffe/blog@sha256:0cfdaced91cb46dd7af48309799a3c351e4ca2d5e1ee9737ca0cbd932cb79898"
+	podList, err := os.ReadFile(podListFilePath)
+	s.Require().NoError(err)
+	s.podList = append(s.podList, []byte(strings.ReplaceAll(string(podList), fixtureImageID, signed)))
+	s.addCgroupsResponse(cgPidInPodFilePath)
+
+	var expectedSelectors []*common.Selector
+	for _, selector := range testPodSelectors {
+		expectedSelectors = append(expectedSelectors, &common.Selector{
+			Type:  selector.Type,
+			Value: strings.ReplaceAll(selector.Value, fixtureImageID, signed),
+		})
+	}
+	expectedSelectors = append(expectedSelectors,
+		&common.Selector{Type: "k8s", Value: "image-signature-subject:release:localhost/spiffe/blog:latest"},
+		&common.Selector{Type: "k8s", Value: "image-signed-by:release"},
+	)
+	util.SortSelectors(expectedSelectors)
+
+	s.startInsecureKubelet()
+	p := s.loadPlugin(fmt.Sprintf(`
+		kubelet_read_only_port = %d
+		image_signature_verification {
+			public_keys = {
+				"release" = %q
+			}
+		}
+`, s.kubeletPort(), keyPath))
+
+	s.requireAttestSuccess(p, expectedSelectors)
+}
+
+func (s *Suite) TestAttestViaAPIServer() {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "default",
+			Annotations: map[string]string{
+				"example.org/team": "blog",
+				"example.org/env":  "prod",
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(append(s.loadPods(podListFilePath), serviceAccount)...)
+	s.addCgroupsResponse(cgPidInPodFilePath)
+
+	p, v1 := s.loadAPIServerPlugin(client, `
+		use_api_server = true
+		node_name = "k8s-node-1"
+`)
+	s.waitForPodWatcherSync(p)
+
+	expectedSelectors := append([]*common.Selector{
+		{Type: "k8s", Value: "sa-annotation:example.org/env:prod"},
+		{Type: "k8s", Value: "sa-annotation:example.org/team:blog"},
+	}, testPodSelectors...)
+	util.SortSelectors(expectedSelectors)
+	s.requireAttestSuccess(v1, expectedSelectors)
+
+	// The service account is fetched on demand and cached, instead of
+	// watching every service account in the cluster
+	s.requireAttestSuccess(v1, expectedSelectors)
+	s.Require().Equal(1, countServiceAccountGets(client))
+	s.clock.Add(serviceAccountCacheTTL)
+	s.requireAttestSuccess(v1, expectedSelectors)
+	s.Require().Equal(2, countServiceAccountGets(client))
+	for _, action := range client.Actions() {
+		if action.GetResource().Resource == "serviceaccounts" {
+			s.Require().Equal("get", action.GetVerb())
+			s.Require().Equal("default", action.GetNamespace())
+		}
+	}
+
+	// Annotation selectors are sorted
+	saSelectorValues, err := getServiceAccountSelectorValues(context.Background(), p.config.PodWatcher, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec:       corev1.PodSpec{ServiceAccountName: "default"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{
+		"sa-annotation:example.org/env:prod",
+		"sa-annotation:example.org/team:blog",
+	}, saSelectorValues)
+
+	// Only the pods scheduled on the node are watched
+	var podListRestrictions []k8stesting.ListRestrictions
+	for _, action := range client.Actions() {
+		if listAction, ok := action.(k8stesting.ListAction); ok && action.GetResource().Resource == "pods" {
+			podListRestrictions = append(podListRestrictions, listAction.GetListRestrictions())
+		}
+	}
+	s.Require().NotEmpty(podListRestrictions)
+	for _, restrictions := range podListRestrictions {
+		s.Require().Equal("spec.nodeName=k8s-node-1", restrictions.Fields.String())
+	}
+}
+
+func (s *Suite) TestCloseStopsPodWatcher() {
+	p := s.newPlugin()
+	p.newKubeClient = func(string) (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(), nil
+	}
+	plugin := plugintest.Load(s.T(), builtin(p), nil,
+		plugintest.Configure(`
+		use_api_server = true
+		node_name = "k8s-node-1"
+`),
+	)
+
+	watcher := p.config.PodWatcher
+	stopped := false
+	cancel := watcher.cancel
+	watcher.cancel = func() {
+		stopped = true
+		cancel()
+	}
+
+	s.Require().NoError(plugin.Close())
+	s.Require().True(stopped)
+}
+
+func (s *Suite) TestAttestViaAPIServerAfterRetry() {
+	client := fake.NewSimpleClientset()
+	s.addCgroupsResponse(cgPidInPodFilePath)
+
+	p, v1 := s.loadAPIServerPlugin(client, `
+		use_api_server = true
+		node_name = "k8s-node-1"
+		max_poll_attempts = 5
+		poll_retry_interval = "1s"
+`)
+	s.waitForPodWatcherSync(p)
+
+	resultCh := s.goAttest(v1)
+	s.clock.WaitForAfter(time.Minute, "waiting for retry timer")
+
+	// The pod is scheduled while the plugin waits to retry
+	for _, obj := range s.loadPods(podListFilePath) {
+		pod := obj.(*corev1.Pod)
+		_, err := client.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+		s.Require().NoError(err)
+	}
+	s.Require().Eventually(func() bool {
+		_, ok, err := p.config.PodWatcher.GetPod("2c48913c-b29f-11e7-9350-020968147796")
+		return err == nil && ok
+	}, time.Minute, 10*time.Millisecond)
+	s.clock.Add(time.Second)
+
+	select {
+	case result := <-resultCh:
+		s.Require().NoError(result.err)
+		s.requireSelectorsEqual(testPodSelectors, result.selectors)
+	case <-time.After(time.Minute):
+		s.FailNow("timed out waiting for attest response")
+	}
+}
+
+func (s *Suite) TestConfigureAPIServer() {
+	newKubeClientErr := errors.New("oh no")
+
+	for _, tt := range []struct {
+		name          string
+		hcl           string
+		newKubeClient func(string) (kubernetes.Interface, error)
+		expectCode    codes.Code
+		expectMsg     string
+	}{
+		{
+			name:       "missing node name",
+			hcl:        `use_api_server = true`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "node name is required when using the API server",
+		},
+		{
+			name: "client creation fails",
+			hcl: `
+				use_api_server = true
+				node_name = "k8s-node-1"
+				kube_config_file_path = "kubeconfig"
+			`,
+			newKubeClient: func(path string) (kubernetes.Interface, error) {
+				s.Require().Equal("kubeconfig", path)
+				return nil, newKubeClientErr
+			},
+			expectCode: codes.Internal,
+			expectMsg:  "unable to create API server client: oh no",
+		},
+	} {
+		tt := tt
+		s.Run(tt.name, func() {
+			p := s.newPlugin()
+			if tt.newKubeClient != nil {
+				p.newKubeClient = tt.newKubeClient
+			}
+
+			var err error
+			plugintest.Load(s.T(), builtin(p), nil,
+				plugintest.Configure(tt.hcl),
+				plugintest.CaptureConfigureError(&err),
+			)
+			s.RequireGRPCStatus(err, tt.expectCode, tt.expectMsg)
+		})
+	}
+}
+
 func (s *Suite) TestConfigure() {
 	s.generateCerts("")
 
@@ -564,6 +789,17 @@ func (s *Suite) TestConfigure() {
 			`,
 			err: "unable to load private key",
 		},
+		{
+			name: "bad image signature verification",
+			hcl: `
+				image_signature_verification {
+					public_keys = {
+						"release" = "no-such-file"
+					}
+				}
+			`,
+			err: `invalid image signature verification configuration: unable to load public key "release"`,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -624,6 +860,53 @@ func (s *Suite) newPlugin() *Plugin {
 	return p
 }
 
+func (s *Suite) loadAPIServerPlugin(client kubernetes.Interface, configuration string) (*Plugin, workloadattestor.WorkloadAttestor) {
+	p := s.newPlugin()
+	p.newKubeClient = func(string) (kubernetes.Interface, error) {
+		return client, nil
+	}
+
+	v1 := new(workloadattestor.V1)
+	plugintest.Load(s.T(), builtin(p), v1,
+		plugintest.Configure(configuration),
+	)
+	s.T().Cleanup(func() {
+		p.config.PodWatcher.Stop()
+	})
+	return p, v1
+}
+
+func countServiceAccountGets(client *fake.Clientset) int {
+	count := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "serviceaccounts" {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Suite) waitForPodWatcherSync(p *Plugin) {
+	s.Require().Eventually(func() bool {
+		watcher := p.config.PodWatcher
+		return watcher.pods.HasSynced()
+	}, time.Minute, 10*time.Millisecond)
+}
+
+func (s *Suite) loadPods(fixturePath string) []runtime.Object {
+	data, err := os.ReadFile(fixturePath)
+	s.Require().NoError(err)
+
+	podList := new(corev1.PodList)
+	s.Require().NoError(json.Unmarshal(data, podList))
+
+	var pods []runtime.Object
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	return pods
+}
+
 func (s *Suite) setServer(server *httptest.Server) {
 	if s.server != nil {
 		s.server.Close()
@@ -854,6 +1137,32 @@ func (s *Suite) addCgroupsResponse(fixturePath string) {
 	s.Require().NoError(os.Symlink(filepath.Join(wd, fixturePath), cgroupPath))
 }
 
+func TestImageDigestRef(t *testing.T) {
+	for _, tt := range []struct {
+		imageID  string
+		expected string
+	}{
+		{
+			imageID:  "docker-pullable://localhost/spiffe/blog@sha256:0cfdaced91cb46dd7af48309799a3c351e4ca2d5e1ee9737ca0cbd932cb79898",
+			expected: "localhost/spiffe/blog@sha256:0cfdaced91cb46dd7af48309799a3c351e4ca2d5e1ee9737ca0cbd932cb79898",
+		},
+		{
+			imageID:  "gcr.io/spiffe-io/spire-agent@sha256:1e4c481d76e9ecbd3d8684891e0e46aa021a30920ca04936e1fdcc552747d941",
+			expected: "gcr.io/spiffe-io/spire-agent@sha256:1e4c481d76e9ecbd3d8684891e0e46aa021a30920ca04936e1fdcc552747d941",
+		},
+		{
+			imageID: "docker://sha256:0cfdaced91cb46dd7af48309799a3c351e4ca2d5e1ee9737ca0cbd932cb79898",
+		},
+		{
+			imageID: "",
+		},
+	} {
+		imageRef, ok := imageDigestRef(tt.imageID)
+		assert.Equal(t, tt.expected != "", ok, tt.imageID)
+		assert.Equal(t, tt.expected, imageRef, tt.imageID)
+	}
+}
+
 func TestGetContainerIDFromCGroups(t *testing.T) {
 	makeCGroups := func(groupPaths []string) []cgroups.Cgroup {
 		var out []cgroups.Cgroup
@@ -0,0 +1,99 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// apiVersion is the version of the libpod API used by the client. It is
+// supported by Podman 3.0 and later.
+const apiVersion = "v3.0.0"
+
+type containerJSON struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Pod    string `json:"Pod"`
+	Config struct {
+		Image  string            `json:"Image"`
+		User   string            `json:"User"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+type podJSON struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+// client is a minimal client for the libpod API served on a unix socket.
+type client struct {
+	http *http.Client
+}
+
+func newClient(socketPath string) *client {
+	return &client{
+		http: &http.Client{
+			Transport: &http.Transport{
+				// Clients are not reused across attestations
+				DisableKeepAlives: true,
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// InspectContainer returns the container with the given ID, or nil if it
+// does not exist.
+func (c *client) InspectContainer(ctx context.Context, id string) (*containerJSON, error) {
+	container := new(containerJSON)
+	found, err := c.get(ctx, "/libpod/containers/"+url.PathEscape(id)+"/json", container)
+	if err != nil || !found {
+		return nil, err
+	}
+	return container, nil
+}
+
+// InspectPod returns the pod with the given ID, or nil if it does not exist.
+func (c *client) InspectPod(ctx context.Context, id string) (*podJSON, error) {
+	pod := new(podJSON)
+	found, err := c.get(ctx, "/libpod/pods/"+url.PathEscape(id)+"/json", pod)
+	if err != nil || !found {
+		return nil, err
+	}
+	return pod, nil
+}
+
+func (c *client) get(ctx context.Context, path string, out interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://d/"+apiVersion+path, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return true, nil
+}
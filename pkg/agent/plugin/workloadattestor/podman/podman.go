@@ -0,0 +1,295 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"text/template"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	workloadattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/workloadattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor/docker/cgroup"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "podman"
+
+	defaultSocketPath         = "/run/podman/podman.sock"
+	defaultRootlessSocketPath = "/run/user/{{ .UID }}/podman/podman.sock"
+
+	subselectorLabel       = "label"
+	subselectorImageID     = "image_id"
+	subselectorPod         = "pod"
+	subselectorUser        = "user"
+	subselectorRootless    = "rootless"
+	subselectorRootlessUID = "rootless_uid"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		workloadattestorv1.WorkloadAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+type podmanPluginConfig struct {
+	// SocketPath is the location of the rootful Podman API socket
+	// (default: "/run/podman/podman.sock").
+	SocketPath string `hcl:"socket_path"`
+	// RootlessSocketPath is a template for the location of the Podman API
+	// socket of the user owning a rootless container
+	// (default: "/run/user/{{ .UID }}/podman/podman.sock").
+	RootlessSocketPath string `hcl:"rootless_socket_path"`
+	// ContainerIDCGroupMatchers is a list of patterns used to discover container IDs from cgroup entries.
+	// See the documentation for cgroup.NewContainerIDFinder in the docker cgroup subpackage for more information.
+	ContainerIDCGroupMatchers []string `hcl:"container_id_cgroup_matchers"`
+}
+
+type Plugin struct {
+	workloadattestorv1.UnsafeWorkloadAttestorServer
+	configv1.UnsafeConfigServer
+
+	log hclog.Logger
+	fs  cgroups.FileSystem
+
+	mtx                sync.RWMutex
+	socketPath         string
+	rootlessSocketPath *template.Template
+	containerIDFinder  cgroup.ContainerIDFinder
+}
+
+func New() *Plugin {
+	return &Plugin{
+		fs: cgroups.OSFileSystem{},
+	}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestRequest) (*workloadattestorv1.AttestResponse, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.containerIDFinder == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+
+	cgroupList, err := cgroups.GetCgroups(req.Pid, p.fs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read cgroups: %v", err)
+	}
+
+	containerID, err := getContainerIDFromCGroups(p.containerIDFinder, cgroupList)
+	switch {
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	case containerID == "":
+		// Not a podman workload. Nothing more to do.
+		return &workloadattestorv1.AttestResponse{}, nil
+	}
+
+	// Rootless containers are managed by the Podman service of the user
+	// owning them, which is found in the cgroup path
+	socketPath := p.socketPath
+	rootlessUID, rootless := getRootlessUIDFromCGroups(cgroupList)
+	if rootless {
+		socketPath, err = p.getRootlessSocketPath(rootlessUID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to build rootless socket path: %v", err)
+		}
+	}
+
+	client := newClient(socketPath)
+	container, err := client.InspectContainer(ctx, containerID)
+	switch {
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to inspect container %q: %v", containerID, err)
+	case container == nil:
+		// The container is managed by another runtime or user.
+		p.log.Debug("Container not found in Podman", "container_id", containerID, "socket_path", socketPath)
+		return &workloadattestorv1.AttestResponse{}, nil
+	}
+
+	var podName string
+	if container.Pod != "" {
+		pod, err := client.InspectPod(ctx, container.Pod)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to inspect pod %q: %v", container.Pod, err)
+		}
+		if pod != nil {
+			podName = pod.Name
+		}
+	}
+
+	selectorValues := getSelectorValues(container, podName)
+	if rootless {
+		// The configuration of a rootless container (labels, image, etc.) is
+		// fully controlled by the user owning it, so its selectors are scoped
+		// to that user. Otherwise any local user could create a container
+		// matching the selectors of a rootful one.
+		for i, selectorValue := range selectorValues {
+			selectorValues[i] = fmt.Sprintf("%s:%d:%s", subselectorRootless, rootlessUID, selectorValue)
+		}
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%d", subselectorRootlessUID, rootlessUID))
+	}
+	return &workloadattestorv1.AttestResponse{
+		SelectorValues: selectorValues,
+	}, nil
+}
+
+func getSelectorValues(container *containerJSON, podName string) []string {
+	var selectorValues []string
+	labels := make([]string, 0, len(container.Config.Labels))
+	for label, value := range container.Config.Labels {
+		labels = append(labels, fmt.Sprintf("%s:%s:%s", subselectorLabel, label, value))
+	}
+	sort.Strings(labels)
+	selectorValues = append(selectorValues, labels...)
+
+	if container.Config.Image != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorImageID, container.Config.Image))
+	}
+	if podName != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorPod, podName))
+	}
+	if container.Config.User != "" {
+		selectorValues = append(selectorValues, fmt.Sprintf("%s:%s", subselectorUser, container.Config.User))
+	}
+	return selectorValues
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := &podmanPluginConfig{}
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode configuration: %v", err)
+	}
+
+	if config.SocketPath == "" {
+		config.SocketPath = defaultSocketPath
+	}
+	if config.RootlessSocketPath == "" {
+		config.RootlessSocketPath = defaultRootlessSocketPath
+	}
+
+	rootlessSocketPath, err := template.New("rootless_socket_path").Option("missingkey=error").Parse(config.RootlessSocketPath)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid rootless_socket_path: %v", err)
+	}
+
+	var containerIDFinder cgroup.ContainerIDFinder = &defaultContainerIDFinder{}
+	if len(config.ContainerIDCGroupMatchers) > 0 {
+		containerIDFinder, err = cgroup.NewContainerIDFinder(config.ContainerIDCGroupMatchers)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid container_id_cgroup_matchers: %v", err)
+		}
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.socketPath = config.SocketPath
+	p.rootlessSocketPath = rootlessSocketPath
+	p.containerIDFinder = containerIDFinder
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getRootlessSocketPath(uid int) (string, error) {
+	var buf bytes.Buffer
+	if err := p.rootlessSocketPath.Execute(&buf, struct{ UID int }{UID: uid}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// getContainerIDFromCGroups returns the container ID from a set of cgroups
+// using the given finder. The container ID found on each cgroup path (if any)
+// must be consistent. If no container ID is found among the cgroups, i.e.,
+// this isn't a podman workload, the function returns an empty string. If more
+// than one container ID is found, or the "found" container ID is blank, the
+// function will fail.
+func getContainerIDFromCGroups(finder cgroup.ContainerIDFinder, cgroups []cgroups.Cgroup) (string, error) {
+	var hasPodmanEntries bool
+	var containerID string
+	for _, cgroup := range cgroups {
+		candidate, ok := finder.FindContainerID(cgroup.GroupPath)
+		if !ok {
+			continue
+		}
+
+		hasPodmanEntries = true
+
+		switch {
+		case containerID == "":
+			// This is the first container ID found so far.
+			containerID = candidate
+		case containerID != candidate:
+			// More than one container ID found in the cgroups.
+			return "", fmt.Errorf("multiple container IDs found in cgroups (%s, %s)", containerID, candidate)
+		}
+	}
+
+	switch {
+	case !hasPodmanEntries:
+		return "", nil
+	case containerID == "":
+		// The "finder" found a container ID, but it was blank. This is a
+		// defensive measure against bad matcher patterns and shouldn't
+		// be possible with the default finder.
+		return "", errors.New("a pattern matched, but no container id was found")
+	default:
+		return containerID, nil
+	}
+}
+
+// userServiceRE matches the user manager unit that rootless containers are
+// placed under on cgroup v2 hosts managed by systemd.
+var userServiceRE = regexp.MustCompile(`/user@(\d+)\.service/`)
+
+// getRootlessUIDFromCGroups returns the UID of the user owning the container,
+// if the container is rootless.
+func getRootlessUIDFromCGroups(cgroups []cgroups.Cgroup) (int, bool) {
+	for _, cgroup := range cgroups {
+		if m := userServiceRE.FindStringSubmatch(cgroup.GroupPath); m != nil {
+			uid, err := strconv.Atoi(m[1])
+			if err == nil {
+				return uid, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// podmanCGroupRE matches cgroup paths of podman containers, with the
+// 64 hex-character container id in a `libpod-<id>` path component. Podman
+// may run the container processes in a `container` child cgroup. The
+// cgroup of the conmon monitor process (`libpod-conmon-<id>`) is not matched.
+var podmanCGroupRE = regexp.MustCompile(`/libpod-([[:xdigit:]]{64})(?:\.scope)?(?:/container)?$`)
+
+type defaultContainerIDFinder struct{}
+
+// FindContainerID returns the container ID in the given cgroup path. This
+// covers rootful containers with the systemd and cgroupfs cgroup managers,
+// and rootless containers, which are placed under the user manager.
+func (f *defaultContainerIDFinder) FindContainerID(cgroupPath string) (string, bool) {
+	m := podmanCGroupRE.FindStringSubmatch(cgroupPath)
+	if m != nil {
+		return m[1], true
+	}
+	return "", false
+}
@@ -0,0 +1,239 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	testPID         = 123
+	testContainerID = "6469646e742064657465637420746869732073656372657420636f6e7461696e"
+	testPodID       = "706f6469643132333435363738393031323334353637383930313233343536373"
+)
+
+var (
+	rootfulCgroups  = fmt.Sprintf("0::/machine.slice/libpod-%s.scope/container\n", testContainerID)
+	cgroupfsCgroups = fmt.Sprintf("10:memory:/libpod_parent/libpod-%s\n", testContainerID)
+	rootlessCgroups = fmt.Sprintf("0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-%s.scope\n", testContainerID)
+)
+
+func TestAttest(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	rootfulSocket := filepath.Join(dir, "podman.sock")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "1000"), 0755))
+	startFakePodman(t, rootfulSocket, map[string]interface{}{
+		"/v3.0.0/libpod/containers/" + testContainerID + "/json": map[string]interface{}{
+			"Id":  testContainerID,
+			"Pod": testPodID,
+			"Config": map[string]interface{}{
+				"Image":  "docker.io/library/nginx:latest",
+				"Labels": map[string]string{"app": "web", "tier": "frontend"},
+			},
+		},
+		"/v3.0.0/libpod/pods/" + testPodID + "/json": map[string]interface{}{
+			"Id":   testPodID,
+			"Name": "webpod",
+		},
+	})
+	startFakePodman(t, filepath.Join(dir, "1000", "podman.sock"), map[string]interface{}{
+		"/v3.0.0/libpod/containers/" + testContainerID + "/json": map[string]interface{}{
+			"Id": testContainerID,
+			"Config": map[string]interface{}{
+				"Image":  "quay.io/example/app:1.0",
+				"User":   "app",
+				"Labels": map[string]string{"app": "ci"},
+			},
+		},
+	})
+
+	config := fmt.Sprintf(`
+		socket_path = %q
+		rootless_socket_path = "%s/{{ .UID }}/podman.sock"
+	`, rootfulSocket, dir)
+
+	for _, tt := range []struct {
+		name            string
+		config          string
+		cgroups         string
+		expectSelectors []string
+		expectCode      codes.Code
+		expectMsg       string
+	}{
+		{
+			name:    "rootful container with systemd",
+			cgroups: rootfulCgroups,
+			expectSelectors: []string{
+				"label:app:web",
+				"label:tier:frontend",
+				"image_id:docker.io/library/nginx:latest",
+				"pod:webpod",
+			},
+		},
+		{
+			name:    "rootful container with cgroupfs",
+			cgroups: cgroupfsCgroups,
+			expectSelectors: []string{
+				"label:app:web",
+				"label:tier:frontend",
+				"image_id:docker.io/library/nginx:latest",
+				"pod:webpod",
+			},
+		},
+		{
+			name:    "rootless container",
+			cgroups: rootlessCgroups,
+			expectSelectors: []string{
+				"rootless:1000:label:app:ci",
+				"rootless:1000:image_id:quay.io/example/app:1.0",
+				"rootless:1000:user:app",
+				"rootless_uid:1000",
+			},
+		},
+		{
+			name:    "custom matcher",
+			config:  `container_id_cgroup_matchers = ["/podman/<id>"]`,
+			cgroups: fmt.Sprintf("0::/podman/%s\n", testContainerID),
+			expectSelectors: []string{
+				"label:app:web",
+				"label:tier:frontend",
+				"image_id:docker.io/library/nginx:latest",
+				"pod:webpod",
+			},
+		},
+		{
+			name:    "conmon process",
+			cgroups: fmt.Sprintf("0::/machine.slice/libpod-conmon-%s.scope\n", testContainerID),
+		},
+		{
+			name:    "not a container",
+			cgroups: "0::/system.slice/nginx.service\n",
+		},
+		{
+			name:    "container unknown to podman",
+			cgroups: strings.ReplaceAll(rootfulCgroups, testContainerID, strings.Repeat("a", 64)),
+		},
+		{
+			name:       "rootless socket unavailable",
+			cgroups:    strings.ReplaceAll(rootlessCgroups, "1000", "1001"),
+			expectCode: codes.Internal,
+			expectMsg:  fmt.Sprintf("workloadattestor(podman): failed to inspect container %q:", testContainerID),
+		},
+		{
+			name:       "multiple container IDs",
+			cgroups:    rootfulCgroups + strings.ReplaceAll(cgroupfsCgroups, testContainerID, strings.Repeat("a", 64)),
+			expectCode: codes.Internal,
+			expectMsg:  fmt.Sprintf("workloadattestor(podman): multiple container IDs found in cgroups (%s, %s)", testContainerID, strings.Repeat("a", 64)),
+		},
+		{
+			name:       "malformed cgroups",
+			cgroups:    "malformed\n",
+			expectCode: codes.Internal,
+			expectMsg:  `workloadattestor(podman): failed to read cgroups: cgroup entry contains 1 colons, but expected at least 2 colons: "malformed"`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := New()
+			p.fs = fakeFileSystem{fmt.Sprintf("/proc/%d/cgroup", testPID): tt.cgroups}
+
+			attestor := new(workloadattestor.V1)
+			plugintest.Load(t, builtin(p), attestor, plugintest.Configure(config+tt.config))
+
+			selectors, err := attestor.Attest(context.Background(), testPID)
+			spiretest.RequireGRPCStatusContains(t, err, tt.expectCode, tt.expectMsg)
+			if tt.expectCode != codes.OK {
+				return
+			}
+
+			var selectorValues []string
+			for _, selector := range selectors {
+				require.Equal(t, pluginName, selector.Type)
+				selectorValues = append(selectorValues, selector.Value)
+			}
+			require.Equal(t, tt.expectSelectors, selectorValues)
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		config    string
+		expectMsg string
+	}{
+		{
+			name:      "malformed configuration",
+			config:    "socket_path = [",
+			expectMsg: "failed to decode configuration",
+		},
+		{
+			name:      "invalid rootless socket path",
+			config:    `rootless_socket_path = "/run/user/{{ .UID"`,
+			expectMsg: "invalid rootless_socket_path",
+		},
+		{
+			name:      "invalid cgroup matcher",
+			config:    `container_id_cgroup_matchers = ["/podman/"]`,
+			expectMsg: "invalid container_id_cgroup_matchers",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, BuiltIn(), new(workloadattestor.V1),
+				plugintest.Configure(tt.config),
+				plugintest.CaptureConfigureError(&err))
+			spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, tt.expectMsg)
+		})
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	attestor := new(workloadattestor.V1)
+	plugintest.Load(t, BuiltIn(), attestor)
+
+	_, err := attestor.Attest(context.Background(), testPID)
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "workloadattestor(podman): not configured")
+}
+
+func startFakePodman(t *testing.T, socketPath string, responses map[string]interface{}) {
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			resp, ok := responses[req.URL.Path]
+			if !ok {
+				http.Error(w, "no such object", http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		}),
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { server.Close() })
+}
+
+type fakeFileSystem map[string]string
+
+func (fs fakeFileSystem) Open(path string) (io.ReadCloser, error) {
+	data, ok := fs[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
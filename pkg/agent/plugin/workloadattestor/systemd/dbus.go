@@ -0,0 +1,51 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	systemdDest       = "org.freedesktop.systemd1"
+	systemdPath       = dbus.ObjectPath("/org/freedesktop/systemd1")
+	getUnitMethod     = "org.freedesktop.systemd1.Manager.GetUnit"
+	getPropertyMethod = "org.freedesktop.DBus.Properties.Get"
+)
+
+// dbusClient queries the system service manager over the system bus. A new
+// connection is used for every query so that the agent does not depend on
+// the bus being available when the plugin is configured.
+type dbusClient struct{}
+
+func (dbusClient) GetUnitProperty(ctx context.Context, unit, property string) (string, error) {
+	i := strings.LastIndex(property, ".")
+	if i < 0 {
+		return "", fmt.Errorf("property %q is not qualified by an interface", property)
+	}
+	iface, name := property[:i], property[i+1:]
+
+	conn, err := dbus.ConnectSystemBus(dbus.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to the system bus: %w", err)
+	}
+	defer conn.Close()
+
+	var unitPath dbus.ObjectPath
+	if err := conn.Object(systemdDest, systemdPath).CallWithContext(ctx, getUnitMethod, 0, unit).Store(&unitPath); err != nil {
+		return "", err
+	}
+
+	var value dbus.Variant
+	if err := conn.Object(systemdDest, unitPath).CallWithContext(ctx, getPropertyMethod, 0, iface, name).Store(&value); err != nil {
+		return "", err
+	}
+
+	s, ok := value.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("property %q has unexpected type %s", property, value.Signature())
+	}
+	return s, nil
+}
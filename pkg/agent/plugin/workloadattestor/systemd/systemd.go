@@ -0,0 +1,187 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	workloadattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/workloadattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "systemd"
+
+	unitFragmentPathProperty = "org.freedesktop.systemd1.Unit.FragmentPath"
+	serviceUserProperty      = "org.freedesktop.systemd1.Service.User"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		workloadattestorv1.WorkloadAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// UnitPropertyGetter retrieves properties of a loaded systemd unit from the
+// service manager.
+type UnitPropertyGetter interface {
+	// GetUnitProperty returns the value of the given fully qualified
+	// string property (e.g. "org.freedesktop.systemd1.Unit.FragmentPath")
+	// of the named unit.
+	GetUnitProperty(ctx context.Context, unit, property string) (string, error)
+}
+
+type Configuration struct {
+	// DiscoverUser enables the "user" selector, derived from the User=
+	// setting of service units.
+	DiscoverUser bool `hcl:"discover_user"`
+}
+
+type Plugin struct {
+	workloadattestorv1.UnsafeWorkloadAttestorServer
+	configv1.UnsafeConfigServer
+
+	log   hclog.Logger
+	fs    cgroups.FileSystem
+	units UnitPropertyGetter
+
+	mu     sync.RWMutex
+	config *Configuration
+}
+
+func New() *Plugin {
+	return &Plugin{
+		fs:    cgroups.OSFileSystem{},
+		units: dbusClient{},
+	}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestRequest) (*workloadattestorv1.AttestResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cgroupList, err := cgroups.GetCgroups(req.Pid, p.fs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read cgroups: %v", err)
+	}
+
+	unit, slice, ok := getUnitFromCgroups(cgroupList)
+	if !ok {
+		// Not managed by systemd. Nothing more to do.
+		return &workloadattestorv1.AttestResponse{}, nil
+	}
+
+	selectorValues := []string{makeSelectorValue("id", unit)}
+	if slice != "" {
+		selectorValues = append(selectorValues, makeSelectorValue("slice", slice))
+	}
+
+	fragmentPath, err := p.units.GetUnitProperty(ctx, unit, unitFragmentPathProperty)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get fragment path of unit %q: %v", unit, err)
+	}
+	// Transient units and scopes are not backed by a unit file
+	if fragmentPath != "" {
+		selectorValues = append(selectorValues, makeSelectorValue("fragment_path", fragmentPath))
+	}
+
+	if config.DiscoverUser && strings.HasSuffix(unit, ".service") {
+		user, err := p.units.GetUnitProperty(ctx, unit, serviceUserProperty)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get user of unit %q: %v", unit, err)
+		}
+		if user != "" {
+			selectorValues = append(selectorValues, makeSelectorValue("user", user))
+		}
+	}
+
+	return &workloadattestorv1.AttestResponse{
+		SelectorValues: selectorValues,
+	}, nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode configuration: %v", err)
+	}
+
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfig() (*Configuration, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+// getUnitFromCgroups returns the unit owning the process and the slice that
+// unit belongs to, based on the systemd cgroup hierarchy. That is the unified
+// hierarchy on cgroup v2 hosts and the "name=systemd" hierarchy on cgroup v1
+// hosts. The unit is the first path component that is not a slice, which
+// matches the unit returned by the system manager for the process. For
+// instance, processes of user services belong to the "user@<uid>.service"
+// unit.
+func getUnitFromCgroups(cgroupList []cgroups.Cgroup) (unit, slice string, ok bool) {
+	var groupPath string
+	for _, cgroup := range cgroupList {
+		switch {
+		case cgroup.ControllerList == "name=systemd":
+			groupPath = cgroup.GroupPath
+		case cgroup.HierarchyID == "0" && cgroup.ControllerList == "" && groupPath == "":
+			groupPath = cgroup.GroupPath
+		}
+	}
+
+	for _, elem := range strings.Split(groupPath, "/") {
+		switch {
+		case elem == "":
+		case strings.HasSuffix(elem, ".slice"):
+			slice = elem
+		case isUnitName(elem):
+			return elem, slice, true
+		default:
+			return "", "", false
+		}
+	}
+	return "", "", false
+}
+
+// isUnitName returns true if name has the suffix of a unit type that
+// processes can belong to.
+func isUnitName(name string) bool {
+	for _, suffix := range []string{".service", ".scope", ".socket", ".mount", ".swap"} {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func makeSelectorValue(kind, value string) string {
+	return fmt.Sprintf("%s:%s", kind, value)
+}
@@ -0,0 +1,219 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+const testPID = 123
+
+var fakeUnits = fakeUnitPropertyGetter{
+	"nginx.service": {
+		unitFragmentPathProperty: "/lib/systemd/system/nginx.service",
+		serviceUserProperty:      "www-data",
+	},
+	"root.service": {
+		unitFragmentPathProperty: "/etc/systemd/system/root.service",
+	},
+	"session-1.scope": {
+		unitFragmentPathProperty: "",
+	},
+	"user@1000.service": {
+		unitFragmentPathProperty: "/lib/systemd/system/user@.service",
+	},
+}
+
+func TestAttest(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		config          string
+		cgroups         string
+		units           UnitPropertyGetter
+		expectSelectors []string
+		expectCode      codes.Code
+		expectMsg       string
+	}{
+		{
+			name:    "cgroup v2 service",
+			cgroups: "0::/system.slice/nginx.service\n",
+			expectSelectors: []string{
+				"id:nginx.service",
+				"slice:system.slice",
+				"fragment_path:/lib/systemd/system/nginx.service",
+			},
+		},
+		{
+			name:    "cgroup v1 service",
+			cgroups: "12:cpu,cpuacct:/system.slice/nginx.service\n1:name=systemd:/system.slice/nginx.service\n0::/system.slice/nginx.service\n",
+			expectSelectors: []string{
+				"id:nginx.service",
+				"slice:system.slice",
+				"fragment_path:/lib/systemd/system/nginx.service",
+			},
+		},
+		{
+			name:    "service with delegated subgroup",
+			cgroups: "0::/system.slice/nginx.service/workers\n",
+			expectSelectors: []string{
+				"id:nginx.service",
+				"slice:system.slice",
+				"fragment_path:/lib/systemd/system/nginx.service",
+			},
+		},
+		{
+			name:    "user discovery",
+			config:  "discover_user = true",
+			cgroups: "0::/system.slice/nginx.service\n",
+			expectSelectors: []string{
+				"id:nginx.service",
+				"slice:system.slice",
+				"fragment_path:/lib/systemd/system/nginx.service",
+				"user:www-data",
+			},
+		},
+		{
+			name:    "user discovery without User=",
+			config:  "discover_user = true",
+			cgroups: "0::/system.slice/root.service\n",
+			expectSelectors: []string{
+				"id:root.service",
+				"slice:system.slice",
+				"fragment_path:/etc/systemd/system/root.service",
+			},
+		},
+		{
+			name:    "scope without fragment path",
+			config:  "discover_user = true",
+			cgroups: "0::/user.slice/user-1000.slice/session-1.scope\n",
+			expectSelectors: []string{
+				"id:session-1.scope",
+				"slice:user-1000.slice",
+			},
+		},
+		{
+			name:    "user manager unit",
+			cgroups: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service\n",
+			expectSelectors: []string{
+				"id:user@1000.service",
+				"slice:user-1000.slice",
+				"fragment_path:/lib/systemd/system/user@.service",
+			},
+		},
+		{
+			name:    "not a systemd unit",
+			cgroups: "0::/docker/2a4b6c\n",
+		},
+		{
+			name:    "root cgroup",
+			cgroups: "0::/\n",
+		},
+		{
+			name:    "no systemd hierarchy",
+			cgroups: "12:cpu,cpuacct:/system.slice/nginx.service\n",
+		},
+		{
+			name:       "malformed cgroups",
+			cgroups:    "malformed\n",
+			expectCode: codes.Internal,
+			expectMsg:  `workloadattestor(systemd): failed to read cgroups: cgroup entry contains 1 colons, but expected at least 2 colons: "malformed"`,
+		},
+		{
+			name:       "unknown unit",
+			cgroups:    "0::/system.slice/unknown.service\n",
+			expectCode: codes.Internal,
+			expectMsg:  `workloadattestor(systemd): failed to get fragment path of unit "unknown.service": unit not loaded`,
+		},
+		{
+			name:       "user lookup fails",
+			config:     "discover_user = true",
+			cgroups:    "0::/system.slice/nginx.service\n",
+			units:      failingUserGetter{fakeUnits},
+			expectCode: codes.Internal,
+			expectMsg:  `workloadattestor(systemd): failed to get user of unit "nginx.service": bus error`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := New()
+			p.fs = fakeFileSystem{fmt.Sprintf("/proc/%d/cgroup", testPID): tt.cgroups}
+			p.units = fakeUnits
+			if tt.units != nil {
+				p.units = tt.units
+			}
+
+			attestor := new(workloadattestor.V1)
+			plugintest.Load(t, builtin(p), attestor, plugintest.Configure(tt.config))
+
+			selectors, err := attestor.Attest(context.Background(), testPID)
+			spiretest.RequireGRPCStatus(t, err, tt.expectCode, tt.expectMsg)
+			if tt.expectCode != codes.OK {
+				return
+			}
+
+			var selectorValues []string
+			for _, selector := range selectors {
+				require.Equal(t, pluginName, selector.Type)
+				selectorValues = append(selectorValues, selector.Value)
+			}
+			require.Equal(t, tt.expectSelectors, selectorValues)
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	var err error
+	plugintest.Load(t, BuiltIn(), new(workloadattestor.V1),
+		plugintest.Configure("discover_user = ["),
+		plugintest.CaptureConfigureError(&err))
+	spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, "failed to decode configuration")
+}
+
+func TestNotConfigured(t *testing.T) {
+	attestor := new(workloadattestor.V1)
+	plugintest.Load(t, BuiltIn(), attestor)
+
+	_, err := attestor.Attest(context.Background(), testPID)
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "workloadattestor(systemd): not configured")
+}
+
+type fakeFileSystem map[string]string
+
+func (fs fakeFileSystem) Open(path string) (io.ReadCloser, error) {
+	data, ok := fs[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+type fakeUnitPropertyGetter map[string]map[string]string
+
+func (g fakeUnitPropertyGetter) GetUnitProperty(ctx context.Context, unit, property string) (string, error) {
+	properties, ok := g[unit]
+	if !ok {
+		return "", errors.New("unit not loaded")
+	}
+	return properties[property], nil
+}
+
+type failingUserGetter struct {
+	fakeUnitPropertyGetter
+}
+
+func (g failingUserGetter) GetUnitProperty(ctx context.Context, unit, property string) (string, error) {
+	if property == serviceUserProperty {
+		return "", errors.New("bus error")
+	}
+	return g.fakeUnitPropertyGetter.GetUnitProperty(ctx, unit, property)
+}
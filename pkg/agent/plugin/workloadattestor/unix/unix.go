@@ -10,7 +10,9 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,6 +22,7 @@ import (
 	"github.com/shirou/gopsutil/process"
 	workloadattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/agent/workloadattestor/v1"
 	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -46,6 +49,11 @@ type processInfo interface {
 	Groups() ([]string, error)
 	Exe() (string, error)
 	NamespacedExe() string
+	CmdlineSlice() ([]string, error)
+	Cwd() (string, error)
+	Ppid() (int32, error)
+	CreateTime() (int64, error)
+	Cgroups() ([]cgroups.Cgroup, error)
 }
 
 type PSProcessInfo struct {
@@ -56,6 +64,10 @@ func (ps PSProcessInfo) NamespacedExe() string {
 	return getProcPath(ps.Pid, "exe")
 }
 
+func (ps PSProcessInfo) Cgroups() ([]cgroups.Cgroup, error) {
+	return cgroups.GetCgroups(ps.Pid, cgroups.OSFileSystem{})
+}
+
 // Groups returns the supplementary group IDs
 // This is a custom implementation that only works for linux until the next issue is fixed
 // https://github.com/shirou/gopsutil/issues/913
@@ -97,6 +109,20 @@ func (ps PSProcessInfo) Groups() ([]string, error) {
 type Configuration struct {
 	DiscoverWorkloadPath bool  `hcl:"discover_workload_path"`
 	WorkloadSizeLimit    int64 `hcl:"workload_size_limit"`
+
+	// ArgvPatterns maps names to regular expressions matched against the
+	// command line of the workload.
+	ArgvPatterns             map[string]string `hcl:"argv_patterns"`
+	ParentProcessDepth       int               `hcl:"parent_process_depth"`
+	DiscoverWorkingDirectory bool              `hcl:"discover_working_directory"`
+	DiscoverCgroupPath       bool              `hcl:"discover_cgroup_path"`
+
+	argvPatterns []argvPattern
+}
+
+type argvPattern struct {
+	name string
+	re   *regexp.Regexp
 }
 
 type Plugin struct {
@@ -194,6 +220,39 @@ func (p *Plugin) Attest(ctx context.Context, req *workloadattestorv1.AttestReque
 		}
 	}
 
+	// the remaining selectors are opt-in to keep attestation cheap
+	if len(config.argvPatterns) > 0 {
+		argvSelectors, err := getArgvSelectorValues(proc, config.argvPatterns)
+		if err != nil {
+			return nil, err
+		}
+		selectorValues = append(selectorValues, argvSelectors...)
+	}
+
+	if config.DiscoverWorkingDirectory {
+		cwd, err := proc.Cwd()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "working directory lookup: %v", err)
+		}
+		selectorValues = append(selectorValues, makeSelectorValue("cwd", cwd))
+	}
+
+	if config.DiscoverCgroupPath {
+		cgroupSelectors, err := getCgroupSelectorValues(proc)
+		if err != nil {
+			return nil, err
+		}
+		selectorValues = append(selectorValues, cgroupSelectors...)
+	}
+
+	if config.ParentProcessDepth > 0 {
+		parentSelectors, err := p.getParentSelectorValues(proc, config.ParentProcessDepth)
+		if err != nil {
+			return nil, err
+		}
+		selectorValues = append(selectorValues, parentSelectors...)
+	}
+
 	return &workloadattestorv1.AttestResponse{
 		SelectorValues: selectorValues,
 	}, nil
@@ -204,6 +263,22 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "failed to decode configuration: %v", err)
 	}
+
+	if config.ParentProcessDepth < 0 {
+		return nil, status.Error(codes.InvalidArgument, "parent_process_depth cannot be negative")
+	}
+
+	for name, pattern := range config.ArgvPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid argv pattern %q: %v", name, err)
+		}
+		config.argvPatterns = append(config.argvPatterns, argvPattern{name: name, re: re})
+	}
+	sort.Slice(config.argvPatterns, func(i, j int) bool {
+		return config.argvPatterns[i].name < config.argvPatterns[j].name
+	})
+
 	p.setConfig(config)
 	return &configv1.ConfigureResponse{}, nil
 }
@@ -287,6 +362,104 @@ func (p *Plugin) getNamespacedPath(proc processInfo) string {
 	return proc.NamespacedExe()
 }
 
+// getArgvSelectorValues matches the command line of the process, with the
+// arguments joined by spaces, against the configured patterns. For each
+// matching pattern, the first capture group (or the whole match if the
+// pattern has no groups) is used as the selector value.
+func getArgvSelectorValues(proc processInfo, patterns []argvPattern) ([]string, error) {
+	args, err := proc.CmdlineSlice()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "command line lookup: %v", err)
+	}
+	cmdline := strings.Join(args, " ")
+
+	var selectorValues []string
+	for _, pattern := range patterns {
+		m := pattern.re.FindStringSubmatch(cmdline)
+		if m == nil {
+			continue
+		}
+		value := m[0]
+		if len(m) > 1 {
+			value = m[1]
+		}
+		selectorValues = append(selectorValues, makeSelectorValue("argv", pattern.name+":"+value))
+	}
+	return selectorValues, nil
+}
+
+func getCgroupSelectorValues(proc processInfo) ([]string, error) {
+	cgroupList, err := proc.Cgroups()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cgroup lookup: %v", err)
+	}
+
+	var selectorValues []string
+	seen := make(map[string]bool)
+	for _, cgroup := range cgroupList {
+		if seen[cgroup.GroupPath] {
+			continue
+		}
+		seen[cgroup.GroupPath] = true
+		selectorValues = append(selectorValues, makeSelectorValue("cgroup", cgroup.GroupPath))
+	}
+	return selectorValues, nil
+}
+
+// getParentSelectorValues walks up the process tree up to depth levels,
+// emitting the path and uid of each ancestor, prefixed with its level (1 for
+// the parent). The walk stops early when init or the root of the PID
+// namespace is reached.
+func (p *Plugin) getParentSelectorValues(proc processInfo, depth int) ([]string, error) {
+	var selectorValues []string
+	for level := 1; level <= depth; level++ {
+		ppid, err := proc.Ppid()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "parent process lookup: %v", err)
+		}
+		if ppid < 1 {
+			break
+		}
+		childStart, err := proc.CreateTime()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "process start time lookup: %v", err)
+		}
+
+		proc, err = p.hooks.newProcess(ppid)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get parent process: %v", err)
+		}
+
+		// The parent may have exited since its PID was read, and the PID been
+		// reused by another process. A parent always starts before its child,
+		// so a later start time means the process is not the parent.
+		parentStart, err := proc.CreateTime()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "parent process start time lookup: %v", err)
+		}
+		if parentStart > childStart {
+			return nil, status.Errorf(codes.Internal, "parent process %d started after its child; it may have exited during attestation", ppid)
+		}
+
+		path, err := proc.Exe()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "parent path lookup: %v", err)
+		}
+		uid, err := p.getUID(proc)
+		if err != nil {
+			return nil, err
+		}
+		selectorValues = append(selectorValues,
+			makeSelectorValue("parent_path", fmt.Sprintf("%d:%s", level, path)),
+			makeSelectorValue("parent_uid", fmt.Sprintf("%d:%s", level, uid)),
+		)
+		if ppid == 1 {
+			break
+		}
+	}
+	return selectorValues, nil
+}
+
 func getSHA256Digest(path string, limit int64) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/agent/common/cgroups"
 	"github.com/spiffe/spire/pkg/agent/plugin/workloadattestor"
 	"github.com/spiffe/spire/test/plugintest"
 	"github.com/spiffe/spire/test/spiretest"
@@ -227,6 +228,107 @@ func (s *Suite) TestAttest() {
 			expectCode: codes.Internal,
 			expectMsg:  "workloadattestor(unix): supplementary GIDs lookup: some error for PID 14",
 		},
+		{
+			name:   "success matching argv patterns",
+			pid:    15,
+			config: `argv_patterns = { config = "--config (\\S+)" port = "--port \\d+" missing = "--missing" }`,
+			selectorValues: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				"argv:config:/etc/app.conf",
+				"argv:port:--port 8080",
+			},
+		},
+		{
+			name:       "fail to get command line",
+			pid:        18,
+			config:     `argv_patterns = { any = ".*" }`,
+			expectCode: codes.Internal,
+			expectMsg:  "workloadattestor(unix): command line lookup: unable to get command line for PID 18",
+		},
+		{
+			name:   "success getting working directory",
+			pid:    15,
+			config: "discover_working_directory = true",
+			selectorValues: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				"cwd:/srv/app",
+			},
+		},
+		{
+			name:       "fail to get working directory",
+			pid:        18,
+			config:     "discover_working_directory = true",
+			expectCode: codes.Internal,
+			expectMsg:  "workloadattestor(unix): working directory lookup: unable to get working directory for PID 18",
+		},
+		{
+			name:   "success getting cgroup paths",
+			pid:    15,
+			config: "discover_cgroup_path = true",
+			selectorValues: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				"cgroup:/system.slice/app.service",
+				"cgroup:/system.slice/app.service/main",
+			},
+		},
+		{
+			name:       "fail to get cgroup paths",
+			pid:        18,
+			config:     "discover_cgroup_path = true",
+			expectCode: codes.Internal,
+			expectMsg:  "workloadattestor(unix): cgroup lookup: unable to get cgroups for PID 18",
+		},
+		{
+			name:   "success getting parent process",
+			pid:    15,
+			config: "parent_process_depth = 1",
+			selectorValues: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				"parent_path:1:/usr/bin/supervisor",
+				"parent_uid:1:1100",
+			},
+		},
+		{
+			name:   "parent process walk stops at the root of the tree",
+			pid:    15,
+			config: "parent_process_depth = 5",
+			selectorValues: []string{
+				"uid:1000",
+				"user:u1000",
+				"gid:2000",
+				"group:g2000",
+				"parent_path:1:/usr/bin/supervisor",
+				"parent_uid:1:1100",
+				"parent_path:2:/sbin/init",
+				"parent_uid:2:0",
+			},
+		},
+		{
+			name:       "fail to get parent process",
+			pid:        18,
+			config:     "parent_process_depth = 1",
+			expectCode: codes.Internal,
+			expectMsg:  "workloadattestor(unix): parent process lookup: unable to get parent PID for PID 18",
+		},
+		{
+			name:       "parent process replaced during attestation",
+			pid:        19,
+			config:     "parent_process_depth = 1",
+			expectCode: codes.Internal,
+			expectMsg:  "workloadattestor(unix): parent process 20 started after its child; it may have exited during attestation",
+		},
 	}
 
 	// prepare the "exe" for hashing
@@ -259,6 +361,41 @@ func (s *Suite) TestAttest() {
 	}
 }
 
+func (s *Suite) TestConfigure() {
+	testCases := []struct {
+		name      string
+		config    string
+		expectErr string
+	}{
+		{
+			name:      "malformed configuration",
+			config:    "bad juju",
+			expectErr: "failed to decode configuration",
+		},
+		{
+			name:      "invalid argv pattern",
+			config:    `argv_patterns = { bad = "(" }`,
+			expectErr: `invalid argv pattern "bad"`,
+		},
+		{
+			name:      "negative parent process depth",
+			config:    "parent_process_depth = -1",
+			expectErr: "parent_process_depth cannot be negative",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		s.T().Run(testCase.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, builtin(s.newPlugin()), new(workloadattestor.V1),
+				plugintest.Configure(testCase.config),
+				plugintest.CaptureConfigureError(&err))
+			spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, testCase.expectErr)
+		})
+	}
+}
+
 func (s *Suite) writeFile(path string, data []byte) {
 	s.Require().NoError(os.WriteFile(filepath.Join(s.dir, path), data, 0600))
 }
@@ -296,8 +433,12 @@ func (p fakeProcess) Uids() ([]int32, error) {
 		return nil, fmt.Errorf("unable to get UIDs for PID %d", p.pid)
 	case 3:
 		return []int32{1999}, nil
-	case 4, 5, 6, 7, 9, 10, 11, 12, 13, 14:
+	case 4, 5, 6, 7, 9, 10, 11, 12, 13, 14, 15, 18, 19:
 		return []int32{1000}, nil
+	case 16:
+		return []int32{1100}, nil
+	case 17:
+		return []int32{0}, nil
 	case 8:
 		return []int32{1000, 1100}, nil
 	default:
@@ -313,7 +454,7 @@ func (p fakeProcess) Gids() ([]int32, error) {
 		return nil, fmt.Errorf("unable to get GIDs for PID %d", p.pid)
 	case 6:
 		return []int32{2999}, nil
-	case 3, 7, 9, 10, 11, 12, 13, 14:
+	case 3, 7, 9, 10, 11, 12, 13, 14, 15, 18, 19:
 		return []int32{2000}, nil
 	case 8:
 		return []int32{2000, 2100}, nil
@@ -341,6 +482,10 @@ func (p fakeProcess) Exe() (string, error) {
 		return filepath.Join(p.dir, "unreadable-exe"), nil
 	case 11, 12:
 		return filepath.Join(p.dir, "exe"), nil
+	case 16:
+		return "/usr/bin/supervisor", nil
+	case 17:
+		return "/sbin/init", nil
 	default:
 		return "", fmt.Errorf("unhandled exe test case %d", p.pid)
 	}
@@ -355,6 +500,68 @@ func (p fakeProcess) NamespacedExe() string {
 	}
 }
 
+func (p fakeProcess) CmdlineSlice() ([]string, error) {
+	switch p.pid {
+	case 15:
+		return []string{"/usr/bin/server", "--config", "/etc/app.conf", "--port", "8080"}, nil
+	default:
+		return nil, fmt.Errorf("unable to get command line for PID %d", p.pid)
+	}
+}
+
+func (p fakeProcess) Cwd() (string, error) {
+	switch p.pid {
+	case 15:
+		return "/srv/app", nil
+	default:
+		return "", fmt.Errorf("unable to get working directory for PID %d", p.pid)
+	}
+}
+
+func (p fakeProcess) Ppid() (int32, error) {
+	switch p.pid {
+	case 15:
+		return 16, nil
+	case 16:
+		return 17, nil
+	case 17:
+		return 0, nil
+	case 19:
+		return 20, nil
+	default:
+		return 0, fmt.Errorf("unable to get parent PID for PID %d", p.pid)
+	}
+}
+
+func (p fakeProcess) CreateTime() (int64, error) {
+	switch p.pid {
+	case 15, 19:
+		return 3000, nil
+	case 16:
+		return 2000, nil
+	case 17:
+		return 1000, nil
+	case 20:
+		// reused the PID of the parent of 19 after it exited
+		return 4000, nil
+	default:
+		return 0, fmt.Errorf("unable to get start time for PID %d", p.pid)
+	}
+}
+
+func (p fakeProcess) Cgroups() ([]cgroups.Cgroup, error) {
+	switch p.pid {
+	case 15:
+		return []cgroups.Cgroup{
+			{HierarchyID: "1", ControllerList: "cpu,cpuacct", GroupPath: "/system.slice/app.service"},
+			{HierarchyID: "2", ControllerList: "memory", GroupPath: "/system.slice/app.service"},
+			{HierarchyID: "0", ControllerList: "", GroupPath: "/system.slice/app.service/main"},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unable to get cgroups for PID %d", p.pid)
+	}
+}
+
 func newFakeProcess(pid int32, dir string) processInfo {
 	return fakeProcess{pid: pid, dir: dir}
 }
@@ -12,10 +12,14 @@ import (
 	"github.com/spiffe/spire/pkg/agent/client"
 	"github.com/spiffe/spire/pkg/agent/common/backoff"
 	"github.com/spiffe/spire/pkg/agent/plugin/keymanager"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
 	"github.com/spiffe/spire/pkg/common/nodeutil"
 	"github.com/spiffe/spire/pkg/common/rotationutil"
 	telemetry_agent "github.com/spiffe/spire/pkg/common/telemetry/agent"
+	telemetry_common "github.com/spiffe/spire/pkg/common/telemetry/common"
 	"github.com/spiffe/spire/pkg/common/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Rotator interface {
@@ -29,6 +33,7 @@ type Rotator interface {
 
 type Client interface {
 	RenewSVID(ctx context.Context, csr []byte) (*client.X509SVID, error)
+	ReattestSVID(ctx context.Context, csr []byte, attestor nodeattestor.NodeAttestor) (*client.X509SVID, error)
 	Release()
 }
 
@@ -159,7 +164,7 @@ func (r *rotator) rotateSVID(ctx context.Context) (err error) {
 		return err
 	}
 
-	svid, err := r.client.RenewSVID(ctx, csr)
+	svid, err := r.newSVID(ctx, csr)
 	if err != nil {
 		return err
 	}
@@ -183,3 +188,31 @@ func (r *rotator) rotateSVID(ctx context.Context) (err error) {
 
 	return nil
 }
+
+// newSVID obtains a new SVID for the agent, re-attesting if configured to do
+// so. If the server does not allow the agent to re-attest with its node
+// attestor, the SVID is renewed instead so the agent is not left with an
+// expired SVID. Any other failure, including a denied re-attestation, fails
+// the rotation so the selectors of the agent are never silently kept.
+func (r *rotator) newSVID(ctx context.Context, csr []byte) (*client.X509SVID, error) {
+	if r.c.NodeAttestor == nil {
+		return r.client.RenewSVID(ctx, csr)
+	}
+
+	svid, err := r.reattestSVID(ctx, csr)
+	switch status.Code(err) {
+	case codes.FailedPrecondition:
+		r.c.Log.WithError(err).Warn("Server did not allow re-attestation; renewing agent SVID instead")
+		return r.client.RenewSVID(ctx, csr)
+	default:
+		return svid, err
+	}
+}
+
+func (r *rotator) reattestSVID(ctx context.Context, csr []byte) (_ *client.X509SVID, err error) {
+	counter := telemetry_agent.StartReattestAgentSVIDCall(r.c.Metrics)
+	defer counter.Done(&err)
+	telemetry_common.AddAttestorType(counter, r.c.NodeAttestor.Name())
+
+	return r.client.ReattestSVID(ctx, csr, r.c.NodeAttestor)
+}
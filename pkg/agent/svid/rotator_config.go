@@ -14,6 +14,7 @@ import (
 	"github.com/spiffe/spire/pkg/agent/common/backoff"
 	"github.com/spiffe/spire/pkg/agent/manager/cache"
 	"github.com/spiffe/spire/pkg/agent/plugin/keymanager"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 )
 
@@ -32,6 +33,10 @@ type RotatorConfig struct {
 
 	BundleStream *cache.BundleStream
 
+	// NodeAttestor, if set, is used to re-attest the agent instead of
+	// renewing its SVID when it is due for rotation
+	NodeAttestor nodeattestor.NodeAttestor
+
 	// How long to wait between expiry checks
 	Interval time.Duration
 
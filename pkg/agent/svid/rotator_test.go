@@ -17,12 +17,16 @@ import (
 	"github.com/spiffe/spire/pkg/agent/client"
 	"github.com/spiffe/spire/pkg/agent/manager/cache"
 	"github.com/spiffe/spire/pkg/agent/plugin/keymanager"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/fakes/fakeagentkeymanager"
+	"github.com/spiffe/spire/test/fakes/fakeagentnodeattestor"
 	"github.com/spiffe/spire/test/testca"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestRotator(t *testing.T) {
@@ -150,14 +154,115 @@ func TestRotator(t *testing.T) {
 	}
 }
 
+func TestRotatorReattest(t *testing.T) {
+	caCert, caKey := testca.CreateCACertificate(t, nil, nil)
+
+	for _, tt := range []struct {
+		name             string
+		reattest         bool
+		reattestErr      error
+		expectErr        string
+		expectRenewed    bool
+		expectReattested bool
+	}{
+		{
+			name:          "renews when re-attestation is not configured",
+			expectRenewed: true,
+		},
+		{
+			name:             "re-attests when configured",
+			reattest:         true,
+			expectReattested: true,
+		},
+		{
+			name:             "falls back to renewal when re-attestation is not allowed",
+			reattest:         true,
+			reattestErr:      status.Error(codes.FailedPrecondition, "re-attestation is not allowed"),
+			expectRenewed:    true,
+			expectReattested: true,
+		},
+		{
+			name:             "fails when re-attestation is denied by policy",
+			reattest:         true,
+			reattestErr:      status.Error(codes.PermissionDenied, "failed to attest: denied by attestation policy"),
+			expectErr:        "denied by attestation policy",
+			expectReattested: true,
+		},
+		{
+			name:             "fails when re-attestation fails",
+			reattest:         true,
+			reattestErr:      status.Error(codes.Unavailable, "oh no"),
+			expectErr:        "oh no",
+			expectReattested: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			svidKM := keymanager.ForSVID(fakeagentkeymanager.New(t, ""))
+			clk := clock.NewMock(t)
+			log, _ := test.NewNullLogger()
+			client := &fakeClient{clk: clk, caCert: caCert, caKey: caKey, reattestErr: tt.reattestErr}
+
+			svidKey, err := svidKM.GenerateKey(context.Background(), nil)
+			require.NoError(t, err)
+			svid := createTestSVID(t, svidKey, caCert, caKey, clk.Now(), clk.Now())
+
+			config := &RotatorConfig{
+				SVIDKeyManager: svidKM,
+				Log:            log,
+				Metrics:        telemetry.Blackhole{},
+				TrustDomain:    spiffeid.RequireTrustDomainFromString("example.org"),
+				BundleStream:   cache.NewBundleStream(observer.NewProperty([]*x509.Certificate(nil)).Observe()),
+				Clk:            clk,
+				SVID:           svid,
+				SVIDKey:        svidKey,
+			}
+			if tt.reattest {
+				config.NodeAttestor = fakeagentnodeattestor.New(t, fakeagentnodeattestor.Config{})
+			}
+			rotator, _ := newRotator(config)
+			rotator.client = client
+
+			err = rotator.rotateSVID(context.Background())
+			if tt.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectErr)
+				assert.Equal(t, svid, rotator.State().SVID)
+			} else {
+				require.NoError(t, err)
+				assert.NotEqual(t, svid, rotator.State().SVID)
+			}
+			assert.Equal(t, tt.expectRenewed, client.renewed)
+			assert.Equal(t, tt.expectReattested, client.reattested)
+		})
+	}
+}
+
 type fakeClient struct {
 	clk          clock.Clock
 	caCert       *x509.Certificate
 	caKey        crypto.Signer
 	releaseCount int
+
+	reattestErr error
+	renewed     bool
+	reattested  bool
 }
 
 func (c *fakeClient) RenewSVID(ctx context.Context, csrBytes []byte) (*client.X509SVID, error) {
+	c.renewed = true
+	return c.newSVID(csrBytes)
+}
+
+func (c *fakeClient) ReattestSVID(ctx context.Context, csrBytes []byte, attestor nodeattestor.NodeAttestor) (*client.X509SVID, error) {
+	c.reattested = true
+	if c.reattestErr != nil {
+		return nil, c.reattestErr
+	}
+	return c.newSVID(csrBytes)
+}
+
+func (c *fakeClient) newSVID(csrBytes []byte) (*client.X509SVID, error) {
 	csr, err := x509.ParseCertificateRequest(csrBytes)
 	if err != nil {
 		return nil, err
@@ -3,6 +3,7 @@ package bundleutil
 import (
 	"crypto/x509"
 	"encoding/json"
+	"sort"
 	"time"
 
 	"gopkg.in/square/go-jose.v2"
@@ -10,6 +11,7 @@ import (
 
 type marshalConfig struct {
 	refreshHint    time.Duration
+	sequenceNumber uint64
 	noX509SVIDKeys bool
 	noJWTSVIDKeys  bool
 	standardJWKS   bool
@@ -33,6 +35,14 @@ func OverrideRefreshHint(value time.Duration) MarshalOption {
 	})
 }
 
+// SequenceNumber sets the sequence number in the bundle
+func SequenceNumber(value uint64) MarshalOption {
+	return marshalOption(func(c *marshalConfig) error {
+		c.sequenceNumber = value
+		return nil
+	})
+}
+
 // NoX509SVIDKeys skips marshalling X509 SVID keys
 func NoX509SVIDKeys() MarshalOption {
 	return marshalOption(func(c *marshalConfig) error {
@@ -86,9 +96,16 @@ func Marshal(bundle *Bundle, opts ...MarshalOption) ([]byte, error) {
 	}
 
 	if !c.noJWTSVIDKeys {
-		for keyID, jwtSigningKey := range bundle.JWTSigningKeys() {
+		// sort the keys by key ID so the output is deterministic
+		jwtSigningKeys := bundle.JWTSigningKeys()
+		keyIDs := make([]string, 0, len(jwtSigningKeys))
+		for keyID := range jwtSigningKeys {
+			keyIDs = append(keyIDs, keyID)
+		}
+		sort.Strings(keyIDs)
+		for _, keyID := range keyIDs {
 			jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
-				Key:   jwtSigningKey,
+				Key:   jwtSigningKeys[keyID],
 				KeyID: keyID,
 				Use:   maybeUse(jwtSVIDUse),
 			})
@@ -99,6 +116,7 @@ func Marshal(bundle *Bundle, opts ...MarshalOption) ([]byte, error) {
 	if !c.standardJWKS {
 		out = bundleDoc{
 			JSONWebKeySet: jwks,
+			Sequence:      c.sequenceNumber,
 			RefreshHint:   int(c.refreshHint / time.Second),
 		}
 	}
@@ -31,6 +31,14 @@ func TestMarshal(t *testing.T) {
 			},
 			out: `{"keys":null, "spiffe_refresh_hint": 10}`,
 		},
+		{
+			name:  "with sequence number",
+			empty: true,
+			opts: []MarshalOption{
+				SequenceNumber(42),
+			},
+			out: `{"keys":null, "spiffe_sequence": 42, "spiffe_refresh_hint": 60}`,
+		},
 		{
 			name: "without X509 SVID keys",
 			opts: []MarshalOption{
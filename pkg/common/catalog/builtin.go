@@ -62,10 +62,28 @@ func loadBuiltIn(ctx context.Context, builtIn BuiltIn, config BuiltInConfig) (_
 	}()
 	closers = append(closers, dialer)
 
-	pluginServers := append([]pluginsdk.ServiceServer{builtIn.Plugin}, builtIn.Services...)
+	var pluginServers []pluginsdk.ServiceServer
+	for _, server := range append([]pluginsdk.ServiceServer{builtIn.Plugin}, builtIn.Services...) {
+		pluginServers = append(pluginServers, &implServer{ServiceServer: server})
+	}
 
 	private.Register(builtinServer, pluginServers, logger, dialer)
 
+	// Implementations that implement io.Closer are closed when the plugin is
+	// unloaded, after the server has stopped. The same implementation may
+	// back more than one server, but is only closed once.
+	closed := make(map[io.Closer]struct{})
+	for _, server := range pluginServers {
+		closer, ok := server.(*implServer).impl.(io.Closer)
+		if !ok {
+			continue
+		}
+		if _, ok := closed[closer]; !ok {
+			closers = append(closers, closer)
+			closed[closer] = struct{}{}
+		}
+	}
+
 	builtinConn, err := startPipeServer(builtinServer, config.Log)
 	if err != nil {
 		return nil, err
@@ -87,6 +105,18 @@ func newBuiltInServer() *grpc.Server {
 	)
 }
 
+// implServer keeps the implementation registered by the wrapped service
+// server.
+type implServer struct {
+	pluginsdk.ServiceServer
+	impl interface{}
+}
+
+func (s *implServer) RegisterServer(server *grpc.Server) interface{} {
+	s.impl = s.ServiceServer.RegisterServer(server)
+	return s.impl
+}
+
 type builtinDialer struct {
 	pluginName   string
 	log          logrus.FieldLogger
@@ -46,6 +46,35 @@ func TestBuiltInPlugin(t *testing.T) {
 	})
 }
 
+func TestBuiltInPluginIsClosedOnUnload(t *testing.T) {
+	plugin := new(closerPlugin)
+	builtIn := catalog.MakeBuiltIn("closer",
+		test.SomePluginPluginServer(plugin),
+		test.SomeServiceServiceServer(plugin),
+	)
+
+	log, _ := log_test.NewNullLogger()
+	conn, err := catalog.LoadBuiltIn(context.Background(), builtIn, catalog.BuiltInConfig{Log: log})
+	require.NoError(t, err)
+	require.Zero(t, plugin.closed)
+
+	// The implementation backs both servers but is only closed once
+	require.NoError(t, conn.Close())
+	require.Equal(t, 1, plugin.closed)
+}
+
+type closerPlugin struct {
+	test.UnimplementedSomePluginServer
+	test.UnimplementedSomeServiceServer
+
+	closed int
+}
+
+func (p *closerPlugin) Close() error {
+	p.closed++
+	return nil
+}
+
 func TestExternalPlugin(t *testing.T) {
 	pluginPath := buildTestPlugin(t, "./testplugin/main.go")
 
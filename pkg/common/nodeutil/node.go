@@ -10,9 +10,8 @@ import (
 )
 
 // IsAgentBanned determines if a given attested node is banned or not.
-// An agent is considered as "banned" if its X509 SVID serial number is empty.
 func IsAgentBanned(node *common.AttestedNode) bool {
-	return node.CertSerialNumber == ""
+	return node.Banned
 }
 
 // ShouldAgentReattest returns true if the Server returned an error worth rebooting the Agent
@@ -15,8 +15,9 @@ import (
 )
 
 func TestIsAgentBanned(t *testing.T) {
-	require.True(t, nodeutil.IsAgentBanned(&common.AttestedNode{}))
+	require.True(t, nodeutil.IsAgentBanned(&common.AttestedNode{Banned: true}))
 	require.False(t, nodeutil.IsAgentBanned(&common.AttestedNode{CertSerialNumber: "non-empty-serial"}))
+	require.False(t, nodeutil.IsAgentBanned(&common.AttestedNode{}))
 }
 
 func TestShouldAgentReattest(t *testing.T) {
@@ -0,0 +1,147 @@
+package httpchallenge
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"text/template"
+
+	"github.com/spiffe/spire/pkg/common/idutil"
+)
+
+const (
+	PluginName = "http_challenge"
+
+	// nonceSize is the size of the random nonce in bytes
+	nonceSize = 32
+)
+
+// DefaultAgentPathTemplate is the default text/template
+var DefaultAgentPathTemplate = template.Must(template.New("agent-path").Parse("{{ .PluginName }}/{{ .HostName }}"))
+
+var (
+	agentNameRE = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	hostNameRE  = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// AttestationData is the payload sent by the agent.
+type AttestationData struct {
+	// HostName is the DNS name the agent claims. The server fetches the
+	// challenge from this name.
+	HostName string `json:"hostname"`
+	// AgentName distinguishes multiple agents running on the same host.
+	AgentName string `json:"agentname"`
+	// Port is the port the agent serves the challenge on.
+	Port int `json:"port"`
+}
+
+// Challenge is sent by the server to the agent. The agent must serve the
+// nonce on the challenge URL.
+type Challenge struct {
+	Nonce string `json:"nonce"`
+}
+
+// Response is sent by the agent once it is serving the challenge.
+type Response struct {
+	Ready bool `json:"ready"`
+}
+
+type agentPathTemplateData struct {
+	AttestationData
+	PluginName string
+}
+
+// ValidateAttestationData checks that the attestation data is well formed.
+func ValidateAttestationData(data *AttestationData) error {
+	if len(data.HostName) > 253 || !hostNameRE.MatchString(data.HostName) {
+		return fmt.Errorf("invalid hostname %q", data.HostName)
+	}
+	if !agentNameRE.MatchString(data.AgentName) {
+		return fmt.Errorf("invalid agent name %q", data.AgentName)
+	}
+	if data.Port <= 0 || data.Port > 65535 {
+		return fmt.Errorf("invalid port %d", data.Port)
+	}
+	return nil
+}
+
+// GenerateChallenge generates a challenge with a random nonce.
+func GenerateChallenge() (*Challenge, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &Challenge{
+		Nonce: base64.RawURLEncoding.EncodeToString(nonce),
+	}, nil
+}
+
+// ChallengePath returns the path the agent with the given name serves the
+// challenge on.
+func ChallengePath(agentName string) string {
+	return fmt.Sprintf("/.well-known/spiffe/nodeattestor/%s/%s/challenge", PluginName, agentName)
+}
+
+// ChallengeURL returns the URL the server fetches the challenge from.
+func ChallengeURL(data *AttestationData) string {
+	u := url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(data.HostName, strconv.Itoa(data.Port)),
+		Path:   ChallengePath(data.AgentName),
+	}
+	return u.String()
+}
+
+// VerifyChallenge fetches the challenge from the agent and verifies that it
+// matches the expected nonce.
+func VerifyChallenge(ctx context.Context, client *http.Client, data *AttestationData, challenge *Challenge) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ChallengeURL(data), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// The nonce is small; anything larger than it is not a valid response
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2*int64(len(challenge.Nonce))))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(bytes.TrimSpace(body), []byte(challenge.Nonce)) != 1 {
+		return errors.New("challenge response does not match")
+	}
+	return nil
+}
+
+// MakeAgentID makes an agent SPIFFE ID. The ID always has a host value equal
+// to the given trust domain, the path is created using the given
+// agentPathTemplate.
+func MakeAgentID(trustDomain string, agentPathTemplate *template.Template, data *AttestationData) (*url.URL, error) {
+	var agentPath bytes.Buffer
+	if err := agentPathTemplate.Execute(&agentPath, agentPathTemplateData{
+		AttestationData: *data,
+		PluginName:      PluginName,
+	}); err != nil {
+		return nil, err
+	}
+
+	return idutil.AgentURI(trustDomain, agentPath.String()), nil
+}
@@ -0,0 +1,51 @@
+package httpchallenge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAttestationData(t *testing.T) {
+	valid := AttestationData{HostName: "node-1.example.org", AgentName: "default", Port: 80}
+	require.NoError(t, ValidateAttestationData(&valid))
+
+	for _, tt := range []struct {
+		name      string
+		alter     func(*AttestationData)
+		expectErr string
+	}{
+		{name: "empty hostname", alter: func(d *AttestationData) { d.HostName = "" }, expectErr: `invalid hostname ""`},
+		{name: "hostname with port", alter: func(d *AttestationData) { d.HostName = "example.org:80" }, expectErr: `invalid hostname "example.org:80"`},
+		{name: "hostname with path", alter: func(d *AttestationData) { d.HostName = "example.org/x" }, expectErr: `invalid hostname "example.org/x"`},
+		{name: "hostname with userinfo", alter: func(d *AttestationData) { d.HostName = "a@example.org" }, expectErr: `invalid hostname "a@example.org"`},
+		{name: "empty agent name", alter: func(d *AttestationData) { d.AgentName = "" }, expectErr: `invalid agent name ""`},
+		{name: "agent name with path", alter: func(d *AttestationData) { d.AgentName = "../x" }, expectErr: `invalid agent name "../x"`},
+		{name: "zero port", alter: func(d *AttestationData) { d.Port = 0 }, expectErr: "invalid port 0"},
+		{name: "port out of range", alter: func(d *AttestationData) { d.Port = 65536 }, expectErr: "invalid port 65536"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			data := valid
+			tt.alter(&data)
+			err := ValidateAttestationData(&data)
+			require.Error(t, err)
+			assert.Equal(t, tt.expectErr, err.Error())
+		})
+	}
+}
+
+func TestGenerateChallenge(t *testing.T) {
+	c1, err := GenerateChallenge()
+	require.NoError(t, err)
+	c2, err := GenerateChallenge()
+	require.NoError(t, err)
+	assert.Len(t, c1.Nonce, 43)
+	assert.NotEqual(t, c1.Nonce, c2.Nonce)
+}
+
+func TestChallengeURL(t *testing.T) {
+	assert.Equal(t, "http://node-1.example.org:8080/.well-known/spiffe/nodeattestor/http_challenge/default/challenge",
+		ChallengeURL(&AttestationData{HostName: "node-1.example.org", AgentName: "default", Port: 8080}))
+}
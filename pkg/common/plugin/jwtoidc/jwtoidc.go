@@ -0,0 +1,66 @@
+package jwtoidc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/spiffe/spire/pkg/common/idutil"
+)
+
+const (
+	PluginName = "jwt_oidc"
+)
+
+// DefaultAgentPathTemplate is the default text/template used to build the
+// agent ID path. The token ID (jti) is used so every token yields a distinct
+// agent ID, which is what ephemeral workloads such as CI runners need.
+var DefaultAgentPathTemplate = template.Must(template.New("agent-path").Parse("{{ .PluginName }}/{{ .IssuerHost }}/{{ .TokenID }}"))
+
+// AttestationData is the payload sent by the agent.
+type AttestationData struct {
+	Token string `json:"token"`
+}
+
+// AgentPathTemplateData is the data available to the agent path template.
+type AgentPathTemplateData struct {
+	PluginName string
+	// Issuer is the "iss" claim and IssuerHost the host of the issuer URL.
+	Issuer     string
+	IssuerHost string
+	// Subject is the "sub" claim.
+	Subject string
+	// TokenID is the "jti" claim.
+	TokenID string
+	// Claims holds all of the token claims, e.g. {{ index .Claims "repository" }}.
+	Claims map[string]interface{}
+}
+
+// MakeAgentID makes an agent SPIFFE ID. The ID always has a host value equal
+// to the given trust domain, the path is created using the given
+// agentPathTemplate.
+func MakeAgentID(trustDomain string, agentPathTemplate *template.Template, data AgentPathTemplateData) (*url.URL, error) {
+	data.PluginName = PluginName
+
+	var agentPath bytes.Buffer
+	if err := agentPathTemplate.Execute(&agentPath, data); err != nil {
+		return nil, err
+	}
+
+	// Templates referencing missing claims render empty segments, which
+	// would produce ambiguous (and often shared) agent IDs.
+	for _, segment := range strings.Split(strings.Trim(agentPath.String(), "/"), "/") {
+		if segment == "" || segment == "<no value>" {
+			return nil, errors.New("agent path template rendered an empty path segment")
+		}
+	}
+
+	id := idutil.AgentURI(trustDomain, agentPath.String())
+	if err := idutil.CheckAgentIDStringNormalization(id.String()); err != nil {
+		return nil, fmt.Errorf("agent path template rendered an invalid agent ID: %w", err)
+	}
+	return id, nil
+}
@@ -5,10 +5,12 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -134,9 +136,12 @@ func (s *ServerHandshake) VerifyAttestationData(data []byte) error {
 		return status.Errorf(codes.Internal, "cert has no valid principals")
 	}
 	addr := fmt.Sprintf("%s:22", cert.ValidPrincipals[0])
-	if err := s.s.certChecker.CheckHostKey(addr, &net.IPAddr{}, cert); err != nil {
+	if err := s.s.getCertChecker().CheckHostKey(addr, &net.IPAddr{}, cert); err != nil {
 		return status.Errorf(codes.Internal, "failed to check host key: %v", err)
 	}
+	if s.s.isRevoked(cert) {
+		return status.Error(codes.PermissionDenied, "host certificate has been revoked")
+	}
 	s.hostname, err = decanonicalizeHostname(cert.ValidPrincipals[0], s.s.canonicalDomain)
 	if err != nil {
 		return status.Errorf(codes.Internal, "failed to decanonicalize hostname: %v", err)
@@ -200,6 +205,46 @@ func (s *ServerHandshake) AgentID() (string, error) {
 	return makeAgentID(s.s.trustDomain, s.s.agentPathTemplate, s.cert, s.hostname)
 }
 
+// Selectors returns the selectors derived from the host certificate.
+func (s *ServerHandshake) Selectors() []string {
+	return buildSelectorValues(s.cert)
+}
+
+func buildSelectorValues(cert *ssh.Certificate) []string {
+	var selectorValues []string
+	for _, principal := range cert.ValidPrincipals {
+		selectorValues = append(selectorValues, "principal:"+principal)
+	}
+	if cert.KeyId != "" {
+		selectorValues = append(selectorValues, "key_id:"+cert.KeyId)
+	}
+
+	extensions := make([]string, 0, len(cert.Extensions))
+	for name := range cert.Extensions {
+		extensions = append(extensions, name)
+	}
+	sort.Strings(extensions)
+	for _, name := range extensions {
+		value := extensionValue(cert.Extensions[name])
+		if value == "" {
+			selectorValues = append(selectorValues, "extension:"+name)
+			continue
+		}
+		selectorValues = append(selectorValues, "extension:"+name+":"+value)
+	}
+	return selectorValues
+}
+
+// extensionValue returns the value of a certificate extension. Values set
+// by ssh-keygen are encoded as an SSH string, in which case the string is
+// unwrapped.
+func extensionValue(data string) string {
+	if len(data) >= 4 && int(binary.BigEndian.Uint32([]byte(data[:4]))) == len(data)-4 {
+		return data[4:]
+	}
+	return data
+}
+
 func newNonce() ([]byte, error) {
 	b := make([]byte, nonceLen)
 	if _, err := rand.Read(b); err != nil {
@@ -228,6 +228,50 @@ func TestVerifyAttestationData(t *testing.T) {
 	}
 }
 
+func TestVerifyAttestationDataRevoked(t *testing.T) {
+	c, s := newTestHandshake(t)
+	attestationData := marshalAttestationData(t, c.c.cert.Marshal())
+
+	krl := &krl{
+		keys:         map[string]bool{},
+		sha1Hashes:   map[string]bool{},
+		sha256Hashes: map[string]bool{},
+	}
+	s.s.krl = krl
+	require.NoError(t, s.VerifyAttestationData(attestationData))
+
+	krl.keys[string(c.c.cert.Key.Marshal())] = true
+	s.state = stateServerInit
+	err := s.VerifyAttestationData(attestationData)
+	spiretest.RequireGRPCStatus(t, err, codes.PermissionDenied, "host certificate has been revoked")
+}
+
+func TestSelectors(t *testing.T) {
+	tt := newTest(t, principal("foo-host"), principal("foo-host.example.org"), func(cert *ssh.Certificate) {
+		cert.KeyId = "group:web"
+		cert.Extensions = map[string]string{
+			"permit-pty":          "",
+			"group@example.org":   string(ssh.Marshal(struct{ Value string }{"web"})),
+			"region@example.org":  "us-west-1",
+			"expires@example.org": "",
+		}
+	})
+
+	s := &ServerHandshake{cert: tt.Certificate}
+	require.Equal(t, []string{
+		"principal:foo-host",
+		"principal:foo-host.example.org",
+		"key_id:group:web",
+		"extension:expires@example.org",
+		"extension:group@example.org:web",
+		"extension:permit-pty",
+		"extension:region@example.org:us-west-1",
+	}, s.Selectors())
+
+	s = &ServerHandshake{cert: newTest(t).Certificate}
+	require.Empty(t, s.Selectors())
+}
+
 func marshalAttestationData(t *testing.T, cert []byte) []byte {
 	b, err := json.Marshal(attestationData{
 		Certificate: cert,
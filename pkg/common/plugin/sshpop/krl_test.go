@@ -0,0 +1,137 @@
+package sshpop
+
+import (
+	"crypto/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// The KRLs in testdata were generated with ssh-keygen -k:
+//   - krl_key_id revokes certificates with key ID "foo-host" issued by the CA
+//   - krl_serials revokes serials 1-5, 7, 9, 11 and 100-200 issued by the CA
+//   - krl_key explicitly revokes the CA key
+//   - krl_hash revokes the CA key by its SHA256 fingerprint
+func TestKRL(t *testing.T) {
+	keyBytes, err := os.ReadFile("./testdata/dummy_agent_ssh_key")
+	require.NoError(t, err)
+	caSigner, err := ssh.ParsePrivateKey(keyBytes)
+	require.NoError(t, err)
+	otherCA := newTest(t)
+
+	newCert := func(t *testing.T, signer ssh.Signer, serial uint64, keyID string) *ssh.Certificate {
+		hostKey := newTest(t)
+		cert := &ssh.Certificate{
+			Key:             hostKey.Signer.PublicKey(),
+			Serial:          serial,
+			CertType:        ssh.HostCert,
+			KeyId:           keyID,
+			ValidPrincipals: []string{"foo-host"},
+			ValidBefore:     ssh.CertTimeInfinity,
+		}
+		require.NoError(t, cert.SignCert(rand.Reader, signer))
+		return cert
+	}
+
+	tests := []struct {
+		desc          string
+		krl           string
+		cert          *ssh.Certificate
+		expectRevoked bool
+	}{
+		{
+			desc:          "revoked key ID",
+			krl:           "krl_key_id",
+			cert:          newCert(t, caSigner, 0, "foo-host"),
+			expectRevoked: true,
+		},
+		{
+			desc: "other key ID",
+			krl:  "krl_key_id",
+			cert: newCert(t, caSigner, 0, "bar-host"),
+		},
+		{
+			desc: "revoked key ID from another CA",
+			krl:  "krl_key_id",
+			cert: newCert(t, otherCA.Signer, 0, "foo-host"),
+		},
+		{
+			desc:          "serial in list",
+			krl:           "krl_serials",
+			cert:          newCert(t, caSigner, 7, ""),
+			expectRevoked: true,
+		},
+		{
+			desc:          "serial in bitmap",
+			krl:           "krl_serials",
+			cert:          newCert(t, caSigner, 3, ""),
+			expectRevoked: true,
+		},
+		{
+			desc:          "serial in range",
+			krl:           "krl_serials",
+			cert:          newCert(t, caSigner, 150, ""),
+			expectRevoked: true,
+		},
+		{
+			desc: "serial not revoked",
+			krl:  "krl_serials",
+			cert: newCert(t, caSigner, 8, ""),
+		},
+		{
+			desc: "serial after range",
+			krl:  "krl_serials",
+			cert: newCert(t, caSigner, 201, ""),
+		},
+		{
+			desc: "revoked serial from another CA",
+			krl:  "krl_serials",
+			cert: newCert(t, otherCA.Signer, 7, ""),
+		},
+		{
+			desc:          "revoked CA key",
+			krl:           "krl_key",
+			cert:          newCert(t, caSigner, 0, ""),
+			expectRevoked: true,
+		},
+		{
+			desc:          "revoked CA key fingerprint",
+			krl:           "krl_hash",
+			cert:          newCert(t, caSigner, 0, ""),
+			expectRevoked: true,
+		},
+		{
+			desc: "other CA key",
+			krl:  "krl_hash",
+			cert: newCert(t, otherCA.Signer, 0, ""),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			krl, err := loadKRL("./testdata/" + tt.krl)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectRevoked, krl.isRevoked(tt.cert))
+		})
+	}
+}
+
+func TestParseKRL(t *testing.T) {
+	data, err := os.ReadFile("./testdata/krl_serials")
+	require.NoError(t, err)
+
+	_, err = parseKRL(data)
+	require.NoError(t, err)
+
+	_, err = parseKRL([]byte("not a krl"))
+	require.EqualError(t, err, "bad magic")
+
+	_, err = parseKRL([]byte("SSHKRL"))
+	require.EqualError(t, err, "unexpected end of data")
+
+	_, err = parseKRL(data[:len(data)-1])
+	require.EqualError(t, err, "unexpected end of data")
+}
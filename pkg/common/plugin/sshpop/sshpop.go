@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire/pkg/common/util"
 	"golang.org/x/crypto/ssh"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -44,10 +46,21 @@ type Client struct {
 
 // Server is a factory for generating server handshake objects.
 type Server struct {
-	certChecker       *ssh.CertChecker
 	agentPathTemplate *template.Template
 	trustDomain       string
 	canonicalDomain   string
+
+	// certAuthorities are the cert authorities configured inline. They are
+	// combined with the ones in certAuthoritiesPath when reloading.
+	certAuthorities     []string
+	certAuthoritiesPath string
+	certAuthoritiesFile *util.FileChangeDetector
+	krlPath             string
+	krlFile             *util.FileChangeDetector
+
+	mu          sync.RWMutex
+	certChecker *ssh.CertChecker
+	krl         *krl
 }
 
 // ClientConfig configures the client.
@@ -64,6 +77,9 @@ type ServerConfig struct {
 	// the certificate's valid principals. See CanonicalDomains in ssh_config(5).
 	CanonicalDomain   string `hcl:"canonical_domain"`
 	AgentPathTemplate string `hcl:"agent_path_template"`
+	// KRLPath is the path to an OpenSSH key revocation list. Host
+	// certificates revoked by the KRL are rejected.
+	KRLPath string `hcl:"krl_path"`
 }
 
 func NewClient(configString string) (*Client, error) {
@@ -125,21 +141,31 @@ func NewServer(trustDomain, configString string) (*Server, error) {
 	if config.CertAuthorities == nil && config.CertAuthoritiesPath == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "missing required config value for \"cert_authorities\" or \"cert_authorities_path\"")
 	}
-	var certAuthorities []string
-	if config.CertAuthorities != nil {
-		certAuthorities = append(certAuthorities, config.CertAuthorities...)
+	s := &Server{
+		trustDomain:         trustDomain,
+		canonicalDomain:     config.CanonicalDomain,
+		certAuthorities:     config.CertAuthorities,
+		certAuthoritiesPath: config.CertAuthoritiesPath,
 	}
 	if config.CertAuthoritiesPath != "" {
-		fileCertAuthorities, err := pubkeysFromPath(config.CertAuthoritiesPath)
-		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "failed to get cert authorities from file: %v", err)
-		}
-		certAuthorities = append(certAuthorities, fileCertAuthorities...)
+		s.certAuthoritiesFile = util.NewFileChangeDetector(config.CertAuthoritiesPath)
 	}
-	certChecker, err := certCheckerFromPubkeys(certAuthorities)
+	certChecker, err := s.loadCertChecker()
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create cert checker: %v", err)
+		return nil, err
 	}
+	s.certChecker = certChecker
+
+	if config.KRLPath != "" {
+		s.krlPath = config.KRLPath
+		s.krlFile = util.NewFileChangeDetector(config.KRLPath)
+		krl, err := loadKRL(config.KRLPath)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to load KRL: %v", err)
+		}
+		s.krl = krl
+	}
+
 	agentPathTemplate := DefaultAgentPathTemplate
 	if len(config.AgentPathTemplate) > 0 {
 		tmpl, err := template.New("agent-path").Parse(config.AgentPathTemplate)
@@ -148,12 +174,85 @@ func NewServer(trustDomain, configString string) (*Server, error) {
 		}
 		agentPathTemplate = tmpl
 	}
-	return &Server{
-		certChecker:       certChecker,
-		agentPathTemplate: agentPathTemplate,
-		trustDomain:       trustDomain,
-		canonicalDomain:   config.CanonicalDomain,
-	}, nil
+	s.agentPathTemplate = agentPathTemplate
+	return s, nil
+}
+
+// ReloadCertAuthorities reloads the cert authorities from the configured
+// cert_authorities_path if the file changed since it was last loaded. It
+// returns true if a change was detected. If the new cert authorities cannot be
+// loaded, an error is returned and the previous ones remain in use.
+func (s *Server) ReloadCertAuthorities() (bool, error) {
+	if s.certAuthoritiesFile == nil {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.certAuthoritiesFile.Changed() {
+		return false, nil
+	}
+
+	certChecker, err := s.loadCertChecker()
+	if err != nil {
+		return true, err
+	}
+	s.certChecker = certChecker
+	return true, nil
+}
+
+// ReloadKRL reloads the key revocation list from the configured krl_path if
+// the file changed since it was last loaded. It returns true if a change was
+// detected. If the new KRL cannot be loaded, an error is returned and the
+// previous KRL remains in use.
+func (s *Server) ReloadKRL() (bool, error) {
+	if s.krlFile == nil {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.krlFile.Changed() {
+		return false, nil
+	}
+
+	krl, err := loadKRL(s.krlPath)
+	if err != nil {
+		return true, err
+	}
+	s.krl = krl
+	return true, nil
+}
+
+func (s *Server) isRevoked(cert *ssh.Certificate) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.krl != nil && s.krl.isRevoked(cert)
+}
+
+func (s *Server) getCertChecker() *ssh.CertChecker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.certChecker
+}
+
+func (s *Server) loadCertChecker() (*ssh.CertChecker, error) {
+	var certAuthorities []string
+	certAuthorities = append(certAuthorities, s.certAuthorities...)
+	if s.certAuthoritiesPath != "" {
+		fileCertAuthorities, err := pubkeysFromPath(s.certAuthoritiesPath)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to get cert authorities from file: %v", err)
+		}
+		certAuthorities = append(certAuthorities, fileCertAuthorities...)
+	}
+	certChecker, err := certCheckerFromPubkeys(certAuthorities)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create cert checker: %v", err)
+	}
+	return certChecker, nil
 }
 
 func pubkeysFromPath(pubkeysPath string) ([]string, error) {
@@ -103,6 +103,23 @@ func TestNewServer(t *testing.T) {
 			trustDomain:  "foo.test",
 			expectErr:    `failed to create cert checker: failed to parse public key`,
 		},
+		{
+			desc: "bad KRL",
+			configString: fmt.Sprintf(`cert_authorities = [%q]
+									   krl_path = "./testdata/dummy_ssh_cert_authority.pub"`, testCertAuthority),
+			trustDomain: "foo.test",
+			expectErr:   `failed to load KRL: failed to parse KRL "./testdata/dummy_ssh_cert_authority.pub": bad magic`,
+		},
+		{
+			desc: "success with KRL",
+			configString: fmt.Sprintf(`cert_authorities = [%q]
+									   krl_path = "./testdata/krl_key_id"`, testCertAuthority),
+			trustDomain: "foo.test",
+			requireServer: func(t *testing.T, s *Server) {
+				require.NotNil(t, s.krl)
+				require.True(t, s.krl.certs[0].keyIDs["foo-host"])
+			},
+		},
 		{
 			desc: "success",
 			configString: fmt.Sprintf(`cert_authorities = [%q]
@@ -0,0 +1,56 @@
+package tpmek
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+)
+
+const PluginName = "tpm_ek"
+
+// PCRHashAlg is the PCR bank that is quoted to build PCR selectors
+const PCRHashAlg = tpm2.AlgSHA256
+
+type AttestationRequest struct {
+	// EKCert is the endorsement certificate. It can be omitted if the TPM
+	// does not have one provisioned.
+	EKCert []byte
+	EKPub  []byte
+
+	AKPub []byte
+
+	// PCRs are the indexes of the PCRs that the agent will quote
+	PCRs []int
+}
+
+type ChallengeRequest struct {
+	CredActivation *tpmdevid.CredActivation
+
+	// QuoteNonce is the qualifying data for the PCR quote. It is only set if
+	// the agent requested to quote PCRs.
+	QuoteNonce []byte
+}
+
+type ChallengeResponse struct {
+	CredActivation []byte
+
+	Quote          []byte
+	QuoteSignature []byte
+	PCRValues      map[int][]byte
+}
+
+// PubHash returns the hex encoded SHA-256 hash of the PKIX encoding of the
+// given endorsement public key.
+func PubHash(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
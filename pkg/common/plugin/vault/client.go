@@ -0,0 +1,282 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+	vapi "github.com/hashicorp/vault/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+)
+
+const (
+	EnvVaultAddr            = "VAULT_ADDR"
+	EnvVaultToken           = "VAULT_TOKEN"
+	EnvVaultClientCert      = "VAULT_CLIENT_CERT"
+	EnvVaultClientKey       = "VAULT_CLIENT_KEY"
+	EnvVaultCACert          = "VAULT_CACERT"
+	EnvVaultAppRoleID       = "VAULT_APPROLE_ID"
+	EnvVaultAppRoleSecretID = "VAULT_APPROLE_SECRET_ID" // #nosec G101
+	EnvVaultNamespace       = "VAULT_NAMESPACE"
+
+	DefaultCertMountPoint    = "cert"
+	DefaultAppRoleMountPoint = "approle"
+	DefaultK8sMountPoint     = "kubernetes"
+)
+
+type AuthMethod int
+
+const (
+	_ AuthMethod = iota
+	CERT
+	TOKEN
+	APPROLE
+	K8S
+)
+
+// ClientParams are the parameters used to create and authenticate a Vault
+// client.
+type ClientParams struct {
+	// A URL of Vault server. (e.g., https://vault.example.com:8443/)
+	VaultAddr string
+	// token string to use when auth method is 'token'
+	Token string
+	// Name of mount point where TLS Cert auth method is mounted. (e.g., /auth/<mount_point>/login )
+	CertAuthMountPoint string
+	// Name of the Vault role.
+	// If given, the plugin authenticates against only the named role
+	CertAuthRoleName string
+	// Path to a client certificate file to be used when auth method is 'cert'
+	ClientCertPath string
+	// Path to a client private key file to be used when auth method is 'cert'
+	ClientKeyPath string
+	// Path to a CA certificate file to be used when client verifies a server certificate
+	CACertPath string
+	// Name of mount point where AppRole auth method is mounted. (e.g., /auth/<mount_point>/login )
+	AppRoleAuthMountPoint string
+	// An identifier of AppRole
+	AppRoleID string
+	// A credential set of AppRole
+	AppRoleSecretID string
+	// Name of the mount point where Kubernetes auth method is mounted. (e.g., /auth/<mount_point>/login)
+	K8sAuthMountPoint string
+	// Name of the Vault role.
+	// The plugin authenticates against the named role.
+	K8sAuthRoleName string
+	// Path to a K8s Service Account Token to be used when auth method is 'k8s'
+	K8sAuthTokenPath string
+	// If true, client accepts any certificates.
+	// It should be used only test environment so on.
+	TLSSKipVerify bool
+	// MaxRetries controls the number of times to retry to connect
+	// Set to 0 to disable retrying.
+	// If the value is nil, to use the default in hashicorp/vault/api.
+	MaxRetries *int
+	// Name of the Vault namespace
+	Namespace string
+}
+
+// NewAuthenticatedClient returns a new vault client authenticated with the
+// given method. renewCh is closed when the token can no longer be renewed,
+// after which a new client must be created. The token is renewed until ctx
+// is done, which must happen once the client is no longer used.
+func NewAuthenticatedClient(ctx context.Context, params *ClientParams, method AuthMethod, renewCh chan struct{}, logger hclog.Logger) (*vapi.Client, error) {
+	config := vapi.DefaultConfig()
+	config.Address = params.VaultAddr
+	if params.MaxRetries != nil {
+		config.MaxRetries = *params.MaxRetries
+	}
+
+	if err := ConfigureTLS(config, params); err != nil {
+		return nil, err
+	}
+	vc, err := vapi.NewClient(config)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to create Vault client: %v", err)
+	}
+
+	if params.Namespace != "" {
+		vc.SetNamespace(params.Namespace)
+	}
+
+	var sec *vapi.Secret
+	switch method {
+	case TOKEN:
+		sec, err = lookupSelf(vc, params.Token)
+	case CERT:
+		path := fmt.Sprintf("auth/%v/login", params.CertAuthMountPoint)
+		sec, err = auth(vc, path, map[string]interface{}{
+			"name": params.CertAuthRoleName,
+		})
+	case APPROLE:
+		path := fmt.Sprintf("auth/%v/login", params.AppRoleAuthMountPoint)
+		sec, err = auth(vc, path, map[string]interface{}{
+			"role_id":   params.AppRoleID,
+			"secret_id": params.AppRoleSecretID,
+		})
+	case K8S:
+		var b []byte
+		b, err = os.ReadFile(params.K8sAuthTokenPath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to read k8s service account token: %v", err)
+		}
+		path := fmt.Sprintf("auth/%s/login", params.K8sAuthMountPoint)
+		sec, err = auth(vc, path, map[string]interface{}{
+			"role": params.K8sAuthRoleName,
+			"jwt":  string(b),
+		})
+	default:
+		return nil, status.Errorf(codes.Internal, "unknown authentication method %d", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handleRenewToken(ctx, vc, sec, renewCh, logger); err != nil {
+		return nil, err
+	}
+
+	return vc, nil
+}
+
+// handleRenewToken handles renewing the vault token.
+// if the token is non-renewable or renew failed, renewCh will be closed.
+func handleRenewToken(ctx context.Context, vc *vapi.Client, sec *vapi.Secret, renewCh chan struct{}, logger hclog.Logger) error {
+	if sec == nil || sec.Auth == nil {
+		return status.Error(codes.InvalidArgument, "secret is nil")
+	}
+
+	if sec.Auth.LeaseDuration == 0 {
+		logger.Debug("Token will never expire")
+		return nil
+	}
+	if !sec.Auth.Renewable {
+		logger.Debug("Token is not renewable")
+		close(renewCh)
+		return nil
+	}
+	renew, err := NewRenew(vc, sec, logger)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(renewCh)
+		renew.Run(ctx)
+	}()
+
+	logger.Debug("Token will be renewed")
+
+	return nil
+}
+
+// ConfigureTLS configures TLS for the Vault client.
+func ConfigureTLS(vc *vapi.Config, params *ClientParams) error {
+	if vc.HttpClient == nil {
+		vc.HttpClient = vapi.DefaultConfig().HttpClient
+	}
+	clientTLSConfig := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
+
+	var clientCert tls.Certificate
+	foundClientCert := false
+
+	switch {
+	case params.ClientCertPath != "" && params.ClientKeyPath != "":
+		c, err := tls.LoadX509KeyPair(params.ClientCertPath, params.ClientKeyPath)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to parse client cert and private-key: %v", err)
+		}
+		clientCert = c
+		foundClientCert = true
+	case params.ClientCertPath != "" || params.ClientKeyPath != "":
+		return status.Error(codes.InvalidArgument, "both client cert and client key are required")
+	}
+
+	if params.CACertPath != "" {
+		certs, err := pemutil.LoadCertificates(params.CACertPath)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to load CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+		clientTLSConfig.RootCAs = pool
+	}
+
+	if params.TLSSKipVerify {
+		clientTLSConfig.InsecureSkipVerify = true
+	}
+
+	if foundClientCert {
+		clientTLSConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &clientCert, nil
+		}
+	}
+
+	return nil
+}
+
+// auth logs in using the given auth method path and sets the returned token
+// on the client.
+func auth(vc *vapi.Client, path string, body map[string]interface{}) (*vapi.Secret, error) {
+	vc.ClearToken()
+	secret, err := vc.Logical().Write(path, body)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication failed %v: %v", path, err)
+	}
+	if secret == nil || secret.Auth == nil {
+		return nil, status.Errorf(codes.Internal, "authentication response from %v is empty", path)
+	}
+
+	tokenID, err := secret.TokenID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "authentication is successful, but could not get token: %v", err)
+	}
+	vc.SetToken(tokenID)
+	return secret, nil
+}
+
+// lookupSelf sets the token on the client and looks up its properties, which
+// are needed to determine how the token is renewed.
+func lookupSelf(vc *vapi.Client, token string) (*vapi.Secret, error) {
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is empty")
+	}
+	vc.SetToken(token)
+
+	secret, err := vc.Logical().Read("auth/token/lookup-self")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "token lookup failed: %v", err)
+	}
+	if secret == nil {
+		return nil, status.Error(codes.Internal, "lookup self response is nil")
+	}
+
+	id, err := secret.TokenID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to get TokenID: %v", err)
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to determine if token is renewable: %v", err)
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to get token ttl: %v", err)
+	}
+	secret.Auth = &vapi.SecretAuth{
+		ClientToken:   id,
+		Renewable:     renewable,
+		LeaseDuration: int(ttl.Seconds()),
+		// don't care any parameters
+	}
+	return secret, nil
+}
@@ -0,0 +1,196 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	vapi "github.com/hashicorp/vault/api"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	testRootCert          = "testdata/keys/EC/root_cert.pem"
+	testInvalidRootCert   = "testdata/keys/EC/invalid_root_cert.pem"
+	testClientCert        = "testdata/keys/EC/client_cert.pem"
+	testClientKey         = "testdata/keys/EC/client_key.pem"
+	testInvalidClientCert = "testdata/keys/EC/invalid_client_cert.pem"
+	testInvalidClientKey  = "testdata/keys/EC/invalid_client_key.pem"
+)
+
+func TestConfigureTLSWithCertAuth(t *testing.T) {
+	cp := &ClientParams{
+		VaultAddr:      "http://example.org:8200",
+		ClientCertPath: testClientCert,
+		ClientKeyPath:  testClientKey,
+		CACertPath:     testRootCert,
+	}
+	vc := vapi.DefaultConfig()
+	err := ConfigureTLS(vc, cp)
+	require.NoError(t, err)
+
+	tcc := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
+	cert, err := tcc.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+
+	testCert, err := testClientCertificatePair()
+	require.NoError(t, err)
+	require.Equal(t, testCert.Certificate, cert.Certificate)
+
+	testPool, err := testRootCAs()
+	require.NoError(t, err)
+	require.Equal(t, testPool.Subjects(), tcc.RootCAs.Subjects())
+}
+
+func TestConfigureTLSWithTokenAuth(t *testing.T) {
+	cp := &ClientParams{
+		VaultAddr:  "http://example.org:8200",
+		CACertPath: testRootCert,
+		Token:      "test-token",
+	}
+	vc := vapi.DefaultConfig()
+	err := ConfigureTLS(vc, cp)
+	require.NoError(t, err)
+
+	tcc := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
+	require.Nil(t, tcc.GetClientCertificate)
+
+	testPool, err := testRootCAs()
+	require.NoError(t, err)
+	require.Equal(t, testPool.Subjects(), tcc.RootCAs.Subjects())
+}
+
+func TestConfigureTLSWithAppRoleAuth(t *testing.T) {
+	cp := &ClientParams{
+		VaultAddr:       "http://example.org:8200",
+		CACertPath:      testRootCert,
+		AppRoleID:       "test-approle-id",
+		AppRoleSecretID: "test-approle-secret",
+	}
+	vc := vapi.DefaultConfig()
+	err := ConfigureTLS(vc, cp)
+	require.NoError(t, err)
+
+	tcc := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
+	require.Nil(t, tcc.GetClientCertificate)
+
+	testPool, err := testRootCAs()
+	require.NoError(t, err)
+	require.Equal(t, testPool.Subjects(), tcc.RootCAs.Subjects())
+}
+
+func TestConfigureTLSInvalidCACert(t *testing.T) {
+	cp := &ClientParams{
+		VaultAddr:      "http://example.org:8200",
+		ClientCertPath: testClientCert,
+		ClientKeyPath:  testClientKey,
+		CACertPath:     testInvalidRootCert,
+	}
+	vc := vapi.DefaultConfig()
+	err := ConfigureTLS(vc, cp)
+	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.InvalidArgument, "failed to load CA certificate: no PEM blocks")
+}
+
+func TestConfigureTLSInvalidClientKey(t *testing.T) {
+	cp := &ClientParams{
+		VaultAddr:      "http://example.org:8200",
+		ClientCertPath: testClientCert,
+		ClientKeyPath:  testInvalidClientKey,
+		CACertPath:     testRootCert,
+	}
+	vc := vapi.DefaultConfig()
+	err := ConfigureTLS(vc, cp)
+	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.InvalidArgument, "failed to parse client cert and private-key: tls: failed to find any PEM data in key input")
+}
+
+func TestConfigureTLSInvalidClientCert(t *testing.T) {
+	cp := &ClientParams{
+		VaultAddr:      "http://example.org:8200",
+		ClientCertPath: testInvalidClientCert,
+		ClientKeyPath:  testClientKey,
+		CACertPath:     testRootCert,
+	}
+	vc := vapi.DefaultConfig()
+	err := ConfigureTLS(vc, cp)
+	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.InvalidArgument, fmt.Sprintf("failed to parse client cert and private-key: open testdata/keys/EC/invalid_client_cert.pem: %s", spiretest.FileNotFound()))
+}
+
+func TestConfigureTLSRequireClientCertAndKey(t *testing.T) {
+	cp := &ClientParams{
+		VaultAddr:      "http://example.org:8200",
+		ClientCertPath: testClientCert,
+		CACertPath:     testRootCert,
+	}
+	vc := vapi.DefaultConfig()
+	err := ConfigureTLS(vc, cp)
+	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, "both client cert and client key are required")
+}
+
+func TestNewAuthenticatedClientStopsRenewal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"id": "test-token", "ttl": 3600, "renewable": true}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	renewCh := make(chan struct{})
+	_, err := NewAuthenticatedClient(ctx, &ClientParams{
+		VaultAddr: srv.URL,
+		Token:     "test-token",
+	}, TOKEN, renewCh, hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	select {
+	case <-renewCh:
+		require.FailNow(t, "renewal stopped before the context was done")
+	default:
+	}
+
+	cancel()
+	select {
+	case <-renewCh:
+	case <-time.After(time.Minute):
+		require.FailNow(t, "renewal did not stop after the context was done")
+	}
+}
+
+func testClientCertificatePair() (tls.Certificate, error) {
+	cert, err := os.ReadFile(testClientCert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	key, err := os.ReadFile(testClientKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(cert, key)
+}
+
+func testRootCAs() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	pem, err := os.ReadFile(testRootCert)
+	if err != nil {
+		return nil, err
+	}
+	ok := pool.AppendCertsFromPEM(pem)
+	if !ok {
+		return nil, err
+	}
+	return pool, nil
+}
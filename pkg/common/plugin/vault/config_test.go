@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestParseAuthMethod(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		config       *Config
+		expectMethod AuthMethod
+		expectCode   codes.Code
+		expectMsg    string
+	}{
+		{
+			name:         "token",
+			config:       &Config{TokenAuth: &TokenAuthConfig{}},
+			expectMethod: TOKEN,
+		},
+		{
+			name:         "cert",
+			config:       &Config{CertAuth: &CertAuthConfig{}},
+			expectMethod: CERT,
+		},
+		{
+			name:         "approle",
+			config:       &Config{AppRoleAuth: &AppRoleAuthConfig{}},
+			expectMethod: APPROLE,
+		},
+		{
+			name:         "k8s",
+			config:       &Config{K8sAuth: &K8sAuthConfig{}},
+			expectMethod: K8S,
+		},
+		{
+			name:       "none",
+			config:     &Config{},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "must be configured one of these authentication method 'Token, Client Certificate, AppRole or Kubernetes",
+		},
+		{
+			name:       "multiple",
+			config:     &Config{TokenAuth: &TokenAuthConfig{}, K8sAuth: &K8sAuthConfig{}},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "only one authentication method can be configured",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			method, err := ParseAuthMethod(tt.config)
+			spiretest.RequireGRPCStatus(t, err, tt.expectCode, tt.expectMsg)
+			require.Equal(t, tt.expectMethod, method)
+		})
+	}
+}
+
+func TestGenClientParams(t *testing.T) {
+	env := map[string]string{
+		EnvVaultAddr:  "https://env.example.org",
+		EnvVaultToken: "env-token",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	cp, err := GenClientParams(TOKEN, &Config{
+		VaultAddr:  "https://vault.example.org",
+		CACertPath: "ca.pem",
+		TokenAuth:  &TokenAuthConfig{Token: "test-token"},
+	}, lookupEnv)
+	require.NoError(t, err)
+	require.Equal(t, &ClientParams{
+		VaultAddr:  "https://env.example.org",
+		CACertPath: "ca.pem",
+		Token:      "env-token",
+	}, cp)
+
+	cp, err = GenClientParams(APPROLE, &Config{
+		AppRoleAuth: &AppRoleAuthConfig{RoleID: "role-id", SecretID: "secret-id"},
+	}, lookupEnv)
+	require.NoError(t, err)
+	require.Equal(t, &ClientParams{
+		VaultAddr:             "https://env.example.org",
+		AppRoleAuthMountPoint: DefaultAppRoleMountPoint,
+		AppRoleID:             "role-id",
+		AppRoleSecretID:       "secret-id",
+	}, cp)
+}
@@ -1,6 +1,8 @@
 package vault
 
 import (
+	"context"
+
 	"github.com/hashicorp/go-hclog"
 	vapi "github.com/hashicorp/vault/api"
 	"google.golang.org/grpc/codes"
@@ -30,12 +32,16 @@ func NewRenew(client *vapi.Client, secret *vapi.Secret, logger hclog.Logger) (*R
 	}, nil
 }
 
-func (r *Renew) Run() {
+// Run renews the token until it can no longer be renewed or ctx is done.
+func (r *Renew) Run(ctx context.Context) {
 	go r.watcher.Start()
 	defer r.watcher.Stop()
 
 	for {
 		select {
+		case <-ctx.Done():
+			r.logger.Debug("Stopped renewing auth token")
+			return
 		case err := <-r.watcher.DoneCh():
 			if err != nil {
 				r.logger.Error("Failed to renew auth token", "err", err)
@@ -37,6 +37,35 @@ func SetConnectionTotalGauge(m telemetry.Metrics, connections int32) {
 	m.SetGauge([]string{telemetry.WorkloadAPI, telemetry.Connections}, float32(connections))
 }
 
+// IncrAttestationCacheHitCounter indicates a workload attestation was
+// served from the attestation cache
+func IncrAttestationCacheHitCounter(m telemetry.Metrics) {
+	m.IncrCounter([]string{telemetry.WorkloadAPI, telemetry.WorkloadAttestation, telemetry.Cache, telemetry.Hit}, 1)
+}
+
+// IncrAttestationCacheMissCounter indicates a workload attestation could not
+// be served from the attestation cache
+func IncrAttestationCacheMissCounter(m telemetry.Metrics) {
+	m.IncrCounter([]string{telemetry.WorkloadAPI, telemetry.WorkloadAttestation, telemetry.Cache, telemetry.Miss}, 1)
+}
+
+// IncrAttestationCacheEvictionCounter indicates an entry was evicted from
+// the attestation cache for the given reason
+func IncrAttestationCacheEvictionCounter(m telemetry.Metrics, reason string) {
+	m.IncrCounterWithLabels([]string{telemetry.WorkloadAPI, telemetry.WorkloadAttestation, telemetry.Cache, telemetry.Evict}, 1, []telemetry.Label{
+		{
+			Name:  telemetry.Reason,
+			Value: reason,
+		},
+	})
+}
+
+// SetAttestationCacheSizeGauge sets the number of entries in the attestation
+// cache
+func SetAttestationCacheSizeGauge(m telemetry.Metrics, size int) {
+	m.SetGauge([]string{telemetry.WorkloadAPI, telemetry.WorkloadAttestation, telemetry.Cache, telemetry.Size}, float32(size))
+}
+
 // End Counters
 
 // Add Samples (metric on count of some object, entries, event...)
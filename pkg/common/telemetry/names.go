@@ -34,6 +34,10 @@ const (
 	// to add clarity
 	Delete = "delete"
 
+	// Evict functionality related to evicting some entity (such as a cache entry);
+	// should be used with other tags to add clarity
+	Evict = "evict"
+
 	// Fetch functionality related to fetching some entity; should be used with other tags
 	// to add clarity
 	Fetch = "fetch"
@@ -83,6 +87,10 @@ const (
 	// to add clarity
 	Push = "push"
 
+	// Reattest functionality related to re-attesting an agent instead of
+	// renewing its SVID; should be used with other tags to add clarity
+	Reattest = "reattest"
+
 	// Reload functionality related to reloading of a cache
 	Reload = "reload"
 
@@ -117,6 +125,10 @@ const (
 	// with other tags to add clarity
 	Update = "update"
 
+	// Use functionality related to using some entity, such as a join token;
+	// should be used with other tags to add clarity
+	Use = "use"
+
 	// Mint functionality related to minting identities
 	Mint = "mint"
 )
@@ -133,6 +145,9 @@ const (
 	// Agent SPIFFE ID
 	AgentID = "agent_id"
 
+	// AgentIDTemplate tags a template used to build agent IDs
+	AgentIDTemplate = "agent_id_template"
+
 	// Attempt tags some count of attempts
 	Attempt = "attempt"
 
@@ -265,9 +280,16 @@ const (
 	// Generation represents an objection generation (i.e. version)
 	Generation = "generation"
 
+	// Hit tags a lookup that was served from a cache; should be used with
+	// other tags to add clarity
+	Hit = "hit"
+
 	// IDType tags some type of ID (eg. registration ID, SPIFFE ID...)
 	IDType = "id_type"
 
+	// ImageRef tags a container image reference, most likely for use in attestation
+	ImageRef = "image_ref"
+
 	// IssuedAt tags an issuance timestamp
 	IssuedAt = "issued_at"
 
@@ -290,6 +312,13 @@ const (
 	// Kid tags some key ID
 	Kid = "kid"
 
+	// Miss tags a lookup that could not be served from a cache; should be used
+	// with other tags to add clarity
+	Miss = "miss"
+
+	// MaxUses tags how many times some entity, such as a join token, can be used
+	MaxUses = "max_uses"
+
 	// Mode tags a bundle deletion mode
 	Mode = "mode"
 
@@ -410,6 +439,9 @@ const (
 	// SerialNumber tags a certificate serial number
 	SerialNumber = "serial_num"
 
+	// Size tags the number of elements of some collection, such as a cache
+	Size = "size"
+
 	// Slot X509 CA Slot ID
 	Slot = "slot"
 
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"github.com/spiffe/spire/pkg/common/telemetry"
+)
+
+// Counters (literal increments, not call counters)
+
+// IncrAgentReattestCounter indicate the number of agents that re-attested
+// to obtain a new SVID instead of renewing it
+func IncrAgentReattestCounter(m telemetry.Metrics, nodeAttestorType string) {
+	m.IncrCounterWithLabels([]string{
+		telemetry.AgentSVID,
+		telemetry.Reattest,
+	}, 1, []telemetry.Label{
+		{Name: telemetry.NodeAttestorType, Value: nodeAttestorType},
+	})
+}
+
+// End Counters
@@ -25,10 +25,22 @@ func StartFetchJoinTokenCall(m telemetry.Metrics) *telemetry.CallCounter {
 	return telemetry.StartCall(m, telemetry.Datastore, telemetry.JoinToken, telemetry.Fetch)
 }
 
+// StartListJoinTokensCall return metric
+// for server's datastore, on listing join tokens.
+func StartListJoinTokensCall(m telemetry.Metrics) *telemetry.CallCounter {
+	return telemetry.StartCall(m, telemetry.Datastore, telemetry.JoinToken, telemetry.List)
+}
+
 // StartPruneJoinTokenCall return metric
 // for server's datastore, on pruning join tokens.
 func StartPruneJoinTokenCall(m telemetry.Metrics) *telemetry.CallCounter {
 	return telemetry.StartCall(m, telemetry.Datastore, telemetry.JoinToken, telemetry.Prune)
 }
 
+// StartUseJoinTokenCall return metric
+// for server's datastore, on using a join token.
+func StartUseJoinTokenCall(m telemetry.Metrics) *telemetry.CallCounter {
+	return telemetry.StartCall(m, telemetry.Datastore, telemetry.JoinToken, telemetry.Use)
+}
+
 // End Call Counters
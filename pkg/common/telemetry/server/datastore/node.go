@@ -61,4 +61,10 @@ func StartUpdateNodeCall(m telemetry.Metrics) *telemetry.CallCounter {
 	return telemetry.StartCall(m, telemetry.Datastore, telemetry.Node, telemetry.Update)
 }
 
+// StartUpdateNodeAndSelectorsCall return metric
+// for server's datastore, on updating a node and its selectors.
+func StartUpdateNodeAndSelectorsCall(m telemetry.Metrics) *telemetry.CallCounter {
+	return telemetry.StartCall(m, telemetry.Datastore, telemetry.Node, telemetry.Selectors, telemetry.Update)
+}
+
 // End Call Counters
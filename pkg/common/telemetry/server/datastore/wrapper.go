@@ -185,6 +185,12 @@ func (w metricsWrapper) PruneBundle(ctx context.Context, trustDomainID string, e
 	return w.ds.PruneBundle(ctx, trustDomainID, expiresBefore)
 }
 
+func (w metricsWrapper) ListJoinTokens(ctx context.Context) (_ []*datastore.JoinToken, err error) {
+	callCounter := StartListJoinTokensCall(w.m)
+	defer callCounter.Done(&err)
+	return w.ds.ListJoinTokens(ctx)
+}
+
 func (w metricsWrapper) PruneJoinTokens(ctx context.Context, expiresBefore time.Time) (err error) {
 	callCounter := StartPruneJoinTokenCall(w.m)
 	defer callCounter.Done(&err)
@@ -215,6 +221,12 @@ func (w metricsWrapper) UpdateAttestedNode(ctx context.Context, node *common.Att
 	return w.ds.UpdateAttestedNode(ctx, node, mask)
 }
 
+func (w metricsWrapper) UpdateAttestedNodeAndSelectors(ctx context.Context, node *common.AttestedNode, mask *common.AttestedNodeMask, selectors []*common.Selector) (_ *common.AttestedNode, err error) {
+	callCounter := StartUpdateNodeAndSelectorsCall(w.m)
+	defer callCounter.Done(&err)
+	return w.ds.UpdateAttestedNodeAndSelectors(ctx, node, mask, selectors)
+}
+
 func (w metricsWrapper) UpdateBundle(ctx context.Context, bundle *common.Bundle, mask *common.BundleMask) (_ *common.Bundle, err error) {
 	callCounter := StartUpdateBundleCall(w.m)
 	defer callCounter.Done(&err)
@@ -232,3 +244,9 @@ func (w metricsWrapper) UpdateFederationRelationship(ctx context.Context, fr *da
 	defer callCounter.Done(&err)
 	return w.ds.UpdateFederationRelationship(ctx, fr, mask)
 }
+
+func (w metricsWrapper) UseJoinToken(ctx context.Context, token string) (_ *datastore.JoinToken, err error) {
+	callCounter := StartUseJoinTokenCall(w.m)
+	defer callCounter.Done(&err)
+	return w.ds.UseJoinToken(ctx, token)
+}
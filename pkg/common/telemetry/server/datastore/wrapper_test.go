@@ -133,6 +133,10 @@ func TestWithMetrics(t *testing.T) {
 			key:        "datastore.node.selectors.list",
 			methodName: "ListNodeSelectors",
 		},
+		{
+			key:        "datastore.join_token.list",
+			methodName: "ListJoinTokens",
+		},
 		{
 			key:        "datastore.registration_entry.list",
 			methodName: "ListRegistrationEntries",
@@ -165,6 +169,10 @@ func TestWithMetrics(t *testing.T) {
 			key:        "datastore.node.update",
 			methodName: "UpdateAttestedNode",
 		},
+		{
+			key:        "datastore.node.selectors.update",
+			methodName: "UpdateAttestedNodeAndSelectors",
+		},
 		{
 			key:        "datastore.bundle.update",
 			methodName: "UpdateBundle",
@@ -177,6 +185,10 @@ func TestWithMetrics(t *testing.T) {
 			key:        "datastore.registration_entry.update",
 			methodName: "UpdateRegistrationEntry",
 		},
+		{
+			key:        "datastore.join_token.use",
+			methodName: "UseJoinToken",
+		},
 	} {
 		tt := tt
 		methodType, ok := wt.MethodByName(tt.methodName)
@@ -353,6 +365,10 @@ func (ds *fakeDataStore) ListBundles(context.Context, *datastore.ListBundlesRequ
 	return &datastore.ListBundlesResponse{}, ds.err
 }
 
+func (ds *fakeDataStore) ListJoinTokens(context.Context) ([]*datastore.JoinToken, error) {
+	return []*datastore.JoinToken{}, ds.err
+}
+
 func (ds *fakeDataStore) ListNodeSelectors(context.Context, *datastore.ListNodeSelectorsRequest) (*datastore.ListNodeSelectorsResponse, error) {
 	return &datastore.ListNodeSelectorsResponse{}, ds.err
 }
@@ -385,6 +401,10 @@ func (ds *fakeDataStore) UpdateAttestedNode(context.Context, *common.AttestedNod
 	return &common.AttestedNode{}, ds.err
 }
 
+func (ds *fakeDataStore) UpdateAttestedNodeAndSelectors(context.Context, *common.AttestedNode, *common.AttestedNodeMask, []*common.Selector) (*common.AttestedNode, error) {
+	return &common.AttestedNode{}, ds.err
+}
+
 func (ds *fakeDataStore) UpdateBundle(context.Context, *common.Bundle, *common.BundleMask) (*common.Bundle, error) {
 	return &common.Bundle{}, ds.err
 }
@@ -396,3 +416,7 @@ func (ds *fakeDataStore) UpdateRegistrationEntry(context.Context, *common.Regist
 func (ds *fakeDataStore) UpdateFederationRelationship(context.Context, *datastore.FederationRelationship, *types.FederationRelationshipMask) (*datastore.FederationRelationship, error) {
 	return &datastore.FederationRelationship{}, ds.err
 }
+
+func (ds *fakeDataStore) UseJoinToken(context.Context, string) (*datastore.JoinToken, error) {
+	return &datastore.JoinToken{}, ds.err
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"os"
+)
+
+// FileChangeDetector detects changes to a set of files, based on their
+// identity, modification time and size. Files replaced through a rename are
+// detected even when the modification time and size are unchanged. It is used
+// to reload configuration files without restarting.
+type FileChangeDetector struct {
+	paths []string
+	infos []os.FileInfo
+}
+
+// NewFileChangeDetector returns a detector for the given files. The state of
+// the files at the time of the call is used as the baseline, so it should be
+// created before the files are loaded for the first time.
+func NewFileChangeDetector(paths ...string) *FileChangeDetector {
+	d := &FileChangeDetector{
+		paths: paths,
+	}
+	d.infos = d.currentInfos()
+	return d
+}
+
+// Changed reports whether any of the files changed since the last call, or
+// since the detector was created. Files that are removed or that cannot be
+// read are reported as changed.
+func (d *FileChangeDetector) Changed() bool {
+	infos := d.currentInfos()
+	changed := false
+	for i := range infos {
+		if fileChanged(d.infos[i], infos[i]) {
+			changed = true
+			break
+		}
+	}
+	d.infos = infos
+	return changed
+}
+
+func (d *FileChangeDetector) currentInfos() []os.FileInfo {
+	infos := make([]os.FileInfo, len(d.paths))
+	for i, path := range d.paths {
+		if info, err := os.Stat(path); err == nil {
+			infos[i] = info
+		}
+	}
+	return infos
+}
+
+func fileChanged(prev, curr os.FileInfo) bool {
+	switch {
+	case prev == nil && curr == nil:
+		return false
+	case prev == nil || curr == nil:
+		return true
+	}
+	return !os.SameFile(prev, curr) ||
+		!prev.ModTime().Equal(curr.ModTime()) ||
+		prev.Size() != curr.Size()
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileChangeDetector(t *testing.T) {
+	dir := t.TempDir()
+	pathA := filepath.Join(dir, "a")
+	pathB := filepath.Join(dir, "b")
+	require.NoError(t, os.WriteFile(pathA, []byte("a"), 0600))
+	require.NoError(t, os.WriteFile(pathB, []byte("b"), 0600))
+
+	d := NewFileChangeDetector(pathA, pathB)
+	require.False(t, d.Changed())
+
+	// Size change
+	require.NoError(t, os.WriteFile(pathB, []byte("bb"), 0600))
+	require.True(t, d.Changed())
+	require.False(t, d.Changed())
+
+	// Modification time change
+	require.NoError(t, os.Chtimes(pathA, time.Now(), time.Now().Add(time.Hour)))
+	require.True(t, d.Changed())
+	require.False(t, d.Changed())
+
+	// Replacement through a rename, with the same size and modification time
+	info, err := os.Stat(pathA)
+	require.NoError(t, err)
+	tmpPath := filepath.Join(dir, "tmp")
+	require.NoError(t, os.WriteFile(tmpPath, []byte("A"), 0600))
+	require.NoError(t, os.Chtimes(tmpPath, info.ModTime(), info.ModTime()))
+	require.NoError(t, os.Rename(tmpPath, pathA))
+	require.True(t, d.Changed())
+	require.False(t, d.Changed())
+
+	// Removal
+	require.NoError(t, os.Remove(pathA))
+	require.True(t, d.Changed())
+	require.False(t, d.Changed())
+
+	// Creation
+	require.NoError(t, os.WriteFile(pathA, []byte("a"), 0600))
+	require.True(t, d.Changed())
+	require.False(t, d.Changed())
+}
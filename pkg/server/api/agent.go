@@ -23,7 +23,7 @@ func ProtoFromAttestedNode(n *common.AttestedNode) (*types.Agent, error) {
 		Id:                   ProtoFromID(spiffeID),
 		X509SvidExpiresAt:    n.CertNotAfter,
 		X509SvidSerialNumber: n.CertSerialNumber,
-		Banned:               n.CertSerialNumber == "",
+		Banned:               n.Banned,
 		Selectors:            ProtoFromSelectors(n.Selectors),
 	}, nil
 }
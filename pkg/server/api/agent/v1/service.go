@@ -17,9 +17,11 @@ import (
 	"github.com/spiffe/spire/pkg/common/idutil"
 	"github.com/spiffe/spire/pkg/common/nodeutil"
 	"github.com/spiffe/spire/pkg/common/telemetry"
+	telemetry_server "github.com/spiffe/spire/pkg/common/telemetry/server"
 	"github.com/spiffe/spire/pkg/common/x509util"
 	"github.com/spiffe/spire/pkg/server/api"
 	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
 	"github.com/spiffe/spire/pkg/server/ca"
 	"github.com/spiffe/spire/pkg/server/catalog"
 	"github.com/spiffe/spire/pkg/server/datastore"
@@ -40,6 +42,15 @@ type Config struct {
 	ServerCA    ca.ServerCA
 	AgentTTL    time.Duration
 	TrustDomain spiffeid.TrustDomain
+	Metrics     telemetry.Metrics
+
+	// ReattestableNodeAttestors are the node attestor types that agents are
+	// allowed to re-attest with
+	ReattestableNodeAttestors []string
+
+	// AttestationPolicyEngine, if set, decides whether agents that passed
+	// node attestation are admitted
+	AttestationPolicyEngine *attestpolicy.Engine
 }
 
 // Service implements the v1 agent service
@@ -52,17 +63,34 @@ type Service struct {
 	ca       ca.ServerCA
 	td       spiffeid.TrustDomain
 	agentTTL time.Duration
+	metrics  telemetry.Metrics
+
+	reattestable map[string]bool
+	policyEngine *attestpolicy.Engine
 }
 
 // New creates a new agent service
 func New(config Config) *Service {
+	reattestable := make(map[string]bool, len(config.ReattestableNodeAttestors))
+	for _, nodeAttestorType := range config.ReattestableNodeAttestors {
+		reattestable[nodeAttestorType] = true
+	}
+
+	metrics := config.Metrics
+	if metrics == nil {
+		metrics = telemetry.Blackhole{}
+	}
+
 	return &Service{
-		cat:      config.Catalog,
-		clk:      config.Clock,
-		ds:       config.DataStore,
-		ca:       config.ServerCA,
-		td:       config.TrustDomain,
-		agentTTL: config.AgentTTL,
+		cat:          config.Catalog,
+		clk:          config.Clock,
+		ds:           config.DataStore,
+		ca:           config.ServerCA,
+		td:           config.TrustDomain,
+		agentTTL:     config.AgentTTL,
+		metrics:      metrics,
+		reattestable: reattestable,
+		policyEngine: config.AttestationPolicyEngine,
 	}
 }
 
@@ -224,12 +252,13 @@ func (s *Service) BanAgent(ctx context.Context, req *agentv1.BanAgentRequest) (*
 
 	log = log.WithField(telemetry.SPIFFEID, id.String())
 
-	// The agent "Banned" state is pointed out by setting its
-	// serial numbers (current and new) to empty strings.
-	banned := &common.AttestedNode{SpiffeId: id.String()}
+	// Banning an agent also clears its serial numbers (current and new) so
+	// the SVIDs it holds are no longer accepted, even if it is unbanned.
+	banned := &common.AttestedNode{SpiffeId: id.String(), Banned: true}
 	mask := &common.AttestedNodeMask{
 		CertSerialNumber:    true,
 		NewCertSerialNumber: true,
+		Banned:              true,
 	}
 	_, err = s.ds.UpdateAttestedNode(ctx, banned, mask)
 
@@ -270,6 +299,14 @@ func (s *Service) AttestAgent(stream agentv1.Agent_AttestAgentServer) error {
 
 	log = log.WithField(telemetry.NodeAttestorType, params.Data.Type)
 
+	// agents that authenticate with their current SVID are re-attesting in
+	// order to obtain a new one
+	callerID, reattesting := rpccontext.CallerID(ctx)
+	reattesting = reattesting && idutil.IsAgentPath(callerID.Path())
+	if reattesting && !s.reattestable[params.Data.Type] {
+		return api.MakeErr(log, codes.FailedPrecondition, fmt.Sprintf("re-attestation is not allowed for node attestor %q", params.Data.Type), nil)
+	}
+
 	// attest
 	var attestResult *nodeattestor.AttestResult
 	if params.Data.Type == "join_token" {
@@ -307,10 +344,13 @@ func (s *Service) AttestAgent(stream agentv1.Agent_AttestAgentServer) error {
 		return api.MakeErr(log, codes.PermissionDenied, "failed to attest: agent is banned", nil)
 	}
 
-	// parse and sign CSR
-	svid, err := s.signSvid(ctx, agentSpiffeID, params.Params.Csr, log)
-	if err != nil {
-		return err
+	if reattesting {
+		if agentSpiffeID != callerID {
+			return api.MakeErr(log, codes.PermissionDenied, "failed to re-attest: attested agent ID does not match caller", nil)
+		}
+		if attestedNode != nil && attestedNode.AttestationDataType != params.Data.Type {
+			return api.MakeErr(log, codes.FailedPrecondition, fmt.Sprintf("failed to re-attest: agent was attested using node attestor %q", attestedNode.AttestationDataType), nil)
+		}
 	}
 
 	// augment selectors with resolver
@@ -318,14 +358,25 @@ func (s *Service) AttestAgent(stream agentv1.Agent_AttestAgentServer) error {
 	if err != nil {
 		return api.MakeErr(log, codes.Internal, "failed to resolve selectors", err)
 	}
-	// store augmented selectors
-	err = s.ds.SetNodeSelectors(ctx, agentID, append(attestResult.Selectors, resolvedSelectors...))
+	selectors := append(attestResult.Selectors, resolvedSelectors...)
+
+	// admit the agent according to the attestation policy
+	if err := s.admitAgent(ctx, log, params.Data.Type, agentID, selectors); err != nil {
+		return err
+	}
+
+	// parse and sign CSR
+	svid, err := s.signSvid(ctx, agentSpiffeID, params.Params.Csr, log)
 	if err != nil {
-		return api.MakeErr(log, codes.Internal, "failed to update selectors", err)
+		return err
 	}
 
-	// create or update attested entry
+	// create or update attested entry along with the augmented selectors
 	if attestedNode == nil {
+		if err := s.ds.SetNodeSelectors(ctx, agentID, selectors); err != nil {
+			return api.MakeErr(log, codes.Internal, "failed to update selectors", err)
+		}
+
 		node := &common.AttestedNode{
 			AttestationDataType: params.Data.Type,
 			SpiffeId:            agentID,
@@ -341,11 +392,17 @@ func (s *Service) AttestAgent(stream agentv1.Agent_AttestAgentServer) error {
 			CertNotAfter:     svid[0].NotAfter.Unix(),
 			CertSerialNumber: svid[0].SerialNumber.String(),
 		}
-		if _, err := s.ds.UpdateAttestedNode(ctx, node, nil); err != nil {
+		// selectors and serial numbers are updated in a single transaction so
+		// a re-attested agent never ends up with stale selectors
+		if _, err := s.ds.UpdateAttestedNodeAndSelectors(ctx, node, nil, selectors); err != nil {
 			return api.MakeErr(log, codes.Internal, "failed to update attested agent", err)
 		}
 	}
 
+	if reattesting {
+		telemetry_server.IncrAgentReattestCounter(s.metrics, params.Data.Type)
+	}
+
 	// build and send response
 	response := getAttestAgentResponse(agentSpiffeID, svid)
 
@@ -540,25 +597,26 @@ func (s *Service) getSelectorsFromAgentID(ctx context.Context, agentID string) (
 func (s *Service) attestJoinToken(ctx context.Context, token string) (*nodeattestor.AttestResult, error) {
 	log := rpccontext.Logger(ctx).WithField(telemetry.NodeAttestorType, "join_token")
 
-	joinToken, err := s.ds.FetchJoinToken(ctx, token)
+	// Using the token counts the attempt against its maximum uses, even if
+	// the token turns out to be expired
+	joinToken, err := s.ds.UseJoinToken(ctx, token)
 	switch {
 	case err != nil:
-		return nil, api.MakeErr(log, codes.Internal, "failed to fetch join token", err)
+		return nil, api.MakeErr(log, codes.Internal, "failed to use join token", err)
 	case joinToken == nil:
 		return nil, api.MakeErr(log, codes.InvalidArgument, "failed to attest: join token does not exist or has already been used", nil)
-	}
-
-	err = s.ds.DeleteJoinToken(ctx, token)
-	switch {
-	case err != nil:
-		return nil, api.MakeErr(log, codes.Internal, "failed to delete join token", err)
 	case joinToken.Expiry.Before(s.clk.Now()):
 		return nil, api.MakeErr(log, codes.InvalidArgument, "join token expired", nil)
 	}
 
-	tokenPath := path.Join("spire", "agent", "join_token", token)
+	agentID, err := api.JoinTokenAgentID(s.td, joinToken, joinToken.Uses)
+	if err != nil {
+		return nil, api.MakeErr(log, codes.Internal, "failed to build agent ID from join token", err)
+	}
+
 	return &nodeattestor.AttestResult{
-		AgentID: s.td.NewID(tokenPath).String(),
+		AgentID:   agentID.String(),
+		Selectors: joinToken.Selectors,
 	}, nil
 }
 
@@ -595,6 +653,31 @@ func (s *Service) attestChallengeResponse(ctx context.Context, agentStream agent
 	return result, nil
 }
 
+// admitAgent evaluates the attestation policy, if any, for an attested agent.
+// Denials are logged along with the selectors the decision was based on.
+func (s *Service) admitAgent(ctx context.Context, log logrus.FieldLogger, attestationType, agentID string, selectors []*common.Selector) error {
+	if s.policyEngine == nil {
+		return nil
+	}
+
+	result, err := s.policyEngine.Eval(ctx, attestpolicy.NewInput(attestationType, agentID, selectors))
+	if err != nil {
+		return api.MakeErr(log, codes.Internal, "failed to evaluate attestation policy", err)
+	}
+	if result.Allow {
+		return nil
+	}
+
+	fields := logrus.Fields{
+		telemetry.Selectors: api.SelectorFieldFromProto(api.ProtoFromSelectors(selectors)),
+	}
+	if result.Reason != "" {
+		fields[telemetry.Reason] = result.Reason
+	}
+	rpccontext.AddRPCAuditFields(ctx, fields)
+	return api.MakeErr(log.WithFields(fields), codes.PermissionDenied, "failed to attest: denied by attestation policy", nil)
+}
+
 func (s *Service) resolveSelectors(ctx context.Context, agentID string, attestationType string) ([]*common.Selector, error) {
 	if nodeResolver, ok := s.cat.GetNodeResolverNamed(attestationType); ok {
 		return nodeResolver.Resolve(ctx, agentID)
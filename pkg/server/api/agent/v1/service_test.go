@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
@@ -21,10 +22,12 @@ import (
 	"github.com/spiffe/spire/pkg/server/api/agent/v1"
 	"github.com/spiffe/spire/pkg/server/api/middleware"
 	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
 	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/spiffe/spire/test/fakes/fakemetrics"
 	"github.com/spiffe/spire/test/fakes/fakenoderesolver"
 	"github.com/spiffe/spire/test/fakes/fakeserverca"
 	"github.com/spiffe/spire/test/fakes/fakeservercatalog"
@@ -62,6 +65,7 @@ var (
 			SpiffeId:            agent2,
 			AttestationDataType: "type-2",
 			CertNotAfter:        3,
+			Banned:              true,
 		},
 	}
 
@@ -300,6 +304,7 @@ func TestListAgents(t *testing.T) {
 		CertNotAfter:        notAfter,
 		NewCertNotAfter:     newNoAfter,
 		NewCertSerialNumber: "",
+		Banned:              true,
 	}
 	_, err = test.ds.CreateAttestedNode(ctx, node3)
 	require.NoError(t, err)
@@ -1134,6 +1139,7 @@ func TestBanAgent(t *testing.T) {
 				require.NotNil(t, attestedNode)
 				require.NotZero(t, attestedNode.CertSerialNumber)
 				require.NotZero(t, attestedNode.NewCertSerialNumber)
+				require.False(t, attestedNode.Banned)
 				return
 			}
 
@@ -1146,6 +1152,7 @@ func TestBanAgent(t *testing.T) {
 
 			node.CertSerialNumber = ""
 			node.NewCertSerialNumber = ""
+			node.Banned = true
 			spiretest.RequireProtoEqual(t, node, attestedNode)
 		})
 	}
@@ -2399,6 +2406,64 @@ func TestAttestAgent(t *testing.T) {
 			},
 		},
 
+		{
+			name:       "attest with multi-use join token",
+			retry:      true,
+			request:    getAttestAgentRequest("join_token", []byte("multi_use_token"), testCsr),
+			expectedID: td.NewID("/spire/agent/join_token/multi_use_token/2"),
+			expectedSelectors: []*common.Selector{
+				{Type: "join_token", Value: "group:a"},
+			},
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "Agent attestation request completed",
+					Data: logrus.Fields{
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/join_token/multi_use_token/2",
+						telemetry.NodeAttestorType: "join_token",
+						telemetry.Address:          "",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:           "success",
+						telemetry.Type:             "audit",
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/join_token/multi_use_token/2",
+						telemetry.NodeAttestorType: "join_token",
+					},
+				},
+			},
+		},
+
+		{
+			name:       "attest with join token with agent ID template",
+			request:    getAttestAgentRequest("join_token", []byte("templated_token"), testCsr),
+			expectedID: td.NewID("/spire/agent/group-a/templated_token"),
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "Agent attestation request completed",
+					Data: logrus.Fields{
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/group-a/templated_token",
+						telemetry.NodeAttestorType: "join_token",
+						telemetry.Address:          "",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:           "success",
+						telemetry.Type:             "audit",
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/group-a/templated_token",
+						telemetry.NodeAttestorType: "join_token",
+					},
+				},
+			},
+		},
+
 		{
 			name:       "attest with join token is banned",
 			request:    getAttestAgentRequest("join_token", []byte("banned_token"), testCsr),
@@ -2636,6 +2701,36 @@ func TestAttestAgent(t *testing.T) {
 			},
 		},
 
+		{
+			name:       "attest unbanned",
+			request:    getAttestAgentRequest("test_type", []byte("payload_unbanned"), testCsr),
+			expectedID: td.NewID("/spire/agent/test_type/id_unbanned"),
+			expectedSelectors: []*common.Selector{
+				{Type: "test_type", Value: "unbanned"},
+			},
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "Agent attestation request completed",
+					Data: logrus.Fields{
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/test_type/id_unbanned",
+						telemetry.NodeAttestorType: "test_type",
+						telemetry.Address:          "",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:           "success",
+						telemetry.Type:             "audit",
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/test_type/id_unbanned",
+						telemetry.NodeAttestorType: "test_type",
+					},
+				},
+			},
+		},
+
 		{
 			name:       "attest with bad attestor",
 			request:    getAttestAgentRequest("bad_type", []byte("payload_with_result"), testCsr),
@@ -2695,49 +2790,17 @@ func TestAttestAgent(t *testing.T) {
 		},
 
 		{
-			name:       "ds: fails to fetch join token",
-			request:    getAttestAgentRequest("join_token", []byte("test_token"), testCsr),
-			expectCode: codes.Internal,
-			expectMsg:  "failed to fetch join token",
-			dsError: []error{
-				errors.New("some error"),
-			},
-			expectLogs: []spiretest.LogEntry{
-				{
-					Level:   logrus.ErrorLevel,
-					Message: "Failed to fetch join token",
-					Data: logrus.Fields{
-						telemetry.NodeAttestorType: "join_token",
-						logrus.ErrorKey:            "some error",
-					},
-				},
-				{
-					Level:   logrus.InfoLevel,
-					Message: "API accessed",
-					Data: logrus.Fields{
-						telemetry.Status:           "error",
-						telemetry.Type:             "audit",
-						telemetry.StatusCode:       "Internal",
-						telemetry.StatusMessage:    "failed to fetch join token: some error",
-						telemetry.NodeAttestorType: "join_token",
-					},
-				},
-			},
-		},
-
-		{
-			name:       "ds: fails to delete join token",
+			name:       "ds: fails to use join token",
 			request:    getAttestAgentRequest("join_token", []byte("test_token"), testCsr),
 			expectCode: codes.Internal,
-			expectMsg:  "failed to delete join token",
+			expectMsg:  "failed to use join token",
 			dsError: []error{
-				nil,
 				errors.New("some error"),
 			},
 			expectLogs: []spiretest.LogEntry{
 				{
 					Level:   logrus.ErrorLevel,
-					Message: "Failed to delete join token",
+					Message: "Failed to use join token",
 					Data: logrus.Fields{
 						telemetry.NodeAttestorType: "join_token",
 						logrus.ErrorKey:            "some error",
@@ -2750,7 +2813,7 @@ func TestAttestAgent(t *testing.T) {
 						telemetry.Status:           "error",
 						telemetry.Type:             "audit",
 						telemetry.StatusCode:       "Internal",
-						telemetry.StatusMessage:    "failed to delete join token: some error",
+						telemetry.StatusMessage:    "failed to use join token: some error",
 						telemetry.NodeAttestorType: "join_token",
 					},
 				},
@@ -2763,7 +2826,6 @@ func TestAttestAgent(t *testing.T) {
 			expectCode: codes.Internal,
 			expectMsg:  "failed to fetch agent",
 			dsError: []error{
-				nil,
 				nil,
 				errors.New("some error"),
 			},
@@ -2798,7 +2860,6 @@ func TestAttestAgent(t *testing.T) {
 			expectCode: codes.Internal,
 			expectMsg:  "failed to update selectors",
 			dsError: []error{
-				nil,
 				nil,
 				nil,
 				errors.New("some error"),
@@ -2838,7 +2899,6 @@ func TestAttestAgent(t *testing.T) {
 				nil,
 				nil,
 				nil,
-				nil,
 				errors.New("some error"),
 			},
 			expectLogs: []spiretest.LogEntry{
@@ -2872,7 +2932,6 @@ func TestAttestAgent(t *testing.T) {
 			expectCode: codes.Internal,
 			expectMsg:  "failed to update attested agent",
 			dsError: []error{
-				nil,
 				nil,
 				errors.New("some error"),
 			},
@@ -2964,6 +3023,229 @@ func TestAttestAgent(t *testing.T) {
 	}
 }
 
+func TestAttestAgentReattestation(t *testing.T) {
+	testCsr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, testkey.MustEC256())
+	require.NoError(t, err)
+
+	attestedBeforeID := td.NewID("/spire/agent/test_type/id_attested_before")
+
+	for _, tt := range []struct {
+		name                      string
+		reattestableNodeAttestors []string
+		callerID                  spiffeid.ID
+		request                   *agentv1.AttestAgentRequest
+		expectCode                codes.Code
+		expectMsg                 string
+		expectMetrics             []fakemetrics.MetricItem
+	}{
+		{
+			name:                      "success",
+			reattestableNodeAttestors: []string{"test_type"},
+			callerID:                  attestedBeforeID,
+			request:                   getAttestAgentRequest("test_type", []byte("payload_attested_before"), testCsr),
+			expectMetrics: []fakemetrics.MetricItem{
+				{
+					Type:   fakemetrics.IncrCounterWithLabelsType,
+					Key:    []string{telemetry.AgentSVID, telemetry.Reattest},
+					Val:    1,
+					Labels: []telemetry.Label{{Name: telemetry.NodeAttestorType, Value: "test_type"}},
+				},
+			},
+		},
+		{
+			name:       "node attestor not reattestable",
+			callerID:   attestedBeforeID,
+			request:    getAttestAgentRequest("test_type", []byte("payload_attested_before"), testCsr),
+			expectCode: codes.FailedPrecondition,
+			expectMsg:  `re-attestation is not allowed for node attestor "test_type"`,
+		},
+		{
+			name:                      "attested agent ID does not match caller",
+			reattestableNodeAttestors: []string{"test_type"},
+			callerID:                  attestedBeforeID,
+			request:                   getAttestAgentRequest("test_type", []byte("payload_with_result"), testCsr),
+			expectCode:                codes.PermissionDenied,
+			expectMsg:                 "failed to re-attest: attested agent ID does not match caller",
+		},
+		{
+			name:                      "caller is not an agent",
+			reattestableNodeAttestors: nil,
+			callerID:                  td.NewID("/workload"),
+			request:                   getAttestAgentRequest("test_type", []byte("payload_attested_before"), testCsr),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test := setupServiceTestWithConfig(t, agent.Config{
+				ReattestableNodeAttestors: tt.reattestableNodeAttestors,
+			})
+			defer test.Cleanup()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			test.setupAttestor(t)
+			test.setupNodes(ctx, t)
+			test.rateLimiter.count = 1
+			test.callerID = tt.callerID
+
+			stream, err := test.client.AttestAgent(ctx)
+			require.NoError(t, err)
+			result, err := attest(t, stream, tt.request)
+			require.NoError(t, stream.CloseSend())
+
+			spiretest.RequireGRPCStatusContains(t, err, tt.expectCode, tt.expectMsg)
+			if tt.expectCode != codes.OK {
+				require.Nil(t, result)
+				// the attested node must be left untouched
+				attestedNode, err := test.ds.FetchAttestedNode(ctx, attestedBeforeID.String())
+				require.NoError(t, err)
+				require.Equal(t, "test_serial_number", attestedNode.CertSerialNumber)
+				require.Empty(t, test.metrics.AllMetrics())
+				return
+			}
+
+			require.NotNil(t, result)
+			test.assertAttestAgentResult(t, attestedBeforeID, result)
+			test.assertAgentWasStored(t, attestedBeforeID.String(), []*common.Selector{
+				{Type: "test_type", Value: "attested_before"},
+			})
+			require.ElementsMatch(t, tt.expectMetrics, test.metrics.AllMetrics())
+		})
+	}
+}
+
+func TestAttestAgentPolicy(t *testing.T) {
+	testCsr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, testkey.MustEC256())
+	require.NoError(t, err)
+
+	// only admit agents with the "result" selector
+	policyEngine, err := attestpolicy.NewEngineFromRego(ctx, `
+package spire.attestation
+
+default allow = false
+
+allow {
+	input.attestation_type == "test_type"
+	input.selectors[_] == {"type": "test_type", "value": "result"}
+}
+
+result = {
+	"allow": allow,
+	"reason": "missing result selector",
+}
+`, inmem.NewFromObject(map[string]interface{}{}))
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name       string
+		request    *agentv1.AttestAgentRequest
+		expectedID spiffeid.ID
+		expectCode codes.Code
+		expectMsg  string
+		expectLogs []spiretest.LogEntry
+	}{
+		{
+			name:       "admitted",
+			request:    getAttestAgentRequest("test_type", []byte("payload_with_result"), testCsr),
+			expectedID: td.NewID("/spire/agent/test_type/id_with_result"),
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "Agent attestation request completed",
+					Data: logrus.Fields{
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/test_type/id_with_result",
+						telemetry.NodeAttestorType: "test_type",
+						telemetry.Address:          "",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:           "success",
+						telemetry.Type:             "audit",
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/test_type/id_with_result",
+						telemetry.NodeAttestorType: "test_type",
+					},
+				},
+			},
+		},
+		{
+			name:       "denied",
+			request:    getAttestAgentRequest("test_type", []byte("payload_with_challenge"), testCsr),
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "failed to attest: denied by attestation policy",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Failed to attest: denied by attestation policy",
+					Data: logrus.Fields{
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/test_type/id_with_challenge",
+						telemetry.NodeAttestorType: "test_type",
+						telemetry.Selectors:        "test_type:challenge,test_type:resolved_too",
+						telemetry.Reason:           "missing result selector",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:           "error",
+						telemetry.Type:             "audit",
+						telemetry.StatusCode:       "PermissionDenied",
+						telemetry.StatusMessage:    "failed to attest: denied by attestation policy",
+						telemetry.AgentID:          "spiffe://example.org/spire/agent/test_type/id_with_challenge",
+						telemetry.NodeAttestorType: "test_type",
+						telemetry.Selectors:        "test_type:challenge,test_type:resolved_too",
+						telemetry.Reason:           "missing result selector",
+					},
+				},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test := setupServiceTestWithConfig(t, agent.Config{
+				AttestationPolicyEngine: policyEngine,
+			})
+			defer test.Cleanup()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			test.setupAttestor(t)
+			test.setupResolver(t)
+			test.rateLimiter.count = 1
+
+			stream, err := test.client.AttestAgent(ctx)
+			require.NoError(t, err)
+			result, err := attest(t, stream, tt.request)
+			require.NoError(t, stream.CloseSend())
+
+			spiretest.RequireGRPCStatusContains(t, err, tt.expectCode, tt.expectMsg)
+			// Clean address on logs
+			for _, e := range test.logHook.AllEntries() {
+				if _, ok := e.Data[telemetry.Address]; ok {
+					e.Data[telemetry.Address] = ""
+				}
+			}
+			spiretest.AssertLogs(t, test.logHook.AllEntries(), tt.expectLogs)
+			if tt.expectCode != codes.OK {
+				require.Nil(t, result)
+				// denied agents are not stored
+				attestedNode, err := test.ds.FetchAttestedNode(ctx, td.NewID("/spire/agent/test_type/id_with_challenge").String())
+				require.NoError(t, err)
+				require.Nil(t, attestedNode)
+				return
+			}
+
+			require.NotNil(t, result)
+			test.assertAttestAgentResult(t, tt.expectedID, result)
+		})
+	}
+}
+
 type serviceTest struct {
 	client       agentv1.AgentClient
 	done         func()
@@ -2972,8 +3254,10 @@ type serviceTest struct {
 	cat          *fakeservercatalog.Catalog
 	clk          clock.Clock
 	logHook      *test.Hook
+	metrics      *fakemetrics.FakeMetrics
 	rateLimiter  *fakeRateLimiter
 	withCallerID bool
+	callerID     spiffeid.ID
 	pluginCloser func()
 }
 
@@ -2985,19 +3269,23 @@ func (s *serviceTest) Cleanup() {
 }
 
 func setupServiceTest(t *testing.T, agentTTL time.Duration) *serviceTest {
+	return setupServiceTestWithConfig(t, agent.Config{AgentTTL: agentTTL})
+}
+
+func setupServiceTestWithConfig(t *testing.T, config agent.Config) *serviceTest {
 	ca := fakeserverca.New(t, td, &fakeserverca.Options{})
 	ds := fakedatastore.New(t)
 	cat := fakeservercatalog.New()
 	clk := clock.NewMock(t)
+	metrics := fakemetrics.New()
 
-	service := agent.New(agent.Config{
-		ServerCA:    ca,
-		DataStore:   ds,
-		TrustDomain: td,
-		Clock:       clk,
-		Catalog:     cat,
-		AgentTTL:    agentTTL,
-	})
+	config.ServerCA = ca
+	config.DataStore = ds
+	config.TrustDomain = td
+	config.Clock = clk
+	config.Catalog = cat
+	config.Metrics = metrics
+	service := agent.New(config)
 
 	log, logHook := test.NewNullLogger()
 	log.Level = logrus.DebugLevel
@@ -3013,6 +3301,7 @@ func setupServiceTest(t *testing.T, agentTTL time.Duration) *serviceTest {
 		cat:         cat,
 		clk:         clk,
 		logHook:     logHook,
+		metrics:     metrics,
 		rateLimiter: rateLimiter,
 	}
 
@@ -3022,6 +3311,9 @@ func setupServiceTest(t *testing.T, agentTTL time.Duration) *serviceTest {
 		if test.withCallerID {
 			ctx = rpccontext.WithCallerID(ctx, agentID)
 		}
+		if !test.callerID.IsZero() {
+			ctx = rpccontext.WithCallerID(ctx, test.callerID)
+		}
 		return ctx, nil
 	})
 	unaryInterceptor, streamInterceptor := middleware.Interceptors(middleware.Chain(
@@ -3048,12 +3340,14 @@ func (s *serviceTest) setupAttestor(t *testing.T) {
 			"payload_with_challenge":  "id_with_challenge",
 			"payload_with_result":     "id_with_result",
 			"payload_banned":          "id_banned",
+			"payload_unbanned":        "id_unbanned",
 		},
 		Selectors: map[string][]string{
 			"id_with_result":     {"result"},
 			"id_attested_before": {"attested_before"},
 			"id_with_challenge":  {"challenge"},
 			"id_banned":          {"banned"},
+			"id_unbanned":        {"unbanned"},
 		},
 	}
 
@@ -3087,6 +3381,16 @@ func (s *serviceTest) setupNodes(ctx context.Context, t *testing.T) {
 		SpiffeId:            td.NewID("/spire/agent/test_type/id_banned").String(),
 		CertNotAfter:        0,
 		CertSerialNumber:    "",
+		Banned:              true,
+	}
+	_, err = s.ds.CreateAttestedNode(ctx, node)
+	require.NoError(t, err)
+
+	node = &common.AttestedNode{
+		AttestationDataType: "test_type",
+		SpiffeId:            td.NewID("/spire/agent/test_type/id_unbanned").String(),
+		CertNotAfter:        0,
+		CertSerialNumber:    "",
 	}
 	_, err = s.ds.CreateAttestedNode(ctx, node)
 	require.NoError(t, err)
@@ -3096,6 +3400,7 @@ func (s *serviceTest) setupNodes(ctx context.Context, t *testing.T) {
 		SpiffeId:            td.NewID("/spire/agent/join_token/banned_token").String(),
 		CertNotAfter:        0,
 		CertSerialNumber:    "",
+		Banned:              true,
 	}
 	_, err = s.ds.CreateAttestedNode(ctx, node)
 	require.NoError(t, err)
@@ -3115,6 +3420,21 @@ func (s *serviceTest) setupJoinTokens(ctx context.Context, t *testing.T) {
 	})
 	require.NoError(t, err)
 
+	err = s.ds.CreateJoinToken(ctx, &datastore.JoinToken{
+		Token:     "multi_use_token",
+		Expiry:    now.Add(time.Second * 600),
+		MaxUses:   2,
+		Selectors: []*common.Selector{{Type: "join_token", Value: "group:a"}},
+	})
+	require.NoError(t, err)
+
+	err = s.ds.CreateJoinToken(ctx, &datastore.JoinToken{
+		Token:           "templated_token",
+		Expiry:          now.Add(time.Second * 600),
+		AgentIDTemplate: "group-a/{{ .Token }}",
+	})
+	require.NoError(t, err)
+
 	err = s.ds.CreateJoinToken(ctx, &datastore.JoinToken{
 		Token:  "expired_token",
 		Expiry: now.Add(-time.Second * 600),
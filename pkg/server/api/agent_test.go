@@ -49,6 +49,7 @@ func TestProtoFromAttestedNode(t *testing.T) {
 			name: "banned",
 			n: &common.AttestedNode{
 				SpiffeId: "spiffe://example.org/node",
+				Banned:   true,
 			},
 			expectAgent: &types.Agent{
 				Id:     &types.SPIFFEID{TrustDomain: "example.org", Path: "/node"},
@@ -0,0 +1,79 @@
+package agentban
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/server/api"
+	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	"github.com/spiffe/spire/pkg/server/datastore"
+	agentbanv1 "github.com/spiffe/spire/proto/private/server/agentban/v1"
+	"github.com/spiffe/spire/proto/spire/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Config is the service configuration
+type Config struct {
+	DataStore   datastore.DataStore
+	TrustDomain spiffeid.TrustDomain
+}
+
+// Service implements the v1 agent ban service
+type Service struct {
+	agentbanv1.UnsafeAgentBanServer
+
+	ds datastore.DataStore
+	td spiffeid.TrustDomain
+}
+
+// New creates a new agent ban service
+func New(config Config) *Service {
+	return &Service{
+		ds: config.DataStore,
+		td: config.TrustDomain,
+	}
+}
+
+// RegisterService registers the agent ban service on the gRPC server.
+func RegisterService(s *grpc.Server, service *Service) {
+	agentbanv1.RegisterAgentBanServer(s, service)
+}
+
+// UnbanAgent lifts the ban of the given agent. The serial numbers cleared when
+// the agent was banned are not restored, so the agent has to attest again.
+func (s *Service) UnbanAgent(ctx context.Context, req *agentbanv1.UnbanAgentRequest) (*emptypb.Empty, error) {
+	log := rpccontext.Logger(ctx)
+
+	id, err := api.TrustDomainAgentIDFromProto(s.td, req.Id)
+	if err != nil {
+		return nil, api.MakeErr(log, codes.InvalidArgument, "invalid agent ID", err)
+	}
+	rpccontext.AddRPCAuditFields(ctx, logrus.Fields{telemetry.SPIFFEID: id.String()})
+
+	log = log.WithField(telemetry.SPIFFEID, id.String())
+
+	attestedNode, err := s.ds.FetchAttestedNode(ctx, id.String())
+	switch {
+	case err != nil:
+		return nil, api.MakeErr(log, codes.Internal, "failed to fetch agent", err)
+	case attestedNode == nil:
+		return nil, api.MakeErr(log, codes.NotFound, "agent not found", nil)
+	case !attestedNode.Banned:
+		return nil, api.MakeErr(log, codes.FailedPrecondition, "agent is not banned", nil)
+	}
+
+	unbanned := &common.AttestedNode{SpiffeId: id.String(), Banned: false}
+	mask := &common.AttestedNodeMask{Banned: true}
+	if _, err := s.ds.UpdateAttestedNode(ctx, unbanned, mask); err != nil {
+		return nil, api.MakeErr(log, codes.Internal, "failed to unban agent", err)
+	}
+
+	log.Info("Agent unbanned")
+	rpccontext.AuditRPC(ctx)
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,267 @@
+package agentban_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/server/api/agentban/v1"
+	"github.com/spiffe/spire/pkg/server/api/middleware"
+	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	agentbanv1 "github.com/spiffe/spire/proto/private/server/agentban/v1"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	ctx = context.Background()
+	td  = spiffeid.RequireTrustDomainFromString("example.org")
+)
+
+func TestUnbanAgent(t *testing.T) {
+	bannedID := "spiffe://example.org/spire/agent/banned"
+	activeID := "spiffe://example.org/spire/agent/active"
+
+	for _, tt := range []struct {
+		name       string
+		reqID      *types.SPIFFEID
+		dsError    error
+		expectCode codes.Code
+		expectMsg  string
+		expectLogs []spiretest.LogEntry
+	}{
+		{
+			name:  "success",
+			reqID: &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/banned"},
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "Agent unbanned",
+					Data: logrus.Fields{
+						telemetry.SPIFFEID: bannedID,
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:   "success",
+						telemetry.Type:     "audit",
+						telemetry.SPIFFEID: bannedID,
+					},
+				},
+			},
+		},
+		{
+			name:       "invalid agent ID",
+			reqID:      &types.SPIFFEID{TrustDomain: "example.org", Path: "/workload"},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `invalid agent ID: "spiffe://example.org/workload" is not an agent in trust domain "example.org"; path is not in the agent namespace`,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Invalid argument: invalid agent ID",
+					Data: logrus.Fields{
+						logrus.ErrorKey: `"spiffe://example.org/workload" is not an agent in trust domain "example.org"; path is not in the agent namespace`,
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "InvalidArgument",
+						telemetry.StatusMessage: `invalid agent ID: "spiffe://example.org/workload" is not an agent in trust domain "example.org"; path is not in the agent namespace`,
+					},
+				},
+			},
+		},
+		{
+			name:       "agent not found",
+			reqID:      &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/unknown"},
+			expectCode: codes.NotFound,
+			expectMsg:  "agent not found",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Agent not found",
+					Data: logrus.Fields{
+						telemetry.SPIFFEID: "spiffe://example.org/spire/agent/unknown",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "NotFound",
+						telemetry.StatusMessage: "agent not found",
+						telemetry.SPIFFEID:      "spiffe://example.org/spire/agent/unknown",
+					},
+				},
+			},
+		},
+		{
+			name:       "agent not banned",
+			reqID:      &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/active"},
+			expectCode: codes.FailedPrecondition,
+			expectMsg:  "agent is not banned",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Agent is not banned",
+					Data: logrus.Fields{
+						telemetry.SPIFFEID: activeID,
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "FailedPrecondition",
+						telemetry.StatusMessage: "agent is not banned",
+						telemetry.SPIFFEID:      activeID,
+					},
+				},
+			},
+		},
+		{
+			name:       "datastore failure",
+			reqID:      &types.SPIFFEID{TrustDomain: "example.org", Path: "/spire/agent/banned"},
+			dsError:    errors.New("oh no"),
+			expectCode: codes.Internal,
+			expectMsg:  "failed to fetch agent: oh no",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Failed to fetch agent",
+					Data: logrus.Fields{
+						logrus.ErrorKey:    "oh no",
+						telemetry.SPIFFEID: bannedID,
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "Internal",
+						telemetry.StatusMessage: "failed to fetch agent: oh no",
+						telemetry.SPIFFEID:      bannedID,
+					},
+				},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test := setupServiceTest(t)
+			defer test.Cleanup()
+
+			_, err := test.ds.CreateAttestedNode(ctx, &common.AttestedNode{
+				SpiffeId:            bannedID,
+				AttestationDataType: "test",
+				CertNotAfter:        100,
+				Banned:              true,
+			})
+			require.NoError(t, err)
+			_, err = test.ds.CreateAttestedNode(ctx, &common.AttestedNode{
+				SpiffeId:            activeID,
+				AttestationDataType: "test",
+				CertSerialNumber:    "1",
+				CertNotAfter:        100,
+			})
+			require.NoError(t, err)
+
+			test.ds.SetNextError(tt.dsError)
+
+			resp, err := test.client.UnbanAgent(ctx, &agentbanv1.UnbanAgentRequest{Id: tt.reqID})
+			spiretest.AssertLogs(t, test.logHook.AllEntries(), tt.expectLogs)
+			if tt.expectCode != codes.OK {
+				spiretest.RequireGRPCStatus(t, err, tt.expectCode, tt.expectMsg)
+				require.Nil(t, resp)
+
+				attestedNode, err := test.ds.FetchAttestedNode(ctx, bannedID)
+				require.NoError(t, err)
+				require.True(t, attestedNode.Banned)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, resp)
+
+			attestedNode, err := test.ds.FetchAttestedNode(ctx, bannedID)
+			require.NoError(t, err)
+			spiretest.RequireProtoEqual(t, &common.AttestedNode{
+				SpiffeId:            bannedID,
+				AttestationDataType: "test",
+				CertNotAfter:        100,
+			}, attestedNode)
+		})
+	}
+}
+
+type serviceTest struct {
+	client  agentbanv1.AgentBanClient
+	ds      *fakedatastore.DataStore
+	logHook *test.Hook
+	done    func()
+}
+
+func (s *serviceTest) Cleanup() {
+	s.done()
+}
+
+func setupServiceTest(t *testing.T) *serviceTest {
+	ds := fakedatastore.New(t)
+	service := agentban.New(agentban.Config{
+		DataStore:   ds,
+		TrustDomain: td,
+	})
+
+	log, logHook := test.NewNullLogger()
+	log.Level = logrus.DebugLevel
+	registerFn := func(s *grpc.Server) {
+		agentban.RegisterService(s, service)
+	}
+
+	test := &serviceTest{
+		ds:      ds,
+		logHook: logHook,
+	}
+
+	ppMiddleware := middleware.Preprocess(func(ctx context.Context, fullMethod string, req interface{}) (context.Context, error) {
+		ctx = rpccontext.WithLogger(ctx, log)
+		return ctx, nil
+	})
+
+	unaryInterceptor, streamInterceptor := middleware.Interceptors(middleware.Chain(
+		ppMiddleware,
+		// Add audit log with uds tracking disabled
+		middleware.WithAuditLog(false),
+	))
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.StreamInterceptor(streamInterceptor),
+	)
+
+	conn, done := spiretest.NewAPIServerWithMiddleware(t, registerFn, server)
+	test.done = done
+	test.client = agentbanv1.NewAgentBanClient(conn)
+
+	return test
+}
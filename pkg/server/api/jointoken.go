@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"text/template"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/server/datastore"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
+)
+
+const (
+	// DefaultJoinTokenAgentIDTemplate is the agent ID path template used
+	// for single-use join tokens. It is relative to /spire/agent.
+	DefaultJoinTokenAgentIDTemplate = "join_token/{{ .Token }}"
+
+	// DefaultMultiUseJoinTokenAgentIDTemplate is the agent ID path template
+	// used for join tokens that can be used more than once. It is relative
+	// to /spire/agent.
+	DefaultMultiUseJoinTokenAgentIDTemplate = "join_token/{{ .Token }}/{{ .Use }}"
+)
+
+// joinTokenAgentIDTemplateData is used to hydrate join token agent ID
+// templates.
+type joinTokenAgentIDTemplateData struct {
+	// Token is the join token value
+	Token string
+
+	// Use is the one-based count of the use of the join token
+	Use int32
+}
+
+// JoinTokenAgentID returns the ID of the agent that attests with the given
+// use of a join token. The use is one-based.
+func JoinTokenAgentID(td spiffeid.TrustDomain, joinToken *datastore.JoinToken, use int32) (spiffeid.ID, error) {
+	text := joinToken.AgentIDTemplate
+	if text == "" {
+		text = DefaultJoinTokenAgentIDTemplate
+		if joinToken.MaxUses > 1 {
+			text = DefaultMultiUseJoinTokenAgentIDTemplate
+		}
+	}
+
+	tmpl, err := template.New("agent-id").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return spiffeid.ID{}, fmt.Errorf("invalid agent ID template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, joinTokenAgentIDTemplateData{
+		Token: joinToken.Token,
+		Use:   use,
+	}); err != nil {
+		return spiffeid.ID{}, fmt.Errorf("invalid agent ID template: %w", err)
+	}
+
+	// The rendered path must not be able to escape /spire/agent, so it
+	// has to be relative and already clean.
+	agentPath := buf.String()
+	switch {
+	case agentPath == "":
+		return spiffeid.ID{}, errors.New("agent ID template rendered an empty path")
+	case path.Join("/spire/agent", agentPath) != "/spire/agent/"+agentPath:
+		return spiffeid.ID{}, fmt.Errorf("agent ID template rendered an invalid path %q", agentPath)
+	}
+
+	return td.NewID(path.Join("spire", "agent", agentPath)), nil
+}
+
+// ProtoFromJoinToken converts a join token from the datastore to its private
+// API representation
+func ProtoFromJoinToken(joinToken *datastore.JoinToken) *jointokenv1.Token {
+	return &jointokenv1.Token{
+		Value:           joinToken.Token,
+		ExpiresAt:       joinToken.Expiry.Unix(),
+		MaxUses:         joinToken.MaxUses,
+		Uses:            joinToken.Uses,
+		Selectors:       ProtoFromSelectors(joinToken.Selectors),
+		AgentIdTemplate: joinToken.AgentIDTemplate,
+	}
+}
@@ -0,0 +1,191 @@
+package jointoken
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/idutil"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/server/api"
+	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	"github.com/spiffe/spire/pkg/server/datastore"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
+	"github.com/spiffe/spire/proto/spire/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Config is the service configuration
+type Config struct {
+	Clock       clock.Clock
+	DataStore   datastore.DataStore
+	TrustDomain spiffeid.TrustDomain
+}
+
+// Service implements the v1 join token service
+type Service struct {
+	jointokenv1.UnsafeJoinTokenServer
+
+	clk clock.Clock
+	ds  datastore.DataStore
+	td  spiffeid.TrustDomain
+}
+
+// New creates a new join token service
+func New(config Config) *Service {
+	return &Service{
+		clk: config.Clock,
+		ds:  config.DataStore,
+		td:  config.TrustDomain,
+	}
+}
+
+// RegisterService registers the join token service on the gRPC server.
+func RegisterService(s *grpc.Server, service *Service) {
+	jointokenv1.RegisterJoinTokenServer(s, service)
+}
+
+// CreateJoinToken creates a join token that can be used by one or more agents
+// to attest.
+func (s *Service) CreateJoinToken(ctx context.Context, req *jointokenv1.CreateJoinTokenRequest) (*jointokenv1.Token, error) {
+	log := rpccontext.Logger(ctx)
+	rpccontext.AddRPCAuditFields(ctx, fieldsFromCreateJoinTokenRequest(req))
+
+	switch {
+	case req.Ttl < 1:
+		return nil, api.MakeErr(log, codes.InvalidArgument, "ttl is required, you must provide one", nil)
+	case req.MaxUses < 0:
+		return nil, api.MakeErr(log, codes.InvalidArgument, "max uses cannot be negative", nil)
+	case req.MaxUses > 1 && req.AgentId != nil:
+		return nil, api.MakeErr(log, codes.InvalidArgument, "agent ID is only supported by single-use tokens", nil)
+	}
+
+	maxUses := req.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+
+	selectors, err := api.SelectorsFromProto(req.Selectors)
+	if err != nil {
+		return nil, api.MakeErr(log, codes.InvalidArgument, "invalid selectors", err)
+	}
+
+	// If provided, check that the AgentID is valid BEFORE creating the join token so we can fail early
+	var agentID spiffeid.ID
+	if req.AgentId != nil {
+		agentID, err = api.TrustDomainWorkloadIDFromProto(s.td, req.AgentId)
+		if err != nil {
+			return nil, api.MakeErr(log, codes.InvalidArgument, "invalid agent ID", err)
+		}
+		if err := idutil.CheckIDProtoNormalization(req.AgentId); err != nil {
+			return nil, api.MakeErr(log, codes.InvalidArgument, "agent ID is malformed", err)
+		}
+	}
+
+	// Generate a token if one wasn't specified
+	if req.Token == "" {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return nil, api.MakeErr(log, codes.Internal, "failed to generate token UUID", err)
+		}
+		req.Token = u.String()
+	}
+
+	joinToken := &datastore.JoinToken{
+		Token:           req.Token,
+		Expiry:          s.clk.Now().Add(time.Second * time.Duration(req.Ttl)),
+		MaxUses:         maxUses,
+		Selectors:       selectors,
+		AgentIDTemplate: req.AgentIdTemplate,
+	}
+
+	// Render the template for the first use so malformed templates are
+	// rejected now instead of when agents attest
+	firstAgentID, err := api.JoinTokenAgentID(s.td, joinToken, 1)
+	if err != nil {
+		return nil, api.MakeErr(log, codes.InvalidArgument, "invalid agent ID template", err)
+	}
+
+	// Every use of a multi-use token must produce a distinct agent ID,
+	// otherwise agents attesting with the token would share an identity
+	if maxUses > 1 {
+		secondAgentID, err := api.JoinTokenAgentID(s.td, joinToken, 2)
+		if err != nil {
+			return nil, api.MakeErr(log, codes.InvalidArgument, "invalid agent ID template", err)
+		}
+		if secondAgentID == firstAgentID {
+			return nil, api.MakeErr(log, codes.InvalidArgument, "invalid agent ID template", errors.New("agent ID template renders the same agent ID for every use of the token"))
+		}
+	}
+
+	if err := s.ds.CreateJoinToken(ctx, joinToken); err != nil {
+		return nil, api.MakeErr(log, codes.Internal, "failed to create token", err)
+	}
+
+	if req.AgentId != nil {
+		if err := s.createJoinTokenRegistrationEntry(ctx, firstAgentID, agentID); err != nil {
+			return nil, api.MakeErr(log, codes.Internal, "failed to create join token registration entry", err)
+		}
+	}
+	rpccontext.AuditRPC(ctx)
+
+	return api.ProtoFromJoinToken(joinToken), nil
+}
+
+// ListJoinTokens returns the join tokens that have not been used up. Expired
+// tokens are returned until they are pruned.
+func (s *Service) ListJoinTokens(ctx context.Context, req *jointokenv1.ListJoinTokensRequest) (*jointokenv1.ListJoinTokensResponse, error) {
+	log := rpccontext.Logger(ctx)
+
+	joinTokens, err := s.ds.ListJoinTokens(ctx)
+	if err != nil {
+		return nil, api.MakeErr(log, codes.Internal, "failed to list join tokens", err)
+	}
+
+	resp := &jointokenv1.ListJoinTokensResponse{}
+	for _, joinToken := range joinTokens {
+		resp.Tokens = append(resp.Tokens, api.ProtoFromJoinToken(joinToken))
+	}
+	rpccontext.AuditRPC(ctx)
+
+	return resp, nil
+}
+
+func (s *Service) createJoinTokenRegistrationEntry(ctx context.Context, parentID spiffeid.ID, agentID spiffeid.ID) error {
+	entry := &common.RegistrationEntry{
+		ParentId: parentID.String(),
+		SpiffeId: agentID.String(),
+		Selectors: []*common.Selector{
+			{Type: "spiffe_id", Value: parentID.String()},
+		},
+	}
+	_, err := s.ds.CreateRegistrationEntry(ctx, entry)
+	return err
+}
+
+func fieldsFromCreateJoinTokenRequest(req *jointokenv1.CreateJoinTokenRequest) logrus.Fields {
+	fields := logrus.Fields{}
+	if req.Ttl > 0 {
+		fields[telemetry.TTL] = req.Ttl
+	}
+	if req.MaxUses > 0 {
+		fields[telemetry.MaxUses] = req.MaxUses
+	}
+	if len(req.Selectors) > 0 {
+		fields[telemetry.Selectors] = api.SelectorFieldFromProto(req.Selectors)
+	}
+	if req.AgentIdTemplate != "" {
+		fields[telemetry.AgentIDTemplate] = req.AgentIdTemplate
+	}
+	if req.AgentId != nil {
+		if id, err := idutil.IDProtoString(req.AgentId); err == nil {
+			fields[telemetry.SPIFFEID] = id
+		}
+	}
+	return fields
+}
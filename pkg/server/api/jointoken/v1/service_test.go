@@ -0,0 +1,487 @@
+package jointoken_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/server/api/jointoken/v1"
+	"github.com/spiffe/spire/pkg/server/api/middleware"
+	"github.com/spiffe/spire/pkg/server/api/rpccontext"
+	"github.com/spiffe/spire/pkg/server/datastore"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/fakes/fakedatastore"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	ctx = context.Background()
+	td  = spiffeid.RequireTrustDomainFromString("example.org")
+)
+
+func TestCreateJoinToken(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		request      *jointokenv1.CreateJoinTokenRequest
+		dsError      error
+		expectCode   codes.Code
+		expectMsg    string
+		expectToken  *jointokenv1.Token
+		expectLogs   []spiretest.LogEntry
+		expectParent string
+	}{
+		{
+			name: "single-use token",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:   1000,
+				Token: "token",
+			},
+			expectToken: &jointokenv1.Token{
+				Value:   "token",
+				MaxUses: 1,
+			},
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status: "success",
+						telemetry.Type:   "audit",
+						telemetry.TTL:    "1000",
+					},
+				},
+			},
+		},
+		{
+			name: "multi-use token with selectors and template",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:             1000,
+				Token:           "token",
+				MaxUses:         3,
+				Selectors:       []*types.Selector{{Type: "rack", Value: "1"}, {Type: "env", Value: "prod"}},
+				AgentIdTemplate: "rack-1/{{ .Token }}/{{ .Use }}",
+			},
+			expectToken: &jointokenv1.Token{
+				Value:           "token",
+				MaxUses:         3,
+				Selectors:       []*types.Selector{{Type: "rack", Value: "1"}, {Type: "env", Value: "prod"}},
+				AgentIdTemplate: "rack-1/{{ .Token }}/{{ .Use }}",
+			},
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:          "success",
+						telemetry.Type:            "audit",
+						telemetry.TTL:             "1000",
+						telemetry.MaxUses:         "3",
+						telemetry.Selectors:       "rack:1,env:prod",
+						telemetry.AgentIDTemplate: "rack-1/{{ .Token }}/{{ .Use }}",
+					},
+				},
+			},
+		},
+		{
+			name: "single-use token with agent ID",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:     1000,
+				Token:   "token",
+				AgentId: &types.SPIFFEID{TrustDomain: "example.org", Path: "/valid"},
+			},
+			expectToken: &jointokenv1.Token{
+				Value:   "token",
+				MaxUses: 1,
+			},
+			expectParent: "spiffe://example.org/spire/agent/join_token/token",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:   "success",
+						telemetry.Type:     "audit",
+						telemetry.TTL:      "1000",
+						telemetry.SPIFFEID: "spiffe://example.org/valid",
+					},
+				},
+			},
+		},
+		{
+			name:       "missing ttl",
+			request:    &jointokenv1.CreateJoinTokenRequest{},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "ttl is required, you must provide one",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Invalid argument: ttl is required, you must provide one",
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "InvalidArgument",
+						telemetry.StatusMessage: "ttl is required, you must provide one",
+					},
+				},
+			},
+		},
+		{
+			name: "negative max uses",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:     1000,
+				MaxUses: -1,
+			},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "max uses cannot be negative",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Invalid argument: max uses cannot be negative",
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "InvalidArgument",
+						telemetry.StatusMessage: "max uses cannot be negative",
+						telemetry.TTL:           "1000",
+					},
+				},
+			},
+		},
+		{
+			name: "agent ID with multi-use token",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:     1000,
+				MaxUses: 2,
+				AgentId: &types.SPIFFEID{TrustDomain: "example.org", Path: "/valid"},
+			},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "agent ID is only supported by single-use tokens",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Invalid argument: agent ID is only supported by single-use tokens",
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "InvalidArgument",
+						telemetry.StatusMessage: "agent ID is only supported by single-use tokens",
+						telemetry.TTL:           "1000",
+						telemetry.MaxUses:       "2",
+						telemetry.SPIFFEID:      "spiffe://example.org/valid",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid selector",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:       1000,
+				Selectors: []*types.Selector{{Type: "rack"}},
+			},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "invalid selectors: missing selector value",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Invalid argument: invalid selectors",
+					Data: logrus.Fields{
+						logrus.ErrorKey: "missing selector value",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "InvalidArgument",
+						telemetry.StatusMessage: "invalid selectors: missing selector value",
+						telemetry.TTL:           "1000",
+						telemetry.Selectors:     "rack:",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid agent ID template",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:             1000,
+				Token:           "token",
+				AgentIdTemplate: "../{{ .Token }}",
+			},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `invalid agent ID template: agent ID template rendered an invalid path "../token"`,
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Invalid argument: invalid agent ID template",
+					Data: logrus.Fields{
+						logrus.ErrorKey: `agent ID template rendered an invalid path "../token"`,
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:          "error",
+						telemetry.Type:            "audit",
+						telemetry.StatusCode:      "InvalidArgument",
+						telemetry.StatusMessage:   `invalid agent ID template: agent ID template rendered an invalid path "../token"`,
+						telemetry.TTL:             "1000",
+						telemetry.AgentIDTemplate: "../{{ .Token }}",
+					},
+				},
+			},
+		},
+		{
+			name: "agent ID template shared by every use",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:             1000,
+				Token:           "token",
+				MaxUses:         3,
+				AgentIdTemplate: "lab/{{ .Token }}",
+			},
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "invalid agent ID template: agent ID template renders the same agent ID for every use of the token",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Invalid argument: invalid agent ID template",
+					Data: logrus.Fields{
+						logrus.ErrorKey: "agent ID template renders the same agent ID for every use of the token",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:          "error",
+						telemetry.Type:            "audit",
+						telemetry.StatusCode:      "InvalidArgument",
+						telemetry.StatusMessage:   "invalid agent ID template: agent ID template renders the same agent ID for every use of the token",
+						telemetry.TTL:             "1000",
+						telemetry.MaxUses:         "3",
+						telemetry.AgentIDTemplate: "lab/{{ .Token }}",
+					},
+				},
+			},
+		},
+		{
+			name: "datastore failure",
+			request: &jointokenv1.CreateJoinTokenRequest{
+				Ttl:   1000,
+				Token: "token",
+			},
+			dsError:    errors.New("oh no"),
+			expectCode: codes.Internal,
+			expectMsg:  "failed to create token: oh no",
+			expectLogs: []spiretest.LogEntry{
+				{
+					Level:   logrus.ErrorLevel,
+					Message: "Failed to create token",
+					Data: logrus.Fields{
+						logrus.ErrorKey: "oh no",
+					},
+				},
+				{
+					Level:   logrus.InfoLevel,
+					Message: "API accessed",
+					Data: logrus.Fields{
+						telemetry.Status:        "error",
+						telemetry.Type:          "audit",
+						telemetry.StatusCode:    "Internal",
+						telemetry.StatusMessage: "failed to create token: oh no",
+						telemetry.TTL:           "1000",
+					},
+				},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			test := setupServiceTest(t)
+			defer test.Cleanup()
+
+			test.ds.SetNextError(tt.dsError)
+
+			token, err := test.client.CreateJoinToken(ctx, tt.request)
+			spiretest.AssertLogs(t, test.logHook.AllEntries(), tt.expectLogs)
+			if tt.expectCode != codes.OK {
+				spiretest.RequireGRPCStatus(t, err, tt.expectCode, tt.expectMsg)
+				require.Nil(t, token)
+				return
+			}
+			require.NoError(t, err)
+
+			tt.expectToken.ExpiresAt = test.clk.Now().Add(1000 * time.Second).Unix()
+			spiretest.AssertProtoEqual(t, tt.expectToken, token)
+
+			stored, err := test.ds.FetchJoinToken(ctx, token.Value)
+			require.NoError(t, err)
+			require.NotNil(t, stored)
+			require.Equal(t, token.MaxUses, stored.MaxUses)
+			require.Equal(t, token.AgentIdTemplate, stored.AgentIDTemplate)
+
+			if tt.expectParent != "" {
+				resp, err := test.ds.ListRegistrationEntries(ctx, &datastore.ListRegistrationEntriesRequest{})
+				require.NoError(t, err)
+				require.Len(t, resp.Entries, 1)
+				require.Equal(t, tt.expectParent, resp.Entries[0].ParentId)
+				require.Equal(t, "spiffe://example.org/valid", resp.Entries[0].SpiffeId)
+			}
+		})
+	}
+}
+
+func TestCreateJoinTokenGeneratesValue(t *testing.T) {
+	test := setupServiceTest(t)
+	defer test.Cleanup()
+
+	token, err := test.client.CreateJoinToken(ctx, &jointokenv1.CreateJoinTokenRequest{Ttl: 1000})
+	require.NoError(t, err)
+	require.NotEmpty(t, token.Value)
+}
+
+func TestListJoinTokens(t *testing.T) {
+	test := setupServiceTest(t)
+	defer test.Cleanup()
+
+	resp, err := test.client.ListJoinTokens(ctx, &jointokenv1.ListJoinTokensRequest{})
+	require.NoError(t, err)
+	require.Empty(t, resp.Tokens)
+
+	expiry := test.clk.Now().Add(time.Hour).Truncate(time.Second)
+	require.NoError(t, test.ds.CreateJoinToken(ctx, &datastore.JoinToken{
+		Token:     "token1",
+		Expiry:    expiry,
+		MaxUses:   2,
+		Selectors: []*common.Selector{{Type: "rack", Value: "1"}},
+	}))
+	_, err = test.ds.UseJoinToken(ctx, "token1")
+	require.NoError(t, err)
+	require.NoError(t, test.ds.CreateJoinToken(ctx, &datastore.JoinToken{
+		Token:   "token2",
+		Expiry:  expiry,
+		MaxUses: 1,
+	}))
+
+	resp, err = test.client.ListJoinTokens(ctx, &jointokenv1.ListJoinTokensRequest{})
+	require.NoError(t, err)
+	spiretest.AssertProtoListEqual(t, []*jointokenv1.Token{
+		{
+			Value:     "token1",
+			ExpiresAt: expiry.Unix(),
+			MaxUses:   2,
+			Uses:      1,
+			Selectors: []*types.Selector{{Type: "rack", Value: "1"}},
+		},
+		{
+			Value:     "token2",
+			ExpiresAt: expiry.Unix(),
+			MaxUses:   1,
+		},
+	}, resp.Tokens)
+
+	test.logHook.Reset()
+	test.ds.SetNextError(errors.New("oh no"))
+	_, err = test.client.ListJoinTokens(ctx, &jointokenv1.ListJoinTokensRequest{})
+	spiretest.RequireGRPCStatus(t, err, codes.Internal, "failed to list join tokens: oh no")
+	spiretest.AssertLogs(t, test.logHook.AllEntries(), []spiretest.LogEntry{
+		{
+			Level:   logrus.ErrorLevel,
+			Message: "Failed to list join tokens",
+			Data: logrus.Fields{
+				logrus.ErrorKey: "oh no",
+			},
+		},
+		{
+			Level:   logrus.InfoLevel,
+			Message: "API accessed",
+			Data: logrus.Fields{
+				telemetry.Status:        "error",
+				telemetry.Type:          "audit",
+				telemetry.StatusCode:    "Internal",
+				telemetry.StatusMessage: "failed to list join tokens: oh no",
+			},
+		},
+	})
+}
+
+type serviceTest struct {
+	client  jointokenv1.JoinTokenClient
+	ds      *fakedatastore.DataStore
+	clk     *clock.Mock
+	logHook *test.Hook
+	done    func()
+}
+
+func (s *serviceTest) Cleanup() {
+	s.done()
+}
+
+func setupServiceTest(t *testing.T) *serviceTest {
+	ds := fakedatastore.New(t)
+	clk := clock.NewMock(t)
+	service := jointoken.New(jointoken.Config{
+		Clock:       clk,
+		DataStore:   ds,
+		TrustDomain: td,
+	})
+
+	log, logHook := test.NewNullLogger()
+	log.Level = logrus.DebugLevel
+	registerFn := func(s *grpc.Server) {
+		jointoken.RegisterService(s, service)
+	}
+
+	test := &serviceTest{
+		ds:      ds,
+		clk:     clk,
+		logHook: logHook,
+	}
+
+	ppMiddleware := middleware.Preprocess(func(ctx context.Context, fullMethod string, req interface{}) (context.Context, error) {
+		ctx = rpccontext.WithLogger(ctx, log)
+		return ctx, nil
+	})
+
+	unaryInterceptor, streamInterceptor := middleware.Interceptors(middleware.Chain(
+		ppMiddleware,
+		// Add audit log with uds tracking disabled
+		middleware.WithAuditLog(false),
+	))
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.StreamInterceptor(streamInterceptor),
+	)
+
+	conn, done := spiretest.NewAPIServerWithMiddleware(t, registerFn, server)
+	test.done = done
+	test.client = jointokenv1.NewJoinTokenClient(conn)
+
+	return test
+}
@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	"github.com/spiffe/spire/pkg/server/api"
+	"github.com/spiffe/spire/pkg/server/datastore"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinTokenAgentID(t *testing.T) {
+	td := spiffeid.RequireTrustDomainFromString("example.org")
+
+	for _, tt := range []struct {
+		name      string
+		token     *datastore.JoinToken
+		use       int32
+		expectID  string
+		expectErr string
+	}{
+		{
+			name:     "single-use default",
+			token:    &datastore.JoinToken{Token: "foo"},
+			use:      1,
+			expectID: "spiffe://example.org/spire/agent/join_token/foo",
+		},
+		{
+			name:     "multi-use default",
+			token:    &datastore.JoinToken{Token: "foo", MaxUses: 3},
+			use:      2,
+			expectID: "spiffe://example.org/spire/agent/join_token/foo/2",
+		},
+		{
+			name:     "template",
+			token:    &datastore.JoinToken{Token: "foo", MaxUses: 3, AgentIDTemplate: "rack-1/{{ .Token }}-{{ .Use }}"},
+			use:      3,
+			expectID: "spiffe://example.org/spire/agent/rack-1/foo-3",
+		},
+		{
+			name:      "malformed template",
+			token:     &datastore.JoinToken{Token: "foo", AgentIDTemplate: "{{ .Token "},
+			expectErr: "invalid agent ID template:",
+		},
+		{
+			name:      "unknown template field",
+			token:     &datastore.JoinToken{Token: "foo", AgentIDTemplate: "{{ .Unknown }}"},
+			expectErr: "invalid agent ID template:",
+		},
+		{
+			name:      "empty path",
+			token:     &datastore.JoinToken{Token: "foo", AgentIDTemplate: "{{ if false }}x{{ end }}"},
+			expectErr: "agent ID template rendered an empty path",
+		},
+		{
+			name:      "path escapes agent namespace",
+			token:     &datastore.JoinToken{Token: "foo", AgentIDTemplate: "../../{{ .Token }}"},
+			expectErr: `agent ID template rendered an invalid path "../../foo"`,
+		},
+		{
+			name:      "path is not clean",
+			token:     &datastore.JoinToken{Token: "foo", AgentIDTemplate: "a//{{ .Token }}/"},
+			expectErr: `agent ID template rendered an invalid path "a//foo/"`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := api.JoinTokenAgentID(td, tt.token, tt.use)
+			if tt.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectID, id.String())
+		})
+	}
+}
+
+func TestProtoFromJoinToken(t *testing.T) {
+	token := api.ProtoFromJoinToken(&datastore.JoinToken{
+		Token:           "foo",
+		Expiry:          time.Unix(1234, 0),
+		MaxUses:         3,
+		Uses:            1,
+		Selectors:       []*common.Selector{{Type: "a", Value: "1"}},
+		AgentIDTemplate: "{{ .Token }}",
+	})
+	spiretest.AssertProtoEqual(t, &jointokenv1.Token{
+		Value:           "foo",
+		ExpiresAt:       1234,
+		MaxUses:         3,
+		Uses:            1,
+		Selectors:       []*types.Selector{{Type: "a", Value: "1"}},
+		AgentIdTemplate: "{{ .Token }}",
+	}, token)
+}
@@ -0,0 +1,176 @@
+package attestpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/spiffe/spire/proto/spire/common"
+)
+
+const (
+	allowKey  = "allow"
+	reasonKey = "reason"
+)
+
+// Engine evaluates the attestation admission policy for agents that have
+// passed node attestation.
+type Engine struct {
+	rego rego.PartialResult
+}
+
+type OpaEngineConfig struct {
+	LocalOpaProvider *LocalOpaProviderConfig `hcl:"local"`
+}
+
+type LocalOpaProviderConfig struct {
+	RegoPath       string `hcl:"rego_path"`
+	PolicyDataPath string `hcl:"policy_data_path"`
+}
+
+// Selector is a selector produced while attesting the agent.
+type Selector struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Input represents the attestation being admitted.
+type Input struct {
+	// AttestationType is the type of the node attestor used by the agent.
+	AttestationType string `json:"attestation_type"`
+
+	// AgentID is the SPIFFE ID the agent attested as.
+	AgentID string `json:"agent_id"`
+
+	// Selectors are the selectors produced by the node attestor and the
+	// node resolvers.
+	Selectors []Selector `json:"selectors"`
+}
+
+type Result struct {
+	Allow bool `json:"allow"`
+
+	// Reason optionally explains why the agent was denied.
+	Reason string `json:"reason"`
+}
+
+// NewInput builds the policy input for an attested agent.
+func NewInput(attestationType, agentID string, selectors []*common.Selector) Input {
+	input := Input{
+		AttestationType: attestationType,
+		AgentID:         agentID,
+		Selectors:       make([]Selector, 0, len(selectors)),
+	}
+	for _, s := range selectors {
+		input.Selectors = append(input.Selectors, Selector{Type: s.Type, Value: s.Value})
+	}
+	return input
+}
+
+// NewEngineFromConfig returns a new policy engine, or nil if no config is
+// provided, in which case every attested agent is admitted.
+func NewEngineFromConfig(ctx context.Context, cfg *OpaEngineConfig) (*Engine, error) {
+	switch {
+	case cfg == nil:
+		return nil, nil
+	case cfg.LocalOpaProvider == nil:
+		return nil, errors.New("attestation policy engine configuration must define a provider")
+	}
+
+	module, err := os.ReadFile(cfg.LocalOpaProvider.RegoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var store storage.Store
+	// If a data file is defined use it, else provide an empty store
+	if cfg.LocalOpaProvider.PolicyDataPath != "" {
+		storefile, err := os.Open(cfg.LocalOpaProvider.PolicyDataPath)
+		if err != nil {
+			return nil, err
+		}
+		defer storefile.Close()
+
+		d := util.NewJSONDecoder(storefile)
+		var data map[string]interface{}
+		if err := d.Decode(&data); err != nil {
+			return nil, fmt.Errorf("error decoding JSON databindings: %w", err)
+		}
+		store = inmem.NewFromObject(data)
+	} else {
+		store = inmem.NewFromObject(map[string]interface{}{})
+	}
+
+	return NewEngineFromRego(ctx, string(module), store)
+}
+
+// NewEngineFromRego is a helper to create the Engine object
+func NewEngineFromRego(ctx context.Context, regoPolicy string, dataStore storage.Store) (*Engine, error) {
+	rego := rego.New(
+		rego.Query("data.spire.attestation.result"),
+		rego.Package("spire.attestation"),
+		rego.Module("attestation.rego", regoPolicy),
+		rego.Store(dataStore),
+	)
+	pr, err := rego.PartialResult(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		rego: pr,
+	}
+
+	// Evaluate the policy on a sample input to ensure it produces a
+	// well-formed result.
+	if _, err := e.Eval(ctx, sampleInput); err != nil {
+		return nil, fmt.Errorf("attestation policy is misconfigured: %w", err)
+	}
+
+	return e, nil
+}
+
+// Eval determines whether the attested agent should be admitted.
+func (e *Engine) Eval(ctx context.Context, input Input) (result Result, err error) {
+	rs, err := e.rego.Rego(rego.Input(input)).Eval(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return Result{}, errors.New("policy: no matching policies found")
+	}
+
+	exp := rs[0].Expressions[0]
+	resultMap, ok := exp.Value.(map[string]interface{})
+	if !ok {
+		return Result{}, errors.New("unexpected type in evaluating policy result expression")
+	}
+
+	allow, ok := resultMap[allowKey].(bool)
+	if !ok {
+		return Result{}, fmt.Errorf("policy: result did not contain %q bool value", allowKey)
+	}
+	result.Allow = allow
+
+	if value, ok := resultMap[reasonKey]; ok {
+		reason, ok := value.(string)
+		if !ok {
+			return Result{}, fmt.Errorf("policy: result %q value is not a string", reasonKey)
+		}
+		result.Reason = reason
+	}
+
+	return result, nil
+}
+
+var sampleInput = Input{
+	AttestationType: "join_token",
+	AgentID:         "spiffe://example.org/spire/agent/join_token/token",
+	Selectors:       []Selector{},
+}
@@ -0,0 +1,205 @@
+package attestpolicy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/stretchr/testify/require"
+)
+
+const accountsRego = `
+package spire.attestation
+
+default allow = false
+
+allow {
+	input.attestation_type != "aws_iid"
+}
+
+allow {
+	input.attestation_type == "aws_iid"
+	selector := input.selectors[_]
+	selector.type == "aws_iid"
+	selector.value == concat(":", ["account", data.accounts[_]])
+}
+
+default reason = ""
+
+reason = "account is not allowed" {
+	not allow
+}
+
+result = {
+	"allow": allow,
+	"reason": reason,
+}
+`
+
+const accountsData = `{"accounts": ["111111111111", "222222222222"]}`
+
+func TestPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	regoPath := filepath.Join(tmpDir, "attestation.rego")
+	dataPath := filepath.Join(tmpDir, "attestation_data.json")
+	require.NoError(t, os.WriteFile(regoPath, []byte(accountsRego), 0600))
+	require.NoError(t, os.WriteFile(dataPath, []byte(accountsData), 0600))
+
+	var data map[string]interface{}
+	require.NoError(t, util.UnmarshalJSON([]byte(accountsData), &data))
+
+	fromRego, err := attestpolicy.NewEngineFromRego(context.Background(), accountsRego, inmem.NewFromObject(data))
+	require.NoError(t, err)
+
+	fromConfig, err := attestpolicy.NewEngineFromConfig(context.Background(), &attestpolicy.OpaEngineConfig{
+		LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+			RegoPath:       regoPath,
+			PolicyDataPath: dataPath,
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name         string
+		input        attestpolicy.Input
+		expectResult attestpolicy.Result
+	}{
+		{
+			name:  "other attestor",
+			input: attestpolicy.NewInput("join_token", "spiffe://example.org/spire/agent/join_token/foo", nil),
+			expectResult: attestpolicy.Result{
+				Allow: true,
+			},
+		},
+		{
+			name: "allowed account",
+			input: attestpolicy.NewInput("aws_iid", "spiffe://example.org/spire/agent/aws_iid/222222222222/us-east-1/i-1", []*common.Selector{
+				{Type: "aws_iid", Value: "az:us-east-1a"},
+				{Type: "aws_iid", Value: "account:222222222222"},
+			}),
+			expectResult: attestpolicy.Result{
+				Allow: true,
+			},
+		},
+		{
+			name: "denied account",
+			input: attestpolicy.NewInput("aws_iid", "spiffe://example.org/spire/agent/aws_iid/333333333333/us-east-1/i-1", []*common.Selector{
+				{Type: "aws_iid", Value: "account:333333333333"},
+			}),
+			expectResult: attestpolicy.Result{
+				Allow:  false,
+				Reason: "account is not allowed",
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for _, engine := range []*attestpolicy.Engine{fromRego, fromConfig} {
+				result, err := engine.Eval(context.Background(), tt.input)
+				require.NoError(t, err)
+				require.Equal(t, tt.expectResult, result)
+			}
+		})
+	}
+}
+
+func TestNewEngineFromConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	for _, tt := range []struct {
+		name      string
+		config    *attestpolicy.OpaEngineConfig
+		expectNil bool
+		expectErr string
+	}{
+		{
+			name:      "no config",
+			expectNil: true,
+		},
+		{
+			name:      "no provider",
+			config:    &attestpolicy.OpaEngineConfig{},
+			expectErr: "attestation policy engine configuration must define a provider",
+		},
+		{
+			name: "missing rego file",
+			config: &attestpolicy.OpaEngineConfig{
+				LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+					RegoPath: filepath.Join(tmpDir, "missing.rego"),
+				},
+			},
+			expectErr: "no such file or directory",
+		},
+		{
+			name: "malformed data file",
+			config: &attestpolicy.OpaEngineConfig{
+				LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+					RegoPath:       writeFile("allow.rego", "package spire.attestation\nresult = {\"allow\": true}\n"),
+					PolicyDataPath: writeFile("malformed.json", "{"),
+				},
+			},
+			expectErr: "error decoding JSON databindings",
+		},
+		{
+			name: "result without allow",
+			config: &attestpolicy.OpaEngineConfig{
+				LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+					RegoPath: writeFile("noallow.rego", "package spire.attestation\nresult = {\"reason\": \"\"}\n"),
+				},
+			},
+			expectErr: `attestation policy is misconfigured: policy: result did not contain "allow" bool value`,
+		},
+		{
+			name: "non-string reason",
+			config: &attestpolicy.OpaEngineConfig{
+				LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+					RegoPath: writeFile("badreason.rego", "package spire.attestation\nresult = {\"allow\": false, \"reason\": 1}\n"),
+				},
+			},
+			expectErr: `attestation policy is misconfigured: policy: result "reason" value is not a string`,
+		},
+		{
+			name: "no result",
+			config: &attestpolicy.OpaEngineConfig{
+				LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+					RegoPath: writeFile("noresult.rego", "package spire.attestation\nallow = true\n"),
+				},
+			},
+			expectErr: "attestation policy is misconfigured: policy: no matching policies found",
+		},
+		{
+			name: "success",
+			config: &attestpolicy.OpaEngineConfig{
+				LocalOpaProvider: &attestpolicy.LocalOpaProviderConfig{
+					RegoPath: writeFile("success.rego", "package spire.attestation\nresult = {\"allow\": true}\n"),
+				},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := attestpolicy.NewEngineFromConfig(context.Background(), tt.config)
+			if tt.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectNil {
+				require.Nil(t, engine)
+				return
+			}
+			require.NotNil(t, engine)
+		})
+	}
+}
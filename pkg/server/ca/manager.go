@@ -73,10 +73,11 @@ type ManagerConfig struct {
 }
 
 type Manager struct {
-	c                  ManagerConfig
-	bundleUpdatedCh    chan struct{}
-	upstreamClient     *UpstreamClient
-	upstreamPluginName string
+	c                       ManagerConfig
+	bundleUpdatedCh         chan struct{}
+	upstreamX509CAChangedCh chan struct{}
+	upstreamClient          *UpstreamClient
+	upstreamPluginName      string
 
 	currentX509CA *x509CASlot
 	nextX509CA    *x509CASlot
@@ -88,6 +89,10 @@ type Manager struct {
 	// For keeping track of number of failed rotations.
 	failedRotationNum uint64
 
+	// Incremented every time the upstream CA changes. X509 CA slots record
+	// the generation they were signed under.
+	upstreamGeneration uint64
+
 	// Used to log a warning only once when the UpstreamAuthority does not support JWT-SVIDs.
 	jwtUnimplementedWarnOnce sync.Once
 }
@@ -101,8 +106,9 @@ func NewManager(c ManagerConfig) *Manager {
 	}
 
 	m := &Manager{
-		c:               c,
-		bundleUpdatedCh: make(chan struct{}, 1),
+		c:                       c,
+		bundleUpdatedCh:         make(chan struct{}, 1),
+		upstreamX509CAChangedCh: make(chan struct{}, 1),
 	}
 
 	if upstreamAuthority, ok := c.Catalog.GetUpstreamAuthority(); ok {
@@ -114,6 +120,7 @@ func NewManager(c ManagerConfig) *Manager {
 				ds:            c.Catalog.GetDataStore(),
 				updated:       m.bundleUpdated,
 			},
+			UpstreamX509CAChanged: m.upstreamX509CAChanged,
 		})
 		m.upstreamPluginName = upstreamAuthority.Name()
 	}
@@ -172,6 +179,13 @@ func (m *Manager) rotateEvery(ctx context.Context, interval time.Duration) error
 			// by rotate is used by the unit tests, so we need to keep it for
 			// now.
 			_ = m.rotate(ctx)
+		case <-m.upstreamX509CAChangedCh:
+			// Rotation prepares a new next X509 CA signed by the new upstream
+			// CA certificate, which is activated on the regular schedule. If
+			// preparation fails it is retried on the next tick.
+			m.c.Log.Info("Upstream CA changed; preparing a new X509 CA")
+			m.upstreamGeneration++
+			_ = m.rotate(ctx)
 		case <-ctx.Done():
 			return nil
 		}
@@ -205,9 +219,16 @@ func (m *Manager) rotateX509CA(ctx context.Context) error {
 		m.activateX509CA()
 	}
 
+	// if the next keypair was signed before the upstream CA changed, discard
+	// it so one signed by the new upstream CA is prepared instead.
+	if !m.nextX509CA.IsEmpty() && m.nextX509CA.upstreamGeneration != m.upstreamGeneration {
+		m.nextX509CA.Reset()
+	}
+
 	// if there is no next keypair set and the current is within the
-	// preparation threshold, generate one.
-	if m.nextX509CA.IsEmpty() && m.currentX509CA.ShouldPrepareNext(now) {
+	// preparation threshold, or was signed before the upstream CA changed,
+	// generate one.
+	if m.nextX509CA.IsEmpty() && (m.currentX509CA.ShouldPrepareNext(now) || m.currentX509CA.upstreamGeneration != m.upstreamGeneration) {
 		if err := m.prepareX509CA(ctx, m.nextX509CA); err != nil {
 			return err
 		}
@@ -263,6 +284,7 @@ func (m *Manager) prepareX509CA(ctx context.Context, slot *x509CASlot) (err erro
 
 	slot.issuedAt = now
 	slot.x509CA = x509CA
+	slot.upstreamGeneration = m.upstreamGeneration
 
 	if err := m.journal.AppendX509CA(slot.id, slot.issuedAt, slot.x509CA); err != nil {
 		log.WithError(err).Error("Unable to append X509 CA to journal")
@@ -711,6 +733,13 @@ func (m *Manager) bundleUpdated() {
 	}
 }
 
+func (m *Manager) upstreamX509CAChanged() {
+	select {
+	case m.upstreamX509CAChangedCh <- struct{}{}:
+	default:
+	}
+}
+
 func (m *Manager) dropBundleUpdated() {
 	select {
 	case <-m.bundleUpdatedCh:
@@ -891,6 +920,10 @@ type x509CASlot struct {
 	id       string
 	issuedAt time.Time
 	x509CA   *X509CA
+
+	// upstreamGeneration is the upstream generation of the manager when the
+	// X509 CA was prepared.
+	upstreamGeneration uint64
 }
 
 func newX509CASlot(id string) *x509CASlot {
@@ -13,12 +13,14 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	upstreamauthorityv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/upstreamauthority/v1"
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	telemetry_server "github.com/spiffe/spire/pkg/common/telemetry/server"
 	"github.com/spiffe/spire/pkg/server/plugin/keymanager"
@@ -182,6 +184,82 @@ func (s *ManagerSuite) TestUpstreamSigned() {
 	)
 }
 
+func (s *ManagerSuite) TestUpstreamX509CAChangedPreparesNextX509CA() {
+	upstreamAuthority, fakeUA := fakeupstreamauthority.Load(s.T(), fakeupstreamauthority.Config{
+		TrustDomain:           testTrustDomain,
+		DisallowPublishJWTKey: true,
+	})
+
+	s.initUpstreamSignedManager(upstreamAuthority)
+	s.Nil(s.nextX509CA())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.m.rotateEvery(ctx, time.Hour)
+	}()
+
+	// Rotate the upstream CA and signal the change through the open stream.
+	fakeUA.RotateX509CA()
+	fakeUA.TriggerUpstreamX509CAChanged()
+
+	s.Require().Eventually(func() bool {
+		return s.countLogEntries(logrus.InfoLevel, "Upstream CA changed; preparing a new X509 CA") == 1
+	}, time.Minute, 10*time.Millisecond)
+	cancel()
+	s.Require().NoError(<-errCh)
+
+	// A next X509 CA was minted by the new upstream CA while the current one
+	// remains active.
+	nextX509CA := s.nextX509CA()
+	s.Require().NotNil(nextX509CA)
+	s.requireX509CANotEqual(s.currentX509CA(), nextX509CA)
+	s.Equal(fakeUA.X509Root().Subject, nextX509CA.Certificate.Issuer)
+}
+
+func (s *ManagerSuite) TestUpstreamX509CAChangedReplacesStaleNextX509CA() {
+	var failMint int32
+	upstreamAuthority, fakeUA := fakeupstreamauthority.Load(s.T(), fakeupstreamauthority.Config{
+		TrustDomain:           testTrustDomain,
+		DisallowPublishJWTKey: true,
+		MutateMintX509CAResponse: func(resp *upstreamauthorityv1.MintX509CAResponse) {
+			if atomic.LoadInt32(&failMint) == 1 {
+				resp.X509CaChain = nil
+			}
+		},
+	})
+
+	s.initUpstreamSignedManager(upstreamAuthority)
+	current := s.currentX509CA()
+
+	// Prepare a next X509 CA signed by the original upstream CA.
+	s.setTimeAndRotateX509CA(s.clock.Now().Add(prepareAfter + time.Minute))
+	staleNext := s.nextX509CA()
+	s.Require().NotNil(staleNext)
+
+	// The upstream CA changes but the new next X509 CA cannot be prepared.
+	// The stale next X509 CA is discarded and the current one stays active.
+	fakeUA.RotateX509CA()
+	s.m.upstreamGeneration++
+	atomic.StoreInt32(&failMint, 1)
+	s.Require().Error(s.m.rotateX509CA(context.Background()))
+	s.Nil(s.nextX509CA())
+	s.requireX509CAEqual(current, s.currentX509CA())
+
+	// Preparation is retried on the next rotation.
+	atomic.StoreInt32(&failMint, 0)
+	s.addTimeAndRotateX509CA(time.Second)
+	next := s.nextX509CA()
+	s.Require().NotNil(next)
+	s.requireX509CANotEqual(staleNext, next)
+	s.Equal(fakeUA.X509Root().Subject, next.Certificate.Issuer)
+
+	// A next X509 CA prepared after the change is kept.
+	s.addTimeAndRotateX509CA(time.Second)
+	s.requireX509CAEqual(next, s.nextX509CA())
+	s.requireX509CAEqual(current, s.currentX509CA())
+}
+
 func (s *ManagerSuite) TestUpstreamSignedProducesInvalidChain() {
 	upstreamAuthority, _ := fakeupstreamauthority.Load(s.T(), fakeupstreamauthority.Config{
 		TrustDomain: testTrustDomain,
@@ -27,10 +27,16 @@ type BundleUpdater interface {
 type ValidateX509CAFunc = func(x509CA, x509Roots []*x509.Certificate) error
 
 // UpstreamClientConfig is the configuration for an UpstreamClient. Each field
-// is required.
+// is required unless otherwise noted.
 type UpstreamClientConfig struct {
 	UpstreamAuthority upstreamauthority.UpstreamAuthority
 	BundleUpdater     BundleUpdater
+
+	// UpstreamX509CAChanged is optional. It is invoked when the
+	// UpstreamAuthority plugin ends the MintX509CA stream with an Aborted
+	// status, which signals that the upstream signing certificate changed and
+	// a new X509 CA should be minted. It must not block.
+	UpstreamX509CAChanged func()
 }
 
 // UpstreamClient is used to interact with and stream updates from the
@@ -165,6 +171,12 @@ func (u *UpstreamClient) runMintX509CAStream(ctx context.Context, csr []byte, tt
 			case status.Code(err) == codes.Canceled:
 				// This is normal. This client cancels this stream when opening
 				// a new stream.
+			case status.Code(err) == codes.Aborted:
+				// The plugin detected that the upstream signing certificate
+				// changed.
+				if u.c.UpstreamX509CAChanged != nil {
+					u.c.UpstreamX509CAChanged()
+				}
 			default:
 				u.c.BundleUpdater.LogError(err, "The upstream authority plugin stopped streaming X.509 root updates prematurely. Please report this bug. Will retry later.")
 			}
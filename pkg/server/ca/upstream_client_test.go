@@ -47,6 +47,45 @@ func TestUpstreamClientMintX509CA_HandlesBundleUpdates(t *testing.T) {
 	require.Equal(t, ua.X509Roots(), updater.WaitForAppendedX509Roots(t))
 }
 
+func TestUpstreamClientMintX509CA_NotifiesUpstreamX509CAChanged(t *testing.T) {
+	plugin, ua := fakeupstreamauthority.Load(t, fakeupstreamauthority.Config{
+		TrustDomain: trustDomain,
+	})
+	updater := newFakeBundleUpdater()
+	changedCh := make(chan struct{}, 1)
+
+	client := ca.NewUpstreamClient(ca.UpstreamClientConfig{
+		UpstreamAuthority: plugin,
+		BundleUpdater:     updater,
+		UpstreamX509CAChanged: func() {
+			changedCh <- struct{}{}
+		},
+	})
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+
+	_, err := client.MintX509CA(context.Background(), csr, 0, func(_, _ []*x509.Certificate) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, ua.X509Roots(), updater.WaitForAppendedX509Roots(t))
+
+	// Ending the stream with an Aborted status notifies the change and is
+	// not logged as an error.
+	ua.TriggerUpstreamX509CAChanged()
+	select {
+	case <-changedCh:
+	case <-time.After(time.Minute):
+		require.FailNow(t, "timed out waiting for upstream X509 CA change notification")
+	}
+	select {
+	case e := <-updater.errorCh:
+		require.FailNow(t, "unexpected error logged", "%s: %v", e.msg, e.err)
+	default:
+	}
+}
+
 func TestUpstreamClientMintX509CA_FailsOnBadFirstResponse(t *testing.T) {
 	for _, tt := range []struct {
 		name       string
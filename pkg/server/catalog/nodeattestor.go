@@ -6,11 +6,14 @@ import (
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/aws"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/azure"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/gcp"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/httpchallenge"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/jointoken"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/jwtoidc"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/k8s/psat"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/k8s/sat"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/sshpop"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmdevid"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmek"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/x509pop"
 )
 
@@ -37,11 +40,14 @@ func (repo *nodeAttestorRepository) BuiltIns() []catalog.BuiltIn {
 		aws.BuiltIn(),
 		azure.BuiltIn(),
 		gcp.BuiltIn(),
+		httpchallenge.BuiltIn(),
 		jointoken.BuiltIn(),
+		jwtoidc.BuiltIn(),
 		psat.BuiltIn(),
 		sat.BuiltIn(),
 		sshpop.BuiltIn(),
 		tpmdevid.BuiltIn(),
+		tpmek.BuiltIn(),
 		x509pop.BuiltIn(),
 	}
 }
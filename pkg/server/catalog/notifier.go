@@ -3,8 +3,11 @@ package catalog
 import (
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/server/plugin/notifier"
+	"github.com/spiffe/spire/pkg/server/plugin/notifier/filebundle"
 	"github.com/spiffe/spire/pkg/server/plugin/notifier/gcsbundle"
 	"github.com/spiffe/spire/pkg/server/plugin/notifier/k8sbundle"
+	"github.com/spiffe/spire/pkg/server/plugin/notifier/s3bundle"
+	"github.com/spiffe/spire/pkg/server/plugin/notifier/webhook"
 )
 
 type notifierRepository struct {
@@ -27,8 +30,11 @@ func (repo *notifierRepository) Versions() []catalog.Version {
 
 func (repo *notifierRepository) BuiltIns() []catalog.BuiltIn {
 	return []catalog.BuiltIn{
+		filebundle.BuiltIn(),
 		gcsbundle.BuiltIn(),
 		k8sbundle.BuiltIn(),
+		s3bundle.BuiltIn(),
+		webhook.BuiltIn(),
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 	common "github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/health"
 	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
 	bundle_client "github.com/spiffe/spire/pkg/server/bundle/client"
 	"github.com/spiffe/spire/pkg/server/endpoints"
@@ -55,6 +56,10 @@ type Config struct {
 	// AgentTTL is time-to-live for agent SVIDs
 	AgentTTL time.Duration
 
+	// ReattestableNodeAttestors are the node attestor types that agents are
+	// allowed to re-attest with when their SVID is due for rotation
+	ReattestableNodeAttestors []string
+
 	// SVIDTTL is default time-to-live for SVIDs
 	SVIDTTL time.Duration
 
@@ -93,6 +98,10 @@ type Config struct {
 
 	// AuthPolicyEngineConfig determines the config for authz policy
 	AuthOpaPolicyEngineConfig *authpolicy.OpaEngineConfig
+
+	// AttestationOpaPolicyEngineConfig determines the config for the
+	// attestation admission policy
+	AttestationOpaPolicyEngineConfig *attestpolicy.OpaEngineConfig
 }
 
 type ExperimentalConfig struct {
@@ -40,6 +40,7 @@ type DataStore interface {
 	FetchAttestedNode(ctx context.Context, spiffeID string) (*common.AttestedNode, error)
 	ListAttestedNodes(context.Context, *ListAttestedNodesRequest) (*ListAttestedNodesResponse, error)
 	UpdateAttestedNode(context.Context, *common.AttestedNode, *common.AttestedNodeMask) (*common.AttestedNode, error)
+	UpdateAttestedNodeAndSelectors(context.Context, *common.AttestedNode, *common.AttestedNodeMask, []*common.Selector) (*common.AttestedNode, error)
 
 	// Node selectors
 	GetNodeSelectors(ctx context.Context, spiffeID string, dataConsistency DataConsistency) ([]*common.Selector, error)
@@ -50,7 +51,9 @@ type DataStore interface {
 	CreateJoinToken(context.Context, *JoinToken) error
 	DeleteJoinToken(ctx context.Context, token string) error
 	FetchJoinToken(ctx context.Context, token string) (*JoinToken, error)
+	ListJoinTokens(context.Context) ([]*JoinToken, error)
 	PruneJoinTokens(context.Context, time.Time) error
+	UseJoinToken(ctx context.Context, token string) (*JoinToken, error)
 
 	// Federation Relationships
 	CreateFederationRelationship(context.Context, *FederationRelationship) (*FederationRelationship, error)
@@ -121,6 +124,33 @@ type BySelectors struct {
 type JoinToken struct {
 	Token  string
 	Expiry time.Time
+
+	// MaxUses is how many times the token can be used to attest an agent.
+	// Tokens with a value lower than one can be used once.
+	MaxUses int32
+
+	// Uses is how many times the token has been used.
+	Uses int32
+
+	// Selectors are assigned to every agent that attests with the token.
+	Selectors []*common.Selector
+
+	// AgentIDTemplate is the template used to build the path of the ID of
+	// the agents that attest with the token. If empty, the default path
+	// is used.
+	AgentIDTemplate string
+}
+
+// RemainingUses returns how many more times the token can be used.
+func (t *JoinToken) RemainingUses() int32 {
+	maxUses := t.MaxUses
+	if maxUses < 1 {
+		maxUses = 1
+	}
+	if t.Uses >= maxUses {
+		return 0
+	}
+	return maxUses - t.Uses
 }
 
 type Pagination struct {
@@ -19,7 +19,7 @@ import (
 
 const (
 	// the latest schema version of the database in the code
-	latestSchemaVersion = 17
+	latestSchemaVersion = 19
 )
 
 var (
@@ -172,6 +172,7 @@ func initDB(db *gorm.DB, dbType string, log logrus.FieldLogger) (err error) {
 		&NodeSelector{},
 		&RegisteredEntry{},
 		&JoinToken{},
+		&JoinTokenSelector{},
 		&Selector{},
 		&Migration{},
 		&DNSName{},
@@ -237,6 +238,8 @@ func migrateVersion(tx *gorm.DB, currVersion int, log logrus.FieldLogger) (versi
 		migrateToV15,
 		migrateToV16,
 		migrateToV17,
+		migrateToV18,
+		migrateToV19,
 	}
 
 	if currVersion >= len(migrations) {
@@ -489,6 +492,37 @@ func migrateToV17(tx *gorm.DB) error {
 	return nil
 }
 
+func migrateToV18(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&JoinToken{}, &JoinTokenSelector{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	// Existing join tokens are single-use and have not been used yet. Set the
+	// new columns explicitly, since using a token is conditioned on its
+	// current number of uses and NULL never matches.
+	if err := tx.Model(&JoinToken{}).Where("uses IS NULL").Update("uses", 0).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	if err := tx.Model(&JoinToken{}).Where("max_uses IS NULL").Update("max_uses", 1).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	return nil
+}
+
+func migrateToV19(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&AttestedNode{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	// Agents used to be banned by clearing their serial numbers. Carry that
+	// state over to the new column.
+	if err := tx.Model(&AttestedNode{}).Where("serial_number = ''").Update("banned", true).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	if err := tx.Model(&AttestedNode{}).Where("banned IS NULL").Update("banned", false).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+	return nil
+}
+
 func addFederatedRegistrationEntriesRegisteredEntryIDIndex(tx *gorm.DB) error {
 	// GORM creates the federated_registration_entries implicitly with a primary
 	// key tuple (bundle_id, registered_entry_id). Unfortunately, MySQL5 does
@@ -32,6 +32,7 @@ type AttestedNode struct {
 	ExpiresAt       time.Time `gorm:"index"`
 	NewSerialNumber string
 	NewExpiresAt    *time.Time
+	Banned          bool
 
 	Selectors []*NodeSelector
 }
@@ -100,6 +101,26 @@ type JoinToken struct {
 
 	Token  string `gorm:"unique_index"`
 	Expiry int64
+
+	// MaxUses is how many times the token can be used. Tokens created before
+	// this column was introduced are set to one by the migration to v18.
+	MaxUses int32
+
+	// Uses is how many times the token has been used
+	Uses int32
+
+	// AgentIDTemplate is the template for the path of the agent IDs
+	AgentIDTemplate string
+}
+
+// JoinTokenSelector holds a selector assigned to the agents that attest with
+// a join token
+type JoinTokenSelector struct {
+	Model
+
+	Token string `gorm:"unique_index:idx_join_token_selector"`
+	Type  string `gorm:"unique_index:idx_join_token_selector"`
+	Value string `gorm:"unique_index:idx_join_token_selector"`
 }
 
 type Selector struct {
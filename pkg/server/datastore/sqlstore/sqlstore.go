@@ -260,6 +260,24 @@ func (ds *Plugin) UpdateAttestedNode(ctx context.Context, n *common.AttestedNode
 	return node, nil
 }
 
+// UpdateAttestedNodeAndSelectors updates the given node and replaces its
+// selectors in a single transaction
+func (ds *Plugin) UpdateAttestedNodeAndSelectors(ctx context.Context, n *common.AttestedNode, mask *common.AttestedNodeMask, selectors []*common.Selector) (node *common.AttestedNode, err error) {
+	// A write transaction is used instead of a read-modify-write one since
+	// locking the selector rows for update reintroduces the MySQL deadlocks
+	// described in setNodeSelectors.
+	if err = ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
+		node, err = updateAttestedNode(tx, n, mask)
+		if err != nil {
+			return err
+		}
+		return setNodeSelectors(tx, n.SpiffeId, selectors)
+	}); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
 // DeleteAttestedNode deletes the given attested node
 func (ds *Plugin) DeleteAttestedNode(ctx context.Context, spiffeID string) (attestedNode *common.AttestedNode, err error) {
 	if err = ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
@@ -428,6 +446,18 @@ func (ds *Plugin) DeleteJoinToken(ctx context.Context, token string) (err error)
 	})
 }
 
+// ListJoinTokens lists all the join tokens
+func (ds *Plugin) ListJoinTokens(ctx context.Context) (resp []*datastore.JoinToken, err error) {
+	if err = ds.withReadTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = listJoinTokens(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 // PruneJoinTokens takes a Token message, and deletes all tokens which have expired
 // before the date in the message
 func (ds *Plugin) PruneJoinTokens(ctx context.Context, expiry time.Time) (err error) {
@@ -437,6 +467,20 @@ func (ds *Plugin) PruneJoinTokens(ctx context.Context, expiry time.Time) (err er
 	})
 }
 
+// UseJoinToken records a use of the given join token and returns it, with
+// the use accounted for. The token is deleted once it has been used up. If
+// the token does not exist or has already been used up, nil is returned.
+func (ds *Plugin) UseJoinToken(ctx context.Context, token string) (resp *datastore.JoinToken, err error) {
+	if err = ds.withWriteTx(ctx, func(tx *gorm.DB) (err error) {
+		resp, err = useJoinToken(tx, token)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 // CreateFederationRelationship creates a new federation relationship. If the bundle endpoint
 // profile is 'https_spiffe' and the given federation relationship contains a bundle, the current
 // stored bundle is overridden.
@@ -1034,6 +1078,7 @@ func createAttestedNode(tx *gorm.DB, node *common.AttestedNode) (*common.Atteste
 		ExpiresAt:       time.Unix(node.CertNotAfter, 0),
 		NewSerialNumber: node.NewCertSerialNumber,
 		NewExpiresAt:    nullableUnixTimeToDBTime(node.NewCertNotAfter),
+		Banned:          node.Banned,
 	}
 
 	if err := tx.Create(&model).Error; err != nil {
@@ -1264,17 +1309,14 @@ func buildListAttestedNodesQueryCTE(req *datastore.ListAttestedNodesRequest, dbT
 		args = append(args, req.ByAttestationType)
 	}
 
-	// Filter by banned, an Attestation Node is banned when serial number is empty.
+	// Filter by banned
 	// This filter allows 3 outputs:
 	// - nil:  returns all
 	// - true: returns banned entries
 	// - false: returns no banned entries
 	if req.ByBanned != nil {
-		if *req.ByBanned {
-			builder.WriteString("\t\tAND serial_number = ''\n")
-		} else {
-			builder.WriteString("\t\tAND serial_number <> ''\n")
-		}
+		builder.WriteString("\t\tAND banned = ?\n")
+		args = append(args, *req.ByBanned)
 	}
 
 	builder.WriteString(")")
@@ -1302,7 +1344,8 @@ SELECT
 	serial_number,
 	expires_at,
 	new_serial_number,
-	new_expires_at,`)
+	new_expires_at,
+	banned,`)
 
 	// Add "optional" fields for selectors
 	if fetchSelectors {
@@ -1435,7 +1478,8 @@ SELECT
 	N.serial_number,
 	N.expires_at,
 	N.new_serial_number,
-	N.new_expires_at,`)
+	N.new_expires_at,
+	N.banned,`)
 
 	// Add "optional" fields for selectors
 	if fetchSelectors {
@@ -1481,17 +1525,14 @@ FROM attested_node_entries N
 			args = append(args, req.ByAttestationType)
 		}
 
-		// Filter by banned, an Attestation Node is banned when serial number is empty.
+		// Filter by banned
 		// This filter allows 3 outputs:
 		// - nil:  returns all
 		// - true: returns banned entries
 		// - false: returns no banned entries
 		if req.ByBanned != nil {
-			if *req.ByBanned {
-				builder.WriteString(" AND N.serial_number = ''")
-			} else {
-				builder.WriteString(" AND N.serial_number <> ''")
-			}
+			builder.WriteString(" AND N.banned = ?")
+			args = append(args, *req.ByBanned)
 		}
 		return nil
 	}
@@ -1593,6 +1634,9 @@ func updateAttestedNode(tx *gorm.DB, n *common.AttestedNode, mask *common.Attest
 	if mask.NewCertSerialNumber {
 		updates["new_serial_number"] = n.NewCertSerialNumber
 	}
+	if mask.Banned {
+		updates["banned"] = n.Banned
+	}
 
 	if err := tx.Model(&model).Updates(updates).Error; err != nil {
 		return nil, sqlError.Wrap(err)
@@ -2888,6 +2932,7 @@ type nodeRow struct {
 	ExpiresAt       sql.NullTime
 	NewSerialNumber sql.NullString
 	NewExpiresAt    sql.NullTime
+	Banned          sql.NullBool
 	SelectorType    sql.NullString
 	SelectorValue   sql.NullString
 }
@@ -2901,6 +2946,7 @@ func scanNodeRow(rs *sql.Rows, r *nodeRow) error {
 		&r.ExpiresAt,
 		&r.NewSerialNumber,
 		&r.NewExpiresAt,
+		&r.Banned,
 		&r.SelectorType,
 		&r.SelectorValue,
 	))
@@ -2931,6 +2977,10 @@ func fillNodeFromRow(node *common.AttestedNode, r *nodeRow) error {
 		node.NewCertSerialNumber = r.NewSerialNumber.String
 	}
 
+	if r.Banned.Valid {
+		node.Banned = r.Banned.Bool
+	}
+
 	if r.SelectorType.Valid {
 		if !r.SelectorValue.Valid {
 			return sqlError.New("expected non-nil selector.value value for attested node %s", node.SpiffeId)
@@ -3231,14 +3281,27 @@ func pruneRegistrationEntries(tx *gorm.DB, expiresBefore time.Time, logger logru
 
 func createJoinToken(tx *gorm.DB, token *datastore.JoinToken) error {
 	t := JoinToken{
-		Token:  token.Token,
-		Expiry: token.Expiry.Unix(),
+		Token:           token.Token,
+		Expiry:          token.Expiry.Unix(),
+		MaxUses:         token.MaxUses,
+		AgentIDTemplate: token.AgentIDTemplate,
 	}
 
 	if err := tx.Create(&t).Error; err != nil {
 		return sqlError.Wrap(err)
 	}
 
+	for _, selector := range token.Selectors {
+		model := &JoinTokenSelector{
+			Token: token.Token,
+			Type:  selector.Type,
+			Value: selector.Value,
+		}
+		if err := tx.Create(model).Error; err != nil {
+			return sqlError.Wrap(err)
+		}
+	}
+
 	return nil
 }
 
@@ -3251,7 +3314,34 @@ func fetchJoinToken(tx *gorm.DB, token string) (*datastore.JoinToken, error) {
 		return nil, sqlError.Wrap(err)
 	}
 
-	return modelToJoinToken(model), nil
+	var selectors []JoinTokenSelector
+	if err := tx.Find(&selectors, "token = ?", token).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	return modelToJoinToken(model, selectors), nil
+}
+
+func listJoinTokens(tx *gorm.DB) ([]*datastore.JoinToken, error) {
+	var models []JoinToken
+	if err := tx.Order("id asc").Find(&models).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+
+	var selectorModels []JoinTokenSelector
+	if err := tx.Order("id asc").Find(&selectorModels).Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+	selectors := make(map[string][]JoinTokenSelector)
+	for _, selector := range selectorModels {
+		selectors[selector.Token] = append(selectors[selector.Token], selector)
+	}
+
+	tokens := make([]*datastore.JoinToken, 0, len(models))
+	for _, model := range models {
+		tokens = append(tokens, modelToJoinToken(model, selectors[model.Token]))
+	}
+	return tokens, nil
 }
 
 func deleteJoinToken(tx *gorm.DB, token string) error {
@@ -3264,10 +3354,19 @@ func deleteJoinToken(tx *gorm.DB, token string) error {
 		return sqlError.Wrap(err)
 	}
 
+	if err := tx.Where("token = ?", token).Delete(&JoinTokenSelector{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+
 	return nil
 }
 
 func pruneJoinTokens(tx *gorm.DB, expiresBefore time.Time) error {
+	expired := tx.Model(&JoinToken{}).Select("token").Where("expiry < ?", expiresBefore.Unix()).QueryExpr()
+	if err := tx.Where("token IN (?)", expired).Delete(&JoinTokenSelector{}).Error; err != nil {
+		return sqlError.Wrap(err)
+	}
+
 	if err := tx.Where("expiry < ?", expiresBefore.Unix()).Delete(&JoinToken{}).Error; err != nil {
 		return sqlError.Wrap(err)
 	}
@@ -3275,6 +3374,39 @@ func pruneJoinTokens(tx *gorm.DB, expiresBefore time.Time) error {
 	return nil
 }
 
+func useJoinToken(tx *gorm.DB, token string) (*datastore.JoinToken, error) {
+	joinToken, err := fetchJoinToken(tx, token)
+	if err != nil || joinToken == nil {
+		return nil, err
+	}
+
+	remainingUses := joinToken.RemainingUses()
+	if remainingUses < 1 {
+		return nil, nil
+	}
+
+	// The update is conditioned on the number of uses read above, so
+	// concurrent uses of the same token cannot exceed its maximum uses
+	result := tx.Model(&JoinToken{}).
+		Where("token = ? AND uses = ?", token, joinToken.Uses).
+		Update("uses", joinToken.Uses+1)
+	if err := result.Error; err != nil {
+		return nil, sqlError.Wrap(err)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	joinToken.Uses++
+
+	if remainingUses == 1 {
+		if err := deleteJoinToken(tx, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return joinToken, nil
+}
+
 func createFederationRelationship(tx *gorm.DB, fr *datastore.FederationRelationship) (*datastore.FederationRelationship, error) {
 	model := FederatedTrustDomain{
 		TrustDomain:           fr.TrustDomain.String(),
@@ -3643,13 +3775,26 @@ func modelToAttestedNode(model AttestedNode) *common.AttestedNode {
 		CertNotAfter:        model.ExpiresAt.Unix(),
 		NewCertSerialNumber: model.NewSerialNumber,
 		NewCertNotAfter:     nullableDBTimeToUnixTime(model.NewExpiresAt),
+		Banned:              model.Banned,
 	}
 }
 
-func modelToJoinToken(model JoinToken) *datastore.JoinToken {
+func modelToJoinToken(model JoinToken, selectorModels []JoinTokenSelector) *datastore.JoinToken {
+	var selectors []*common.Selector
+	for _, selector := range selectorModels {
+		selectors = append(selectors, &common.Selector{
+			Type:  selector.Type,
+			Value: selector.Value,
+		})
+	}
+
 	return &datastore.JoinToken{
-		Token:  model.Token,
-		Expiry: time.Unix(model.Expiry, 0),
+		Token:           model.Token,
+		Expiry:          time.Unix(model.Expiry, 0),
+		MaxUses:         model.MaxUses,
+		Uses:            model.Uses,
+		Selectors:       selectors,
+		AgentIDTemplate: model.AgentIDTemplate,
 	}
 }
 
@@ -650,6 +650,7 @@ func (s *PluginSuite) TestListAttestedNodes() {
 			CertSerialNumber:    sn,
 			CertNotAfter:        notAfter.Unix(),
 			Selectors:           makeSelectors(selectors...),
+			Banned:              sn == "",
 		}
 	}
 
@@ -1053,6 +1054,25 @@ func (s *PluginSuite) TestUpdateAttestedNode() {
 				NewCertSerialNumber: updatedNewSerial,
 			},
 		},
+		{
+			name: "ban attested node",
+			updateNode: &common.AttestedNode{
+				SpiffeId: nodeID,
+				Banned:   true,
+			},
+			updateNodeMask: &common.AttestedNodeMask{
+				CertSerialNumber:    true,
+				NewCertSerialNumber: true,
+				Banned:              true,
+			},
+			expUpdatedNode: &common.AttestedNode{
+				SpiffeId:            nodeID,
+				AttestationDataType: attestationType,
+				CertNotAfter:        expires,
+				NewCertNotAfter:     newExpires,
+				Banned:              true,
+			},
+		},
 	} {
 		tt := tt
 		s.T().Run(tt.name, func(t *testing.T) {
@@ -1089,6 +1109,53 @@ func (s *PluginSuite) TestUpdateAttestedNode() {
 	}
 }
 
+func (s *PluginSuite) TestUpdateAttestedNodeAndSelectors() {
+	node := &common.AttestedNode{
+		SpiffeId:            "spiffe://example.org/spire/agent/test",
+		AttestationDataType: "test",
+		CertSerialNumber:    "1",
+		CertNotAfter:        1,
+		NewCertSerialNumber: "2",
+		NewCertNotAfter:     2,
+	}
+	oldSelectors := []*common.Selector{
+		{Type: "test", Value: "old"},
+	}
+	newSelectors := []*common.Selector{
+		{Type: "test", Value: "new-1"},
+		{Type: "test", Value: "new-2"},
+	}
+
+	// the node must exist
+	updatedNode, err := s.ds.UpdateAttestedNodeAndSelectors(ctx, node, nil, newSelectors)
+	s.RequireGRPCStatus(err, codes.NotFound, _notFoundErrMsg)
+	s.Require().Nil(updatedNode)
+
+	_, err = s.ds.CreateAttestedNode(ctx, node)
+	s.Require().NoError(err)
+	s.setNodeSelectors(node.SpiffeId, oldSelectors)
+
+	updatedNode, err = s.ds.UpdateAttestedNodeAndSelectors(ctx, &common.AttestedNode{
+		SpiffeId:         node.SpiffeId,
+		CertSerialNumber: "3",
+		CertNotAfter:     3,
+	}, nil, newSelectors)
+	s.Require().NoError(err)
+
+	expectedNode := &common.AttestedNode{
+		SpiffeId:            node.SpiffeId,
+		AttestationDataType: "test",
+		CertSerialNumber:    "3",
+		CertNotAfter:        3,
+	}
+	s.RequireProtoEqual(expectedNode, updatedNode)
+
+	fetchedNode, err := s.ds.FetchAttestedNode(ctx, node.SpiffeId)
+	s.Require().NoError(err)
+	s.RequireProtoEqual(expectedNode, fetchedNode)
+	s.RequireProtoListEqual(newSelectors, s.getNodeSelectors(node.SpiffeId, datastore.RequireCurrent))
+}
+
 func (s *PluginSuite) TestDeleteAttestedNode() {
 	entry := &common.AttestedNode{
 		SpiffeId:            "foo",
@@ -3235,6 +3302,128 @@ func (s *PluginSuite) TestPruneJoinTokens() {
 	s.Nil(resp)
 }
 
+func (s *PluginSuite) TestPruneJoinTokensRemovesSelectors() {
+	now := time.Now().Truncate(time.Second)
+	err := s.ds.CreateJoinToken(ctx, &datastore.JoinToken{
+		Token:     "foobar",
+		Expiry:    now,
+		Selectors: []*common.Selector{{Type: "a", Value: "1"}},
+	})
+	s.Require().NoError(err)
+
+	err = s.ds.PruneJoinTokens(ctx, now.Add(time.Second*10))
+	s.Require().NoError(err)
+
+	var count int
+	s.Require().NoError(s.ds.db.Model(&JoinTokenSelector{}).Count(&count).Error)
+	s.Equal(0, count)
+}
+
+func (s *PluginSuite) TestCreateAndFetchJoinTokenWithSelectors() {
+	now := time.Now().Truncate(time.Second)
+	joinToken := &datastore.JoinToken{
+		Token:   "foobar",
+		Expiry:  now,
+		MaxUses: 3,
+		Selectors: []*common.Selector{
+			{Type: "a", Value: "1"},
+			{Type: "b", Value: "2"},
+		},
+		AgentIDTemplate: "join_token/{{ .Token }}/{{ .Use }}",
+	}
+
+	err := s.ds.CreateJoinToken(ctx, joinToken)
+	s.Require().NoError(err)
+
+	res, err := s.ds.FetchJoinToken(ctx, joinToken.Token)
+	s.Require().NoError(err)
+	s.Equal(joinToken, res)
+}
+
+func (s *PluginSuite) TestListJoinTokens() {
+	now := time.Now().Truncate(time.Second)
+
+	tokens, err := s.ds.ListJoinTokens(ctx)
+	s.Require().NoError(err)
+	s.Empty(tokens)
+
+	joinToken1 := &datastore.JoinToken{
+		Token:     "foobar",
+		Expiry:    now,
+		MaxUses:   2,
+		Selectors: []*common.Selector{{Type: "a", Value: "1"}},
+	}
+	joinToken2 := &datastore.JoinToken{
+		Token:  "batbaz",
+		Expiry: now,
+	}
+	s.Require().NoError(s.ds.CreateJoinToken(ctx, joinToken1))
+	s.Require().NoError(s.ds.CreateJoinToken(ctx, joinToken2))
+
+	tokens, err = s.ds.ListJoinTokens(ctx)
+	s.Require().NoError(err)
+	s.Equal([]*datastore.JoinToken{joinToken1, joinToken2}, tokens)
+}
+
+func (s *PluginSuite) TestUseJoinToken() {
+	now := time.Now().Truncate(time.Second)
+
+	// Unknown tokens cannot be used
+	resp, err := s.ds.UseJoinToken(ctx, "unknown")
+	s.Require().NoError(err)
+	s.Nil(resp)
+
+	// Single-use tokens are removed after their first use
+	s.Require().NoError(s.ds.CreateJoinToken(ctx, &datastore.JoinToken{
+		Token:  "single",
+		Expiry: now,
+	}))
+
+	resp, err = s.ds.UseJoinToken(ctx, "single")
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Equal(int32(1), resp.Uses)
+
+	resp, err = s.ds.UseJoinToken(ctx, "single")
+	s.Require().NoError(err)
+	s.Nil(resp)
+
+	// Multi-use tokens are kept until they are used up
+	selectors := []*common.Selector{{Type: "a", Value: "1"}}
+	s.Require().NoError(s.ds.CreateJoinToken(ctx, &datastore.JoinToken{
+		Token:     "multi",
+		Expiry:    now,
+		MaxUses:   2,
+		Selectors: selectors,
+	}))
+
+	resp, err = s.ds.UseJoinToken(ctx, "multi")
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Equal(int32(1), resp.Uses)
+	s.Equal(int32(1), resp.RemainingUses())
+	s.Equal(selectors, resp.Selectors)
+
+	fetched, err := s.ds.FetchJoinToken(ctx, "multi")
+	s.Require().NoError(err)
+	s.Require().NotNil(fetched)
+	s.Equal(int32(1), fetched.Uses)
+
+	resp, err = s.ds.UseJoinToken(ctx, "multi")
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+	s.Equal(int32(2), resp.Uses)
+	s.Equal(int32(0), resp.RemainingUses())
+
+	fetched, err = s.ds.FetchJoinToken(ctx, "multi")
+	s.Require().NoError(err)
+	s.Nil(fetched)
+
+	resp, err = s.ds.UseJoinToken(ctx, "multi")
+	s.Require().NoError(err)
+	s.Nil(resp)
+}
+
 func (s *PluginSuite) TestDeleteFederationRelationship() {
 	testCases := []struct {
 		name        string
@@ -4090,6 +4279,41 @@ func (s *PluginSuite) TestMigration() {
 			s.Require().True(s.ds.db.Dialect().HasColumn("federated_trust_domains", "endpoint_spiffe_id"))
 			s.Require().True(s.ds.db.Dialect().HasColumn("federated_trust_domains", "implicit"))
 			s.Require().True(s.ds.db.Dialect().HasIndex("federated_trust_domains", "uix_federated_trust_domains_trust_domain"))
+		case 17:
+			s.Require().True(s.ds.db.Dialect().HasColumn("join_tokens", "max_uses"))
+			s.Require().True(s.ds.db.Dialect().HasColumn("join_tokens", "uses"))
+			s.Require().True(s.ds.db.Dialect().HasColumn("join_tokens", "agent_id_template"))
+			s.Require().True(s.ds.db.Dialect().HasIndex("join_token_selectors", "idx_join_token_selector"))
+
+			// Pre-existing tokens remain usable exactly once
+			token, err := s.ds.UseJoinToken(context.Background(), "foobar")
+			s.Require().NoError(err)
+			s.Require().NotNil(token)
+			s.Require().Equal(int32(1), token.MaxUses)
+			s.Require().Equal(int32(1), token.Uses)
+
+			token, err = s.ds.UseJoinToken(context.Background(), "foobar")
+			s.Require().NoError(err)
+			s.Require().Nil(token)
+		case 18:
+			s.Require().True(s.ds.db.Dialect().HasColumn("attested_node_entries", "banned"))
+
+			// Agents banned by clearing their serial numbers remain banned
+			node, err := s.ds.FetchAttestedNode(context.Background(), "spiffe://example.org/spire/agent/banned")
+			s.Require().NoError(err)
+			s.Require().NotNil(node)
+			s.Require().True(node.Banned)
+
+			node, err = s.ds.FetchAttestedNode(context.Background(), "spiffe://example.org/spire/agent/active")
+			s.Require().NoError(err)
+			s.Require().NotNil(node)
+			s.Require().False(node.Banned)
+
+			banned := true
+			resp, err := s.ds.ListAttestedNodes(context.Background(), &datastore.ListAttestedNodesRequest{ByBanned: &banned})
+			s.Require().NoError(err)
+			s.Require().Len(resp.Nodes, 1)
+			s.Require().Equal("spiffe://example.org/spire/agent/banned", resp.Nodes[0].SpiffeId)
 		default:
 			s.T().Fatalf("no migration test added for version %d", i)
 		}
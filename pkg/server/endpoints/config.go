@@ -14,12 +14,15 @@ import (
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/server/api"
 	agentv1 "github.com/spiffe/spire/pkg/server/api/agent/v1"
+	agentbanv1 "github.com/spiffe/spire/pkg/server/api/agentban/v1"
 	bundlev1 "github.com/spiffe/spire/pkg/server/api/bundle/v1"
 	debugv1 "github.com/spiffe/spire/pkg/server/api/debug/v1"
 	entryv1 "github.com/spiffe/spire/pkg/server/api/entry/v1"
 	healthv1 "github.com/spiffe/spire/pkg/server/api/health/v1"
+	jointokenv1 "github.com/spiffe/spire/pkg/server/api/jointoken/v1"
 	svidv1 "github.com/spiffe/spire/pkg/server/api/svid/v1"
 	trustdomainv1 "github.com/spiffe/spire/pkg/server/api/trustdomain/v1"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
 	bundle_client "github.com/spiffe/spire/pkg/server/bundle/client"
 	"github.com/spiffe/spire/pkg/server/ca"
@@ -53,6 +56,10 @@ type Config struct {
 	// TTL to use when signing agent SVIDs
 	AgentTTL time.Duration
 
+	// ReattestableNodeAttestors are the node attestor types that agents are
+	// allowed to re-attest with to obtain a new SVID
+	ReattestableNodeAttestors []string
+
 	// Bundle endpoint configuration
 	BundleEndpoint bundle.EndpointConfig
 
@@ -62,6 +69,9 @@ type Config struct {
 	// Makes policy decisions
 	AuthPolicyEngine *authpolicy.Engine
 
+	// Admits agents after node attestation
+	AttestationPolicyEngine *attestpolicy.Engine
+
 	Log     logrus.FieldLogger
 	Metrics telemetry.Metrics
 
@@ -126,6 +136,14 @@ func (c *Config) makeAPIServers(entryFetcher api.AuthorizedEntryFetcher) APIServ
 			TrustDomain: c.TrustDomain,
 			Catalog:     c.Catalog,
 			Clock:       c.Clock,
+			Metrics:     c.Metrics,
+
+			ReattestableNodeAttestors: c.ReattestableNodeAttestors,
+			AttestationPolicyEngine:   c.AttestationPolicyEngine,
+		}),
+		AgentBanServer: agentbanv1.New(agentbanv1.Config{
+			DataStore:   ds,
+			TrustDomain: c.TrustDomain,
 		}),
 		BundleServer: bundlev1.New(bundlev1.Config{
 			TrustDomain:       c.TrustDomain,
@@ -148,6 +166,11 @@ func (c *Config) makeAPIServers(entryFetcher api.AuthorizedEntryFetcher) APIServ
 			TrustDomain: c.TrustDomain,
 			DataStore:   ds,
 		}),
+		JoinTokenServer: jointokenv1.New(jointokenv1.Config{
+			TrustDomain: c.TrustDomain,
+			DataStore:   ds,
+			Clock:       c.Clock,
+		}),
 		SVIDServer: svidv1.New(svidv1.Config{
 			TrustDomain:  c.TrustDomain,
 			EntryFetcher: entryFetcher,
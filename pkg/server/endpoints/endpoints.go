@@ -36,6 +36,8 @@ import (
 	"github.com/spiffe/spire/pkg/server/cache/dscache"
 	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/pkg/server/svid"
+	agentbanv1 "github.com/spiffe/spire/proto/private/server/agentban/v1"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
 )
 
 const (
@@ -76,10 +78,12 @@ type Endpoints struct {
 
 type APIServers struct {
 	AgentServer       agentv1.AgentServer
+	AgentBanServer    agentbanv1.AgentBanServer
 	BundleServer      bundlev1.BundleServer
 	DebugServer       debugv1_pb.DebugServer
 	EntryServer       entryv1.EntryServer
 	HealthServer      grpc_health_v1.HealthServer
+	JoinTokenServer   jointokenv1.JoinTokenServer
 	SVIDServer        svidv1.SVIDServer
 	TrustDomainServer trustdomainv1.TrustDomainServer
 }
@@ -149,10 +153,14 @@ func (e *Endpoints) ListenAndServe(ctx context.Context) error {
 	// New APIs
 	agentv1.RegisterAgentServer(tcpServer, e.APIServers.AgentServer)
 	agentv1.RegisterAgentServer(udsServer, e.APIServers.AgentServer)
+	agentbanv1.RegisterAgentBanServer(tcpServer, e.APIServers.AgentBanServer)
+	agentbanv1.RegisterAgentBanServer(udsServer, e.APIServers.AgentBanServer)
 	bundlev1.RegisterBundleServer(tcpServer, e.APIServers.BundleServer)
 	bundlev1.RegisterBundleServer(udsServer, e.APIServers.BundleServer)
 	entryv1.RegisterEntryServer(tcpServer, e.APIServers.EntryServer)
 	entryv1.RegisterEntryServer(udsServer, e.APIServers.EntryServer)
+	jointokenv1.RegisterJoinTokenServer(tcpServer, e.APIServers.JoinTokenServer)
+	jointokenv1.RegisterJoinTokenServer(udsServer, e.APIServers.JoinTokenServer)
 	svidv1.RegisterSVIDServer(tcpServer, e.APIServers.SVIDServer)
 	svidv1.RegisterSVIDServer(udsServer, e.APIServers.SVIDServer)
 	trustdomainv1.RegisterTrustDomainServer(tcpServer, e.APIServers.TrustDomainServer)
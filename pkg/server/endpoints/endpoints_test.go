@@ -27,6 +27,8 @@ import (
 	"github.com/spiffe/spire/pkg/server/datastore"
 	"github.com/spiffe/spire/pkg/server/endpoints/bundle"
 	"github.com/spiffe/spire/pkg/server/svid"
+	agentbanv1 "github.com/spiffe/spire/proto/private/server/agentban/v1"
+	jointokenv1 "github.com/spiffe/spire/proto/private/server/jointoken/v1"
 	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/fakes/fakedatastore"
@@ -112,10 +114,12 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, svidObserver, endpoints.SVIDObserver)
 	assert.Equal(t, testTD, endpoints.TrustDomain)
 	assert.NotNil(t, endpoints.APIServers.AgentServer)
+	assert.NotNil(t, endpoints.APIServers.AgentBanServer)
 	assert.NotNil(t, endpoints.APIServers.BundleServer)
 	assert.NotNil(t, endpoints.APIServers.DebugServer)
 	assert.NotNil(t, endpoints.APIServers.EntryServer)
 	assert.NotNil(t, endpoints.APIServers.HealthServer)
+	assert.NotNil(t, endpoints.APIServers.JoinTokenServer)
 	assert.NotNil(t, endpoints.APIServers.SVIDServer)
 	assert.NotNil(t, endpoints.BundleEndpointServer)
 	assert.Equal(t, cat.GetDataStore(), endpoints.DataStore)
@@ -216,10 +220,12 @@ func TestListenAndServe(t *testing.T) {
 		DataStore:    ds,
 		APIServers: APIServers{
 			AgentServer:       &agentv1.UnimplementedAgentServer{},
+			AgentBanServer:    &agentbanv1.UnimplementedAgentBanServer{},
 			BundleServer:      &bundlev1.UnimplementedBundleServer{},
 			DebugServer:       &debugv1.UnimplementedDebugServer{},
 			EntryServer:       &entryv1.UnimplementedEntryServer{},
 			HealthServer:      &grpc_health_v1.UnimplementedHealthServer{},
+			JoinTokenServer:   &jointokenv1.UnimplementedJoinTokenServer{},
 			SVIDServer:        &svidv1.UnimplementedSVIDServer{},
 			TrustDomainServer: &trustdomainv1.UnimplementedTrustDomainServer{},
 		},
@@ -302,6 +308,12 @@ func TestListenAndServe(t *testing.T) {
 	t.Run("Entry", func(t *testing.T) {
 		testEntryAPI(ctx, t, udsConn, noauthConn, agentConn, adminConn, downstreamConn)
 	})
+	t.Run("AgentBan", func(t *testing.T) {
+		testAgentBanAPI(ctx, t, udsConn, noauthConn, agentConn, adminConn, downstreamConn)
+	})
+	t.Run("JoinToken", func(t *testing.T) {
+		testJoinTokenAPI(ctx, t, udsConn, noauthConn, agentConn, adminConn, downstreamConn)
+	})
 	t.Run("SVID", func(t *testing.T) {
 		testSVIDAPI(ctx, t, udsConn, noauthConn, agentConn, adminConn, downstreamConn)
 	})
@@ -421,6 +433,75 @@ func testAgentAPI(ctx context.Context, t *testing.T, udsConn, noauthConn, agentC
 	})
 }
 
+func testAgentBanAPI(ctx context.Context, t *testing.T, udsConn, noauthConn, agentConn, adminConn, downstreamConn *grpc.ClientConn) {
+	t.Run("UDS", func(t *testing.T) {
+		testAuthorization(ctx, t, agentbanv1.NewAgentBanClient(udsConn), map[string]bool{
+			"UnbanAgent": true,
+		})
+	})
+
+	t.Run("NoAuth", func(t *testing.T) {
+		testAuthorization(ctx, t, agentbanv1.NewAgentBanClient(noauthConn), map[string]bool{
+			"UnbanAgent": false,
+		})
+	})
+
+	t.Run("Agent", func(t *testing.T) {
+		testAuthorization(ctx, t, agentbanv1.NewAgentBanClient(agentConn), map[string]bool{
+			"UnbanAgent": false,
+		})
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		testAuthorization(ctx, t, agentbanv1.NewAgentBanClient(adminConn), map[string]bool{
+			"UnbanAgent": true,
+		})
+	})
+
+	t.Run("Downstream", func(t *testing.T) {
+		testAuthorization(ctx, t, agentbanv1.NewAgentBanClient(downstreamConn), map[string]bool{
+			"UnbanAgent": false,
+		})
+	})
+}
+
+func testJoinTokenAPI(ctx context.Context, t *testing.T, udsConn, noauthConn, agentConn, adminConn, downstreamConn *grpc.ClientConn) {
+	t.Run("UDS", func(t *testing.T) {
+		testAuthorization(ctx, t, jointokenv1.NewJoinTokenClient(udsConn), map[string]bool{
+			"CreateJoinToken": true,
+			"ListJoinTokens":  true,
+		})
+	})
+
+	t.Run("NoAuth", func(t *testing.T) {
+		testAuthorization(ctx, t, jointokenv1.NewJoinTokenClient(noauthConn), map[string]bool{
+			"CreateJoinToken": false,
+			"ListJoinTokens":  false,
+		})
+	})
+
+	t.Run("Agent", func(t *testing.T) {
+		testAuthorization(ctx, t, jointokenv1.NewJoinTokenClient(agentConn), map[string]bool{
+			"CreateJoinToken": false,
+			"ListJoinTokens":  false,
+		})
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		testAuthorization(ctx, t, jointokenv1.NewJoinTokenClient(adminConn), map[string]bool{
+			"CreateJoinToken": true,
+			"ListJoinTokens":  true,
+		})
+	})
+
+	t.Run("Downstream", func(t *testing.T) {
+		testAuthorization(ctx, t, jointokenv1.NewJoinTokenClient(downstreamConn), map[string]bool{
+			"CreateJoinToken": false,
+			"ListJoinTokens":  false,
+		})
+	})
+}
+
 func testHealthAPI(ctx context.Context, t *testing.T, udsConn, noauthConn, agentConn, adminConn, downstreamConn *grpc.ClientConn) {
 	t.Run("UDS", func(t *testing.T) {
 		testAuthorization(ctx, t, grpc_health_v1.NewHealthClient(udsConn), map[string]bool{
@@ -84,7 +84,7 @@ func AgentAuthorizer(log logrus.FieldLogger, ds datastore.DataStore, clk clock.C
 		case attestedNode == nil:
 			log.Error("Agent is not attested")
 			return permissionDenied(types.PermissionDeniedDetails_AGENT_NOT_ATTESTED, "agent %q is not attested", id)
-		case attestedNode.CertSerialNumber == "":
+		case attestedNode.Banned:
 			log.Error("Agent is banned")
 			return permissionDenied(types.PermissionDeniedDetails_AGENT_BANNED, "agent %q is banned", id)
 		case attestedNode.CertSerialNumber == agentSVID.SerialNumber.String():
@@ -168,6 +168,9 @@ func RateLimits(config RateLimitConfig) map[string]api.RateLimiter {
 		"/spire.api.server.agent.v1.Agent/AttestAgent":                                   attestLimit,
 		"/spire.api.server.agent.v1.Agent/RenewAgent":                                    csrLimit,
 		"/spire.api.server.agent.v1.Agent/CreateJoinToken":                               noLimit,
+		"/spire.private.server.agentban.v1.AgentBan/UnbanAgent":                          noLimit,
+		"/spire.private.server.jointoken.v1.JoinToken/CreateJoinToken":                   noLimit,
+		"/spire.private.server.jointoken.v1.JoinToken/ListJoinTokens":                    noLimit,
 		"/spire.api.server.trustdomain.v1.TrustDomain/ListFederationRelationships":       noLimit,
 		"/spire.api.server.trustdomain.v1.TrustDomain/GetFederationRelationship":         noLimit,
 		"/spire.api.server.trustdomain.v1.TrustDomain/BatchCreateFederationRelationship": noLimit,
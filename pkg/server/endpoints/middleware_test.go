@@ -141,6 +141,7 @@ func TestAgentAuthorizer(t *testing.T) {
 			name: "banned",
 			node: &common.AttestedNode{
 				SpiffeId: agentID.String(),
+				Banned:   true,
 			},
 			expectedCode:   codes.PermissionDenied,
 			expectedMsg:    `agent "spiffe://domain.test/spire/agent/foo" is banned`,
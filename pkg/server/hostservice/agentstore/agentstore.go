@@ -67,6 +67,12 @@ func (v1 *agentStoreV1) GetAgentInfo(ctx context.Context, req *agentstorev1.GetA
 	if attestedNode == nil {
 		return nil, status.Error(codes.NotFound, "no such agent")
 	}
+	// Unbanned agents have no active SVID and are expected to attest again,
+	// so they are reported as not attested. Banned agents are still reported
+	// so their attestation data cannot be reused.
+	if !attestedNode.Banned && attestedNode.CertSerialNumber == "" {
+		return nil, status.Error(codes.NotFound, "agent must attest again")
+	}
 
 	return &agentstorev1.GetAgentInfoResponse{
 		Info: &agentstorev1.AgentInfo{
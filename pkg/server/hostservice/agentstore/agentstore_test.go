@@ -16,7 +16,17 @@ import (
 func TestAgentStore(t *testing.T) {
 	ds := fakedatastore.New(t)
 	_, err := ds.CreateAttestedNode(context.Background(), &common.AttestedNode{
-		SpiffeId: "spiffe://domain.test/spire/agent/test/foo",
+		SpiffeId:         "spiffe://domain.test/spire/agent/test/foo",
+		CertSerialNumber: "1",
+	})
+	require.NoError(t, err)
+	_, err = ds.CreateAttestedNode(context.Background(), &common.AttestedNode{
+		SpiffeId: "spiffe://domain.test/spire/agent/test/banned",
+		Banned:   true,
+	})
+	require.NoError(t, err)
+	_, err = ds.CreateAttestedNode(context.Background(), &common.AttestedNode{
+		SpiffeId: "spiffe://domain.test/spire/agent/test/unbanned",
 	})
 	require.NoError(t, err)
 
@@ -49,6 +59,18 @@ func TestAgentStore(t *testing.T) {
 			code:    codes.NotFound,
 			getErr:  "no such agent",
 		},
+		{
+			name:    "unbanned agent must attest again",
+			deps:    deps,
+			agentID: "spiffe://domain.test/spire/agent/test/unbanned",
+			code:    codes.NotFound,
+			getErr:  "agent must attest again",
+		},
+		{
+			name:    "banned agent",
+			agentID: "spiffe://domain.test/spire/agent/test/banned",
+			deps:    deps,
+		},
 		{
 			name:    "success",
 			agentID: "spiffe://domain.test/spire/agent/test/foo",
@@ -0,0 +1,289 @@
+package httpchallenge
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/plugin/httpchallenge"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	nodeattestorbase "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	challengeTimeout = 5 * time.Second
+
+	// maxRootPort is the highest port that only privileged processes can
+	// listen on.
+	maxRootPort = 1023
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(httpchallenge.PluginName,
+		nodeattestorv1.NodeAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// Config is the configuration of the http_challenge server node attestor.
+type Config struct {
+	// AllowedDNSPatterns is a list of regular expressions the claimed
+	// hostname must match. If unset, all hostnames are allowed.
+	AllowedDNSPatterns []string `hcl:"allowed_dns_patterns"`
+	// RequiredPort is the port agents must serve the challenge on. If unset,
+	// any port is allowed.
+	RequiredPort *int `hcl:"required_port"`
+	// AllowNonRootPorts allows agents to serve the challenge on ports that
+	// unprivileged processes can listen on. Defaults to true.
+	AllowNonRootPorts *bool `hcl:"allow_non_root_ports"`
+	// TOFU (trust on first use) prevents a hostname from attesting more than
+	// once. Defaults to true.
+	TOFU *bool `hcl:"tofu"`
+	// AgentPathTemplate is the template used to build the agent ID path.
+	AgentPathTemplate string `hcl:"agent_path_template"`
+}
+
+type configuration struct {
+	trustDomain        string
+	allowedDNSPatterns []*regexp.Regexp
+	requiredPort       int
+	allowNonRootPorts  bool
+	tofu               bool
+	agentPathTemplate  *template.Template
+}
+
+// Plugin implements node attestation for agents that prove control of a DNS
+// name by serving a challenge over HTTP.
+type Plugin struct {
+	nodeattestorbase.Base
+	nodeattestorv1.UnsafeNodeAttestorServer
+	configv1.UnsafeConfigServer
+
+	log        hclog.Logger
+	httpClient *http.Client
+
+	mu     sync.RWMutex
+	config *configuration
+}
+
+func New() *Plugin {
+	return &Plugin{
+		httpClient: &http.Client{
+			Timeout: challengeTimeout,
+			Transport: &http.Transport{
+				// The challenge must be fetched from the claimed hostname
+				// directly and not through a proxy
+				Proxy:             nil,
+				DisableKeepAlives: true,
+				DialContext: (&net.Dialer{
+					Timeout: challengeTimeout,
+				}).DialContext,
+			},
+			// Following redirects would allow the challenge to be served
+			// from a host other than the claimed one
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	payload := req.GetPayload()
+	if payload == nil {
+		return status.Error(codes.InvalidArgument, "missing attestation payload")
+	}
+
+	attestationData := new(httpchallenge.AttestationData)
+	if err := json.Unmarshal(payload, attestationData); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to unmarshal data: %v", err)
+	}
+	if err := httpchallenge.ValidateAttestationData(attestationData); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid attestation data: %v", err)
+	}
+
+	if !config.hostNameAllowed(attestationData.HostName) {
+		return status.Errorf(codes.PermissionDenied, "hostname %q is not allowed", attestationData.HostName)
+	}
+	switch {
+	case config.requiredPort != 0 && attestationData.Port != config.requiredPort:
+		return status.Errorf(codes.PermissionDenied, "port %d is not allowed; port %d is required", attestationData.Port, config.requiredPort)
+	case !config.allowNonRootPorts && attestationData.Port > maxRootPort:
+		return status.Errorf(codes.PermissionDenied, "port %d is not allowed; only ports up to %d are allowed", attestationData.Port, maxRootPort)
+	}
+
+	agentID, err := httpchallenge.MakeAgentID(config.trustDomain, config.agentPathTemplate, attestationData)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to create agent ID: %v", err)
+	}
+
+	if config.tofu {
+		attested, err := p.IsAttested(stream.Context(), agentID.String())
+		switch {
+		case err != nil:
+			return err
+		case attested:
+			return status.Error(codes.PermissionDenied, "attestation data has already been used to attest an agent")
+		}
+	}
+
+	challenge, err := httpchallenge.GenerateChallenge()
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to generate challenge: %v", err)
+	}
+	challengeBytes, err := json.Marshal(challenge)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal challenge: %v", err)
+	}
+
+	if err := stream.Send(&nodeattestorv1.AttestResponse{
+		Response: &nodeattestorv1.AttestResponse_Challenge{
+			Challenge: challengeBytes,
+		},
+	}); err != nil {
+		return err
+	}
+
+	responseReq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	response := new(httpchallenge.Response)
+	if err := json.Unmarshal(responseReq.GetChallengeResponse(), response); err != nil {
+		return status.Errorf(codes.InvalidArgument, "unable to unmarshal challenge response: %v", err)
+	}
+	if !response.Ready {
+		return status.Error(codes.InvalidArgument, "agent is not serving the challenge")
+	}
+
+	if err := httpchallenge.VerifyChallenge(stream.Context(), p.httpClient, attestationData, challenge); err != nil {
+		p.log.Debug("Challenge verification failed",
+			telemetry.Address, httpchallenge.ChallengeURL(attestationData),
+			telemetry.Error, err)
+		return status.Errorf(codes.PermissionDenied, "challenge verification failed: %v", err)
+	}
+
+	return stream.Send(&nodeattestorv1.AttestResponse{
+		Response: &nodeattestorv1.AttestResponse_AgentAttributes{
+			AgentAttributes: &nodeattestorv1.AgentAttributes{
+				SpiffeId: agentID.String(),
+				SelectorValues: []string{
+					"hostname:" + attestationData.HostName,
+					"agent_name:" + attestationData.AgentName,
+				},
+			},
+		},
+	})
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	hclConfig := new(Config)
+	if err := hcl.Decode(hclConfig, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+	if req.CoreConfiguration == nil {
+		return nil, status.Error(codes.InvalidArgument, "core configuration is required")
+	}
+	if req.CoreConfiguration.TrustDomain == "" {
+		return nil, status.Error(codes.InvalidArgument, "core configuration missing trust domain")
+	}
+
+	config := &configuration{
+		trustDomain:       req.CoreConfiguration.TrustDomain,
+		allowNonRootPorts: true,
+		tofu:              true,
+		agentPathTemplate: httpchallenge.DefaultAgentPathTemplate,
+	}
+
+	for _, pattern := range hclConfig.AllowedDNSPatterns {
+		// Patterns must match the whole hostname
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid allowed_dns_patterns entry %q: %v", pattern, err)
+		}
+		config.allowedDNSPatterns = append(config.allowedDNSPatterns, re)
+	}
+	if hclConfig.RequiredPort != nil {
+		if *hclConfig.RequiredPort <= 0 || *hclConfig.RequiredPort > 65535 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid required_port %d", *hclConfig.RequiredPort)
+		}
+		config.requiredPort = *hclConfig.RequiredPort
+	}
+	if hclConfig.AllowNonRootPorts != nil {
+		config.allowNonRootPorts = *hclConfig.AllowNonRootPorts
+	}
+	if !config.allowNonRootPorts && config.requiredPort > maxRootPort {
+		return nil, status.Error(codes.InvalidArgument, "required_port must be a root port when allow_non_root_ports is false")
+	}
+	if hclConfig.TOFU != nil {
+		config.tofu = *hclConfig.TOFU
+	}
+
+	if hclConfig.AgentPathTemplate != "" {
+		tmpl, err := template.New("agent-path").Parse(hclConfig.AgentPathTemplate)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to parse agent path template: %q", hclConfig.AgentPathTemplate)
+		}
+		config.agentPathTemplate = tmpl
+	}
+
+	p.setConfig(config)
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfig() (*configuration, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+func (p *Plugin) setConfig(config *configuration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (c *configuration) hostNameAllowed(hostName string) bool {
+	if len(c.allowedDNSPatterns) == 0 {
+		return true
+	}
+	for _, re := range c.allowedDNSPatterns {
+		if re.MatchString(hostName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,393 @@
+package httpchallenge
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	agentstorev1 "github.com/spiffe/spire-plugin-sdk/proto/spire/hostservice/server/agentstore/v1"
+	agentnodeattestor "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
+	agenthttpchallenge "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/httpchallenge"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/plugin/httpchallenge"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/fakes/fakeagentstore"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAttest(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		config          string
+		hostName        string
+		agentName       string
+		port            int
+		attested        bool
+		notServing      bool
+		wrongNonce      bool
+		notReady        bool
+		expectCode      codes.Code
+		expectMsg       string
+		expectAgentID   string
+		expectSelectors []string
+	}{
+		{
+			name:          "success",
+			expectAgentID: "spiffe://example.org/spire/agent/http_challenge/localhost",
+			expectSelectors: []string{
+				"hostname:localhost",
+				"agent_name:default",
+			},
+		},
+		{
+			name: "success with pattern and template",
+			config: `
+				allowed_dns_patterns = ["other", "local.*"]
+				agent_path_template = "{{ .PluginName }}/{{ .HostName }}/{{ .AgentName }}"
+			`,
+			agentName:     "agent-1",
+			expectAgentID: "spiffe://example.org/spire/agent/http_challenge/localhost/agent-1",
+			expectSelectors: []string{
+				"hostname:localhost",
+				"agent_name:agent-1",
+			},
+		},
+		{
+			name:          "success when already attested without tofu",
+			config:        `tofu = false`,
+			attested:      true,
+			expectAgentID: "spiffe://example.org/spire/agent/http_challenge/localhost",
+			expectSelectors: []string{
+				"hostname:localhost",
+				"agent_name:default",
+			},
+		},
+		{
+			name:       "invalid hostname",
+			hostName:   "local/host",
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `invalid attestation data: invalid hostname "local/host"`,
+		},
+		{
+			name:       "invalid agent name",
+			agentName:  "../agent",
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `invalid attestation data: invalid agent name "../agent"`,
+		},
+		{
+			name:       "hostname not allowed",
+			config:     `allowed_dns_patterns = ["local"]`,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  `hostname "localhost" is not allowed`,
+		},
+		{
+			name:       "port mismatch",
+			config:     `required_port = 80`,
+			port:       8080,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "port 8080 is not allowed; port 80 is required",
+		},
+		{
+			name:       "non root port",
+			config:     `allow_non_root_ports = false`,
+			port:       8080,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "port 8080 is not allowed; only ports up to 1023 are allowed",
+		},
+		{
+			name:       "already attested",
+			attested:   true,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "attestation data has already been used to attest an agent",
+		},
+		{
+			name:       "agent not ready",
+			notReady:   true,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "agent is not serving the challenge",
+		},
+		{
+			name:       "challenge not served",
+			notServing: true,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "challenge verification failed",
+		},
+		{
+			name:       "wrong nonce",
+			wrongNonce: true,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "challenge verification failed: challenge response does not match",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			agentStore := fakeagentstore.New()
+			if tt.attested {
+				agentStore.SetAgentInfo(&agentstorev1.AgentInfo{
+					AgentId: "spiffe://example.org/spire/agent/http_challenge/localhost",
+				})
+			}
+			attestor := loadPlugin(t, agentStore, tt.config)
+
+			agent := newFakeAgent(t)
+			if tt.notServing {
+				agent.close()
+			}
+
+			data := httpchallenge.AttestationData{
+				HostName:  "localhost",
+				AgentName: "default",
+				Port:      agent.port(),
+			}
+			if tt.hostName != "" {
+				data.HostName = tt.hostName
+			}
+			if tt.agentName != "" {
+				data.AgentName = tt.agentName
+			}
+			if tt.port != 0 {
+				data.Port = tt.port
+			}
+			payload, err := json.Marshal(data)
+			require.NoError(t, err)
+
+			result, err := attestor.Attest(context.Background(), payload, func(ctx context.Context, challengeBytes []byte) ([]byte, error) {
+				challenge := new(httpchallenge.Challenge)
+				if err := json.Unmarshal(challengeBytes, challenge); err != nil {
+					return nil, err
+				}
+				nonce := challenge.Nonce
+				if tt.wrongNonce {
+					nonce = "wrong"
+				}
+				agent.serve(data.AgentName, nonce)
+				return json.Marshal(httpchallenge.Response{Ready: !tt.notReady})
+			})
+			if tt.expectCode != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.expectCode, tt.expectMsg)
+				require.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+
+			var expectSelectors []*common.Selector
+			for _, value := range tt.expectSelectors {
+				expectSelectors = append(expectSelectors, &common.Selector{Type: httpchallenge.PluginName, Value: value})
+			}
+			assert.Equal(t, tt.expectAgentID, result.AgentID)
+			spiretest.AssertProtoListEqual(t, expectSelectors, result.Selectors)
+		})
+	}
+}
+
+// TestEndToEnd attests the agent plugin against the server plugin, with the
+// challenge served by the agent plugin on a local port.
+func TestEndToEnd(t *testing.T) {
+	attestor := loadPlugin(t, fakeagentstore.New(), `allowed_dns_patterns = ["localhost"]`)
+
+	agentAttestor := new(agentnodeattestor.V1)
+	plugintest.Load(t, agenthttpchallenge.BuiltIn(), agentAttestor, plugintest.Configure(`
+		hostname = "localhost"
+		port = 0
+	`))
+
+	stream := newServerStream(attestor)
+	require.NoError(t, agentAttestor.Attest(context.Background(), stream))
+	require.NotNil(t, stream.result)
+	assert.Equal(t, "spiffe://example.org/spire/agent/http_challenge/localhost", stream.result.AgentID)
+}
+
+func TestConfigure(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		config     string
+		noTD       bool
+		expectCode codes.Code
+		expectMsg  string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name: "all options",
+			config: `
+				allowed_dns_patterns = ["p[0-9]+\\.example\\.org"]
+				required_port = 80
+				allow_non_root_ports = false
+				tofu = false
+				agent_path_template = "{{ .PluginName }}/{{ .HostName }}/{{ .AgentName }}"
+			`,
+		},
+		{
+			name:       "malformed",
+			config:     `[[[`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "unable to decode configuration",
+		},
+		{
+			name:       "missing trust domain",
+			noTD:       true,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "core configuration missing trust domain",
+		},
+		{
+			name:       "invalid pattern",
+			config:     `allowed_dns_patterns = ["("]`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `invalid allowed_dns_patterns entry "("`,
+		},
+		{
+			name:       "invalid required port",
+			config:     `required_port = 70000`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "invalid required_port 70000",
+		},
+		{
+			name:       "required port not a root port",
+			config:     `required_port = 8080 allow_non_root_ports = false`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "required_port must be a root port when allow_non_root_ports is false",
+		},
+		{
+			name:       "invalid agent path template",
+			config:     `agent_path_template = "{{ .HostName "`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "failed to parse agent path template",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			coreConfig := catalog.CoreConfig{
+				TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+			}
+			if tt.noTD {
+				coreConfig = catalog.CoreConfig{}
+			}
+
+			var err error
+			plugintest.Load(t, BuiltIn(), nil,
+				plugintest.CaptureConfigureError(&err),
+				plugintest.CoreConfig(coreConfig),
+				plugintest.HostServices(agentstorev1.AgentStoreServiceServer(fakeagentstore.New())),
+				plugintest.Configure(tt.config),
+			)
+			if tt.expectCode != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.expectCode, tt.expectMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func loadPlugin(t *testing.T, agentStore *fakeagentstore.AgentStore, config string) nodeattestor.NodeAttestor {
+	v1 := new(nodeattestor.V1)
+	plugintest.Load(t, BuiltIn(), v1,
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+		plugintest.HostServices(agentstorev1.AgentStoreServiceServer(agentStore)),
+		plugintest.Configure(config),
+	)
+	return v1
+}
+
+// fakeAgent serves a challenge on a local port, like the agent plugin does.
+type fakeAgent struct {
+	listener net.Listener
+	server   *http.Server
+
+	mu        sync.Mutex
+	agentName string
+	nonce     string
+}
+
+func newFakeAgent(t *testing.T) *fakeAgent {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	agent := &fakeAgent{listener: listener}
+	agent.server = &http.Server{Handler: http.HandlerFunc(agent.serveHTTP)} //nolint: gosec // test server
+	go func() { _ = agent.server.Serve(listener) }()
+	t.Cleanup(agent.close)
+	return agent
+}
+
+func (a *fakeAgent) port() int {
+	return a.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (a *fakeAgent) serve(agentName, nonce string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.agentName = agentName
+	a.nonce = nonce
+}
+
+func (a *fakeAgent) close() {
+	_ = a.server.Close()
+}
+
+func (a *fakeAgent) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.nonce == "" || r.URL.Path != httpchallenge.ChallengePath(a.agentName) {
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write([]byte(a.nonce))
+}
+
+// serverStream relays the agent plugin attestation to the server plugin.
+type serverStream struct {
+	attestor nodeattestor.NodeAttestor
+
+	challenges chan []byte
+	responses  chan []byte
+	done       chan error
+	result     *nodeattestor.AttestResult
+}
+
+func newServerStream(attestor nodeattestor.NodeAttestor) *serverStream {
+	return &serverStream{
+		attestor:   attestor,
+		challenges: make(chan []byte),
+		responses:  make(chan []byte),
+		done:       make(chan error, 1),
+	}
+}
+
+func (s *serverStream) SendAttestationData(ctx context.Context, attestationData agentnodeattestor.AttestationData) ([]byte, error) {
+	go func() {
+		result, err := s.attestor.Attest(ctx, attestationData.Payload, func(ctx context.Context, challenge []byte) ([]byte, error) {
+			s.challenges <- challenge
+			return <-s.responses, nil
+		})
+		s.result = result
+		s.done <- err
+	}()
+	return s.next()
+}
+
+func (s *serverStream) SendChallengeResponse(ctx context.Context, response []byte) ([]byte, error) {
+	s.responses <- response
+	return s.next()
+}
+
+func (s *serverStream) next() ([]byte, error) {
+	select {
+	case challenge := <-s.challenges:
+		return challenge, nil
+	case err := <-s.done:
+		return nil, err
+	}
+}
@@ -0,0 +1,162 @@
+package jwtoidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/hashicorp/go-hclog"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	// minRefreshInterval limits how often the JWKS is refreshed when a token
+	// is signed by an unknown key, so that tokens with made up key IDs cannot
+	// be used to hammer the issuer.
+	minRefreshInterval = time.Minute
+
+	// maxResponseSize limits the size of the discovery and JWKS documents
+	maxResponseSize = 1 << 20
+)
+
+type jwksProvider interface {
+	// getJWKS returns the key set used to verify tokens. The kid is the key
+	// ID of the token being verified, used to detect key rotations.
+	getJWKS(ctx context.Context, kid string) (*jose.JSONWebKeySet, error)
+}
+
+// staticJWKS is a key set loaded from a file.
+type staticJWKS struct {
+	jwks *jose.JSONWebKeySet
+}
+
+func (s staticJWKS) getJWKS(context.Context, string) (*jose.JSONWebKeySet, error) {
+	return s.jwks, nil
+}
+
+// remoteJWKS fetches the key set of an issuer and caches it. If no JWKS URL
+// is configured, it is discovered using the OpenID Connect discovery document
+// of the issuer.
+type remoteJWKS struct {
+	issuer     string
+	cacheTTL   time.Duration
+	httpClient *http.Client
+	clock      clock.Clock
+	log        hclog.Logger
+
+	mtx       sync.Mutex
+	jwksURL   string
+	jwks      *jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+func newRemoteJWKS(issuer, jwksURL string, cacheTTL time.Duration, clk clock.Clock, log hclog.Logger) *remoteJWKS {
+	return &remoteJWKS{
+		issuer:   issuer,
+		jwksURL:  jwksURL,
+		cacheTTL: cacheTTL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		clock: clk,
+		log:   log,
+	}
+}
+
+func (r *remoteJWKS) getJWKS(ctx context.Context, kid string) (*jose.JSONWebKeySet, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := r.clock.Now()
+	if r.jwks != nil {
+		fresh := now.Sub(r.fetchedAt) < r.cacheTTL
+		known := kid == "" || len(r.jwks.Key(kid)) > 0
+		switch {
+		case fresh && known:
+			return r.jwks, nil
+		case fresh && now.Sub(r.fetchedAt) < minRefreshInterval:
+			// The key is unknown but the key set was just refreshed
+			return r.jwks, nil
+		}
+	}
+
+	jwks, err := r.fetchJWKS(ctx)
+	if err != nil {
+		if r.jwks == nil {
+			return nil, err
+		}
+		// Keep using the cached keys until the issuer is reachable again
+		r.log.Warn("Failed to refresh JWKS; using cached keys", telemetry.Error, err)
+		return r.jwks, nil
+	}
+
+	r.jwks = jwks
+	r.fetchedAt = now
+	return r.jwks, nil
+}
+
+func (r *remoteJWKS) fetchJWKS(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	jwksURL := r.jwksURL
+	if jwksURL == "" {
+		var err error
+		jwksURL, err = r.discoverJWKSURL(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	jwks := new(jose.JSONWebKeySet)
+	if err := r.getJSON(ctx, jwksURL, jwks); err != nil {
+		return nil, fmt.Errorf("unable to fetch JWKS: %w", err)
+	}
+	return jwks, nil
+}
+
+func (r *remoteJWKS) discoverJWKSURL(ctx context.Context) (string, error) {
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := r.getJSON(ctx, strings.TrimSuffix(r.issuer, "/")+discoveryPath, &doc); err != nil {
+		return "", fmt.Errorf("unable to fetch OpenID configuration: %w", err)
+	}
+	if doc.Issuer != r.issuer {
+		return "", fmt.Errorf("OpenID configuration issuer %q does not match %q", doc.Issuer, r.issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", errors.New("OpenID configuration is missing jwks_uri")
+	}
+	return doc.JWKSURI, nil
+}
+
+func (r *remoteJWKS) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+package jwtoidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/plugin/jwtoidc"
+	nodeattestorbase "github.com/spiffe/spire/pkg/server/plugin/nodeattestor/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	defaultAudience     = "spire-server"
+	defaultJWKSCacheTTL = time.Hour
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *AttestorPlugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(jwtoidc.PluginName,
+		nodeattestorv1.NodeAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+// AttestorConfig is the configuration of the jwt_oidc server node attestor.
+type AttestorConfig struct {
+	// Issuer is the expected "iss" claim of the tokens.
+	Issuer string `hcl:"issuer"`
+	// JWKSURL is the URL of the key set of the issuer. If neither JWKSURL nor
+	// JWKSPath are set, the URL is discovered from the OpenID configuration
+	// of the issuer.
+	JWKSURL string `hcl:"jwks_url"`
+	// JWKSPath is the path to a file containing the key set of the issuer.
+	JWKSPath string `hcl:"jwks_path"`
+	// JWKSCacheTTL is how long the fetched key set is cached for.
+	JWKSCacheTTL string `hcl:"jwks_cache_ttl"`
+	// Audience is the list of accepted audiences. Tokens must contain at
+	// least one of them. Defaults to ["spire-server"].
+	Audience []string `hcl:"audience"`
+	// ClaimAllowList maps claim names to the values allowed for them. Tokens
+	// must satisfy every entry.
+	ClaimAllowList map[string][]string `hcl:"claim_allow_list"`
+	// SelectorClaims is the list of claims that are turned into selectors.
+	SelectorClaims []string `hcl:"selector_claims"`
+	// AgentPathTemplate is the template used to build the agent ID path.
+	AgentPathTemplate string `hcl:"agent_path_template"`
+}
+
+type attestorConfig struct {
+	trustDomain       string
+	issuer            string
+	audience          []string
+	claimAllowList    map[string][]string
+	selectorClaims    []string
+	agentPathTemplate *template.Template
+	jwks              jwksProvider
+}
+
+// AttestorPlugin implements node attestation for agents presenting a JWT
+// issued by an OpenID Connect provider.
+type AttestorPlugin struct {
+	nodeattestorbase.Base
+	nodeattestorv1.UnsafeNodeAttestorServer
+	configv1.UnsafeConfigServer
+
+	log    hclog.Logger
+	clock  clock.Clock
+	mu     sync.RWMutex
+	config *attestorConfig
+}
+
+func New() *AttestorPlugin {
+	return &AttestorPlugin{
+		clock: clock.New(),
+	}
+}
+
+func (p *AttestorPlugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *AttestorPlugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	config, err := p.getConfig()
+	if err != nil {
+		return err
+	}
+
+	payload := req.GetPayload()
+	if payload == nil {
+		return status.Error(codes.InvalidArgument, "missing attestation payload")
+	}
+
+	attestationData := new(jwtoidc.AttestationData)
+	if err := json.Unmarshal(payload, attestationData); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to unmarshal data: %v", err)
+	}
+	if attestationData.Token == "" {
+		return status.Error(codes.InvalidArgument, "missing token from attestation data")
+	}
+
+	token, err := jwt.ParseSigned(attestationData.Token)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "unable to parse token: %v", err)
+	}
+
+	var kid string
+	if len(token.Headers) > 0 {
+		kid = token.Headers[0].KeyID
+	}
+	jwks, err := config.jwks.getJWKS(stream.Context(), kid)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to obtain JWKS: %v", err)
+	}
+
+	standardClaims := new(jwt.Claims)
+	claims := make(map[string]interface{})
+	if err := token.Claims(jwks, standardClaims, &claims); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to validate the token signature: %v", err)
+	}
+
+	if err := p.validateClaims(config, standardClaims, claims); err != nil {
+		return err
+	}
+
+	agentID, err := jwtoidc.MakeAgentID(config.trustDomain, config.agentPathTemplate, jwtoidc.AgentPathTemplateData{
+		Issuer:     standardClaims.Issuer,
+		IssuerHost: issuerHost(standardClaims.Issuer),
+		Subject:    standardClaims.Subject,
+		TokenID:    standardClaims.ID,
+		Claims:     claims,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to create agent ID: %v", err)
+	}
+
+	attested, err := p.IsAttested(stream.Context(), agentID.String())
+	switch {
+	case err != nil:
+		return err
+	case attested:
+		return status.Error(codes.PermissionDenied, "token has already been used to attest an agent")
+	}
+
+	return stream.Send(&nodeattestorv1.AttestResponse{
+		Response: &nodeattestorv1.AttestResponse_AgentAttributes{
+			AgentAttributes: &nodeattestorv1.AgentAttributes{
+				SpiffeId:       agentID.String(),
+				SelectorValues: buildSelectorValues(config, standardClaims, claims),
+			},
+		},
+	})
+}
+
+func (p *AttestorPlugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	hclConfig := new(AttestorConfig)
+	if err := hcl.Decode(hclConfig, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+	if req.CoreConfiguration == nil {
+		return nil, status.Error(codes.InvalidArgument, "core configuration is required")
+	}
+	if req.CoreConfiguration.TrustDomain == "" {
+		return nil, status.Error(codes.InvalidArgument, "core configuration missing trust domain")
+	}
+
+	if hclConfig.Issuer == "" {
+		return nil, status.Error(codes.InvalidArgument, "issuer is required")
+	}
+	if hclConfig.JWKSURL != "" && hclConfig.JWKSPath != "" {
+		return nil, status.Error(codes.InvalidArgument, "jwks_url and jwks_path are mutually exclusive")
+	}
+
+	config := &attestorConfig{
+		trustDomain:       req.CoreConfiguration.TrustDomain,
+		issuer:            hclConfig.Issuer,
+		audience:          hclConfig.Audience,
+		claimAllowList:    hclConfig.ClaimAllowList,
+		selectorClaims:    hclConfig.SelectorClaims,
+		agentPathTemplate: jwtoidc.DefaultAgentPathTemplate,
+	}
+	if len(config.audience) == 0 {
+		config.audience = []string{defaultAudience}
+	}
+	for name, values := range config.claimAllowList {
+		if len(values) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "claim_allow_list entry %q has no allowed values", name)
+		}
+	}
+
+	if hclConfig.AgentPathTemplate != "" {
+		tmpl, err := template.New("agent-path").Parse(hclConfig.AgentPathTemplate)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to parse agent path template: %q", hclConfig.AgentPathTemplate)
+		}
+		config.agentPathTemplate = tmpl
+	}
+
+	if hclConfig.JWKSPath != "" {
+		jwks, err := loadJWKS(hclConfig.JWKSPath)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unable to load JWKS from %q: %v", hclConfig.JWKSPath, err)
+		}
+		config.jwks = staticJWKS{jwks: jwks}
+	} else {
+		issuerURL, err := url.Parse(hclConfig.Issuer)
+		if err != nil || (issuerURL.Scheme != "https" && issuerURL.Scheme != "http") || issuerURL.Host == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "issuer %q must be an HTTP(S) URL when jwks_path is not set", hclConfig.Issuer)
+		}
+
+		cacheTTL := defaultJWKSCacheTTL
+		if hclConfig.JWKSCacheTTL != "" {
+			cacheTTL, err = time.ParseDuration(hclConfig.JWKSCacheTTL)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "unable to parse jwks_cache_ttl: %v", err)
+			}
+			if cacheTTL <= 0 {
+				return nil, status.Error(codes.InvalidArgument, "jwks_cache_ttl must be positive")
+			}
+		}
+		config.jwks = newRemoteJWKS(hclConfig.Issuer, hclConfig.JWKSURL, cacheTTL, p.clock, p.log)
+	}
+
+	p.setConfig(config)
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *AttestorPlugin) getConfig() (*attestorConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+func (p *AttestorPlugin) setConfig(config *attestorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (p *AttestorPlugin) validateClaims(config *attestorConfig, standardClaims *jwt.Claims, claims map[string]interface{}) error {
+	if standardClaims.Expiry == nil {
+		return status.Error(codes.PermissionDenied, "token is missing the exp claim")
+	}
+	if err := standardClaims.ValidateWithLeeway(jwt.Expected{
+		Issuer: config.issuer,
+		Time:   p.clock.Now(),
+	}, jwt.DefaultLeeway); err != nil {
+		return status.Errorf(codes.PermissionDenied, "failed to validate the token claims: %v", err)
+	}
+
+	// Expected.Audience requires every audience to be present in the token,
+	// while any one of the configured audiences is enough here.
+	audienceMatches := false
+	for _, audience := range config.audience {
+		if standardClaims.Audience.Contains(audience) {
+			audienceMatches = true
+			break
+		}
+	}
+	if !audienceMatches {
+		return status.Errorf(codes.PermissionDenied, "token audience %q does not contain any of %q", standardClaims.Audience, config.audience)
+	}
+
+	for name, allowed := range config.claimAllowList {
+		value, ok := claims[name]
+		if !ok {
+			return status.Errorf(codes.PermissionDenied, "token is missing the %q claim", name)
+		}
+		if !claimValueAllowed(claimValues(value), allowed) {
+			return status.Errorf(codes.PermissionDenied, "token %q claim is not in the allow list", name)
+		}
+	}
+	return nil
+}
+
+func claimValueAllowed(values, allowed []string) bool {
+	for _, value := range values {
+		for _, a := range allowed {
+			if value == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimValues flattens a claim into string values. Arrays yield one value per
+// element. Objects are not supported and yield no values.
+func claimValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case []interface{}:
+		var values []string
+		for _, elem := range v {
+			if _, ok := elem.([]interface{}); ok {
+				continue
+			}
+			values = append(values, claimValues(elem)...)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func buildSelectorValues(config *attestorConfig, standardClaims *jwt.Claims, claims map[string]interface{}) []string {
+	selectorValues := []string{
+		makeSelectorValue("issuer", standardClaims.Issuer),
+	}
+	if standardClaims.Subject != "" {
+		selectorValues = append(selectorValues, makeSelectorValue("subject", standardClaims.Subject))
+	}
+
+	var claimSelectors []string
+	for _, name := range config.selectorClaims {
+		for _, value := range claimValues(claims[name]) {
+			claimSelectors = append(claimSelectors, makeSelectorValue("claim", name, value))
+		}
+	}
+	sort.Strings(claimSelectors)
+	return append(selectorValues, claimSelectors...)
+}
+
+func issuerHost(issuer string) string {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Hostname() == "" {
+		return issuer
+	}
+	return u.Hostname()
+}
+
+func loadJWKS(path string) (*jose.JSONWebKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	jwks := new(jose.JSONWebKeySet)
+	if err := json.Unmarshal(data, jwks); err != nil {
+		return nil, fmt.Errorf("unable to parse JWKS: %w", err)
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no keys")
+	}
+	return jwks, nil
+}
+
+func makeSelectorValue(kind string, values ...string) string {
+	return fmt.Sprintf("%s:%s", kind, strings.Join(values, ":"))
+}
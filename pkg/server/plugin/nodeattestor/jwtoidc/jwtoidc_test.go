@@ -0,0 +1,571 @@
+package jwtoidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	agentstorev1 "github.com/spiffe/spire-plugin-sdk/proto/spire/hostservice/server/agentstore/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/plugin/jwtoidc"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/fakes/fakeagentstore"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	testKey      = testkey.MustEC256()
+	otherTestKey = testkey.MustEC256()
+)
+
+func TestAttest(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	defaultClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":          "", // set to the issuer URL by the test
+			"sub":          "repo:org/project:ref:refs/heads/main",
+			"aud":          []string{"spire-server"},
+			"jti":          "token-1",
+			"exp":          now.Add(time.Minute).Unix(),
+			"iat":          now.Unix(),
+			"repository":   "org/project",
+			"environments": []string{"prod", "staging"},
+			"run_number":   42,
+		}
+	}
+
+	for _, tt := range []struct {
+		name          string
+		config        string
+		key           crypto.Signer
+		kid           string
+		alterClaims   func(claims map[string]interface{})
+		attested      bool
+		payload       []byte
+		expectCode    codes.Code
+		expectMsg     string
+		expectID      string
+		expectSelects []string
+	}{
+		{
+			name:     "success with defaults",
+			expectID: "spiffe://example.org/spire/agent/jwt_oidc/ISSUER_HOST/token-1",
+			expectSelects: []string{
+				"issuer:ISSUER",
+				"subject:repo:org/project:ref:refs/heads/main",
+			},
+		},
+		{
+			name: "success with claim selectors and template",
+			config: `
+				claim_allow_list = {
+					repository = ["org/other", "org/project"]
+					environments = ["prod"]
+				}
+				selector_claims = ["repository", "environments", "run_number", "missing"]
+				agent_path_template = "{{ .PluginName }}/{{ index .Claims \"repository\" }}/{{ .TokenID }}"
+			`,
+			expectID: "spiffe://example.org/spire/agent/jwt_oidc/org/project/token-1",
+			expectSelects: []string{
+				"issuer:ISSUER",
+				"subject:repo:org/project:ref:refs/heads/main",
+				"claim:environments:prod",
+				"claim:environments:staging",
+				"claim:repository:org/project",
+				"claim:run_number:42",
+			},
+		},
+		{
+			name:     "success with one of many audiences",
+			config:   `audience = ["other", "spire-server"]`,
+			expectID: "spiffe://example.org/spire/agent/jwt_oidc/ISSUER_HOST/token-1",
+			expectSelects: []string{
+				"issuer:ISSUER",
+				"subject:repo:org/project:ref:refs/heads/main",
+			},
+		},
+		{
+			name:       "malformed payload",
+			payload:    []byte("{"),
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "failed to unmarshal data",
+		},
+		{
+			name:       "missing token",
+			payload:    []byte("{}"),
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "missing token from attestation data",
+		},
+		{
+			name:       "malformed token",
+			payload:    []byte(`{"token": "not-a-token"}`),
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "unable to parse token",
+		},
+		{
+			name:       "unknown signing key",
+			key:        otherTestKey,
+			kid:        "other",
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "failed to validate the token signature",
+		},
+		{
+			name:        "issuer mismatch",
+			alterClaims: func(claims map[string]interface{}) { claims["iss"] = "https://evil.example" },
+			expectCode:  codes.PermissionDenied,
+			expectMsg:   "failed to validate the token claims: square/go-jose/jwt: validation failed, invalid issuer claim (iss)",
+		},
+		{
+			name:        "expired",
+			alterClaims: func(claims map[string]interface{}) { claims["exp"] = now.Add(-time.Hour).Unix() },
+			expectCode:  codes.PermissionDenied,
+			expectMsg:   "failed to validate the token claims: square/go-jose/jwt: validation failed, token is expired (exp)",
+		},
+		{
+			name:        "missing expiry",
+			alterClaims: func(claims map[string]interface{}) { delete(claims, "exp") },
+			expectCode:  codes.PermissionDenied,
+			expectMsg:   "token is missing the exp claim",
+		},
+		{
+			name:        "audience mismatch",
+			alterClaims: func(claims map[string]interface{}) { claims["aud"] = "other" },
+			expectCode:  codes.PermissionDenied,
+			expectMsg:   `token audience ["other"] does not contain any of ["spire-server"]`,
+		},
+		{
+			name:       "claim not allowed",
+			config:     `claim_allow_list = { repository = ["org/other"] }`,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  `token "repository" claim is not in the allow list`,
+		},
+		{
+			name:       "allow listed claim missing",
+			config:     `claim_allow_list = { workflow = ["deploy"] }`,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  `token is missing the "workflow" claim`,
+		},
+		{
+			name:       "template renders empty segment",
+			config:     `agent_path_template = "{{ .PluginName }}/{{ index .Claims \"workflow\" }}"`,
+			expectCode: codes.Internal,
+			expectMsg:  "failed to create agent ID: agent path template rendered an empty path segment",
+		},
+		{
+			name:       "already attested",
+			attested:   true,
+			expectCode: codes.PermissionDenied,
+			expectMsg:  "token has already been used to attest an agent",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newFakeIssuer(t, testKey)
+
+			agentStore := fakeagentstore.New()
+			if tt.attested {
+				agentStore.SetAgentInfo(&agentstorev1.AgentInfo{
+					AgentId: fmt.Sprintf("spiffe://example.org/spire/agent/jwt_oidc/%s/token-1", issuer.host()),
+				})
+			}
+			attestor := loadPlugin(t, agentStore, clock.NewMock(t), fmt.Sprintf("issuer = %q\n%s", issuer.url(), tt.config))
+
+			payload := tt.payload
+			if payload == nil {
+				claims := defaultClaims()
+				claims["iss"] = issuer.url()
+				if tt.alterClaims != nil {
+					tt.alterClaims(claims)
+				}
+				key, kid := tt.key, tt.kid
+				if key == nil {
+					key, kid = testKey, "test"
+				}
+				payload = makePayload(t, signToken(t, key, kid, claims))
+			}
+
+			result, err := attestor.Attest(context.Background(), payload, expectNoChallenge)
+			if tt.expectCode != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.expectCode, tt.expectMsg)
+				require.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, result)
+
+			var expectSelectors []*common.Selector
+			for _, value := range tt.expectSelects {
+				expectSelectors = append(expectSelectors, &common.Selector{
+					Type:  jwtoidc.PluginName,
+					Value: issuer.expand(value),
+				})
+			}
+			assert.Equal(t, issuer.expand(tt.expectID), result.AgentID)
+			spiretest.AssertProtoListEqual(t, expectSelectors, result.Selectors)
+		})
+	}
+}
+
+func TestAttestWithJWKSPath(t *testing.T) {
+	jwksPath := filepath.Join(spiretest.TempDir(t), "jwks.json")
+	writeJWKS(t, jwksPath, testKey)
+
+	attestor := loadPlugin(t, fakeagentstore.New(), clock.NewMock(t), fmt.Sprintf(`
+		issuer = "spiffe-ci"
+		jwks_path = %q
+		agent_path_template = "{{ .PluginName }}/{{ .Subject }}"
+	`, jwksPath))
+
+	token := signToken(t, testKey, "test", map[string]interface{}{
+		"iss": "spiffe-ci",
+		"sub": "runner-1",
+		"aud": "spire-server",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	result, err := attestor.Attest(context.Background(), makePayload(t, token), expectNoChallenge)
+	require.NoError(t, err)
+	assert.Equal(t, "spiffe://example.org/spire/agent/jwt_oidc/runner-1", result.AgentID)
+}
+
+func TestJWKSCache(t *testing.T) {
+	clk := clock.NewMock(t)
+	issuer := newFakeIssuer(t, testKey)
+	attestor := loadPlugin(t, fakeagentstore.New(), clk, fmt.Sprintf(`
+		issuer = %q
+		jwks_cache_ttl = "10m"
+		agent_path_template = "{{ .PluginName }}/{{ .TokenID }}"
+	`, issuer.url()))
+
+	attest := func(key crypto.Signer, kid, jti string) error {
+		token := signToken(t, key, kid, map[string]interface{}{
+			"iss": issuer.url(),
+			"aud": "spire-server",
+			"jti": jti,
+			"exp": clk.Now().Add(time.Minute).Unix(),
+		})
+		_, err := attestor.Attest(context.Background(), makePayload(t, token), expectNoChallenge)
+		return err
+	}
+
+	// The first attestation discovers and fetches the JWKS
+	require.NoError(t, attest(testKey, "test", "1"))
+	assert.Equal(t, 1, issuer.discoveryRequests())
+	assert.Equal(t, 1, issuer.jwksRequests())
+
+	// The JWKS is cached
+	require.NoError(t, attest(testKey, "test", "2"))
+	assert.Equal(t, 1, issuer.jwksRequests())
+
+	// The issuer rotates keys. A token signed by the new key is not verified
+	// until the minimum refresh interval has passed.
+	issuer.setKey(otherTestKey, "other")
+	require.Error(t, attest(otherTestKey, "other", "3"))
+	assert.Equal(t, 1, issuer.jwksRequests())
+
+	clk.Add(minRefreshInterval)
+	require.NoError(t, attest(otherTestKey, "other", "4"))
+	assert.Equal(t, 2, issuer.jwksRequests())
+
+	// The JWKS is refreshed once the cache TTL expires. If the issuer is
+	// unavailable, the cached keys are used.
+	issuer.setUnavailable(true)
+	clk.Add(10 * time.Minute)
+	require.NoError(t, attest(otherTestKey, "other", "5"))
+	assert.Equal(t, 3, issuer.discoveryRequests())
+	assert.Equal(t, 2, issuer.jwksRequests())
+}
+
+func TestJWKSUnavailable(t *testing.T) {
+	issuer := newFakeIssuer(t, testKey)
+	issuer.setUnavailable(true)
+	attestor := loadPlugin(t, fakeagentstore.New(), clock.NewMock(t), fmt.Sprintf("issuer = %q", issuer.url()))
+
+	token := signToken(t, testKey, "test", map[string]interface{}{
+		"iss": issuer.url(),
+		"aud": "spire-server",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	_, err := attestor.Attest(context.Background(), makePayload(t, token), expectNoChallenge)
+	spiretest.RequireGRPCStatus(t, err, codes.Internal, "nodeattestor(jwt_oidc): unable to obtain JWKS: unable to fetch OpenID configuration: unexpected status code: 503")
+}
+
+func TestConfigure(t *testing.T) {
+	jwksPath := filepath.Join(spiretest.TempDir(t), "jwks.json")
+	writeJWKS(t, jwksPath, testKey)
+
+	for _, tt := range []struct {
+		name       string
+		config     string
+		noTD       bool
+		expectCode codes.Code
+		expectMsg  string
+	}{
+		{
+			name:   "discovery",
+			config: `issuer = "https://token.example.org"`,
+		},
+		{
+			name:   "jwks_url",
+			config: `issuer = "https://token.example.org" jwks_url = "https://token.example.org/keys"`,
+		},
+		{
+			name:   "jwks_path",
+			config: fmt.Sprintf(`issuer = "ci" jwks_path = %q`, jwksPath),
+		},
+		{
+			name:       "malformed",
+			config:     `[[[`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "unable to decode configuration",
+		},
+		{
+			name:       "missing trust domain",
+			config:     `issuer = "https://token.example.org"`,
+			noTD:       true,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "core configuration missing trust domain",
+		},
+		{
+			name:       "missing issuer",
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "issuer is required",
+		},
+		{
+			name:       "issuer not a URL",
+			config:     `issuer = "ci"`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `issuer "ci" must be an HTTP(S) URL when jwks_path is not set`,
+		},
+		{
+			name:       "jwks_url and jwks_path",
+			config:     fmt.Sprintf(`issuer = "https://token.example.org" jwks_url = "https://token.example.org/keys" jwks_path = %q`, jwksPath),
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "jwks_url and jwks_path are mutually exclusive",
+		},
+		{
+			name:       "jwks_path does not exist",
+			config:     `issuer = "ci" jwks_path = "/does/not/exist.json"`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `unable to load JWKS from "/does/not/exist.json"`,
+		},
+		{
+			name:       "invalid jwks_cache_ttl",
+			config:     `issuer = "https://token.example.org" jwks_cache_ttl = "soon"`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "unable to parse jwks_cache_ttl",
+		},
+		{
+			name:       "empty claim allow list entry",
+			config:     `issuer = "https://token.example.org" claim_allow_list = { repository = [] }`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  `claim_allow_list entry "repository" has no allowed values`,
+		},
+		{
+			name:       "invalid agent path template",
+			config:     `issuer = "https://token.example.org" agent_path_template = "{{ .TokenID "`,
+			expectCode: codes.InvalidArgument,
+			expectMsg:  "failed to parse agent path template",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			coreConfig := catalog.CoreConfig{
+				TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+			}
+			if tt.noTD {
+				coreConfig = catalog.CoreConfig{}
+			}
+
+			var err error
+			plugintest.Load(t, BuiltIn(), nil,
+				plugintest.CaptureConfigureError(&err),
+				plugintest.CoreConfig(coreConfig),
+				plugintest.HostServices(agentstorev1.AgentStoreServiceServer(fakeagentstore.New())),
+				plugintest.Configure(tt.config),
+			)
+			if tt.expectCode != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.expectCode, tt.expectMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func loadPlugin(t *testing.T, agentStore *fakeagentstore.AgentStore, clk *clock.Mock, config string) nodeattestor.NodeAttestor {
+	p := New()
+	p.clock = clk
+
+	v1 := new(nodeattestor.V1)
+	plugintest.Load(t, builtin(p), v1,
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+		plugintest.HostServices(agentstorev1.AgentStoreServiceServer(agentStore)),
+		plugintest.Configure(config),
+	)
+	return v1
+}
+
+func signToken(t *testing.T, key crypto.Signer, kid string, claims interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   key,
+			KeyID: kid,
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func makePayload(t *testing.T, token string) []byte {
+	payload, err := json.Marshal(jwtoidc.AttestationData{Token: token})
+	require.NoError(t, err)
+	return payload
+}
+
+func makeJWKS(key crypto.Signer, kid string) *jose.JSONWebKeySet {
+	return &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       key.Public(),
+				KeyID:     kid,
+				Algorithm: string(jose.ES256),
+				Use:       "sig",
+			},
+		},
+	}
+}
+
+func writeJWKS(t *testing.T, path string, key crypto.Signer) {
+	data, err := json.Marshal(makeJWKS(key, "test"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+}
+
+func expectNoChallenge(context.Context, []byte) ([]byte, error) {
+	return nil, fmt.Errorf("challenge is not expected")
+}
+
+// fakeIssuer serves the OpenID configuration and JWKS of an issuer.
+type fakeIssuer struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	jwks          *jose.JSONWebKeySet
+	unavailable   bool
+	discoveryReqs int
+	jwksReqs      int
+}
+
+func newFakeIssuer(t *testing.T, key crypto.Signer) *fakeIssuer {
+	issuer := &fakeIssuer{
+		jwks: makeJWKS(key, "test"),
+	}
+	issuer.server = httptest.NewServer(http.HandlerFunc(issuer.serveHTTP))
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *fakeIssuer) url() string {
+	return i.server.URL
+}
+
+func (i *fakeIssuer) host() string {
+	u, err := url.Parse(i.server.URL)
+	if err != nil {
+		panic(err)
+	}
+	return u.Hostname()
+}
+
+// expand replaces the ISSUER and ISSUER_HOST placeholders in expectations.
+func (i *fakeIssuer) expand(s string) string {
+	switch {
+	case s == "issuer:ISSUER":
+		return "issuer:" + i.url()
+	default:
+		return strings.ReplaceAll(s, "ISSUER_HOST", i.host())
+	}
+}
+
+func (i *fakeIssuer) setKey(key crypto.Signer, kid string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.jwks = makeJWKS(key, kid)
+}
+
+func (i *fakeIssuer) setUnavailable(unavailable bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.unavailable = unavailable
+}
+
+func (i *fakeIssuer) discoveryRequests() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.discoveryReqs
+}
+
+func (i *fakeIssuer) jwksRequests() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.jwksReqs
+}
+
+func (i *fakeIssuer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	switch r.URL.Path {
+	case discoveryPath:
+		i.discoveryReqs++
+	case "/keys":
+		i.jwksReqs++
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if i.unavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var body interface{}
+	if r.URL.Path == discoveryPath {
+		body = map[string]string{
+			"issuer":   i.url(),
+			"jwks_uri": i.url() + "/keys",
+		}
+	} else {
+		body = i.jwks
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -17,3 +17,22 @@ type AttestResult struct {
 	AgentID   string
 	Selectors []*common.Selector
 }
+
+// nonReattestable lists the built-in node attestor types that cannot be used
+// to re-attest, either because they only allow an agent to be attested once
+// or because they produce a new agent ID on every attestation.
+var nonReattestable = map[string]bool{
+	"aws_iid":        true,
+	"azure_msi":      true,
+	"gcp_iit":        true,
+	"http_challenge": true,
+	"join_token":     true,
+	"jwt_oidc":       true,
+	"k8s_sat":        true,
+}
+
+// SupportsReattestation returns whether agents attested with the given node
+// attestor type are able to re-attest.
+func SupportsReattestation(nodeAttestorType string) bool {
+	return !nonReattestable[nodeAttestorType]
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"sync"
 
+	"github.com/hashicorp/go-hclog"
 	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/nodeattestor/v1"
 	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/plugin/sshpop"
+	"github.com/spiffe/spire/pkg/common/telemetry"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -16,6 +18,8 @@ type Plugin struct {
 	nodeattestorv1.UnsafeNodeAttestorServer
 	configv1.UnsafeConfigServer
 
+	log hclog.Logger
+
 	mu        sync.RWMutex
 	sshserver *sshpop.Server
 }
@@ -35,6 +39,10 @@ func New() *Plugin {
 	return &Plugin{}
 }
 
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
 func (p *Plugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -53,6 +61,19 @@ func (p *Plugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
 		return status.Error(codes.InvalidArgument, "missing attestation payload")
 	}
 
+	switch reloaded, err := p.sshserver.ReloadCertAuthorities(); {
+	case err != nil:
+		p.log.Error("Invalid cert authorities update; keeping the previous cert authorities", telemetry.Error, err)
+	case reloaded:
+		p.log.Info("Reloaded cert authorities")
+	}
+	switch reloaded, err := p.sshserver.ReloadKRL(); {
+	case err != nil:
+		p.log.Error("Invalid KRL update; keeping the previous KRL", telemetry.Error, err)
+	case reloaded:
+		p.log.Info("Reloaded KRL")
+	}
+
 	handshaker := p.sshserver.NewHandshake()
 	if err := handshaker.VerifyAttestationData(payload); err != nil {
 		return err
@@ -87,7 +108,8 @@ func (p *Plugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
 	return stream.Send(&nodeattestorv1.AttestResponse{
 		Response: &nodeattestorv1.AttestResponse_AgentAttributes{
 			AgentAttributes: &nodeattestorv1.AgentAttributes{
-				SpiffeId: agentID,
+				SpiffeId:       agentID,
+				SelectorValues: handshaker.Selectors(),
 			},
 		},
 	})
@@ -5,16 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/plugin/sshpop"
 	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
+	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/fixture"
 	"github.com/spiffe/spire/test/plugintest"
 	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testkey"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 	"google.golang.org/grpc/codes"
 )
 
@@ -84,7 +90,10 @@ func (s *Suite) TestAttestSuccess() {
 	// receive the attestation result
 	require.NoError(s.T(), err)
 	require.Equal(s.T(), "spiffe://example.org/spire/agent/sshpop/21Aic_muK032oJMhLfU1_CMNcGmfAnvESeuH5zyFw_g", result.AgentID)
-	require.Len(s.T(), result.Selectors, 0)
+	spiretest.AssertProtoListEqual(s.T(), []*common.Selector{
+		{Type: "sshpop", Value: "principal:foo-host"},
+		{Type: "sshpop", Value: "key_id:foo-host"},
+	}, result.Selectors)
 }
 
 func (s *Suite) TestAttestFailure() {
@@ -138,6 +147,109 @@ func (s *Suite) TestAttestFailure() {
 	})
 }
 
+func (s *Suite) TestReloadCertAuthorities() {
+	t := s.T()
+
+	certAuthority, err := os.ReadFile(fixture.Join("nodeattestor", "sshpop", "ssh_cert_authority.pub"))
+	require.NoError(t, err)
+	otherKey, err := ssh.NewPublicKey(testkey.NewEC256(t).Public())
+	require.NoError(t, err)
+	otherCertAuthority := ssh.MarshalAuthorizedKey(otherKey)
+
+	certAuthoritiesPath := filepath.Join(t.TempDir(), "cert_authorities.pub")
+	writeCertAuthorities := func(data []byte) {
+		require.NoError(t, os.WriteFile(certAuthoritiesPath+".tmp", data, 0600))
+		require.NoError(t, os.Rename(certAuthoritiesPath+".tmp", certAuthoritiesPath))
+	}
+	writeCertAuthorities(otherCertAuthority)
+
+	log, logHook := test.NewNullLogger()
+	attestor := new(nodeattestor.V1)
+	plugintest.Load(t, BuiltIn(), attestor,
+		plugintest.Log(log),
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+		plugintest.Configure(fmt.Sprintf(`cert_authorities_path = %q`, certAuthoritiesPath)),
+	)
+
+	attest := func() error {
+		client := s.sshclient.NewHandshake()
+		attestationData, err := client.AttestationData()
+		require.NoError(t, err)
+		_, err = attestor.Attest(context.Background(), attestationData, func(ctx context.Context, challenge []byte) ([]byte, error) {
+			return client.RespondToChallenge(challenge)
+		})
+		return err
+	}
+
+	spiretest.RequireGRPCStatusContains(t, attest(), codes.Internal, "failed to check host key")
+
+	// Updated cert authorities take effect without reconfiguring
+	writeCertAuthorities(certAuthority)
+	require.NoError(t, attest())
+	require.Equal(t, "Reloaded cert authorities", logHook.LastEntry().Message)
+
+	// An invalid update is reported and the previous cert authorities are kept
+	writeCertAuthorities([]byte("not a public key\n"))
+	require.NoError(t, attest())
+	entry := logHook.LastEntry()
+	require.Equal(t, logrus.ErrorLevel, entry.Level)
+	require.Equal(t, "Invalid cert authorities update; keeping the previous cert authorities", entry.Message)
+}
+
+func (s *Suite) TestKRL() {
+	t := s.T()
+
+	certAuthority, err := os.ReadFile(fixture.Join("nodeattestor", "sshpop", "ssh_cert_authority.pub"))
+	require.NoError(t, err)
+
+	krlPath := filepath.Join(t.TempDir(), "krl")
+	writeKRL := func(name string) {
+		data, err := os.ReadFile(fixture.Join("nodeattestor", "sshpop", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(krlPath+".tmp", data, 0600))
+		require.NoError(t, os.Rename(krlPath+".tmp", krlPath))
+	}
+	writeKRL("krl_empty")
+
+	log, logHook := test.NewNullLogger()
+	attestor := new(nodeattestor.V1)
+	plugintest.Load(t, BuiltIn(), attestor,
+		plugintest.Log(log),
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+		plugintest.Configure(fmt.Sprintf(`
+			cert_authorities = [%q]
+			krl_path = %q`, certAuthority, krlPath)),
+	)
+
+	attest := func() error {
+		client := s.sshclient.NewHandshake()
+		attestationData, err := client.AttestationData()
+		require.NoError(t, err)
+		_, err = attestor.Attest(context.Background(), attestationData, func(ctx context.Context, challenge []byte) ([]byte, error) {
+			return client.RespondToChallenge(challenge)
+		})
+		return err
+	}
+
+	require.NoError(t, attest())
+
+	// The KRL is reloaded when it changes
+	writeKRL("krl_key_id")
+	spiretest.RequireGRPCStatus(t, attest(), codes.PermissionDenied, "nodeattestor(sshpop): host certificate has been revoked")
+	require.Equal(t, "Reloaded KRL", logHook.LastEntry().Message)
+
+	// An invalid update is reported and the previous KRL is kept
+	writeKRL("ssh_cert_authority.pub")
+	spiretest.RequireGRPCStatus(t, attest(), codes.PermissionDenied, "nodeattestor(sshpop): host certificate has been revoked")
+	entry := logHook.LastEntry()
+	require.Equal(t, logrus.ErrorLevel, entry.Level)
+	require.Equal(t, "Invalid KRL update; keeping the previous KRL", entry.Message)
+}
+
 func expectNoChallenge(ctx context.Context, challenge []byte) ([]byte, error) {
 	return nil, errors.New("challenge is not expected")
 }
@@ -0,0 +1,111 @@
+package tpmek
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-tpm/tpm2"
+	common_ek "github.com/spiffe/spire/pkg/common/plugin/tpmek"
+)
+
+// verifyQuote checks that the quote was signed by the attestation key, that it
+// is fresh and that it covers exactly the requested PCRs with the values
+// reported by the agent.
+func verifyQuote(akPub tpm2.Public, nonce []byte, pcrs []int, resp *common_ek.ChallengeResponse) error {
+	if len(resp.Quote) == 0 || len(resp.QuoteSignature) == 0 {
+		return errors.New("missing quote")
+	}
+
+	if err := checkSignature(akPub, resp.Quote, resp.QuoteSignature); err != nil {
+		return fmt.Errorf("invalid quote signature: %w", err)
+	}
+
+	data, err := tpm2.DecodeAttestationData(resp.Quote)
+	if err != nil {
+		return fmt.Errorf("cannot decode quote: %w", err)
+	}
+
+	if data.Type != tpm2.TagAttestQuote || data.AttestedQuoteInfo == nil {
+		return errors.New("attestation data is not a quote")
+	}
+
+	if !bytes.Equal(data.ExtraData, nonce) {
+		return errors.New("quote nonce mismatch")
+	}
+
+	sel := data.AttestedQuoteInfo.PCRSelection
+	if sel.Hash != common_ek.PCRHashAlg {
+		return fmt.Errorf("unexpected PCR bank 0x%04x", sel.Hash)
+	}
+
+	expected := sortedPCRs(pcrs)
+	quoted := sortedPCRs(sel.PCRs)
+	if len(expected) != len(quoted) {
+		return errors.New("quoted PCR selection does not match the requested one")
+	}
+	for i := range expected {
+		if expected[i] != quoted[i] {
+			return errors.New("quoted PCR selection does not match the requested one")
+		}
+	}
+
+	hash, err := common_ek.PCRHashAlg.Hash()
+	if err != nil {
+		return err
+	}
+
+	// The TPM computes the digest over the PCR values concatenated in
+	// ascending index order
+	h := hash.New()
+	for _, pcr := range expected {
+		value, ok := resp.PCRValues[pcr]
+		if !ok {
+			return fmt.Errorf("missing value for PCR %d", pcr)
+		}
+		if len(value) != hash.Size() {
+			return fmt.Errorf("invalid value size for PCR %d", pcr)
+		}
+		h.Write(value)
+	}
+
+	if !bytes.Equal(h.Sum(nil), data.AttestedQuoteInfo.PCRDigest) {
+		return errors.New("PCR values do not match the quoted digest")
+	}
+
+	return nil
+}
+
+func sortedPCRs(pcrs []int) []int {
+	sorted := append([]int(nil), pcrs...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func checkSignature(pub tpm2.Public, data, sig []byte) error {
+	key, err := pub.Key()
+	if err != nil {
+		return err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("only RSA keys are supported")
+	}
+
+	if pub.RSAParameters == nil || pub.RSAParameters.Sign == nil {
+		return errors.New("missing signature scheme")
+	}
+
+	hash, err := pub.RSAParameters.Sign.Hash.Hash()
+	if err != nil {
+		return err
+	}
+
+	h := hash.New()
+	h.Write(data)
+
+	return rsa.VerifyPKCS1v15(rsaKey, hash, h.Sum(nil), sig)
+}
@@ -0,0 +1,34 @@
+package tpmek
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmdevid"
+)
+
+func buildSelectorValues(ekHash string, chains [][]*x509.Certificate, pcrs []int, pcrValues map[int][]byte) []string {
+	selectorValues := []string{"ek_pubhash:" + ekHash}
+
+	// Used to avoid duplicating selectors.
+	fingerprints := map[string]bool{}
+	for _, chain := range chains {
+		// Iterate over all the certs in the chain (skip leaf at the 0 index)
+		for _, cert := range chain[1:] {
+			fp := tpmdevid.Fingerprint(cert)
+			if fingerprints[fp] {
+				continue
+			}
+			fingerprints[fp] = true
+
+			selectorValues = append(selectorValues, "ca:fingerprint:"+fp)
+		}
+	}
+
+	for _, pcr := range sortedPCRs(pcrs) {
+		selectorValues = append(selectorValues, fmt.Sprintf("pcr:sha256:%d:%s", pcr, hex.EncodeToString(pcrValues[pcr])))
+	}
+
+	return selectorValues
+}
@@ -0,0 +1,371 @@
+package tpmek
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/hashicorp/hcl"
+	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/nodeattestor/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/idutil"
+	common_devid "github.com/spiffe/spire/pkg/common/plugin/tpmdevid"
+	common_ek "github.com/spiffe/spire/pkg/common/plugin/tpmek"
+	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmdevid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// We use a 32 bytes nonce to provide enough cryptographical randomness and to be
+// consistent with other nonces sizes around the project.
+const quoteNonceSize = 32
+
+// maxPCRIndex is the highest PCR index defined by the TCG PC Client Platform
+// TPM Profile
+const maxPCRIndex = 23
+
+// akFlags are the attributes the attestation key must have so that quotes
+// signed by it can be trusted, i.e. a restricted signing key that was
+// generated by, and cannot leave, the TPM.
+const akFlags = tpm2.FlagSign |
+	tpm2.FlagRestricted |
+	tpm2.FlagFixedTPM |
+	tpm2.FlagFixedParent |
+	tpm2.FlagSensitiveDataOrigin
+
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(common_ek.PluginName,
+		nodeattestorv1.NodeAttestorPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+type config struct {
+	trustDomain string
+
+	ekRoots  *x509.CertPool
+	ekHashes map[string]bool
+}
+
+type Config struct {
+	EndorsementBundlePath string   `hcl:"endorsement_ca_path"`
+	EKHashAllowList       []string `hcl:"ek_hash_allow_list"`
+}
+
+type Plugin struct {
+	nodeattestorv1.UnsafeNodeAttestorServer
+	configv1.UnsafeConfigServer
+
+	m sync.Mutex
+	c *config
+}
+
+func New() *Plugin {
+	return &Plugin{}
+}
+
+func (p *Plugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
+	// Receive attestation request
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	conf := p.getConfiguration()
+	if conf == nil {
+		return status.Error(codes.FailedPrecondition, "not configured")
+	}
+
+	payload := req.GetPayload()
+	if payload == nil {
+		return status.Error(codes.InvalidArgument, "missing attestation payload")
+	}
+
+	// Unmarshall received attestation data
+	attData := new(common_ek.AttestationRequest)
+	err = json.Unmarshal(payload, attData)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "unable to unmarshall attestation data: %v", err)
+	}
+
+	if len(attData.EKPub) == 0 {
+		return status.Error(codes.InvalidArgument, "missing endorsement key public blob")
+	}
+	if len(attData.AKPub) == 0 {
+		return status.Error(codes.InvalidArgument, "missing attestation key public blob")
+	}
+	if err := validatePCRs(attData.PCRs); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid PCR selection: %v", err)
+	}
+
+	ekPub, err := tpm2.DecodePublic(attData.EKPub)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "cannot decode endorsement key public blob: %v", err)
+	}
+
+	ekKey, err := ekPub.Key()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "cannot get endorsement public key: %v", err)
+	}
+
+	ekHash, err := common_ek.PubHash(ekKey)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "cannot hash endorsement public key: %v", err)
+	}
+
+	// Verify the EK was issued by a trusted manufacturer or is explicitly allowed
+	chains, err := verifyEK(conf, attData.EKCert, ekKey, ekHash)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied, "endorsement key verification failed: %v", err)
+	}
+
+	akPub, err := tpm2.DecodePublic(attData.AKPub)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "cannot decode attestation key public blob: %v", err)
+	}
+
+	if akPub.Attributes&akFlags != akFlags {
+		return status.Error(codes.InvalidArgument, "attestation key is not a restricted signing key generated by the TPM")
+	}
+
+	// Issue a credential activation challenge (to verify AK is in the same TPM than EK)
+	credActivationChallenge, credActivationNonce, err := tpmdevid.NewCredActivationChallenge(akPub, ekPub)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot generate credential activation challenge: %v", err)
+	}
+
+	var quoteNonce []byte
+	if len(attData.PCRs) > 0 {
+		quoteNonce, err = common_devid.GetRandomBytes(quoteNonceSize)
+		if err != nil {
+			return status.Errorf(codes.Internal, "unable to generate quote nonce: %v", err)
+		}
+	}
+
+	// Marshal challenges
+	challenge, err := json.Marshal(common_ek.ChallengeRequest{
+		CredActivation: credActivationChallenge,
+		QuoteNonce:     quoteNonce,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal challenges data: %v", err)
+	}
+
+	// Send challenges to the agent
+	err = stream.Send(&nodeattestorv1.AttestResponse{
+		Response: &nodeattestorv1.AttestResponse_Challenge{
+			Challenge: challenge,
+		},
+	})
+	if err != nil {
+		return status.Errorf(status.Code(err), "unable to send challenges: %v", err)
+	}
+
+	// Receive challenges response
+	responseReq, err := stream.Recv()
+	if err != nil {
+		return status.Errorf(status.Code(err), "unable to receive challenges response: %v", err)
+	}
+
+	// Unmarshal challenges response
+	challengeResponse := &common_ek.ChallengeResponse{}
+	if err = json.Unmarshal(responseReq.GetChallengeResponse(), challengeResponse); err != nil {
+		return status.Errorf(codes.InvalidArgument, "unable to unmarshall challenges response: %v", err)
+	}
+
+	// Verify credential activation challenge
+	err = tpmdevid.VerifyCredActivationChallenge(credActivationNonce, challengeResponse.CredActivation)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "credential activation failed: %v", err)
+	}
+
+	// Verify the PCR quote, now that the AK is known to be resident in the
+	// same TPM than the EK
+	if len(attData.PCRs) > 0 {
+		err = verifyQuote(akPub, quoteNonce, attData.PCRs, challengeResponse)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "quote verification failed: %v", err)
+		}
+	}
+
+	// Create SPIFFE ID and selectors
+	spiffeID := idutil.AgentID(conf.trustDomain, fmt.Sprintf("%s/%s", common_ek.PluginName, ekHash))
+	selectors := buildSelectorValues(ekHash, chains, attData.PCRs, challengeResponse.PCRValues)
+
+	return stream.Send(&nodeattestorv1.AttestResponse{
+		Response: &nodeattestorv1.AttestResponse_AgentAttributes{
+			AgentAttributes: &nodeattestorv1.AgentAttributes{
+				SpiffeId:       spiffeID,
+				SelectorValues: selectors,
+			},
+		},
+	})
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	err := validateCoreConfig(req.CoreConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	extConf := new(Config)
+	if err := hcl.Decode(extConf, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if extConf.EndorsementBundlePath == "" && len(extConf.EKHashAllowList) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid configuration: endorsement_ca_path or ek_hash_allow_list is required")
+	}
+
+	intConf := &config{
+		trustDomain: req.CoreConfiguration.TrustDomain,
+		ekHashes:    make(map[string]bool, len(extConf.EKHashAllowList)),
+	}
+
+	for _, ekHash := range extConf.EKHashAllowList {
+		intConf.ekHashes[strings.ToLower(ekHash)] = true
+	}
+
+	// Load endorsement bundle if configured
+	if extConf.EndorsementBundlePath != "" {
+		intConf.ekRoots, err = util.LoadCertPool(extConf.EndorsementBundlePath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to load endorsement trust bundle: %v", err)
+		}
+	}
+
+	p.setConfiguration(intConf)
+
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfiguration() *config {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.c
+}
+
+func (p *Plugin) setConfiguration(c *config) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.c = c
+}
+
+func validateCoreConfig(c *configv1.CoreConfiguration) error {
+	if c == nil {
+		return status.Error(codes.InvalidArgument, "core configuration is missing")
+	}
+
+	if c.TrustDomain == "" {
+		return status.Error(codes.InvalidArgument, "trust_domain is required")
+	}
+	return nil
+}
+
+func validatePCRs(pcrs []int) error {
+	seen := make(map[int]bool, len(pcrs))
+	for _, pcr := range pcrs {
+		if pcr < 0 || pcr > maxPCRIndex {
+			return fmt.Errorf("PCR index %d is out of range", pcr)
+		}
+		if seen[pcr] {
+			return fmt.Errorf("PCR index %d is duplicated", pcr)
+		}
+		seen[pcr] = true
+	}
+	return nil
+}
+
+// verifyEK verifies that the endorsement key belongs to a genuine TPM. The EK
+// is accepted if its certificate chains back to one of the configured
+// manufacturer CAs or if the hash of its public key is in the allow list. The
+// verified chains are returned when the EK certificate was verified.
+func verifyEK(conf *config, ekCertBytes []byte, ekKey interface{}, ekHash string) ([][]*x509.Certificate, error) {
+	var certErr error
+	if len(ekCertBytes) > 0 && conf.ekRoots != nil {
+		chains, err := verifyEKCert(ekCertBytes, ekKey, conf.ekRoots)
+		if err == nil {
+			return chains, nil
+		}
+		certErr = err
+	}
+
+	if conf.ekHashes[ekHash] {
+		return nil, nil
+	}
+
+	switch {
+	case certErr != nil:
+		return nil, certErr
+	case conf.ekRoots != nil:
+		return nil, errors.New("missing endorsement certificate and EK public key hash is not in the allow list")
+	default:
+		return nil, errors.New("EK public key hash is not in the allow list")
+	}
+}
+
+func verifyEKCert(ekCertBytes []byte, ekKey interface{}, roots *x509.CertPool) ([][]*x509.Certificate, error) {
+	ekCert, err := x509.ParseCertificate(ekCertBytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse endorsement certificate: %w", err)
+	}
+
+	// Verify the public part of the EK generated from the template is the same
+	// than the one in the EK certificate.
+	if err := verifyEKsMatch(ekCert, ekKey); err != nil {
+		return nil, fmt.Errorf("public key in EK certificate differs from public key created via EK template: %w", err)
+	}
+
+	// Endorsement certificate's SAN is not fully processed by x509 package so
+	// it is safe to ignore it if it is marked critical
+	subjectAlternativeNameOID := []int{2, 5, 29, 17}
+	unhandledExtensions := ekCert.UnhandledCriticalExtensions[:0]
+	for _, oid := range ekCert.UnhandledCriticalExtensions {
+		if !oid.Equal(subjectAlternativeNameOID) {
+			unhandledExtensions = append(unhandledExtensions, oid)
+		}
+	}
+	ekCert.UnhandledCriticalExtensions = unhandledExtensions
+
+	chains, err := ekCert.Verify(x509.VerifyOptions{
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		Roots:     roots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("endorsement certificate verification failed: %w", err)
+	}
+
+	return chains, nil
+}
+
+// verifyEKsMatch checks that the public key generated using the EK template
+// matches the public key included in the Endorsement Certificate.
+func verifyEKsMatch(ekCert *x509.Certificate, ekKey interface{}) error {
+	keyFromCert, ok := ekCert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("key from certificate is not an RSA key")
+	}
+
+	keyFromTemplate, ok := ekKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("key from template is not an RSA key")
+	}
+
+	if !keyFromCert.Equal(keyFromTemplate) {
+		return errors.New("keys do not match")
+	}
+
+	return nil
+}
@@ -0,0 +1,510 @@
+package tpmek_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-tpm-tools/client"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	agentnodeattestor "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor"
+	"github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmdevid/tpmutil"
+	agent_ek "github.com/spiffe/spire/pkg/agent/plugin/nodeattestor/tpmek"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	common_ek "github.com/spiffe/spire/pkg/common/plugin/tpmek"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmdevid"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor/tpmek"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/tpmsimulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	endorsementBundlePath string
+	otherBundlePath       string
+
+	tpmPasswords = tpmutil.TPMPasswords{
+		EndorsementHierarchy: "endorsement-hierarchy-pass",
+		OwnerHierarchy:       "owner-hierarchy-pass",
+	}
+)
+
+func setupSimulator(t *testing.T) *tpmsimulator.TPMSimulator {
+	sim, err := tpmsimulator.New(tpmPasswords.EndorsementHierarchy, tpmPasswords.OwnerHierarchy)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, sim.Close(), "unexpected error encountered closing simulator")
+	})
+	tpmutil.OpenTPM = sim.OpenTPM
+
+	dir := t.TempDir()
+
+	// Write endorsement root certificate into temp directory
+	endorsementBundlePath = path.Join(dir, "endorsement-ca.pem")
+	require.NoError(t, os.WriteFile(endorsementBundlePath, pemutil.EncodeCertificate(sim.GetEKRoot()), 0600))
+
+	// Write an unrelated CA into temp directory
+	otherCA, err := tpmsimulator.NewProvisioningCA(&tpmsimulator.ProvisioningConf{NoIntermediates: true})
+	require.NoError(t, err)
+	otherBundlePath = path.Join(dir, "other-ca.pem")
+	require.NoError(t, os.WriteFile(otherBundlePath, pemutil.EncodeCertificate(otherCA.RootCert), 0600))
+
+	return sim
+}
+
+func TestConfigure(t *testing.T) {
+	setupSimulator(t)
+
+	tests := []struct {
+		name      string
+		hclConf   string
+		coreConf  *catalog.CoreConfig
+		expErrMsg string
+	}{
+		{
+			name:      "Configure fails if trust domain is empty",
+			coreConf:  &catalog.CoreConfig{},
+			expErrMsg: "trust_domain is required",
+		},
+		{
+			name:      "Configure fails if HCL config cannot be decoded",
+			coreConf:  &catalog.CoreConfig{TrustDomain: spiffeid.RequireTrustDomainFromString("example.org")},
+			hclConf:   "not an HCL configuration",
+			expErrMsg: "unable to decode configuration",
+		},
+		{
+			name:      "Configure fails if neither CA path nor allow list is provided",
+			coreConf:  &catalog.CoreConfig{TrustDomain: spiffeid.RequireTrustDomainFromString("example.org")},
+			expErrMsg: "invalid configuration: endorsement_ca_path or ek_hash_allow_list is required",
+		},
+		{
+			name:      "Configure fails if endorsement CA path does not exist",
+			coreConf:  &catalog.CoreConfig{TrustDomain: spiffeid.RequireTrustDomainFromString("example.org")},
+			hclConf:   `endorsement_ca_path = "non-existent/endorsement_ca_path"`,
+			expErrMsg: "unable to load endorsement trust bundle",
+		},
+		{
+			name:     "Configure succeeds with endorsement CA path",
+			coreConf: &catalog.CoreConfig{TrustDomain: spiffeid.RequireTrustDomainFromString("example.org")},
+			hclConf:  fmt.Sprintf(`endorsement_ca_path = %q`, endorsementBundlePath),
+		},
+		{
+			name:     "Configure succeeds with allow list",
+			coreConf: &catalog.CoreConfig{TrustDomain: spiffeid.RequireTrustDomainFromString("example.org")},
+			hclConf:  `ek_hash_allow_list = ["ABCDEF"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			options := []plugintest.Option{
+				plugintest.CaptureConfigureError(&err),
+				plugintest.Configure(tt.hclConf),
+			}
+			if tt.coreConf != nil {
+				options = append(options, plugintest.CoreConfig(*tt.coreConf))
+			}
+
+			plugintest.Load(t, tpmek.BuiltIn(), nil, options...)
+			if tt.expErrMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expErrMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAttestSucceeds(t *testing.T) {
+	sim := setupSimulator(t)
+	ekHash := getEKHash(t, sim)
+
+	pcrValues, err := client.ReadPCRs(sim, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0, 7}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		serverConf        string
+		agentConf         string
+		expectedSelectors []string
+	}{
+		{
+			name:       "Attest succeeds verifying the EK certificate",
+			serverConf: fmt.Sprintf(`endorsement_ca_path = %q`, endorsementBundlePath),
+			expectedSelectors: []string{
+				"ek_pubhash:" + ekHash,
+				"ca:fingerprint:" + tpmdevid.Fingerprint(sim.GetEKRoot()),
+			},
+		},
+		{
+			name:       "Attest succeeds using the allow list",
+			serverConf: fmt.Sprintf(`ek_hash_allow_list = [%q]`, ekHash),
+			expectedSelectors: []string{
+				"ek_pubhash:" + ekHash,
+			},
+		},
+		{
+			name:       "Attest falls back to the allow list if EK certificate is not trusted",
+			serverConf: fmt.Sprintf(`endorsement_ca_path = %q, ek_hash_allow_list = [%q]`, otherBundlePath, ekHash),
+			expectedSelectors: []string{
+				"ek_pubhash:" + ekHash,
+			},
+		},
+		{
+			name:       "Attest succeeds quoting PCRs",
+			serverConf: fmt.Sprintf(`endorsement_ca_path = %q`, endorsementBundlePath),
+			agentConf:  "pcrs = [7, 0]",
+			expectedSelectors: []string{
+				"ek_pubhash:" + ekHash,
+				"ca:fingerprint:" + tpmdevid.Fingerprint(sim.GetEKRoot()),
+				"pcr:sha256:0:" + hex.EncodeToString(pcrValues.Pcrs[0]),
+				"pcr:sha256:7:" + hex.EncodeToString(pcrValues.Pcrs[7]),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := loadPlugin(t, tt.serverConf)
+			agent := loadAgentPlugin(t, tt.agentConf)
+
+			result, err := attest(agent, server, nil)
+			require.NoError(t, err)
+			require.Equal(t, "spiffe://example.org/spire/agent/tpm_ek/"+ekHash, result.AgentID)
+
+			var selectors []string
+			for _, s := range result.Selectors {
+				require.Equal(t, "tpm_ek", s.Type)
+				selectors = append(selectors, s.Value)
+			}
+			require.Equal(t, tt.expectedSelectors, selectors)
+		})
+	}
+}
+
+func TestAttestFailures(t *testing.T) {
+	sim := setupSimulator(t)
+	ekHash := getEKHash(t, sim)
+
+	caConf := fmt.Sprintf(`endorsement_ca_path = %q`, endorsementBundlePath)
+
+	tests := []struct {
+		name          string
+		serverConf    string
+		agentConf     string
+		modifyPayload func(*common_ek.AttestationRequest)
+		modifyResp    func(*common_ek.ChallengeResponse)
+		expErr        string
+	}{
+		{
+			name:       "Attest fails if EK hash is not in the allow list",
+			serverConf: `ek_hash_allow_list = ["abcdef"]`,
+			expErr:     "rpc error: code = PermissionDenied desc = nodeattestor(tpm_ek): endorsement key verification failed: EK public key hash is not in the allow list",
+		},
+		{
+			name:       "Attest fails if EK certificate is signed by an unknown CA",
+			serverConf: fmt.Sprintf(`endorsement_ca_path = %q`, otherBundlePath),
+			expErr:     "rpc error: code = PermissionDenied desc = nodeattestor(tpm_ek): endorsement key verification failed: endorsement certificate verification failed: x509: certificate signed by unknown authority",
+		},
+		{
+			name:       "Attest fails if EK certificate is missing",
+			serverConf: caConf,
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.EKCert = nil
+			},
+			expErr: "rpc error: code = PermissionDenied desc = nodeattestor(tpm_ek): endorsement key verification failed: missing endorsement certificate and EK public key hash is not in the allow list",
+		},
+		{
+			name:       "Attest fails if EK public key does not match the certificate",
+			serverConf: fmt.Sprintf(`endorsement_ca_path = %q, ek_hash_allow_list = [%q]`, endorsementBundlePath, "abcdef"),
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.EKPub = req.AKPub
+			},
+			expErr: "rpc error: code = PermissionDenied desc = nodeattestor(tpm_ek): endorsement key verification failed: public key in EK certificate differs from public key created via EK template: keys do not match",
+		},
+		{
+			name:       "Attest fails if EK public blob is missing",
+			serverConf: caConf,
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.EKPub = nil
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): missing endorsement key public blob",
+		},
+		{
+			name:       "Attest fails if AK public blob is missing",
+			serverConf: caConf,
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.AKPub = nil
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): missing attestation key public blob",
+		},
+		{
+			name:       "Attest fails if EK public blob is malformed",
+			serverConf: caConf,
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.EKPub = []byte("not-a-public-blob")
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): cannot decode endorsement key public blob",
+		},
+		{
+			name:       "Attest fails if AK is not a restricted signing key",
+			serverConf: caConf,
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.AKPub = req.EKPub
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): attestation key is not a restricted signing key generated by the TPM",
+		},
+		{
+			name:       "Attest fails if PCR index is out of range",
+			serverConf: caConf,
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.PCRs = []int{24}
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): invalid PCR selection: PCR index 24 is out of range",
+		},
+		{
+			name:       "Attest fails if credential activation fails",
+			serverConf: caConf,
+			modifyResp: func(resp *common_ek.ChallengeResponse) {
+				resp.CredActivation = []byte("wrong")
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): credential activation failed: nonces are different",
+		},
+		{
+			name:       "Attest fails if quote is missing",
+			serverConf: caConf,
+			agentConf:  "pcrs = [0]",
+			modifyResp: func(resp *common_ek.ChallengeResponse) {
+				resp.Quote = nil
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): quote verification failed: missing quote",
+		},
+		{
+			name:       "Attest fails if quote signature is invalid",
+			serverConf: caConf,
+			agentConf:  "pcrs = [0]",
+			modifyResp: func(resp *common_ek.ChallengeResponse) {
+				resp.QuoteSignature[0] ^= 0xff
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): quote verification failed: invalid quote signature: crypto/rsa: verification error",
+		},
+		{
+			name:       "Attest fails if PCR values do not match the quote",
+			serverConf: caConf,
+			agentConf:  "pcrs = [0]",
+			modifyResp: func(resp *common_ek.ChallengeResponse) {
+				resp.PCRValues[0][0] ^= 0xff
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): quote verification failed: PCR values do not match the quoted digest",
+		},
+		{
+			name:       "Attest fails if PCR value is missing",
+			serverConf: caConf,
+			agentConf:  "pcrs = [0]",
+			modifyResp: func(resp *common_ek.ChallengeResponse) {
+				delete(resp.PCRValues, 0)
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): quote verification failed: missing value for PCR 0",
+		},
+		{
+			name:       "Attest fails if quoted PCRs do not match the requested ones",
+			serverConf: caConf,
+			agentConf:  "pcrs = [0]",
+			modifyPayload: func(req *common_ek.AttestationRequest) {
+				req.PCRs = []int{0, 7}
+			},
+			expErr: "rpc error: code = InvalidArgument desc = nodeattestor(tpm_ek): quote verification failed: quoted PCR selection does not match the requested one",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := loadPlugin(t, tt.serverConf)
+			agent := loadAgentPlugin(t, tt.agentConf)
+
+			result, err := attest(agent, server, &interceptor{
+				modifyPayload: tt.modifyPayload,
+				modifyResp:    tt.modifyResp,
+			})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expErr)
+			require.Nil(t, result)
+		})
+	}
+
+	// The EK hash must be accepted case insensitively
+	t.Run("Attest succeeds with an upper case allow list entry", func(t *testing.T) {
+		server := loadPlugin(t, fmt.Sprintf(`ek_hash_allow_list = [%q]`, fmt.Sprintf("%X", mustDecodeHex(t, ekHash))))
+		agent := loadAgentPlugin(t, "")
+
+		_, err := attest(agent, server, nil)
+		require.NoError(t, err)
+	})
+}
+
+func loadPlugin(t *testing.T, config string) nodeattestor.NodeAttestor {
+	v1 := new(nodeattestor.V1)
+	plugintest.Load(t, tpmek.BuiltIn(), v1,
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+		plugintest.Configure(config),
+	)
+	return v1
+}
+
+func loadAgentPlugin(t *testing.T, config string) agentnodeattestor.NodeAttestor {
+	devicePath := "/dev/tpmrm0"
+	if runtime.GOOS == "windows" {
+		devicePath = ""
+	}
+	agent_ek.AutoDetectTPMPath = func(string) (string, error) {
+		return devicePath, nil
+	}
+
+	v1 := new(agentnodeattestor.V1)
+	plugintest.Load(t, agent_ek.BuiltIn(), v1,
+		plugintest.Configure(fmt.Sprintf(`
+			endorsement_hierarchy_password = %q
+			owner_hierarchy_password = %q
+			%s`, tpmPasswords.EndorsementHierarchy, tpmPasswords.OwnerHierarchy, config)),
+	)
+	return v1
+}
+
+func getEKHash(t *testing.T, sim *tpmsimulator.TPMSimulator) string {
+	ekCertBytes, err := tpm2.NVRead(sim, tpmutil.EKCertificateHandleRSA)
+	require.NoError(t, err)
+	ekCert, err := x509.ParseCertificate(ekCertBytes)
+	require.NoError(t, err)
+	ekHash, err := common_ek.PubHash(ekCert.PublicKey)
+	require.NoError(t, err)
+	return ekHash
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// interceptor allows tests to tamper with the data the agent sends to the
+// server.
+type interceptor struct {
+	modifyPayload func(*common_ek.AttestationRequest)
+	modifyResp    func(*common_ek.ChallengeResponse)
+}
+
+func (i *interceptor) payload(b []byte) []byte {
+	if i == nil || i.modifyPayload == nil {
+		return b
+	}
+	req := new(common_ek.AttestationRequest)
+	if err := json.Unmarshal(b, req); err != nil {
+		return b
+	}
+	i.modifyPayload(req)
+	b, _ = json.Marshal(req)
+	return b
+}
+
+func (i *interceptor) response(b []byte) []byte {
+	if i == nil || i.modifyResp == nil {
+		return b
+	}
+	resp := new(common_ek.ChallengeResponse)
+	if err := json.Unmarshal(b, resp); err != nil {
+		return b
+	}
+	i.modifyResp(resp)
+	b, _ = json.Marshal(resp)
+	return b
+}
+
+// attest runs the agent plugin against the server plugin, bridging the agent
+// server stream with the server challenge function.
+func attest(agent agentnodeattestor.NodeAttestor, server nodeattestor.NodeAttestor, i *interceptor) (*nodeattestor.AttestResult, error) {
+	stream := &bridgeStream{
+		server:      server,
+		interceptor: i,
+		challenges:  make(chan []byte),
+		responses:   make(chan []byte),
+		done:        make(chan struct{}),
+	}
+
+	agentErr := agent.Attest(context.Background(), stream)
+	if stream.started {
+		close(stream.responses)
+		<-stream.done
+	}
+	if stream.err != nil {
+		return nil, stream.err
+	}
+	if agentErr != nil {
+		return nil, agentErr
+	}
+	return stream.result, nil
+}
+
+type bridgeStream struct {
+	server      nodeattestor.NodeAttestor
+	interceptor *interceptor
+
+	started    bool
+	challenges chan []byte
+	responses  chan []byte
+	done       chan struct{}
+
+	result *nodeattestor.AttestResult
+	err    error
+}
+
+func (s *bridgeStream) SendAttestationData(ctx context.Context, attestationData agentnodeattestor.AttestationData) ([]byte, error) {
+	s.started = true
+	go func() {
+		defer close(s.done)
+		s.result, s.err = s.server.Attest(ctx, s.interceptor.payload(attestationData.Payload),
+			func(ctx context.Context, challenge []byte) ([]byte, error) {
+				s.challenges <- challenge
+				response, ok := <-s.responses
+				if !ok {
+					return nil, errors.New("agent stopped responding")
+				}
+				return response, nil
+			})
+	}()
+	return s.next()
+}
+
+func (s *bridgeStream) SendChallengeResponse(ctx context.Context, response []byte) ([]byte, error) {
+	s.responses <- s.interceptor.response(response)
+	return s.next()
+}
+
+func (s *bridgeStream) next() ([]byte, error) {
+	select {
+	case challenge := <-s.challenges:
+		return challenge, nil
+	case <-s.done:
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, nil
+	}
+}
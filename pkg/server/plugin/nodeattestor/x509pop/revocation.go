@@ -0,0 +1,386 @@
+package x509pop
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/hashicorp/go-hclog"
+	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/common/util"
+	"golang.org/x/crypto/ocsp"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// defaultCRLCacheTTL is how long a CRL fetched from a distribution point
+	// is cached when it does not specify a next update time.
+	defaultCRLCacheTTL = time.Hour
+
+	// maxCRLSize limits the size of CRLs fetched from distribution points
+	maxCRLSize = 10 << 20
+
+	// maxOCSPResponseSize limits the size of OCSP responses
+	maxOCSPResponseSize = 1 << 20
+)
+
+type revocationConfig struct {
+	crlPaths []string
+	fetchCDP bool
+	ocsp     bool
+	failOpen bool
+}
+
+// revocationChecker checks the revocation status of the certificates in a
+// verified chain using CRL files, CRL distribution points and OCSP.
+//
+// A certificate that is reported as revoked by any source is always rejected.
+// When a source cannot be consulted (e.g. a distribution point is unreachable
+// or a CRL has expired), the certificate is rejected unless the checker fails
+// open.
+type revocationChecker struct {
+	fetchCDP   bool
+	ocsp       bool
+	failOpen   bool
+	httpClient *http.Client
+	clock      clock.Clock
+	log        hclog.Logger
+
+	// crlFetches deduplicates concurrent fetches of the same distribution
+	// point, which are done without holding mtx
+	crlFetches singleflight.Group
+
+	mtx        sync.Mutex
+	crlFiles   []*crlFile
+	remoteCRLs map[string]*remoteCRL
+}
+
+// crlFile is a CRL loaded from disk. It is reloaded when the file changes.
+type crlFile struct {
+	path    string
+	changes *util.FileChangeDetector
+	crl     *x509.RevocationList
+}
+
+// loadedCRL is a CRL along with where it was loaded from.
+type loadedCRL struct {
+	source string
+	crl    *x509.RevocationList
+}
+
+type remoteCRL struct {
+	crl       *x509.RevocationList
+	expiresAt time.Time
+}
+
+func newRevocationChecker(config *revocationConfig, clk clock.Clock, log hclog.Logger) (*revocationChecker, error) {
+	c := &revocationChecker{
+		fetchCDP: config.fetchCDP,
+		ocsp:     config.ocsp,
+		failOpen: config.failOpen,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		clock:      clk,
+		log:        log,
+		remoteCRLs: make(map[string]*remoteCRL),
+	}
+
+	for _, path := range config.crlPaths {
+		f := &crlFile{
+			path:    path,
+			changes: util.NewFileChangeDetector(path),
+		}
+		if err := f.load(); err != nil {
+			return nil, err
+		}
+		c.crlFiles = append(c.crlFiles, f)
+	}
+
+	return c, nil
+}
+
+// check verifies that no certificate in the given chains has been revoked.
+func (c *revocationChecker) check(ctx context.Context, chains [][]*x509.Certificate) error {
+	crlFiles := c.loadCRLFiles()
+
+	// Certificates are usually shared between chains
+	checked := make(map[string]bool)
+	for _, chain := range chains {
+		// The root of each chain is trusted explicitly, so there is nothing
+		// to check for it
+		for i := 0; i < len(chain)-1; i++ {
+			cert, issuer := chain[i], chain[i+1]
+
+			key := x509pop.Fingerprint(cert) + x509pop.Fingerprint(issuer)
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+
+			if err := c.checkCertificate(ctx, crlFiles, cert, issuer); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *revocationChecker) checkCertificate(ctx context.Context, crlFiles []loadedCRL, cert, issuer *x509.Certificate) error {
+	now := c.clock.Now()
+
+	var failures []error
+	checkCRL := func(source string, crl *x509.RevocationList) error {
+		// Revocations are permanent, so even an expired CRL is authoritative
+		// for the certificates it lists
+		if isRevoked(crl, cert) {
+			return fmt.Errorf("certificate %q has been revoked according to CRL %q", cert.Subject, source)
+		}
+		if !now.Before(crl.NextUpdate) {
+			failures = append(failures, fmt.Errorf("CRL %q has expired", source))
+		}
+		return nil
+	}
+
+	for _, f := range crlFiles {
+		// Only CRLs issued by the issuer of the certificate are relevant
+		if f.crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		if err := checkCRL(f.source, f.crl); err != nil {
+			return err
+		}
+	}
+
+	if c.fetchCDP {
+		for _, url := range cert.CRLDistributionPoints {
+			if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+				continue
+			}
+
+			crl, err := c.getRemoteCRL(ctx, url, issuer, now)
+			if err != nil {
+				failures = append(failures, err)
+				continue
+			}
+			if err := checkCRL(url, crl); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.ocsp && len(cert.OCSPServer) > 0 {
+		revoked, err := c.queryOCSP(ctx, cert, issuer, now)
+		switch {
+		case err != nil:
+			failures = append(failures, err)
+		case revoked:
+			return fmt.Errorf("certificate %q has been revoked according to OCSP", cert.Subject)
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	if c.failOpen {
+		for _, failure := range failures {
+			c.log.Warn("Unable to check certificate revocation status; failing open",
+				telemetry.Subject, cert.Subject.String(),
+				telemetry.Error, failure)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to check revocation status of certificate %q: %w", cert.Subject, failures[0])
+}
+
+// loadCRLFiles reloads the CRL files that changed since they were last
+// loaded and returns the current CRLs. If a file cannot be reloaded, the
+// previously loaded CRL is kept.
+func (c *revocationChecker) loadCRLFiles() []loadedCRL {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	crls := make([]loadedCRL, 0, len(c.crlFiles))
+	for _, f := range c.crlFiles {
+		if f.changes.Changed() {
+			if err := f.load(); err != nil {
+				c.log.Warn("Unable to reload CRL file; keeping the previous CRL",
+					telemetry.Path, f.path,
+					telemetry.Error, err)
+			}
+		}
+		crls = append(crls, loadedCRL{source: f.path, crl: f.crl})
+	}
+	return crls
+}
+
+func (c *revocationChecker) getRemoteCRL(ctx context.Context, url string, issuer *x509.Certificate, now time.Time) (*x509.RevocationList, error) {
+	crl, err := c.fetchRemoteCRL(ctx, url, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// The same distribution point may be shared by certificates from
+	// different issuers, so the signature is checked on every use
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("CRL from %q is not signed by the certificate issuer: %w", url, err)
+	}
+	return crl, nil
+}
+
+// fetchRemoteCRL returns the CRL published at the given distribution point,
+// fetching it if it is not cached or the cached CRL has expired. Concurrent
+// fetches of the same distribution point are shared.
+func (c *revocationChecker) fetchRemoteCRL(ctx context.Context, url string, now time.Time) (*x509.RevocationList, error) {
+	c.mtx.Lock()
+	cached, ok := c.remoteCRLs[url]
+	c.mtx.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.crl, nil
+	}
+
+	v, err, _ := c.crlFetches.Do(url, func() (interface{}, error) {
+		body, err := c.doRequest(ctx, http.MethodGet, url, "", nil, maxCRLSize)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch CRL from %q: %w", url, err)
+		}
+
+		crl, err := parseCRL(body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse CRL from %q: %w", url, err)
+		}
+
+		expiresAt := crl.NextUpdate
+		if expiresAt.IsZero() {
+			expiresAt = now.Add(defaultCRLCacheTTL)
+		}
+
+		c.mtx.Lock()
+		c.remoteCRLs[url] = &remoteCRL{
+			crl:       crl,
+			expiresAt: expiresAt,
+		}
+		c.mtx.Unlock()
+
+		return crl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*x509.RevocationList), nil
+}
+
+// queryOCSP asks the OCSP responders of the certificate for its status. It
+// returns true if the certificate has been revoked.
+func (c *revocationChecker) queryOCSP(ctx context.Context, cert, issuer *x509.Certificate, now time.Time) (bool, error) {
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to create OCSP request: %w", err)
+	}
+
+	var errs []string
+	for _, server := range cert.OCSPServer {
+		body, err := c.doRequest(ctx, http.MethodPost, server, "application/ocsp-request", req, maxOCSPResponseSize)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", server, err))
+			continue
+		}
+
+		resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: unable to parse response: %v", server, err))
+			continue
+		}
+
+		if !resp.NextUpdate.IsZero() && now.After(resp.NextUpdate) {
+			errs = append(errs, fmt.Sprintf("%s: response has expired", server))
+			continue
+		}
+
+		switch resp.Status {
+		case ocsp.Good:
+			return false, nil
+		case ocsp.Revoked:
+			return true, nil
+		default:
+			errs = append(errs, fmt.Sprintf("%s: certificate status is unknown", server))
+		}
+	}
+
+	return false, fmt.Errorf("OCSP check failed: %s", strings.Join(errs, "; "))
+}
+
+func (c *revocationChecker) doRequest(ctx context.Context, method, url, contentType string, body []byte, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errors.New("response is too large")
+	}
+	return data, nil
+}
+
+func (f *crlFile) load() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("unable to load CRL %q: %w", f.path, err)
+	}
+
+	crl, err := parseCRL(data)
+	if err != nil {
+		return fmt.Errorf("unable to parse CRL %q: %w", f.path, err)
+	}
+
+	f.crl = crl
+	return nil
+}
+
+// parseCRL parses a DER or PEM encoded CRL.
+func parseCRL(data []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(data); block != nil && block.Type == "X509 CRL" {
+		data = block.Bytes
+	}
+	return x509.ParseRevocationList(data)
+}
+
+func isRevoked(crl *x509.RevocationList, cert *x509.Certificate) bool {
+	for _, revoked := range crl.RevokedCertificateEntries {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
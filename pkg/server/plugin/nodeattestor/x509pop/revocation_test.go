@@ -0,0 +1,390 @@
+package x509pop
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/hashicorp/go-hclog"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+	"google.golang.org/grpc/codes"
+)
+
+type revocationPKI struct {
+	dir string
+
+	rootCert         *x509.Certificate
+	intermediateCert *x509.Certificate
+	intermediateKey  crypto.Signer
+	leafCert         *x509.Certificate
+	leafKey          crypto.Signer
+}
+
+func newRevocationPKI(t *testing.T, cdpURL, ocspURL string) *revocationPKI {
+	now := time.Now()
+	rootCert, rootKey := testca.CreateCACertificate(t, nil, nil)
+	intermediateCert, intermediateKey := testca.CreateCACertificate(t, rootCert, rootKey,
+		testca.WithKeyUsage(x509.KeyUsageCertSign|x509.KeyUsageCRLSign))
+
+	leafKey := testkey.NewEC256(t)
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    now,
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if cdpURL != "" {
+		leafTmpl.CRLDistributionPoints = []string{cdpURL}
+	}
+	if ocspURL != "" {
+		leafTmpl.OCSPServer = []string{ocspURL}
+	}
+	leafCert := testca.CreateCertificate(t, leafTmpl, intermediateCert, leafKey.Public(), intermediateKey)
+
+	pki := &revocationPKI{
+		dir:              t.TempDir(),
+		rootCert:         rootCert,
+		intermediateCert: intermediateCert,
+		intermediateKey:  intermediateKey,
+		leafCert:         leafCert,
+		leafKey:          leafKey,
+	}
+	require.NoError(t, os.WriteFile(pki.path("root.pem"), pemutil.EncodeCertificate(rootCert), 0600))
+	return pki
+}
+
+func (pki *revocationPKI) path(name string) string {
+	return filepath.Join(pki.dir, name)
+}
+
+// crl returns a DER encoded CRL issued by the intermediate CA
+func (pki *revocationPKI) crl(t *testing.T, nextUpdate time.Time, revoked ...*big.Int) []byte {
+	var revokedCerts []pkix.RevokedCertificate
+	for _, serial := range revoked {
+		revokedCerts = append(revokedCerts, pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: time.Now(),
+		})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:              big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:          time.Now().Add(-time.Minute),
+		NextUpdate:          nextUpdate,
+		RevokedCertificates: revokedCerts,
+	}, pki.intermediateCert, pki.intermediateKey)
+	require.NoError(t, err)
+	return crl
+}
+
+func (pki *revocationPKI) writeCRL(t *testing.T, name string, crl []byte) string {
+	path := pki.path(name)
+	require.NoError(t, os.WriteFile(path+".tmp", crl, 0600))
+	require.NoError(t, os.Rename(path+".tmp", path))
+	return path
+}
+
+func (pki *revocationPKI) attest(t *testing.T, config string) (*nodeattestor.AttestResult, error) {
+	attestor := new(nodeattestor.V1)
+	plugintest.Load(t, BuiltIn(), attestor,
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+		plugintest.Configure(fmt.Sprintf("ca_bundle_path = %q\n%s", pki.path("root.pem"), config)),
+	)
+	return pki.attestWith(t, attestor)
+}
+
+func (pki *revocationPKI) attestWith(t *testing.T, attestor nodeattestor.NodeAttestor) (*nodeattestor.AttestResult, error) {
+	payload := marshal(t, &x509pop.AttestationData{
+		Certificates: [][]byte{pki.leafCert.Raw, pki.intermediateCert.Raw},
+	})
+	return attestor.Attest(context.Background(), payload, func(ctx context.Context, challenge []byte) ([]byte, error) {
+		popChallenge := new(x509pop.Challenge)
+		unmarshal(t, challenge, popChallenge)
+		response, err := x509pop.CalculateResponse(pki.leafKey, popChallenge)
+		require.NoError(t, err)
+		return marshal(t, response), nil
+	})
+}
+
+func TestRevocationCRLFiles(t *testing.T) {
+	pki := newRevocationPKI(t, "", "")
+	nextUpdate := time.Now().Add(time.Hour)
+
+	t.Run("not revoked", func(t *testing.T) {
+		crlPath := pki.writeCRL(t, "clean.crl", pki.crl(t, nextUpdate, big.NewInt(1)))
+		result, err := pki.attest(t, fmt.Sprintf("crl_paths = [%q]", crlPath))
+		require.NoError(t, err)
+		require.NotNil(t, result)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		crlPath := pki.writeCRL(t, "revoked.crl", pki.crl(t, nextUpdate, pki.leafCert.SerialNumber))
+		_, err := pki.attest(t, fmt.Sprintf("crl_paths = [%q]", crlPath))
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "certificate revocation check failed: certificate \"CN=leaf\" has been revoked according to CRL")
+	})
+
+	t.Run("PEM encoded CRL", func(t *testing.T) {
+		crlPEM := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: pki.crl(t, nextUpdate, pki.leafCert.SerialNumber)})
+		crlPath := pki.writeCRL(t, "revoked.pem", crlPEM)
+		_, err := pki.attest(t, fmt.Sprintf("crl_paths = [%q]", crlPath))
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "has been revoked according to CRL")
+	})
+
+	t.Run("reloaded on change", func(t *testing.T) {
+		crlPath := pki.writeCRL(t, "reload.crl", pki.crl(t, nextUpdate))
+
+		attestor := new(nodeattestor.V1)
+		plugintest.Load(t, BuiltIn(), attestor,
+			plugintest.CoreConfig(catalog.CoreConfig{
+				TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+			}),
+			plugintest.Configure(fmt.Sprintf("ca_bundle_path = %q\ncrl_paths = [%q]", pki.path("root.pem"), crlPath)),
+		)
+
+		_, err := pki.attestWith(t, attestor)
+		require.NoError(t, err)
+
+		// An invalid update keeps the previous CRL
+		pki.writeCRL(t, "reload.crl", []byte("not a CRL"))
+		_, err = pki.attestWith(t, attestor)
+		require.NoError(t, err)
+
+		pki.writeCRL(t, "reload.crl", pki.crl(t, nextUpdate, pki.leafCert.SerialNumber))
+		_, err = pki.attestWith(t, attestor)
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "has been revoked according to CRL")
+	})
+
+	t.Run("expired CRL fails closed", func(t *testing.T) {
+		crlPath := pki.writeCRL(t, "expired.crl", pki.crl(t, time.Now().Add(-time.Second)))
+		_, err := pki.attest(t, fmt.Sprintf("crl_paths = [%q]", crlPath))
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "unable to check revocation status of certificate \"CN=leaf\"")
+	})
+
+	t.Run("expired CRL fails open", func(t *testing.T) {
+		crlPath := pki.writeCRL(t, "expired.crl", pki.crl(t, time.Now().Add(-time.Second)))
+		_, err := pki.attest(t, fmt.Sprintf("crl_paths = [%q]\nrevocation_fail_open = true", crlPath))
+		require.NoError(t, err)
+	})
+
+	t.Run("expired CRL still revokes", func(t *testing.T) {
+		crlPath := pki.writeCRL(t, "expired-revoked.crl", pki.crl(t, time.Now().Add(-time.Second), pki.leafCert.SerialNumber))
+		_, err := pki.attest(t, fmt.Sprintf("crl_paths = [%q]\nrevocation_fail_open = true", crlPath))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid CRL path", func(t *testing.T) {
+		var err error
+		plugintest.Load(t, BuiltIn(), nil,
+			plugintest.CaptureConfigureError(&err),
+			plugintest.CoreConfig(catalog.CoreConfig{
+				TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+			}),
+			plugintest.Configure(fmt.Sprintf("ca_bundle_path = %q\ncrl_paths = [\"missing.crl\"]", pki.path("root.pem"))),
+		)
+		spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, "unable to configure revocation checking: unable to load CRL \"missing.crl\"")
+	})
+}
+
+func TestRevocationCRLDistributionPoints(t *testing.T) {
+	var crl []byte
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if crl == nil {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(crl)
+	}))
+	defer server.Close()
+
+	pki := newRevocationPKI(t, server.URL+"/intermediate.crl", "")
+	nextUpdate := time.Now().Add(time.Hour)
+
+	t.Run("not revoked", func(t *testing.T) {
+		crl = pki.crl(t, nextUpdate)
+		requests = 0
+
+		attestor := new(nodeattestor.V1)
+		plugintest.Load(t, BuiltIn(), attestor,
+			plugintest.CoreConfig(catalog.CoreConfig{
+				TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+			}),
+			plugintest.Configure(fmt.Sprintf("ca_bundle_path = %q\ncrl_fetch_distribution_points = true", pki.path("root.pem"))),
+		)
+
+		_, err := pki.attestWith(t, attestor)
+		require.NoError(t, err)
+
+		// The CRL is cached until its next update
+		_, err = pki.attestWith(t, attestor)
+		require.NoError(t, err)
+		require.Equal(t, 1, requests)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		crl = pki.crl(t, nextUpdate, pki.leafCert.SerialNumber)
+		_, err := pki.attest(t, "crl_fetch_distribution_points = true")
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "has been revoked according to CRL \""+server.URL+"/intermediate.crl\"")
+	})
+
+	t.Run("CRL not signed by issuer", func(t *testing.T) {
+		other := newRevocationPKI(t, "", "")
+		crl = other.crl(t, nextUpdate)
+		_, err := pki.attest(t, "crl_fetch_distribution_points = true")
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "is not signed by the certificate issuer")
+	})
+
+	t.Run("unavailable fails closed", func(t *testing.T) {
+		crl = nil
+		_, err := pki.attest(t, "crl_fetch_distribution_points = true")
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "unable to fetch CRL from \""+server.URL+"/intermediate.crl\": unexpected status code: 503")
+	})
+
+	t.Run("unavailable fails open", func(t *testing.T) {
+		crl = nil
+		_, err := pki.attest(t, "crl_fetch_distribution_points = true\nrevocation_fail_open = true")
+		require.NoError(t, err)
+	})
+}
+
+func TestRevocationCRLFetchDoesNotBlockOtherChecks(t *testing.T) {
+	var slowPKI, fastPKI *revocationPKI
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow.crl":
+			close(requested)
+			<-release
+			_, _ = w.Write(slowPKI.crl(t, time.Now().Add(time.Hour)))
+		default:
+			_, _ = w.Write(fastPKI.crl(t, time.Now().Add(time.Hour)))
+		}
+	}))
+	defer server.Close()
+
+	slowPKI = newRevocationPKI(t, server.URL+"/slow.crl", "")
+	fastPKI = newRevocationPKI(t, server.URL+"/fast.crl", "")
+
+	checker, err := newRevocationChecker(&revocationConfig{fetchCDP: true}, clock.New(), hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	slowDone := make(chan error, 1)
+	go func() {
+		slowDone <- checker.check(context.Background(), [][]*x509.Certificate{
+			{slowPKI.leafCert, slowPKI.intermediateCert, slowPKI.rootCert},
+		})
+	}()
+	<-requested
+
+	// A check that needs a different distribution point completes while the
+	// slow distribution point is still being fetched
+	fastDone := make(chan error, 1)
+	go func() {
+		fastDone <- checker.check(context.Background(), [][]*x509.Certificate{
+			{fastPKI.leafCert, fastPKI.intermediateCert, fastPKI.rootCert},
+		})
+	}()
+	select {
+	case err := <-fastDone:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "check blocked by an unrelated CRL fetch")
+	}
+
+	close(release)
+	require.NoError(t, <-slowDone)
+}
+
+func TestRevocationOCSP(t *testing.T) {
+	var ocspStatus int
+	var pki *revocationPKI
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := ocsp.ParseRequest(body)
+		require.NoError(t, err)
+
+		resp, err := ocsp.CreateResponse(pki.intermediateCert, pki.intermediateCert, ocsp.Response{
+			Status:       ocspStatus,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, pki.intermediateKey)
+		require.NoError(t, err)
+		_, _ = w.Write(resp)
+	}))
+	defer server.Close()
+
+	pki = newRevocationPKI(t, "", server.URL)
+
+	t.Run("good", func(t *testing.T) {
+		ocspStatus = ocsp.Good
+		_, err := pki.attest(t, "ocsp_enabled = true")
+		require.NoError(t, err)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		ocspStatus = ocsp.Revoked
+		_, err := pki.attest(t, "ocsp_enabled = true")
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "certificate \"CN=leaf\" has been revoked according to OCSP")
+	})
+
+	t.Run("unknown fails closed", func(t *testing.T) {
+		ocspStatus = ocsp.Unknown
+		_, err := pki.attest(t, "ocsp_enabled = true")
+		spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "OCSP check failed: "+server.URL+": certificate status is unknown")
+	})
+
+	t.Run("unknown fails open", func(t *testing.T) {
+		ocspStatus = ocsp.Unknown
+		_, err := pki.attest(t, "ocsp_enabled = true\nrevocation_fail_open = true")
+		require.NoError(t, err)
+	})
+}
+
+func TestIssuerSelectors(t *testing.T) {
+	pki := newRevocationPKI(t, "", "")
+
+	result, err := pki.attest(t, "")
+	require.NoError(t, err)
+
+	var selectors []string
+	for _, selector := range result.Selectors {
+		selectors = append(selectors, selector.Value)
+	}
+	require.Equal(t, []string{
+		"subject:cn:leaf",
+		"issuer:cn:" + pki.intermediateCert.Subject.CommonName,
+		"issuer:fingerprint:" + x509pop.Fingerprint(pki.intermediateCert),
+		"ca:fingerprint:" + x509pop.Fingerprint(pki.intermediateCert),
+		"ca:fingerprint:" + x509pop.Fingerprint(pki.rootCert),
+	}, selectors)
+}
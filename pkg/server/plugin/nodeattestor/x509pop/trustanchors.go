@@ -0,0 +1,73 @@
+package x509pop
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"github.com/spiffe/spire/pkg/common/util"
+)
+
+// trustAnchors holds the CA certificates used to verify agent certificates.
+// The bundle files are reloaded when they change. The new set of anchors only
+// replaces the previous one when every file could be loaded, so an invalid
+// update never leaves the plugin without trust anchors.
+type trustAnchors struct {
+	paths []string
+	log   hclog.Logger
+
+	mtx     sync.Mutex
+	changes *util.FileChangeDetector
+	pool    *x509.CertPool
+}
+
+func newTrustAnchors(paths []string, log hclog.Logger) (*trustAnchors, error) {
+	t := &trustAnchors{
+		paths:   paths,
+		log:     log,
+		changes: util.NewFileChangeDetector(paths...),
+	}
+
+	pool, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	t.pool = pool
+
+	return t, nil
+}
+
+// get returns the current trust anchors, reloading them first if any of the
+// bundle files changed.
+func (t *trustAnchors) get() *x509.CertPool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if !t.changes.Changed() {
+		return t.pool
+	}
+
+	pool, err := t.load()
+	if err != nil {
+		t.log.Error("Invalid trust bundle update; keeping the previous trust anchors", telemetry.Error, err)
+		return t.pool
+	}
+
+	t.log.Info("Reloaded trust bundle")
+	t.pool = pool
+	return t.pool
+}
+
+func (t *trustAnchors) load() (*x509.CertPool, error) {
+	var cas []*x509.Certificate
+	for _, path := range t.paths {
+		certs, err := util.LoadCertificates(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load trust bundle %q: %w", path, err)
+		}
+		cas = append(cas, certs...)
+	}
+	return util.NewCertPool(cas...), nil
+}
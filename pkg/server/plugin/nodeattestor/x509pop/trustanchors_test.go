@@ -0,0 +1,70 @@
+package x509pop
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/server/plugin/nodeattestor"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestTrustAnchorsReload(t *testing.T) {
+	pki := newRevocationPKI(t, "", "")
+	otherRoot, _ := testca.CreateCACertificate(t, nil, nil)
+
+	writeBundle := func(data []byte) {
+		path := pki.path("bundle.pem")
+		require.NoError(t, ioutil.WriteFile(path+".tmp", data, 0600))
+		require.NoError(t, os.Rename(path+".tmp", path))
+	}
+	writeBundle(pemutil.EncodeCertificate(pki.rootCert))
+
+	log, logHook := test.NewNullLogger()
+	attestor := new(nodeattestor.V1)
+	plugintest.Load(t, BuiltIn(), attestor,
+		plugintest.Log(log),
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+		plugintest.Configure(fmt.Sprintf("ca_bundle_paths = [%q]", pki.path("bundle.pem"))),
+	)
+
+	_, err := pki.attestWith(t, attestor)
+	require.NoError(t, err)
+
+	// Replacing the trust anchors takes effect without reconfiguring
+	writeBundle(pemutil.EncodeCertificate(otherRoot))
+	_, err = pki.attestWith(t, attestor)
+	spiretest.RequireGRPCStatusContains(t, err, codes.PermissionDenied, "certificate verification failed")
+	require.Equal(t, "Reloaded trust bundle", logHook.LastEntry().Message)
+
+	writeBundle(pemutil.EncodeCertificate(pki.rootCert))
+	_, err = pki.attestWith(t, attestor)
+	require.NoError(t, err)
+
+	// An invalid update is reported and the previous trust anchors are kept
+	logHook.Reset()
+	writeBundle([]byte("not a bundle"))
+	_, err = pki.attestWith(t, attestor)
+	require.NoError(t, err)
+	entry := logHook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, logrus.ErrorLevel, entry.Level)
+	require.Equal(t, "Invalid trust bundle update; keeping the previous trust anchors", entry.Message)
+
+	// Removing the file also keeps the previous trust anchors
+	require.NoError(t, os.Remove(pki.path("bundle.pem")))
+	_, err = pki.attestWith(t, attestor)
+	require.NoError(t, err)
+}
@@ -7,12 +7,13 @@ import (
 	"sync"
 	"text/template"
 
+	"github.com/andres-erbsen/clock"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl"
 	nodeattestorv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/nodeattestor/v1"
 	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/plugin/x509pop"
-	"github.com/spiffe/spire/pkg/common/util"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -34,26 +35,52 @@ func builtin(p *Plugin) catalog.BuiltIn {
 
 type configuration struct {
 	trustDomain  string
-	trustBundle  *x509.CertPool
+	trustBundle  *trustAnchors
 	pathTemplate *template.Template
+	revocation   *revocationChecker
 }
 
 type Config struct {
 	CABundlePath      string   `hcl:"ca_bundle_path"`
 	CABundlePaths     []string `hcl:"ca_bundle_paths"`
 	AgentPathTemplate string   `hcl:"agent_path_template"`
+
+	// CRLPaths are CRL files (PEM or DER) checked for revoked certificates.
+	// The files are reloaded when they change.
+	CRLPaths []string `hcl:"crl_paths"`
+
+	// CRLFetchDistributionPoints enables fetching the CRLs advertised in
+	// the CRL distribution points of the certificates over HTTP.
+	CRLFetchDistributionPoints bool `hcl:"crl_fetch_distribution_points"`
+
+	// OCSPEnabled enables querying the OCSP responders advertised in the
+	// certificates.
+	OCSPEnabled bool `hcl:"ocsp_enabled"`
+
+	// RevocationFailOpen accepts certificates whose revocation status cannot
+	// be determined. Certificates known to be revoked are always rejected.
+	RevocationFailOpen bool `hcl:"revocation_fail_open"`
 }
 
 type Plugin struct {
 	nodeattestorv1.UnsafeNodeAttestorServer
 	configv1.UnsafeConfigServer
 
+	log   hclog.Logger
+	clock clock.Clock
+
 	m      sync.Mutex
 	config *configuration
 }
 
 func New() *Plugin {
-	return &Plugin{}
+	return &Plugin{
+		clock: clock.New(),
+	}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
 }
 
 func (p *Plugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
@@ -97,13 +124,19 @@ func (p *Plugin) Attest(stream nodeattestorv1.NodeAttestor_AttestServer) error {
 	// verify the chain of trust
 	chains, err := leaf.Verify(x509.VerifyOptions{
 		Intermediates: intermediates,
-		Roots:         config.trustBundle,
+		Roots:         config.trustBundle.get(),
 		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
 	})
 	if err != nil {
 		return status.Errorf(codes.PermissionDenied, "certificate verification failed: %v", err)
 	}
 
+	if config.revocation != nil {
+		if err := config.revocation.check(stream.Context(), chains); err != nil {
+			return status.Errorf(codes.PermissionDenied, "certificate revocation check failed: %v", err)
+		}
+	}
+
 	// now that the leaf certificate is trusted, issue a challenge to the node
 	// to prove possession of the private key.
 	challenge, err := x509pop.GenerateChallenge(leaf)
@@ -168,11 +201,16 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 		return nil, status.Error(codes.InvalidArgument, "trust_domain is required")
 	}
 
-	bundles, err := getBundles(hclConfig)
+	caPaths, err := getCAPaths(hclConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	trustBundle, err := newTrustAnchors(caPaths, p.log)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	pathTemplate := x509pop.DefaultAgentPathTemplate
 	if len(hclConfig.AgentPathTemplate) > 0 {
 		tmpl, err := template.New("agent-path").Parse(hclConfig.AgentPathTemplate)
@@ -182,16 +220,30 @@ func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest)
 		pathTemplate = tmpl
 	}
 
+	var revocation *revocationChecker
+	if len(hclConfig.CRLPaths) > 0 || hclConfig.CRLFetchDistributionPoints || hclConfig.OCSPEnabled {
+		revocation, err = newRevocationChecker(&revocationConfig{
+			crlPaths: hclConfig.CRLPaths,
+			fetchCDP: hclConfig.CRLFetchDistributionPoints,
+			ocsp:     hclConfig.OCSPEnabled,
+			failOpen: hclConfig.RevocationFailOpen,
+		}, p.clock, p.log)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unable to configure revocation checking: %v", err)
+		}
+	}
+
 	p.setConfiguration(&configuration{
 		trustDomain:  req.CoreConfiguration.TrustDomain,
-		trustBundle:  util.NewCertPool(bundles...),
+		trustBundle:  trustBundle,
 		pathTemplate: pathTemplate,
+		revocation:   revocation,
 	})
 
 	return &configv1.ConfigureResponse{}, nil
 }
 
-func getBundles(config *Config) ([]*x509.Certificate, error) {
+func getCAPaths(config *Config) ([]string, error) {
 	var caPaths []string
 
 	switch {
@@ -205,16 +257,7 @@ func getBundles(config *Config) ([]*x509.Certificate, error) {
 		return nil, status.Error(codes.InvalidArgument, "ca_bundle_path or ca_bundle_paths must be configured")
 	}
 
-	var cas []*x509.Certificate
-	for _, caPath := range caPaths {
-		certs, err := util.LoadCertificates(caPath)
-		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "unable to load trust bundle %q: %v", caPath, err)
-		}
-		cas = append(cas, certs...)
-	}
-
-	return cas, nil
+	return caPaths, nil
 }
 
 func (p *Plugin) getConfig() (*configuration, error) {
@@ -239,6 +282,26 @@ func buildSelectorValues(leaf *x509.Certificate, chains [][]*x509.Certificate) [
 		selectorValues = append(selectorValues, "subject:cn:"+leaf.Subject.CommonName)
 	}
 
+	if leaf.Issuer.CommonName != "" {
+		selectorValues = append(selectorValues, "issuer:cn:"+leaf.Issuer.CommonName)
+	}
+
+	// The issuing CA may be represented by different certificates (e.g. when
+	// it is cross-signed), so there is a selector for each of them.
+	issuers := map[string]bool{}
+	for _, chain := range chains {
+		if len(chain) < 2 {
+			continue
+		}
+		fp := x509pop.Fingerprint(chain[1])
+		if issuers[fp] {
+			continue
+		}
+		issuers[fp] = true
+
+		selectorValues = append(selectorValues, "issuer:fingerprint:"+fp)
+	}
+
 	// Used to avoid duplicating selectors.
 	fingerprints := map[string]*x509.Certificate{}
 	for _, chain := range chains {
@@ -122,6 +122,7 @@ func (s *Suite) TestAttestSuccess() {
 			spiretest.AssertProtoListEqual(t,
 				[]*common.Selector{
 					{Type: "x509pop", Value: "subject:cn:some common name"},
+					{Type: "x509pop", Value: "issuer:fingerprint:" + x509pop.Fingerprint(s.intermediateCert)},
 					{Type: "x509pop", Value: "ca:fingerprint:" + x509pop.Fingerprint(s.intermediateCert)},
 					{Type: "x509pop", Value: "ca:fingerprint:" + x509pop.Fingerprint(s.rootCert)},
 				}, result.Selectors)
@@ -0,0 +1,250 @@
+package filebundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	notifierv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/notifier/v1"
+	plugintypes "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/types"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/coretypes/bundle"
+	"github.com/spiffe/spire/pkg/common/coretypes/x509certificate"
+	"github.com/spiffe/spire/pkg/common/diskutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "filebundle"
+
+	formatPEM    = "pem"
+	formatJWKS   = "jwks"
+	formatSPIFFE = "spiffe"
+
+	defaultFileMode = 0644
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtIn(New())
+}
+
+func builtIn(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		notifierv1.NotifierPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+type pluginConfig struct {
+	// Paths the bundle is written to.
+	Paths []string `hcl:"paths"`
+	// Format of the bundle file. One of "pem", "jwks" or "spiffe". Defaults
+	// to "pem".
+	Format string `hcl:"format"`
+	// Octal file mode for the bundle files (e.g. "0644"). Defaults to 0644.
+	FileMode string `hcl:"file_mode"`
+	// Optional command (and arguments) executed after the bundle files have
+	// changed.
+	ReloadCommand []string `hcl:"reload_command"`
+
+	fileMode os.FileMode
+}
+
+type Plugin struct {
+	notifierv1.UnsafeNotifierServer
+	configv1.UnsafeConfigServer
+
+	mu     sync.RWMutex
+	log    hclog.Logger
+	config *pluginConfig
+
+	// writeMu serializes bundle writes. reloadPending is set when the bundle
+	// files changed and the reload command has not succeeded since, so a
+	// failed reload is retried on the next notification.
+	writeMu       sync.Mutex
+	reloadPending bool
+
+	hooks struct {
+		runCommand func(ctx context.Context, args []string) ([]byte, error)
+	}
+}
+
+func New() *Plugin {
+	p := &Plugin{}
+	p.hooks.runCommand = runCommand
+	return p
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Notify(ctx context.Context, req *notifierv1.NotifyRequest) (*notifierv1.NotifyResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if event, ok := req.Event.(*notifierv1.NotifyRequest_BundleUpdated); ok {
+		if err := p.writeBundle(ctx, config, event.BundleUpdated.Bundle); err != nil {
+			return nil, err
+		}
+	}
+	return &notifierv1.NotifyResponse{}, nil
+}
+
+func (p *Plugin) NotifyAndAdvise(ctx context.Context, req *notifierv1.NotifyAndAdviseRequest) (*notifierv1.NotifyAndAdviseResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if event, ok := req.Event.(*notifierv1.NotifyAndAdviseRequest_BundleLoaded); ok {
+		if err := p.writeBundle(ctx, config, event.BundleLoaded.Bundle); err != nil {
+			return nil, err
+		}
+	}
+	return &notifierv1.NotifyAndAdviseResponse{}, nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := new(pluginConfig)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if len(config.Paths) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "paths must be set")
+	}
+	for _, path := range config.Paths {
+		if path == "" {
+			return nil, status.Error(codes.InvalidArgument, "paths cannot contain an empty path")
+		}
+	}
+
+	switch config.Format {
+	case "":
+		config.Format = formatPEM
+	case formatPEM, formatJWKS, formatSPIFFE:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid format %q: expected %q, %q or %q", config.Format, formatPEM, formatJWKS, formatSPIFFE)
+	}
+
+	if len(config.ReloadCommand) > 0 && config.ReloadCommand[0] == "" {
+		return nil, status.Error(codes.InvalidArgument, "reload_command must start with the command to execute")
+	}
+
+	config.fileMode = defaultFileMode
+	if config.FileMode != "" {
+		mode, err := strconv.ParseUint(config.FileMode, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid file_mode %q: must be an octal permission value", config.FileMode)
+		}
+		config.fileMode = os.FileMode(mode)
+	}
+
+	p.setConfig(config)
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfig() (*pluginConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+func (p *Plugin) setConfig(config *pluginConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (p *Plugin) writeBundle(ctx context.Context, c *pluginConfig, b *plugintypes.Bundle) error {
+	if b == nil {
+		return status.Error(codes.InvalidArgument, "request is missing the bundle")
+	}
+
+	data, err := marshalBundle(c.Format, b)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal bundle: %v", err)
+	}
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	for _, path := range c.Paths {
+		current, err := os.ReadFile(path)
+		switch {
+		case err == nil && bytes.Equal(current, data):
+			p.log.Debug("Bundle file is up to date", telemetry.Path, path)
+			continue
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return status.Errorf(codes.Internal, "unable to read bundle file %q: %v", path, err)
+		}
+
+		if err := diskutil.AtomicWriteFile(path, data, c.fileMode); err != nil {
+			return status.Errorf(codes.Internal, "unable to write bundle file %q: %v", path, err)
+		}
+		// The mode used when creating the file is subject to the umask, so
+		// make sure the configured mode is applied.
+		if err := os.Chmod(path, c.fileMode); err != nil {
+			return status.Errorf(codes.Internal, "unable to set bundle file %q mode: %v", path, err)
+		}
+		p.log.Debug("Bundle file updated", telemetry.Path, path)
+		p.reloadPending = true
+	}
+
+	if p.reloadPending && len(c.ReloadCommand) > 0 {
+		if out, err := p.hooks.runCommand(ctx, c.ReloadCommand); err != nil {
+			return status.Errorf(codes.Internal, "reload command failed: %v: %s", err, bytes.TrimSpace(out))
+		}
+		p.log.Debug("Reload command executed")
+	}
+	p.reloadPending = false
+	return nil
+}
+
+// marshalBundle encodes the bundle using the given format. The output is
+// deterministic so it can be compared against the current file contents.
+func marshalBundle(format string, b *plugintypes.Bundle) ([]byte, error) {
+	if format == formatPEM {
+		x509Authorities, err := x509certificate.FromPluginProtos(b.X509Authorities)
+		if err != nil {
+			return nil, err
+		}
+		return pemutil.EncodeCertificates(x509Authorities), nil
+	}
+
+	commonBundle, err := bundle.ToCommonFromPluginProto(b)
+	if err != nil {
+		return nil, err
+	}
+	utilBundle, err := bundleutil.BundleFromProto(commonBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []bundleutil.MarshalOption{bundleutil.SequenceNumber(b.SequenceNumber)}
+	if format == formatJWKS {
+		opts = append(opts, bundleutil.StandardJWKS())
+	}
+	return bundleutil.Marshal(utilBundle, opts...)
+}
+
+func runCommand(ctx context.Context, args []string) ([]byte, error) {
+	return exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput() //nolint: gosec // command is provided by the operator
+}
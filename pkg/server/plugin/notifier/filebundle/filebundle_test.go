@@ -0,0 +1,278 @@
+package filebundle
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spiffe/spire/pkg/server/plugin/notifier"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestConfigure(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		code   codes.Code
+		desc   string
+	}{
+		{
+			name:   "malformed",
+			config: `MALFORMED`,
+			code:   codes.InvalidArgument,
+			desc:   "unable to decode configuration",
+		},
+		{
+			name:   "missing paths",
+			config: ``,
+			code:   codes.InvalidArgument,
+			desc:   "paths must be set",
+		},
+		{
+			name:   "empty path",
+			config: `paths = [""]`,
+			code:   codes.InvalidArgument,
+			desc:   "paths cannot contain an empty path",
+		},
+		{
+			name: "unsupported format",
+			config: `
+				paths = ["bundle.der"]
+				format = "der"
+			`,
+			code: codes.InvalidArgument,
+			desc: `invalid format "der": expected "pem", "jwks" or "spiffe"`,
+		},
+		{
+			name: "invalid file mode",
+			config: `
+				paths = ["bundle.pem"]
+				file_mode = "0999"
+			`,
+			code: codes.InvalidArgument,
+			desc: `invalid file_mode "0999": must be an octal permission value`,
+		},
+		{
+			name: "file mode out of range",
+			config: `
+				paths = ["bundle.pem"]
+				file_mode = "1777"
+			`,
+			code: codes.InvalidArgument,
+			desc: `invalid file_mode "1777": must be an octal permission value`,
+		},
+		{
+			name: "empty reload command",
+			config: `
+				paths = ["bundle.pem"]
+				reload_command = ["", "arg"]
+			`,
+			code: codes.InvalidArgument,
+			desc: "reload_command must start with the command to execute",
+		},
+		{
+			name: "success",
+			config: `
+				paths = ["bundle.json", "other/bundle.json"]
+				format = "spiffe"
+				file_mode = "0600"
+				reload_command = ["systemctl", "reload", "envoy"]
+			`,
+			code: codes.OK,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, BuiltIn(), nil,
+				plugintest.Configure(tt.config),
+				plugintest.CaptureConfigureError(&err))
+			if tt.code != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.code, tt.desc)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNotifyBundleUpdated(t *testing.T) {
+	testWriteBundle(t, func(n notifier.Notifier, bundle *common.Bundle) error {
+		return n.NotifyBundleUpdated(context.Background(), bundle)
+	})
+}
+
+func TestNotifyAndAdviseBundleLoaded(t *testing.T) {
+	testWriteBundle(t, func(n notifier.Notifier, bundle *common.Bundle) error {
+		return n.NotifyAndAdviseBundleLoaded(context.Background(), bundle)
+	})
+}
+
+func testWriteBundle(t *testing.T, notify func(notifier.Notifier, *common.Bundle) error) {
+	caCert1, _ := testca.CreateCACertificate(t, nil, nil)
+	caCert2, _ := testca.CreateCACertificate(t, nil, nil)
+	bundle1 := &common.Bundle{
+		TrustDomainId: "spiffe://example.org",
+		RootCas:       []*common.Certificate{{DerBytes: caCert1.Raw}},
+	}
+	bundle2 := &common.Bundle{
+		TrustDomainId: "spiffe://example.org",
+		RootCas:       []*common.Certificate{{DerBytes: caCert1.Raw}, {DerBytes: caCert2.Raw}},
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		n := new(notifier.V1)
+		plugintest.Load(t, BuiltIn(), n)
+		err := notify(n, bundle1)
+		spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "notifier(filebundle): not configured")
+	})
+
+	t.Run("writes to all paths and reloads on change", func(t *testing.T) {
+		dir := spiretest.TempDir(t)
+		path1 := filepath.Join(dir, "bundle1.pem")
+		path2 := filepath.Join(dir, "bundle2.pem")
+
+		p := New()
+		var reloads [][]string
+		p.hooks.runCommand = func(ctx context.Context, args []string) ([]byte, error) {
+			reloads = append(reloads, args)
+			return nil, nil
+		}
+
+		n := new(notifier.V1)
+		plugintest.Load(t, builtIn(p), n,
+			plugintest.ConfigureJSON(map[string]interface{}{
+				"paths":          []string{path1, path2},
+				"file_mode":      "0600",
+				"reload_command": []string{"reload", "me"},
+			}))
+
+		require.NoError(t, notify(n, bundle1))
+		requireBundleFile(t, path1, 0600, caCert1.Raw)
+		requireBundleFile(t, path2, 0600, caCert1.Raw)
+		assert.Equal(t, [][]string{{"reload", "me"}}, reloads)
+
+		// Notifying with the same bundle leaves the files alone and does not
+		// run the reload command.
+		require.NoError(t, notify(n, bundle1))
+		assert.Len(t, reloads, 1)
+
+		require.NoError(t, notify(n, bundle2))
+		requireBundleFile(t, path1, 0600, caCert1.Raw, caCert2.Raw)
+		requireBundleFile(t, path2, 0600, caCert1.Raw, caCert2.Raw)
+		assert.Len(t, reloads, 2)
+	})
+
+	t.Run("uses the configured format", func(t *testing.T) {
+		path := filepath.Join(spiretest.TempDir(t), "bundle.json")
+
+		n := new(notifier.V1)
+		plugintest.Load(t, BuiltIn(), n,
+			plugintest.ConfigureJSON(map[string]interface{}{
+				"paths":  []string{path},
+				"format": "spiffe",
+			}))
+
+		require.NoError(t, notify(n, bundle1))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"use": "x509-svid"`)
+	})
+
+	t.Run("fails to write", func(t *testing.T) {
+		path := filepath.Join(spiretest.TempDir(t), "missing", "bundle.pem")
+
+		n := new(notifier.V1)
+		plugintest.Load(t, BuiltIn(), n,
+			plugintest.ConfigureJSON(map[string]interface{}{
+				"paths": []string{path},
+			}))
+
+		err := notify(n, bundle1)
+		spiretest.RequireGRPCStatusHasPrefix(t, err, codes.Internal, "notifier(filebundle): unable to write bundle file")
+	})
+
+	t.Run("reload command fails", func(t *testing.T) {
+		path := filepath.Join(spiretest.TempDir(t), "bundle.pem")
+
+		p := New()
+		p.hooks.runCommand = func(ctx context.Context, args []string) ([]byte, error) {
+			return []byte("some output\n"), errors.New("exit status 1")
+		}
+
+		n := new(notifier.V1)
+		plugintest.Load(t, builtIn(p), n,
+			plugintest.ConfigureJSON(map[string]interface{}{
+				"paths":          []string{path},
+				"reload_command": []string{"reload"},
+			}))
+
+		err := notify(n, bundle1)
+		spiretest.RequireGRPCStatus(t, err, codes.Internal, "notifier(filebundle): reload command failed: exit status 1: some output")
+		requireBundleFile(t, path, 0644, caCert1.Raw)
+	})
+
+	t.Run("failed reload is retried for an unchanged bundle", func(t *testing.T) {
+		path := filepath.Join(spiretest.TempDir(t), "bundle.pem")
+
+		p := New()
+		var reloads int
+		reloadErr := errors.New("exit status 1")
+		p.hooks.runCommand = func(ctx context.Context, args []string) ([]byte, error) {
+			reloads++
+			return nil, reloadErr
+		}
+
+		n := new(notifier.V1)
+		plugintest.Load(t, builtIn(p), n,
+			plugintest.ConfigureJSON(map[string]interface{}{
+				"paths":          []string{path},
+				"reload_command": []string{"reload"},
+			}))
+
+		err := notify(n, bundle1)
+		spiretest.RequireGRPCStatusHasPrefix(t, err, codes.Internal, "notifier(filebundle): reload command failed")
+		assert.Equal(t, 1, reloads)
+
+		// The files are up to date, but the reload command runs again since
+		// the previous run failed.
+		reloadErr = nil
+		require.NoError(t, notify(n, bundle1))
+		assert.Equal(t, 2, reloads)
+
+		// Once the reload succeeded, it does not run for an unchanged bundle.
+		require.NoError(t, notify(n, bundle1))
+		assert.Equal(t, 2, reloads)
+	})
+}
+
+func requireBundleFile(t *testing.T, path string, mode os.FileMode, expected ...[]byte) {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, mode, info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var actual [][]byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		actual = append(actual, block.Bytes)
+	}
+	require.Equal(t, expected, actual)
+}
@@ -0,0 +1,94 @@
+package s3bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type bucketClient interface {
+	// GetObjectETag returns the ETag of the object, or an empty string if
+	// the object does not exist.
+	GetObjectETag(ctx context.Context, bucket, key string) (string, error)
+	// PutObject writes the object only if its ETag still matches the given
+	// one. If the ETag is empty, the object is only written if it does not
+	// exist.
+	PutObject(ctx context.Context, bucket, key string, data []byte, contentType, etag string) error
+}
+
+type s3BucketClient struct {
+	client *s3.S3
+}
+
+func newS3BucketClient(c *pluginConfig) (bucketClient, error) {
+	awsConfig := &aws.Config{
+		Region:           aws.String(c.Region),
+		S3ForcePathStyle: aws.Bool(c.ForcePathStyle),
+	}
+	if c.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(c.Endpoint)
+	}
+	if c.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)
+	}
+
+	awsSession, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3BucketClient{
+		client: s3.New(awsSession),
+	}, nil
+}
+
+func (c *s3BucketClient) GetObjectETag(ctx context.Context, bucket, key string) (string, error) {
+	out, err := c.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var reqErr awserr.RequestFailure
+		if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	return aws.StringValue(out.ETag), nil
+}
+
+func (c *s3BucketClient) PutObject(ctx context.Context, bucket, key string, data []byte, contentType, etag string) error {
+	req, _ := c.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	req.SetContext(ctx)
+
+	// The SDK does not model the conditional write headers for PutObject, so
+	// they are set directly on the request before it is signed.
+	if etag != "" {
+		req.HTTPRequest.Header.Set("If-Match", etag)
+	} else {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	}
+	return req.Send()
+}
+
+func isConditionNotMetError(err error) bool {
+	var reqErr awserr.RequestFailure
+	if !errors.As(err, &reqErr) {
+		return false
+	}
+	// S3 replies with 412 when the condition is not met and with 409 when a
+	// conflicting conditional write is in progress.
+	return reqErr.StatusCode() == http.StatusPreconditionFailed || reqErr.StatusCode() == http.StatusConflict
+}
@@ -0,0 +1,170 @@
+package s3bundle
+
+import (
+	"crypto/md5" //nolint: gosec // S3 ETags are MD5 digests
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	etag        string
+}
+
+// fakeS3Server is a local stand-in for an S3-compatible service (e.g. MinIO).
+// It serves path-style HEAD and PUT object requests, including the
+// If-Match/If-None-Match conditional writes.
+type fakeS3Server struct {
+	t      *testing.T
+	server *httptest.Server
+	bucket string
+
+	mtx         sync.Mutex
+	objects     map[string]*fakeObject
+	accessKeyID string
+	// beforePut, if set, is invoked (once) before a PUT is processed. It
+	// is used to simulate another server racing to update the object.
+	beforePut func()
+}
+
+func newFakeS3Server(t *testing.T, bucket string) *fakeS3Server {
+	s := &fakeS3Server{
+		t:       t,
+		bucket:  bucket,
+		objects: make(map[string]*fakeObject),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *fakeS3Server) url() string {
+	return s.server.URL
+}
+
+func (s *fakeS3Server) putObject(key string, data []byte, contentType string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.putObjectLocked(key, data, contentType)
+}
+
+func (s *fakeS3Server) getObject(key string) *fakeObject {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.objects[key]
+}
+
+func (s *fakeS3Server) setBeforePut(fn func()) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.beforePut = fn
+}
+
+// lastAccessKeyID returns the access key ID used to sign the last request.
+func (s *fakeS3Server) lastAccessKeyID() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.accessKeyID
+}
+
+func (s *fakeS3Server) putObjectLocked(key string, data []byte, contentType string) string {
+	sum := md5.Sum(data) //nolint: gosec // S3 ETags are MD5 digests
+	etag := fmt.Sprintf("%q", hex.EncodeToString(sum[:]))
+	s.objects[key] = &fakeObject{
+		data:        data,
+		contentType: contentType,
+		etag:        etag,
+	}
+	return etag
+}
+
+func (s *fakeS3Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		s.writeError(w, http.StatusBadRequest, "InvalidRequest")
+		return
+	}
+	bucket, key := parts[0], parts[1]
+
+	s.mtx.Lock()
+	s.accessKeyID = parseAccessKeyID(req.Header.Get("Authorization"))
+	beforePut := s.beforePut
+	if req.Method == http.MethodPut {
+		s.beforePut = nil
+	}
+	s.mtx.Unlock()
+
+	if bucket != s.bucket {
+		s.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodHead:
+		obj := s.getObject(key)
+		if obj == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Content-Type", obj.contentType)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPut:
+		if beforePut != nil {
+			beforePut()
+		}
+
+		data, err := io.ReadAll(req.Body)
+		require.NoError(s.t, err)
+
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+
+		existing := s.objects[key]
+		ifMatch := req.Header.Get("If-Match")
+		ifNoneMatch := req.Header.Get("If-None-Match")
+		switch {
+		case ifMatch != "" && (existing == nil || existing.etag != ifMatch):
+			s.writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		case ifNoneMatch == "*" && existing != nil:
+			s.writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+
+		w.Header().Set("ETag", s.putObjectLocked(key, data, req.Header.Get("Content-Type")))
+		w.WriteHeader(http.StatusOK)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *fakeS3Server) writeError(w http.ResponseWriter, statusCode int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(statusCode)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+// parseAccessKeyID extracts the access key ID from a SigV4 authorization
+// header (e.g. "AWS4-HMAC-SHA256 Credential=AKID/20220101/us-east-1/s3/aws4_request, ...").
+func parseAccessKeyID(authorization string) string {
+	const prefix = "Credential="
+	i := strings.Index(authorization, prefix)
+	if i < 0 {
+		return ""
+	}
+	credential := authorization[i+len(prefix):]
+	if j := strings.Index(credential, "/"); j >= 0 {
+		return credential[:j]
+	}
+	return ""
+}
@@ -0,0 +1,250 @@
+package s3bundle
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	"github.com/spiffe/spire-plugin-sdk/pluginsdk"
+	identityproviderv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/hostservice/server/identityprovider/v1"
+	notifierv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/notifier/v1"
+	plugintypes "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/types"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/coretypes/bundle"
+	"github.com/spiffe/spire/pkg/common/coretypes/x509certificate"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	formatPEM    = "pem"
+	formatJWKS   = "jwks"
+	formatSPIFFE = "spiffe"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtIn(New())
+}
+
+func builtIn(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn("s3_bundle",
+		notifierv1.NotifierPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+type pluginConfig struct {
+	Bucket    string `hcl:"bucket"`
+	ObjectKey string `hcl:"object_key"`
+	// Format of the bundle object. One of "pem", "jwks" or "spiffe".
+	// Defaults to "pem".
+	Format string `hcl:"format"`
+
+	Region string `hcl:"region"`
+	// Endpoint of an S3-compatible service (e.g. MinIO or Ceph). If unset,
+	// the AWS S3 endpoint for the region is used.
+	Endpoint string `hcl:"endpoint"`
+	// ForcePathStyle addresses the bucket using the URL path instead of a
+	// virtual host, which most S3-compatible services require.
+	ForcePathStyle bool `hcl:"force_path_style"`
+
+	// Static credentials. If unset, the credentials are obtained from the
+	// environment (environment variables, shared credentials file or
+	// instance role).
+	AccessKeyID     string `hcl:"access_key_id"`
+	SecretAccessKey string `hcl:"secret_access_key"`
+	SessionToken    string `hcl:"session_token"`
+}
+
+type Plugin struct {
+	notifierv1.UnsafeNotifierServer
+	configv1.UnsafeConfigServer
+
+	mu               sync.RWMutex
+	log              hclog.Logger
+	config           *pluginConfig
+	identityProvider identityproviderv1.IdentityProviderServiceClient
+
+	hooks struct {
+		newBucketClient func(config *pluginConfig) (bucketClient, error)
+	}
+}
+
+func New() *Plugin {
+	p := &Plugin{}
+	p.hooks.newBucketClient = newS3BucketClient
+	return p
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) BrokerHostServices(broker pluginsdk.ServiceBroker) error {
+	if !broker.BrokerClient(&p.identityProvider) {
+		return status.Errorf(codes.FailedPrecondition, "IdentityProvider host service is required")
+	}
+	return nil
+}
+
+func (p *Plugin) Notify(ctx context.Context, req *notifierv1.NotifyRequest) (*notifierv1.NotifyResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := req.Event.(*notifierv1.NotifyRequest_BundleUpdated); ok {
+		// ignore the bundle presented in the request. see updateBundleObject for details on why.
+		if err := p.updateBundleObject(ctx, config); err != nil {
+			return nil, err
+		}
+	}
+	return &notifierv1.NotifyResponse{}, nil
+}
+
+func (p *Plugin) NotifyAndAdvise(ctx context.Context, req *notifierv1.NotifyAndAdviseRequest) (*notifierv1.NotifyAndAdviseResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := req.Event.(*notifierv1.NotifyAndAdviseRequest_BundleLoaded); ok {
+		// ignore the bundle presented in the request. see updateBundleObject for details on why.
+		if err := p.updateBundleObject(ctx, config); err != nil {
+			return nil, err
+		}
+	}
+	return &notifierv1.NotifyAndAdviseResponse{}, nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (resp *configv1.ConfigureResponse, err error) {
+	config := new(pluginConfig)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if config.Bucket == "" {
+		return nil, status.Error(codes.InvalidArgument, "bucket must be set")
+	}
+	if config.ObjectKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "object_key must be set")
+	}
+	if config.Region == "" {
+		return nil, status.Error(codes.InvalidArgument, "region must be set")
+	}
+	if (config.AccessKeyID == "") != (config.SecretAccessKey == "") {
+		return nil, status.Error(codes.InvalidArgument, "access_key_id and secret_access_key must be set together")
+	}
+	if config.SessionToken != "" && config.AccessKeyID == "" {
+		return nil, status.Error(codes.InvalidArgument, "session_token requires access_key_id and secret_access_key")
+	}
+	switch config.Format {
+	case "":
+		config.Format = formatPEM
+	case formatPEM, formatJWKS, formatSPIFFE:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid format %q: expected %q, %q or %q", config.Format, formatPEM, formatJWKS, formatSPIFFE)
+	}
+
+	p.setConfig(config)
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfig() (*pluginConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+func (p *Plugin) setConfig(config *pluginConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (p *Plugin) updateBundleObject(ctx context.Context, c *pluginConfig) error {
+	client, err := p.hooks.newBucketClient(c)
+	if err != nil {
+		return status.Errorf(codes.Unknown, "unable to instantiate bucket client: %v", err)
+	}
+
+	for {
+		// Get the bundle object ETag that we can use to resolve conflicts
+		// racing on updates from other servers.
+		etag, err := client.GetObjectETag(ctx, c.Bucket, c.ObjectKey)
+		if err != nil {
+			return status.Errorf(codes.Unknown, "unable to get bundle object %s/%s: %v", c.Bucket, c.ObjectKey, err)
+		}
+		p.log.Debug("Bundle object retrieved", "etag", etag)
+
+		// Load bundle data from the the identity provider. The bundle has to
+		// be loaded after fetching the ETag so we can properly detect and
+		// correct a race updating the bundle (i.e. read-modify-write
+		// semantics).
+		resp, err := p.identityProvider.FetchX509Identity(ctx, &identityproviderv1.FetchX509IdentityRequest{})
+		if err != nil {
+			st := status.Convert(err)
+			return status.Errorf(st.Code(), "unable to fetch bundle from SPIRE server: %v", st.Message())
+		}
+
+		data, err := marshalBundle(c.Format, resp.Bundle)
+		if err != nil {
+			return status.Errorf(codes.Internal, "unable to marshal bundle: %v", err)
+		}
+
+		// Upload the bundle, handling conflicts
+		if err := client.PutObject(ctx, c.Bucket, c.ObjectKey, data, contentType(c.Format), etag); err != nil {
+			// If there is a conflict then some other server won the race updating
+			// the object. We need to retrieve the latest bundle and try again.
+			if isConditionNotMetError(err) {
+				p.log.Debug("Conflict detected setting bundle object", "etag", etag)
+				continue
+			}
+			return status.Errorf(codes.Unknown, "unable to update bundle object %s/%s: %v", c.Bucket, c.ObjectKey, err)
+		}
+		p.log.Debug("Bundle object updated", "etag", etag)
+		return nil
+	}
+}
+
+// marshalBundle encodes the bundle using the given format.
+func marshalBundle(format string, b *plugintypes.Bundle) ([]byte, error) {
+	if format == formatPEM {
+		x509Authorities, err := x509certificate.FromPluginProtos(b.X509Authorities)
+		if err != nil {
+			return nil, err
+		}
+		return pemutil.EncodeCertificates(x509Authorities), nil
+	}
+
+	commonBundle, err := bundle.ToCommonFromPluginProto(b)
+	if err != nil {
+		return nil, err
+	}
+	utilBundle, err := bundleutil.BundleFromProto(commonBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []bundleutil.MarshalOption{bundleutil.SequenceNumber(b.SequenceNumber)}
+	if format == formatJWKS {
+		opts = append(opts, bundleutil.StandardJWKS())
+	}
+	return bundleutil.Marshal(utilBundle, opts...)
+}
+
+// contentType returns the media type of the bundle object for the given
+// format.
+func contentType(format string) string {
+	if format == formatPEM {
+		return "application/x-pem-file"
+	}
+	return "application/json"
+}
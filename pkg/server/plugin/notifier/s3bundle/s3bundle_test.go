@@ -0,0 +1,322 @@
+package s3bundle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	identityproviderv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/hostservice/server/identityprovider/v1"
+	plugintypes "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/types"
+	"github.com/spiffe/spire/pkg/server/plugin/notifier"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/fakes/fakeidentityprovider"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRequiresIdentityProvider(t *testing.T) {
+	var err error
+	plugintest.Load(t, BuiltIn(), nil, plugintest.CaptureLoadError(&err))
+	spiretest.RequireGRPCStatusContains(t, err, codes.FailedPrecondition, "IdentityProvider host service is required")
+}
+
+func TestConfigure(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		code   codes.Code
+		desc   string
+	}{
+		{
+			name:   "malformed",
+			config: `MALFORMED`,
+			code:   codes.InvalidArgument,
+			desc:   "unable to decode configuration",
+		},
+		{
+			name: "missing bucket",
+			config: `
+				object_key = "bundle.pem"
+				region = "us-east-1"
+			`,
+			code: codes.InvalidArgument,
+			desc: "bucket must be set",
+		},
+		{
+			name: "missing object key",
+			config: `
+				bucket = "the-bucket"
+				region = "us-east-1"
+			`,
+			code: codes.InvalidArgument,
+			desc: "object_key must be set",
+		},
+		{
+			name: "missing region",
+			config: `
+				bucket = "the-bucket"
+				object_key = "bundle.pem"
+			`,
+			code: codes.InvalidArgument,
+			desc: "region must be set",
+		},
+		{
+			name: "access key without secret",
+			config: `
+				bucket = "the-bucket"
+				object_key = "bundle.pem"
+				region = "us-east-1"
+				access_key_id = "AKID"
+			`,
+			code: codes.InvalidArgument,
+			desc: "access_key_id and secret_access_key must be set together",
+		},
+		{
+			name: "session token without static credentials",
+			config: `
+				bucket = "the-bucket"
+				object_key = "bundle.pem"
+				region = "us-east-1"
+				session_token = "TOKEN"
+			`,
+			code: codes.InvalidArgument,
+			desc: "session_token requires access_key_id and secret_access_key",
+		},
+		{
+			name: "unsupported format",
+			config: `
+				bucket = "the-bucket"
+				object_key = "bundle.der"
+				region = "us-east-1"
+				format = "der"
+			`,
+			code: codes.InvalidArgument,
+			desc: `invalid format "der": expected "pem", "jwks" or "spiffe"`,
+		},
+		{
+			name: "success with environment credentials",
+			config: `
+				bucket = "the-bucket"
+				object_key = "bundle.pem"
+				region = "us-east-1"
+			`,
+			code: codes.OK,
+		},
+		{
+			name: "success with static credentials and custom endpoint",
+			config: `
+				bucket = "the-bucket"
+				object_key = "bundle.json"
+				region = "us-east-1"
+				format = "spiffe"
+				endpoint = "https://minio.example.org:9000"
+				force_path_style = true
+				access_key_id = "AKID"
+				secret_access_key = "SECRET"
+				session_token = "TOKEN"
+			`,
+			code: codes.OK,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, BuiltIn(), nil,
+				plugintest.Configure(tt.config),
+				plugintest.CaptureConfigureError(&err),
+				plugintest.HostServices(identityproviderv1.IdentityProviderServiceServer(fakeidentityprovider.New())))
+			if tt.code != codes.OK {
+				spiretest.RequireGRPCStatusContains(t, err, tt.code, tt.desc)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNotifyBundleUpdated(t *testing.T) {
+	testUpdateBundleObject(t, func(n notifier.Notifier) error {
+		return n.NotifyBundleUpdated(context.Background(), &common.Bundle{TrustDomainId: "spiffe://example.org"})
+	})
+}
+
+func TestNotifyAndAdviseBundleLoaded(t *testing.T) {
+	testUpdateBundleObject(t, func(n notifier.Notifier) error {
+		return n.NotifyAndAdviseBundleLoaded(context.Background(), &common.Bundle{TrustDomainId: "spiffe://example.org"})
+	})
+}
+
+func testUpdateBundleObject(t *testing.T, notify func(notifier.Notifier) error) {
+	caCert1, _ := testca.CreateCACertificate(t, nil, nil)
+	caCert2, _ := testca.CreateCACertificate(t, nil, nil)
+	bundle1 := &plugintypes.Bundle{TrustDomain: "example.org", X509Authorities: []*plugintypes.X509Certificate{{Asn1: caCert1.Raw}}}
+	bundle2 := &plugintypes.Bundle{TrustDomain: "example.org", X509Authorities: []*plugintypes.X509Certificate{{Asn1: caCert2.Raw}}}
+
+	for _, tt := range []struct {
+		name           string
+		bundles        []*plugintypes.Bundle
+		bucket         string
+		format         string
+		skipConfigure  bool
+		setup          func(s *fakeS3Server)
+		code           codes.Code
+		desc           string
+		expectedBundle *plugintypes.Bundle
+	}{
+		{
+			name:          "not configured",
+			skipConfigure: true,
+			code:          codes.FailedPrecondition,
+			desc:          "notifier(s3_bundle): not configured",
+		},
+		{
+			name: "failed to fetch bundle from identity provider",
+			code: codes.Unknown,
+			desc: "notifier(s3_bundle): unable to fetch bundle from SPIRE server: no bundle",
+		},
+		{
+			name:    "bucket does not exist",
+			bundles: []*plugintypes.Bundle{bundle1},
+			bucket:  "other-bucket",
+			code:    codes.Unknown,
+			desc:    "notifier(s3_bundle): unable to update bundle object other-bucket/bundle.pem: NoSuchBucket",
+		},
+		{
+			name:           "success creating object",
+			bundles:        []*plugintypes.Bundle{bundle1},
+			code:           codes.OK,
+			expectedBundle: bundle1,
+		},
+		{
+			name:    "success replacing object",
+			bundles: []*plugintypes.Bundle{bundle2},
+			setup: func(s *fakeS3Server) {
+				s.putObject("bundle.pem", []byte("old"), "application/x-pem-file")
+			},
+			code:           codes.OK,
+			expectedBundle: bundle2,
+		},
+		{
+			name:           "success with spiffe format",
+			bundles:        []*plugintypes.Bundle{bundle1},
+			format:         formatSPIFFE,
+			code:           codes.OK,
+			expectedBundle: bundle1,
+		},
+		{
+			name:    "success with conflict resolution",
+			bundles: []*plugintypes.Bundle{bundle1, bundle2},
+			setup: func(s *fakeS3Server) {
+				// another server updates the object after this one has
+				// retrieved the ETag
+				s.setBeforePut(func() {
+					s.putObject("bundle.pem", []byte("newer"), "application/x-pem-file")
+				})
+			},
+			code:           codes.OK,
+			expectedBundle: bundle2,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeS3Server(t, "the-bucket")
+			if tt.setup != nil {
+				tt.setup(server)
+			}
+
+			idp := fakeidentityprovider.New()
+			for _, bundle := range tt.bundles {
+				idp.AppendBundle(bundle)
+			}
+
+			bucket := tt.bucket
+			if bucket == "" {
+				bucket = "the-bucket"
+			}
+			format := tt.format
+			if format == "" {
+				format = formatPEM
+			}
+
+			options := []plugintest.Option{
+				plugintest.HostServices(identityproviderv1.IdentityProviderServiceServer(idp)),
+			}
+			if !tt.skipConfigure {
+				options = append(options, plugintest.ConfigureJSON(map[string]interface{}{
+					"bucket":            bucket,
+					"object_key":        "bundle.pem",
+					"region":            "us-east-1",
+					"format":            format,
+					"endpoint":          server.url(),
+					"force_path_style":  true,
+					"access_key_id":     "AKID",
+					"secret_access_key": "SECRET",
+				}))
+			}
+
+			plugin := new(notifier.V1)
+			plugintest.Load(t, BuiltIn(), plugin, options...)
+
+			err := notify(plugin)
+			if tt.code != codes.OK {
+				spiretest.RequireGRPCStatusHasPrefix(t, err, tt.code, tt.desc)
+				return
+			}
+			require.NoError(t, err)
+
+			expectedData, err := marshalBundle(format, tt.expectedBundle)
+			require.NoError(t, err)
+
+			obj := server.getObject("bundle.pem")
+			require.NotNil(t, obj)
+			assert.Equal(t, string(expectedData), string(obj.data))
+			assert.Equal(t, contentType(format), obj.contentType)
+			assert.Equal(t, "AKID", server.lastAccessKeyID())
+		})
+	}
+}
+
+func TestEnvironmentCredentials(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENV-AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "ENV-SECRET")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	caCert, _ := testca.CreateCACertificate(t, nil, nil)
+	server := newFakeS3Server(t, "the-bucket")
+	idp := fakeidentityprovider.New()
+	idp.AppendBundle(&plugintypes.Bundle{TrustDomain: "example.org", X509Authorities: []*plugintypes.X509Certificate{{Asn1: caCert.Raw}}})
+
+	plugin := new(notifier.V1)
+	plugintest.Load(t, BuiltIn(), plugin,
+		plugintest.HostServices(identityproviderv1.IdentityProviderServiceServer(idp)),
+		plugintest.ConfigureJSON(map[string]interface{}{
+			"bucket":           "the-bucket",
+			"object_key":       "bundle.pem",
+			"region":           "us-east-1",
+			"endpoint":         server.url(),
+			"force_path_style": true,
+		}))
+
+	require.NoError(t, plugin.NotifyBundleUpdated(context.Background(), &common.Bundle{TrustDomainId: "spiffe://example.org"}))
+	assert.Equal(t, "ENV-AKID", server.lastAccessKeyID())
+	require.NotNil(t, server.getObject("bundle.pem"))
+}
+
+func TestIsConditionNotMetError(t *testing.T) {
+	assert.True(t, isConditionNotMetError(awserr.NewRequestFailure(awserr.New("PreconditionFailed", "", nil), http.StatusPreconditionFailed, "")))
+	assert.True(t, isConditionNotMetError(awserr.NewRequestFailure(awserr.New("ConditionalRequestConflict", "", nil), http.StatusConflict, "")))
+	assert.False(t, isConditionNotMetError(awserr.NewRequestFailure(awserr.New("AccessDenied", "", nil), http.StatusForbidden, "")))
+	assert.False(t, isConditionNotMetError(errors.New("ohno")))
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	signatureMethodHMAC = "hmac"
+	signatureMethodJWS  = "jws"
+
+	hmacSignaturePrefix = "sha256="
+)
+
+// payloadSigner produces the value of the signature header for a payload.
+type payloadSigner interface {
+	Sign(payload []byte) (string, error)
+}
+
+func newPayloadSigner(config *Configuration) (payloadSigner, error) {
+	switch config.SignatureMethod {
+	case "":
+		if config.HMACSecretPath != "" || config.JWSKeyPath != "" {
+			return nil, errors.New("signature_method must be set when signing material is configured")
+		}
+		return nil, nil
+	case signatureMethodHMAC:
+		if config.HMACSecretPath == "" {
+			return nil, errors.New("hmac_secret_path is required when signature_method is hmac")
+		}
+		secret, err := readSecretFile(config.HMACSecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load HMAC secret: %v", err)
+		}
+		return hmacSigner{secret: secret}, nil
+	case signatureMethodJWS:
+		if config.JWSKeyPath == "" {
+			return nil, errors.New("jws_key_path is required when signature_method is jws")
+		}
+		key, err := pemutil.LoadSigner(config.JWSKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load JWS key: %v", err)
+		}
+		return newJWSSigner(key, config.JWSKeyID)
+	default:
+		return nil, fmt.Errorf("unsupported signature_method %q; expected %q or %q", config.SignatureMethod, signatureMethodHMAC, signatureMethodJWS)
+	}
+}
+
+// hmacSigner signs the payload with HMAC-SHA256. The header value is the hex
+// encoded MAC prefixed with "sha256=".
+type hmacSigner struct {
+	secret []byte
+}
+
+func (s hmacSigner) Sign(payload []byte) (string, error) {
+	mac := hmac.New(sha256.New, s.secret)
+	_, _ = mac.Write(payload)
+	return hmacSignaturePrefix + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// jwsSigner signs the payload with a JWS using the compact serialization
+// with a detached payload (RFC 7515, appendix F).
+type jwsSigner struct {
+	signer jose.Signer
+}
+
+func newJWSSigner(key interface{}, keyID string) (*jwsSigner, error) {
+	var alg jose.SignatureAlgorithm
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			alg = jose.ES256
+		case elliptic.P384():
+			alg = jose.ES384
+		default:
+			return nil, errors.New("unsupported JWS key: EC keys must use the P-256 or P-384 curves")
+		}
+	case *rsa.PrivateKey:
+		alg = jose.RS256
+	default:
+		return nil, fmt.Errorf("unsupported JWS key type %T", key)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: alg,
+		Key: jose.JSONWebKey{
+			Key:   key,
+			KeyID: keyID,
+		},
+	}, new(jose.SignerOptions).WithType("JOSE"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create JWS signer: %v", err)
+	}
+	return &jwsSigner{signer: signer}, nil
+}
+
+func (s *jwsSigner) Sign(payload []byte) (string, error) {
+	jws, err := s.signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return jws.DetachedCompactSerialize()
+}
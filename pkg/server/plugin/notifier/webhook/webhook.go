@@ -0,0 +1,459 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	notifierv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/notifier/v1"
+	plugintypes "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/types"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/bundleutil"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/coretypes/bundle"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/common/telemetry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "webhook"
+
+	defaultTimeout        = 10 * time.Second
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+
+	// maxResponseSize limits how much of the endpoint response is read
+	maxResponseSize = 1 << 16
+
+	eventBundleLoaded  = "bundle_loaded"
+	eventBundleUpdated = "bundle_updated"
+
+	eventHeader     = "X-SPIRE-Event"
+	signatureHeader = "X-SPIRE-Signature"
+)
+
+func BuiltIn() catalog.BuiltIn {
+	return builtIn(New())
+}
+
+func builtIn(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		notifierv1.NotifierPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+type Configuration struct {
+	// URLs the notifications are POSTed to.
+	Endpoints []string `hcl:"endpoints" json:"endpoints"`
+	// Timeout for each delivery attempt. Defaults to 10s.
+	Timeout string `hcl:"timeout" json:"timeout"`
+	// Number of retries after a failed delivery attempt. Defaults to 3.
+	MaxRetries *int `hcl:"max_retries" json:"max_retries"`
+	// Backoff before the first retry. It doubles on every retry up to
+	// max_backoff. Defaults to 1s and 30s respectively.
+	InitialBackoff string `hcl:"initial_backoff" json:"initial_backoff"`
+	MaxBackoff     string `hcl:"max_backoff" json:"max_backoff"`
+	// If true, failing to deliver the bundle loaded notification to any of
+	// the endpoints prevents the server from starting.
+	MustSucceedOnLoad bool `hcl:"must_succeed_on_load" json:"must_succeed_on_load"`
+
+	// TLS configuration used to connect to https endpoints.
+	CACertPath         string `hcl:"ca_cert_path" json:"ca_cert_path"`
+	ClientCertPath     string `hcl:"client_cert_path" json:"client_cert_path"`
+	ClientKeyPath      string `hcl:"client_key_path" json:"client_key_path"`
+	InsecureSkipVerify bool   `hcl:"insecure_skip_verify" json:"insecure_skip_verify"`
+
+	// How the payload is signed. One of "hmac" or "jws". Payloads are not
+	// signed if unset.
+	SignatureMethod string `hcl:"signature_method" json:"signature_method"`
+	// Path to a file holding the HMAC secret.
+	HMACSecretPath string `hcl:"hmac_secret_path" json:"hmac_secret_path"`
+	// Path to the PEM encoded private key used to sign the JWS and the key ID
+	// included in its header.
+	JWSKeyPath string `hcl:"jws_key_path" json:"jws_key_path"`
+	JWSKeyID   string `hcl:"jws_key_id" json:"jws_key_id"`
+}
+
+// notification is the body POSTed to the endpoints.
+type notification struct {
+	Event          string          `json:"event"`
+	TrustDomain    string          `json:"trust_domain"`
+	SequenceNumber uint64          `json:"sequence_number"`
+	Timestamp      int64           `json:"timestamp"`
+	Bundle         json.RawMessage `json:"bundle"`
+}
+
+type pluginConfig struct {
+	endpoints         []string
+	maxRetries        int
+	initialBackoff    time.Duration
+	maxBackoff        time.Duration
+	mustSucceedOnLoad bool
+	httpClient        *http.Client
+	signer            payloadSigner
+}
+
+type Plugin struct {
+	notifierv1.UnsafeNotifierServer
+	configv1.UnsafeConfigServer
+
+	mu     sync.RWMutex
+	log    hclog.Logger
+	config *pluginConfig
+
+	// sequenceNumber is incremented for every notification sent so receivers
+	// can detect reordered deliveries. It starts at the time of the first
+	// notification, in nanoseconds, so it keeps increasing across restarts.
+	seqMu          sync.Mutex
+	sequenceNumber uint64
+
+	clock clock.Clock
+}
+
+func New() *Plugin {
+	return &Plugin{
+		clock: clock.New(),
+	}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Notify(ctx context.Context, req *notifierv1.NotifyRequest) (*notifierv1.NotifyResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if event, ok := req.Event.(*notifierv1.NotifyRequest_BundleUpdated); ok {
+		if err := p.notify(ctx, config, eventBundleUpdated, event.BundleUpdated.Bundle); err != nil {
+			return nil, err
+		}
+	}
+	return &notifierv1.NotifyResponse{}, nil
+}
+
+func (p *Plugin) NotifyAndAdvise(ctx context.Context, req *notifierv1.NotifyAndAdviseRequest) (*notifierv1.NotifyAndAdviseResponse, error) {
+	config, err := p.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if event, ok := req.Event.(*notifierv1.NotifyAndAdviseRequest_BundleLoaded); ok {
+		if err := p.notify(ctx, config, eventBundleLoaded, event.BundleLoaded.Bundle); err != nil {
+			if config.mustSucceedOnLoad {
+				return nil, err
+			}
+			p.log.Warn("Failed to deliver bundle loaded notification", telemetry.Error, err)
+		}
+	}
+	return &notifierv1.NotifyAndAdviseResponse{}, nil
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	pc, err := buildConfig(config)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	p.setConfig(pc)
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) getConfig() (*pluginConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.config == nil {
+		return nil, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.config, nil
+}
+
+func (p *Plugin) setConfig(config *pluginConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+func (p *Plugin) nextSequenceNumber() uint64 {
+	p.seqMu.Lock()
+	defer p.seqMu.Unlock()
+	if p.sequenceNumber == 0 {
+		p.sequenceNumber = uint64(p.clock.Now().UnixNano())
+	}
+	p.sequenceNumber++
+	return p.sequenceNumber
+}
+
+func (p *Plugin) notify(ctx context.Context, c *pluginConfig, event string, b *plugintypes.Bundle) error {
+	if b == nil {
+		return status.Error(codes.InvalidArgument, "request is missing the bundle")
+	}
+
+	bundleDoc, err := marshalBundle(b)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal bundle: %v", err)
+	}
+
+	body, err := json.Marshal(notification{
+		Event:          event,
+		TrustDomain:    b.TrustDomain,
+		SequenceNumber: p.nextSequenceNumber(),
+		Timestamp:      p.clock.Now().Unix(),
+		Bundle:         bundleDoc,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to marshal notification: %v", err)
+	}
+
+	var signature string
+	if c.signer != nil {
+		signature, err = c.signer.Sign(body)
+		if err != nil {
+			return status.Errorf(codes.Internal, "unable to sign notification: %v", err)
+		}
+	}
+
+	// Deliver to all of the endpoints concurrently so a slow or unavailable
+	// endpoint does not delay the rest.
+	var wg sync.WaitGroup
+	errs := make([]error, len(c.endpoints))
+	for i, endpoint := range c.endpoints {
+		wg.Add(1)
+		go func(i int, endpoint string) {
+			defer wg.Done()
+			errs[i] = p.deliver(ctx, c, endpoint, event, body, signature)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	var failures []string
+	for _, err := range errs {
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return status.Errorf(codes.Unavailable, "unable to deliver notification: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// deliver POSTs the notification to the endpoint, retrying with an
+// exponential backoff on transient failures.
+func (p *Plugin) deliver(ctx context.Context, c *pluginConfig, endpoint, event string, body []byte, signature string) error {
+	backoff := c.initialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := p.post(ctx, c, endpoint, event, body, signature)
+		if err == nil {
+			p.log.Debug("Notification delivered", telemetry.Address, endpoint)
+			return nil
+		}
+		if !retryable || attempt == c.maxRetries {
+			return fmt.Errorf("%s: %w", endpoint, err)
+		}
+
+		p.log.Debug("Notification delivery failed; retrying", telemetry.Address, endpoint, telemetry.Error, err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", endpoint, ctx.Err())
+		case <-p.clock.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+// post sends a single delivery attempt. It returns whether a failed attempt
+// can be retried.
+func (p *Plugin) post(ctx context.Context, c *pluginConfig, endpoint, event string, body []byte, signature string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventHeader, event)
+	if signature != "" {
+		req.Header.Set(signatureHeader, signature)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	err = fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retryable, err
+}
+
+// marshalBundle encodes the bundle as a SPIFFE bundle document.
+func marshalBundle(b *plugintypes.Bundle) ([]byte, error) {
+	commonBundle, err := bundle.ToCommonFromPluginProto(b)
+	if err != nil {
+		return nil, err
+	}
+	utilBundle, err := bundleutil.BundleFromProto(commonBundle)
+	if err != nil {
+		return nil, err
+	}
+	return bundleutil.Marshal(utilBundle, bundleutil.SequenceNumber(b.SequenceNumber))
+}
+
+func buildConfig(config *Configuration) (*pluginConfig, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("endpoints must be set")
+	}
+	for _, endpoint := range config.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %v", endpoint, err)
+		}
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return nil, fmt.Errorf("invalid endpoint %q: scheme must be http or https", endpoint)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %q: host is required", endpoint)
+		}
+	}
+
+	timeout, err := parseDuration("timeout", config.Timeout, defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	initialBackoff, err := parseDuration("initial_backoff", config.InitialBackoff, defaultInitialBackoff)
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := parseDuration("max_backoff", config.MaxBackoff, defaultMaxBackoff)
+	if err != nil {
+		return nil, err
+	}
+	if maxBackoff < initialBackoff {
+		return nil, errors.New("max_backoff must not be less than initial_backoff")
+	}
+
+	maxRetries := defaultMaxRetries
+	if config.MaxRetries != nil {
+		if *config.MaxRetries < 0 {
+			return nil, errors.New("max_retries must not be negative")
+		}
+		maxRetries = *config.MaxRetries
+	}
+
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := newPayloadSigner(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pluginConfig{
+		endpoints:         config.Endpoints,
+		maxRetries:        maxRetries,
+		initialBackoff:    initialBackoff,
+		maxBackoff:        maxBackoff,
+		mustSucceedOnLoad: config.MustSucceedOnLoad,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		signer: signer,
+	}, nil
+}
+
+func buildTLSConfig(config *Configuration) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify, // nolint: gosec // only enabled by explicit configuration
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if config.CACertPath != "" {
+		certs, err := pemutil.LoadCertificates(config.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load CA certificates: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		for _, cert := range certs {
+			tlsConfig.RootCAs.AddCert(cert)
+		}
+	}
+
+	if (config.ClientCertPath == "") != (config.ClientKeyPath == "") {
+		return nil, errors.New("client_cert_path and client_key_path must be configured together")
+	}
+	if config.ClientCertPath != "" {
+		clientCert, err := tls.LoadX509KeyPair(config.ClientCertPath, config.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+func parseDuration(name, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse %s: %v", name, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return d, nil
+}
+
+func readSecretFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	return data, nil
+}
@@ -0,0 +1,496 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/server/plugin/notifier"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestConfigure(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	secretPath := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secretPath, []byte("s3cr3t\n"), 0600))
+	emptySecretPath := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(emptySecretPath, []byte("\n"), 0600))
+	keyPath := filepath.Join(dir, "key.pem")
+	writeKey(t, keyPath, testkey.MustEC256())
+
+	testCases := []struct {
+		name   string
+		config string
+		desc   string
+	}{
+		{
+			name:   "malformed",
+			config: `MALFORMED`,
+			desc:   "unable to decode configuration",
+		},
+		{
+			name:   "missing endpoints",
+			config: ``,
+			desc:   "endpoints must be set",
+		},
+		{
+			name:   "invalid endpoint scheme",
+			config: `endpoints = ["ftp://example.org"]`,
+			desc:   `invalid endpoint "ftp://example.org": scheme must be http or https`,
+		},
+		{
+			name:   "missing endpoint host",
+			config: `endpoints = ["https:///path"]`,
+			desc:   `invalid endpoint "https:///path": host is required`,
+		},
+		{
+			name: "invalid timeout",
+			config: `
+				endpoints = ["https://example.org"]
+				timeout = "soon"
+			`,
+			desc: "unable to parse timeout",
+		},
+		{
+			name: "negative max retries",
+			config: `
+				endpoints = ["https://example.org"]
+				max_retries = -1
+			`,
+			desc: "max_retries must not be negative",
+		},
+		{
+			name: "max backoff less than initial backoff",
+			config: `
+				endpoints = ["https://example.org"]
+				initial_backoff = "1m"
+				max_backoff = "1s"
+			`,
+			desc: "max_backoff must not be less than initial_backoff",
+		},
+		{
+			name: "client cert without key",
+			config: `
+				endpoints = ["https://example.org"]
+				client_cert_path = "cert.pem"
+			`,
+			desc: "client_cert_path and client_key_path must be configured together",
+		},
+		{
+			name: "unsupported signature method",
+			config: `
+				endpoints = ["https://example.org"]
+				signature_method = "md5"
+			`,
+			desc: `unsupported signature_method "md5"`,
+		},
+		{
+			name: "signing material without signature method",
+			config: fmt.Sprintf(`
+				endpoints = ["https://example.org"]
+				hmac_secret_path = %q
+			`, secretPath),
+			desc: "signature_method must be set when signing material is configured",
+		},
+		{
+			name: "hmac without secret",
+			config: `
+				endpoints = ["https://example.org"]
+				signature_method = "hmac"
+			`,
+			desc: "hmac_secret_path is required when signature_method is hmac",
+		},
+		{
+			name: "hmac with empty secret",
+			config: fmt.Sprintf(`
+				endpoints = ["https://example.org"]
+				signature_method = "hmac"
+				hmac_secret_path = %q
+			`, emptySecretPath),
+			desc: "unable to load HMAC secret: file is empty",
+		},
+		{
+			name: "jws without key",
+			config: `
+				endpoints = ["https://example.org"]
+				signature_method = "jws"
+			`,
+			desc: "jws_key_path is required when signature_method is jws",
+		},
+		{
+			name: "jws with missing key",
+			config: fmt.Sprintf(`
+				endpoints = ["https://example.org"]
+				signature_method = "jws"
+				jws_key_path = %q
+			`, filepath.Join(dir, "missing.pem")),
+			desc: "unable to load JWS key",
+		},
+		{
+			name: "success with hmac",
+			config: fmt.Sprintf(`
+				endpoints = ["https://example.org", "http://localhost:8080/hook"]
+				timeout = "5s"
+				max_retries = 0
+				must_succeed_on_load = true
+				signature_method = "hmac"
+				hmac_secret_path = %q
+			`, secretPath),
+		},
+		{
+			name: "success with jws",
+			config: fmt.Sprintf(`
+				endpoints = ["https://example.org"]
+				signature_method = "jws"
+				jws_key_path = %q
+				jws_key_id = "KID"
+			`, keyPath),
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, BuiltIn(), nil,
+				plugintest.Configure(tt.config),
+				plugintest.CaptureConfigureError(&err))
+			if tt.desc != "" {
+				spiretest.RequireGRPCStatusContains(t, err, codes.InvalidArgument, tt.desc)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	n := new(notifier.V1)
+	plugintest.Load(t, BuiltIn(), n)
+	bundle := newBundle(t)
+
+	err := n.NotifyBundleUpdated(context.Background(), bundle)
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "notifier(webhook): not configured")
+
+	err = n.NotifyAndAdviseBundleLoaded(context.Background(), bundle)
+	spiretest.RequireGRPCStatus(t, err, codes.FailedPrecondition, "notifier(webhook): not configured")
+}
+
+func TestNotifyDeliversToAllEndpoints(t *testing.T) {
+	receiver1 := newFakeReceiver(t)
+	receiver2 := newFakeReceiver(t)
+
+	n := loadPlugin(t, map[string]interface{}{
+		"endpoints": []string{receiver1.url(), receiver2.url()},
+	})
+
+	start := time.Now()
+	bundle := newBundle(t)
+	require.NoError(t, n.NotifyAndAdviseBundleLoaded(context.Background(), bundle))
+	require.NoError(t, n.NotifyBundleUpdated(context.Background(), bundle))
+
+	for _, receiver := range []*fakeReceiver{receiver1, receiver2} {
+		reqs := receiver.requests()
+		require.Len(t, reqs, 2)
+
+		assert.Equal(t, "bundle_loaded", reqs[0].event)
+		assert.Equal(t, "application/json", reqs[0].contentType)
+		assert.Empty(t, reqs[0].signature)
+		assert.Equal(t, "bundle_updated", reqs[1].event)
+
+		first := decodeNotification(t, reqs[0].body)
+		second := decodeNotification(t, reqs[1].body)
+		assert.Equal(t, "bundle_loaded", first.Event)
+		assert.Equal(t, "example.org", first.TrustDomain)
+		assert.Greater(t, first.SequenceNumber, uint64(start.UnixNano()))
+		assert.NotZero(t, first.Timestamp)
+		assert.Equal(t, "bundle_updated", second.Event)
+		assert.Equal(t, first.SequenceNumber+1, second.SequenceNumber)
+
+		var doc struct {
+			Keys        []jose.JSONWebKey `json:"keys"`
+			RefreshHint int64             `json:"spiffe_refresh_hint"`
+		}
+		require.NoError(t, json.Unmarshal(first.Bundle, &doc))
+		require.Len(t, doc.Keys, 1)
+		assert.Equal(t, "x509-svid", doc.Keys[0].Use)
+		assert.Equal(t, bundle.RootCas[0].DerBytes, doc.Keys[0].Certificates[0].Raw)
+		assert.Equal(t, int64(60), doc.RefreshHint)
+	}
+}
+
+func TestNotifyWithHMACSignature(t *testing.T) {
+	secretPath := filepath.Join(spiretest.TempDir(t), "secret")
+	require.NoError(t, os.WriteFile(secretPath, []byte("s3cr3t\n"), 0600))
+
+	receiver := newFakeReceiver(t)
+	n := loadPlugin(t, map[string]interface{}{
+		"endpoints":        []string{receiver.url()},
+		"signature_method": "hmac",
+		"hmac_secret_path": secretPath,
+	})
+
+	require.NoError(t, n.NotifyBundleUpdated(context.Background(), newBundle(t)))
+
+	reqs := receiver.requests()
+	require.Len(t, reqs, 1)
+
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	_, _ = mac.Write(reqs[0].body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), reqs[0].signature)
+}
+
+func TestNotifyWithJWSSignature(t *testing.T) {
+	key := testkey.MustEC256()
+	keyPath := filepath.Join(spiretest.TempDir(t), "key.pem")
+	writeKey(t, keyPath, key)
+
+	receiver := newFakeReceiver(t)
+	n := loadPlugin(t, map[string]interface{}{
+		"endpoints":        []string{receiver.url()},
+		"signature_method": "jws",
+		"jws_key_path":     keyPath,
+		"jws_key_id":       "KID",
+	})
+
+	require.NoError(t, n.NotifyBundleUpdated(context.Background(), newBundle(t)))
+
+	reqs := receiver.requests()
+	require.Len(t, reqs, 1)
+
+	jws, err := jose.ParseDetached(reqs[0].signature, reqs[0].body)
+	require.NoError(t, err)
+	require.Len(t, jws.Signatures, 1)
+	assert.Equal(t, "KID", jws.Signatures[0].Header.KeyID)
+	assert.Equal(t, string(jose.ES256), jws.Signatures[0].Header.Algorithm)
+	payload, err := jws.Verify(key.Public())
+	require.NoError(t, err)
+	assert.Equal(t, reqs[0].body, payload)
+}
+
+func TestNotifyRetries(t *testing.T) {
+	t.Run("retries transient failures", func(t *testing.T) {
+		receiver := newFakeReceiver(t)
+		receiver.setStatuses(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+		n := loadPlugin(t, map[string]interface{}{
+			"endpoints":       []string{receiver.url()},
+			"initial_backoff": "1ms",
+			"max_backoff":     "2ms",
+		})
+
+		require.NoError(t, n.NotifyBundleUpdated(context.Background(), newBundle(t)))
+		reqs := receiver.requests()
+		require.Len(t, reqs, 3)
+		// every attempt carries the same payload
+		assert.Equal(t, reqs[0].body, reqs[2].body)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		receiver := newFakeReceiver(t)
+		receiver.setStatuses(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+		n := loadPlugin(t, map[string]interface{}{
+			"endpoints":       []string{receiver.url()},
+			"max_retries":     2,
+			"initial_backoff": "1ms",
+		})
+
+		err := n.NotifyBundleUpdated(context.Background(), newBundle(t))
+		spiretest.RequireGRPCStatus(t, err, codes.Unavailable,
+			"notifier(webhook): unable to deliver notification: "+receiver.url()+": unexpected status code 500: oops")
+		assert.Len(t, receiver.requests(), 3)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		receiver := newFakeReceiver(t)
+		receiver.setStatuses(http.StatusBadRequest)
+
+		n := loadPlugin(t, map[string]interface{}{
+			"endpoints":       []string{receiver.url()},
+			"initial_backoff": "1ms",
+		})
+
+		err := n.NotifyBundleUpdated(context.Background(), newBundle(t))
+		spiretest.RequireGRPCStatus(t, err, codes.Unavailable,
+			"notifier(webhook): unable to deliver notification: "+receiver.url()+": unexpected status code 400: oops")
+		assert.Len(t, receiver.requests(), 1)
+	})
+}
+
+func TestNotifyAndAdviseBundleLoadedMustSucceed(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		mustSucceed bool
+		code        codes.Code
+	}{
+		{
+			name:        "failure blocks startup",
+			mustSucceed: true,
+			code:        codes.Unavailable,
+		},
+		{
+			name:        "failure is only logged",
+			mustSucceed: false,
+			code:        codes.OK,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newFakeReceiver(t)
+			receiver.setStatuses(http.StatusForbidden)
+
+			n := loadPlugin(t, map[string]interface{}{
+				"endpoints":            []string{receiver.url()},
+				"must_succeed_on_load": tt.mustSucceed,
+			})
+
+			err := n.NotifyAndAdviseBundleLoaded(context.Background(), newBundle(t))
+			if tt.code != codes.OK {
+				spiretest.RequireGRPCStatusHasPrefix(t, err, tt.code, "notifier(webhook): unable to deliver notification")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNotifyOverTLS(t *testing.T) {
+	receiver := newFakeReceiver(t)
+	server := httptest.NewTLSServer(receiver)
+	t.Cleanup(server.Close)
+
+	caPath := filepath.Join(spiretest.TempDir(t), "ca.pem")
+	require.NoError(t, pemutil.SaveCertificate(caPath, server.Certificate(), 0600))
+
+	t.Run("untrusted", func(t *testing.T) {
+		n := loadPlugin(t, map[string]interface{}{
+			"endpoints":   []string{server.URL + "/hook"},
+			"max_retries": 0,
+		})
+		err := n.NotifyBundleUpdated(context.Background(), newBundle(t))
+		spiretest.RequireGRPCStatusContains(t, err, codes.Unavailable, "certificate")
+	})
+
+	t.Run("trusted", func(t *testing.T) {
+		n := loadPlugin(t, map[string]interface{}{
+			"endpoints":    []string{server.URL + "/hook"},
+			"ca_cert_path": caPath,
+		})
+		require.NoError(t, n.NotifyBundleUpdated(context.Background(), newBundle(t)))
+		assert.Len(t, receiver.requests(), 1)
+	})
+}
+
+func loadPlugin(t *testing.T, config map[string]interface{}) notifier.Notifier {
+	n := new(notifier.V1)
+	plugintest.Load(t, BuiltIn(), n, plugintest.ConfigureJSON(config))
+	return n
+}
+
+func newBundle(t *testing.T) *common.Bundle {
+	caCert, _ := testca.CreateCACertificate(t, nil, nil)
+	return &common.Bundle{
+		TrustDomainId: "spiffe://example.org",
+		RootCas:       []*common.Certificate{{DerBytes: caCert.Raw}},
+		RefreshHint:   60,
+	}
+}
+
+func writeKey(t *testing.T, path string, key interface{}) {
+	keyPEM, err := pemutil.EncodePKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, keyPEM, 0600))
+}
+
+func decodeNotification(t *testing.T, body []byte) notification {
+	var n notification
+	require.NoError(t, json.Unmarshal(body, &n))
+	return n
+}
+
+type receivedRequest struct {
+	event       string
+	contentType string
+	signature   string
+	body        []byte
+}
+
+// fakeReceiver records the notifications it receives. It replies with the
+// queued statuses, one per request, and with 200 once the queue is empty.
+type fakeReceiver struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mtx      sync.Mutex
+	statuses []int
+	reqs     []receivedRequest
+}
+
+func newFakeReceiver(t *testing.T) *fakeReceiver {
+	r := &fakeReceiver{t: t}
+	r.server = httptest.NewServer(r)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *fakeReceiver) url() string {
+	return r.server.URL + "/hook"
+}
+
+func (r *fakeReceiver) setStatuses(statuses ...int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.statuses = statuses
+}
+
+func (r *fakeReceiver) requests() []receivedRequest {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.reqs
+}
+
+func (r *fakeReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/hook") {
+		http.NotFound(w, req)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	require.NoError(r.t, err)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.reqs = append(r.reqs, receivedRequest{
+		event:       req.Header.Get(eventHeader),
+		contentType: req.Header.Get("Content-Type"),
+		signature:   req.Header.Get(signatureHeader),
+		body:        body,
+	})
+
+	if len(r.statuses) > 0 {
+		code := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		http.Error(w, "oops", code)
+	}
+}
@@ -23,6 +23,12 @@ import (
 	"github.com/spiffe/spire/pkg/common/x509util"
 )
 
+const (
+	// watchInterval is how often the CA files are checked for changes while
+	// a MintX509CA stream is open.
+	watchInterval = 5 * time.Second
+)
+
 func BuiltIn() catalog.BuiltIn {
 	return builtin(New())
 }
@@ -58,6 +64,7 @@ type Plugin struct {
 }
 
 type caCerts struct {
+	caCert      *x509.Certificate
 	certChain   []*x509.Certificate
 	trustBundle []*x509.Certificate
 }
@@ -133,10 +140,15 @@ func (p *Plugin) MintX509CAAndSubscribe(request *upstreamauthorityv1.MintX509CAR
 		return status.Errorf(codes.Internal, "unable to form response upstream X.509 roots: %v", err)
 	}
 
-	return stream.Send(&upstreamauthorityv1.MintX509CAResponse{
+	if err := stream.Send(&upstreamauthorityv1.MintX509CAResponse{
 		X509CaChain:       x509CAChain,
 		UpstreamX509Roots: upstreamX509Roots,
-	})
+	}); err != nil {
+		p.log.Error("Cannot send X.509 CA chain and roots", "error", err)
+		return err
+	}
+
+	return p.watchCA(ctx, stream, upstreamCerts)
 }
 
 func (*Plugin) PublishJWTKeyAndSubscribe(*upstreamauthorityv1.PublishJWTKeyRequest, upstreamauthorityv1.UpstreamAuthority_PublishJWTKeyAndSubscribeServer) error {
@@ -153,6 +165,7 @@ func (p *Plugin) reloadCA() (*x509svid.UpstreamCA, *caCerts, error) {
 		p.upstreamCA = upstreamCA
 		p.certs = upstreamCerts
 	case p.upstreamCA != nil:
+		p.log.Warn("Unable to reload upstream CA; using previously loaded CA", "error", err)
 		upstreamCA = p.upstreamCA
 		upstreamCerts = p.certs
 	default:
@@ -162,6 +175,50 @@ func (p *Plugin) reloadCA() (*x509svid.UpstreamCA, *caCerts, error) {
 	return upstreamCA, upstreamCerts, nil
 }
 
+// watchCA reloads the CA files every watchInterval while the stream is open.
+// Changes to the upstream roots are streamed back to the server. When the
+// upstream CA certificate changes, the stream is ended with an Aborted status
+// so the server prepares a new X509 CA signed by the new certificate.
+func (p *Plugin) watchCA(ctx context.Context, stream upstreamauthorityv1.UpstreamAuthority_MintX509CAAndSubscribeServer, current *caCerts) error {
+	ticker := p.clock.Ticker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		_, upstreamCerts, err := p.reloadCA()
+		if err != nil {
+			return status.Errorf(codes.Internal, "unable to reload upstream CA: %v", err)
+		}
+
+		if !areCertsEqual(current.trustBundle, upstreamCerts.trustBundle) {
+			upstreamX509Roots, err := x509certificate.ToPluginProtos(upstreamCerts.trustBundle)
+			if err != nil {
+				return status.Errorf(codes.Internal, "unable to form response upstream X.509 roots: %v", err)
+			}
+
+			p.log.Info("Upstream X.509 roots changed")
+			if err := stream.Send(&upstreamauthorityv1.MintX509CAResponse{
+				UpstreamX509Roots: upstreamX509Roots,
+			}); err != nil {
+				p.log.Error("Cannot send upstream X.509 roots", "error", err)
+				return err
+			}
+		}
+
+		if !current.caCert.Equal(upstreamCerts.caCert) {
+			p.log.Info("Upstream CA certificate changed")
+			return status.Error(codes.Aborted, "upstream CA certificate changed")
+		}
+
+		current = upstreamCerts
+	}
+}
+
 func (p *Plugin) loadUpstreamCAAndCerts(config *Configuration) (*x509svid.UpstreamCA, *caCerts, error) {
 	key, err := pemutil.LoadPrivateKey(config.KeyFilePath)
 	if err != nil {
@@ -221,6 +278,7 @@ func (p *Plugin) loadUpstreamCAAndCerts(config *Configuration) (*x509svid.Upstre
 	}
 
 	caCerts := &caCerts{
+		caCert:      caCert,
 		certChain:   certs,
 		trustBundle: trustBundle,
 	}
@@ -233,3 +291,15 @@ func (p *Plugin) loadUpstreamCAAndCerts(config *Configuration) (*x509svid.Upstre
 		},
 	), caCerts, nil
 }
+
+func areCertsEqual(a, b []*x509.Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, cert := range a {
+		if !cert.Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -3,20 +3,22 @@ package disk
 import (
 	"context"
 	"crypto/x509"
-	"errors"
-	"io"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/cryptoutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
 	"github.com/spiffe/spire/pkg/common/x509svid"
 	"github.com/spiffe/spire/pkg/server/plugin/upstreamauthority"
 	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/clock"
 	"github.com/spiffe/spire/test/plugintest"
 	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
 	"github.com/spiffe/spire/test/testkey"
 	"github.com/spiffe/spire/test/util"
 	"github.com/stretchr/testify/assert"
@@ -174,14 +176,128 @@ func TestMintX509CA(t *testing.T) {
 			assert.Equal(t, tt.expectX509CA, certChainURIs(x509CA))
 			assert.Equal(t, tt.expectedX509Authorities, certChainURIs(x509Authorities))
 
-			// Plugin does not support streaming back changes so assert the
-			// stream returns EOF.
-			_, streamErr := stream.RecvUpstreamX509Authorities()
-			assert.True(t, errors.Is(streamErr, io.EOF))
+			stream.Close()
 		})
 	}
 }
 
+func TestMintX509CAStreamsUpstreamChanges(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	certFilePath := filepath.Join(dir, "cert.pem")
+	keyFilePath := filepath.Join(dir, "key.pem")
+
+	writeCA := func() *x509.Certificate {
+		cert, key := testca.CreateCACertificate(t, nil, nil)
+		keyPEM, err := pemutil.EncodePKCS8PrivateKey(key)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(keyFilePath, keyPEM, 0600))
+		require.NoError(t, pemutil.SaveCertificate(certFilePath, cert, 0600))
+		return cert
+	}
+	oldCA := writeCA()
+
+	csr, err := util.NewCSRTemplateWithKey("spiffe://example.org", testkey.NewEC256(t))
+	require.NoError(t, err)
+
+	p := New()
+	clock := clock.NewMock(t)
+	p.clock = clock
+
+	ua := new(upstreamauthority.V1)
+	plugintest.Load(t, builtin(p), ua,
+		plugintest.ConfigureJSON(Configuration{
+			CertFilePath: certFilePath,
+			KeyFilePath:  keyFilePath,
+		}),
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+	)
+
+	_, x509Authorities, stream, err := ua.MintX509CA(context.Background(), csr, 0)
+	require.NoError(t, err)
+	defer stream.Close()
+	require.Equal(t, []*x509.Certificate{oldCA}, x509Authorities)
+	clock.WaitForTicker(time.Minute, "waiting for the watch ticker")
+
+	// Nothing changed on disk, so no update is streamed.
+	clock.Add(watchInterval)
+
+	// An invalid update on disk is ignored and the loaded CA is kept.
+	copyFile(t, "testdata/keys/empty/cert.pem", certFilePath)
+	clock.Add(watchInterval)
+
+	// Replacing the self-signed CA streams the new root and then ends the
+	// stream so a new X509 CA is prepared.
+	newCA := writeCA()
+	clock.Add(watchInterval)
+
+	x509Authorities, err = stream.RecvUpstreamX509Authorities()
+	require.NoError(t, err)
+	require.Equal(t, []*x509.Certificate{newCA}, x509Authorities)
+
+	_, err = stream.RecvUpstreamX509Authorities()
+	spiretest.RequireGRPCStatus(t, err, codes.Aborted, "upstreamauthority(disk): upstream CA certificate changed")
+}
+
+func TestMintX509CAStreamsBundleChanges(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	certFilePath := filepath.Join(dir, "cert.pem")
+	keyFilePath := filepath.Join(dir, "key.pem")
+	bundleFilePath := filepath.Join(dir, "bundle.pem")
+
+	rootCert, rootKey := testca.CreateCACertificate(t, nil, nil)
+	upstreamCert, upstreamKey := testca.CreateCACertificate(t, rootCert, rootKey)
+	keyPEM, err := pemutil.EncodePKCS8PrivateKey(upstreamKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFilePath, keyPEM, 0600))
+	require.NoError(t, pemutil.SaveCertificate(certFilePath, upstreamCert, 0600))
+	require.NoError(t, pemutil.SaveCertificate(bundleFilePath, rootCert, 0600))
+
+	csr, err := util.NewCSRTemplateWithKey("spiffe://example.org", testkey.NewEC256(t))
+	require.NoError(t, err)
+
+	p := New()
+	clock := clock.NewMock(t)
+	p.clock = clock
+
+	ua := new(upstreamauthority.V1)
+	plugintest.Load(t, builtin(p), ua,
+		plugintest.ConfigureJSON(Configuration{
+			CertFilePath:   certFilePath,
+			KeyFilePath:    keyFilePath,
+			BundleFilePath: bundleFilePath,
+		}),
+		plugintest.CoreConfig(catalog.CoreConfig{
+			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
+		}),
+	)
+
+	_, x509Authorities, stream, err := ua.MintX509CA(context.Background(), csr, 0)
+	require.NoError(t, err)
+	defer stream.Close()
+	require.Equal(t, []*x509.Certificate{rootCert}, x509Authorities)
+	clock.WaitForTicker(time.Minute, "waiting for the watch ticker")
+
+	// Add another root to the bundle. The signing certificate did not change
+	// so the stream stays open after the update.
+	otherRoot, _ := testca.CreateCACertificate(t, nil, nil)
+	require.NoError(t, pemutil.SaveCertificates(bundleFilePath, []*x509.Certificate{rootCert, otherRoot}, 0600))
+	clock.Add(watchInterval)
+
+	x509Authorities, err = stream.RecvUpstreamX509Authorities()
+	require.NoError(t, err)
+	require.Equal(t, []*x509.Certificate{rootCert, otherRoot}, x509Authorities)
+
+	// A further change is streamed as well.
+	require.NoError(t, pemutil.SaveCertificates(bundleFilePath, []*x509.Certificate{otherRoot, rootCert}, 0600))
+	clock.Add(watchInterval)
+
+	x509Authorities, err = stream.RecvUpstreamX509Authorities()
+	require.NoError(t, err)
+	require.Equal(t, []*x509.Certificate{otherRoot, rootCert}, x509Authorities)
+}
+
 func TestPublishJWTKey(t *testing.T) {
 	ua := new(upstreamauthority.V1)
 	plugintest.Load(t, BuiltIn(), ua,
@@ -353,3 +469,9 @@ func certURI(cert *x509.Certificate) string {
 	}
 	return ""
 }
+
+func copyFile(t *testing.T, src, dst string) {
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, data, 0600))
+}
@@ -0,0 +1,320 @@
+package est
+
+import (
+	"context"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/andres-erbsen/clock"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl"
+	upstreamauthorityv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/plugin/server/upstreamauthority/v1"
+	configv1 "github.com/spiffe/spire-plugin-sdk/proto/spire/service/common/config/v1"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/coretypes/x509certificate"
+	"github.com/spiffe/spire/pkg/common/cryptoutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pluginName = "est"
+
+	defaultCACertsPollInterval = 5 * time.Minute
+)
+
+// BuiltIn constructs a catalog.BuiltIn using a new instance of this plugin.
+func BuiltIn() catalog.BuiltIn {
+	return builtin(New())
+}
+
+func builtin(p *Plugin) catalog.BuiltIn {
+	return catalog.MakeBuiltIn(pluginName,
+		upstreamauthorityv1.UpstreamAuthorityPluginServer(p),
+		configv1.ConfigServiceServer(p),
+	)
+}
+
+type Configuration struct {
+	// URL of the EST server (e.g., https://est.example.org). The RFC 7030
+	// "/.well-known/est" path is appended to it.
+	ServerURL string `hcl:"server_url" json:"server_url"`
+	// Optional CA label used when the EST server serves multiple CAs
+	// (e.g., /.well-known/est/<label>/simpleenroll).
+	Label string `hcl:"label" json:"label"`
+	// Path to a PEM file with the CA certificates used to verify the EST
+	// server certificate. If unset, the system roots are used.
+	CACertPath string `hcl:"ca_cert_path" json:"ca_cert_path"`
+	// If true, the EST server certificate is not verified. It should only be
+	// used for testing.
+	InsecureSkipVerify bool `hcl:"insecure_skip_verify" json:"insecure_skip_verify"`
+	// Paths to the PEM encoded client certificate and private key used to
+	// authenticate with the EST server.
+	ClientCertPath string `hcl:"client_cert_path" json:"client_cert_path"`
+	ClientKeyPath  string `hcl:"client_key_path" json:"client_key_path"`
+	// Credentials used to authenticate with the EST server using HTTP basic
+	// authentication.
+	Username string `hcl:"username" json:"username"`
+	Password string `hcl:"password" json:"password"`
+	// How often the CA certificates are polled for changes. Defaults to 5m.
+	CACertsPollInterval string `hcl:"cacerts_poll_interval" json:"cacerts_poll_interval"`
+}
+
+type Plugin struct {
+	upstreamauthorityv1.UnsafeUpstreamAuthorityServer
+	configv1.UnsafeConfigServer
+
+	log hclog.Logger
+
+	mtx          sync.RWMutex
+	client       *estClient
+	pollInterval time.Duration
+
+	// test hooks
+	clock clock.Clock
+}
+
+func New() *Plugin {
+	return &Plugin{
+		clock: clock.New(),
+	}
+}
+
+func (p *Plugin) SetLogger(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) Configure(ctx context.Context, req *configv1.ConfigureRequest) (*configv1.ConfigureResponse, error) {
+	config := new(Configuration)
+	if err := hcl.Decode(config, req.HclConfiguration); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to decode configuration: %v", err)
+	}
+
+	if config.ServerURL == "" {
+		return nil, status.Error(codes.InvalidArgument, "server_url is required")
+	}
+	if (config.ClientCertPath == "") != (config.ClientKeyPath == "") {
+		return nil, status.Error(codes.InvalidArgument, "client_cert_path and client_key_path must be configured together")
+	}
+	if config.ClientCertPath == "" && config.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "either a client certificate or a username must be configured")
+	}
+
+	pollInterval := defaultCACertsPollInterval
+	if config.CACertsPollInterval != "" {
+		var err error
+		pollInterval, err = time.ParseDuration(config.CACertsPollInterval)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unable to parse cacerts_poll_interval: %v", err)
+		}
+		if pollInterval <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "cacerts_poll_interval must be positive")
+		}
+	}
+
+	client, err := newESTClient(config)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to create EST client: %v", err)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.client = client
+	p.pollInterval = pollInterval
+
+	return &configv1.ConfigureResponse{}, nil
+}
+
+func (p *Plugin) MintX509CAAndSubscribe(request *upstreamauthorityv1.MintX509CARequest, stream upstreamauthorityv1.UpstreamAuthority_MintX509CAAndSubscribeServer) error {
+	ctx := stream.Context()
+
+	client, pollInterval, err := p.getClient()
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.ParseCertificateRequest(request.Csr)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "unable to parse CSR: %v", err)
+	}
+
+	caCerts, err := client.fetchCACerts(ctx)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to fetch CA certificates: %v", err)
+	}
+	roots, intermediates := splitCACerts(caCerts)
+	if len(roots) == 0 {
+		return status.Error(codes.Internal, "EST server did not return any root CA certificates")
+	}
+
+	issued, err := client.simpleEnroll(ctx, request.Csr)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to enroll CSR: %v", err)
+	}
+
+	// The EST server may return other certificates along with the issued
+	// one, in any order, so the issued certificate is found by its key.
+	x509CA, others, err := findIssuedCA(issued, csr.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	certChain, err := p.buildX509CAChain(x509CA, roots, append(intermediates, others...))
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to build X.509 CA chain: %v", err)
+	}
+
+	x509CAChain, err := x509certificate.ToPluginProtos(certChain)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to form response X.509 CA chain: %v", err)
+	}
+
+	upstreamX509Roots, err := x509certificate.ToPluginProtos(roots)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to form response upstream X.509 roots: %v", err)
+	}
+
+	if err := stream.Send(&upstreamauthorityv1.MintX509CAResponse{
+		X509CaChain:       x509CAChain,
+		UpstreamX509Roots: upstreamX509Roots,
+	}); err != nil {
+		p.log.Error("Cannot send X.509 CA chain and roots", "error", err)
+		return err
+	}
+
+	// Keep polling the CA certificates so changes to the upstream roots are
+	// streamed back to the server.
+	ticker := p.clock.Ticker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		caCerts, err := client.fetchCACerts(ctx)
+		if err != nil {
+			p.log.Warn("Failed to fetch CA certificates while polling", "error", err)
+			continue
+		}
+
+		newRoots, _ := splitCACerts(caCerts)
+		if len(newRoots) == 0 {
+			p.log.Warn("EST server did not return any root CA certificates while polling")
+			continue
+		}
+		if areCertsEqual(roots, newRoots) {
+			continue
+		}
+
+		upstreamX509Roots, err := x509certificate.ToPluginProtos(newRoots)
+		if err != nil {
+			return status.Errorf(codes.Internal, "unable to form response upstream X.509 roots: %v", err)
+		}
+
+		p.log.Info("Upstream root CA certificates changed")
+		if err := stream.Send(&upstreamauthorityv1.MintX509CAResponse{
+			UpstreamX509Roots: upstreamX509Roots,
+		}); err != nil {
+			p.log.Error("Cannot send upstream X.509 roots", "error", err)
+			return err
+		}
+		roots = newRoots
+	}
+}
+
+func (*Plugin) PublishJWTKeyAndSubscribe(*upstreamauthorityv1.PublishJWTKeyRequest, upstreamauthorityv1.UpstreamAuthority_PublishJWTKeyAndSubscribeServer) error {
+	return status.Error(codes.Unimplemented, "publishing upstream is unsupported")
+}
+
+func (p *Plugin) getClient() (*estClient, time.Duration, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.client == nil {
+		return nil, 0, status.Error(codes.FailedPrecondition, "not configured")
+	}
+	return p.client, p.pollInterval, nil
+}
+
+// findIssuedCA returns the certificate among the ones returned by the
+// simpleenroll operation whose public key matches the CSR, along with the
+// remaining certificates. The certificate must be a CA certificate.
+func findIssuedCA(issued []*x509.Certificate, csrPublicKey interface{}) (*x509.Certificate, []*x509.Certificate, error) {
+	for i, cert := range issued {
+		matches, err := cryptoutil.PublicKeyEqual(cert.PublicKey, csrPublicKey)
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Internal, "unable to compare issued certificate public key: %v", err)
+		}
+		if !matches {
+			continue
+		}
+		if !cert.IsCA {
+			return nil, nil, status.Error(codes.Internal, "issued certificate is not a CA certificate")
+		}
+
+		others := make([]*x509.Certificate, 0, len(issued)-1)
+		others = append(others, issued[:i]...)
+		others = append(others, issued[i+1:]...)
+		return cert, others, nil
+	}
+	return nil, nil, status.Error(codes.Internal, "no issued certificate public key matches the CSR")
+}
+
+// buildX509CAChain returns the chain from the issued certificate up to, but
+// not including, one of the EST server roots.
+func (p *Plugin) buildX509CAChain(x509CA *x509.Certificate, roots, intermediates []*x509.Certificate) ([]*x509.Certificate, error) {
+	rootPool := x509.NewCertPool()
+	for _, root := range roots {
+		rootPool.AddCert(root)
+	}
+	intermediatePool := x509.NewCertPool()
+	for _, intermediate := range intermediates {
+		intermediatePool.AddCert(intermediate)
+	}
+
+	chains, err := x509CA.Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediatePool,
+		CurrentTime:   p.clock.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chain := chains[0]
+	return chain[:len(chain)-1], nil
+}
+
+// splitCACerts splits the certificates returned by the cacerts operation into
+// self-signed roots and intermediates.
+func splitCACerts(certs []*x509.Certificate) (roots, intermediates []*x509.Certificate) {
+	for _, cert := range certs {
+		if isSelfSigned(cert) {
+			roots = append(roots, cert)
+		} else {
+			intermediates = append(intermediates, cert)
+		}
+	}
+	return roots, intermediates
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return cert.CheckSignatureFrom(cert) == nil
+}
+
+func areCertsEqual(a, b []*x509.Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, cert := range a {
+		if !cert.Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
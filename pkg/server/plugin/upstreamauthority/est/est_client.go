@@ -0,0 +1,218 @@
+package est
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+const (
+	// wellKnownPath is the path prefix defined by RFC 7030 for EST operations
+	wellKnownPath = "/.well-known/est"
+
+	cacertsOperation      = "cacerts"
+	simpleEnrollOperation = "simpleenroll"
+
+	// maxResponseSize limits the size of the responses read from the EST server
+	maxResponseSize = 1 << 20
+)
+
+var (
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+// estClient is a minimal client for the RFC 7030 operations needed to obtain
+// an intermediate CA certificate and the CA certificates of the EST server.
+type estClient struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	username   string
+	password   string
+}
+
+func newESTClient(config *Configuration) (*estClient, error) {
+	serverURL, err := url.Parse(config.ServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse server_url: %w", err)
+	}
+	if serverURL.Scheme != "https" {
+		return nil, errors.New("server_url must use the https scheme")
+	}
+	if serverURL.Host == "" {
+		return nil, errors.New("server_url must include a host")
+	}
+
+	baseURL := *serverURL
+	baseURL.Path = path.Join(serverURL.Path, wellKnownPath, config.Label)
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify, // nolint: gosec // only enabled by explicit configuration
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if config.CACertPath != "" {
+		certs, err := pemutil.LoadCertificates(config.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load CA certificates: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		for _, cert := range certs {
+			tlsConfig.RootCAs.AddCert(cert)
+		}
+	}
+
+	if config.ClientCertPath != "" {
+		clientCert, err := tls.LoadX509KeyPair(config.ClientCertPath, config.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return &estClient{
+		baseURL: &baseURL,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		username: config.Username,
+		password: config.Password,
+	}, nil
+}
+
+// fetchCACerts retrieves the current CA certificates from the EST server
+// (RFC 7030 section 4.1).
+func (c *estClient) fetchCACerts(ctx context.Context) ([]*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.operationURL(cacertsOperation), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := c.doCertsOnlyRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("cacerts request failed: %w", err)
+	}
+	return certs, nil
+}
+
+// simpleEnroll submits the DER encoded CSR to the EST server and returns the
+// certificates contained in the response (RFC 7030 section 4.2).
+func (c *estClient) simpleEnroll(ctx context.Context, csr []byte) ([]*x509.Certificate, error) {
+	body := base64.StdEncoding.EncodeToString(csr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.operationURL(simpleEnrollOperation), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/pkcs10")
+	req.Header.Set("Content-Transfer-Encoding", "base64")
+
+	certs, err := c.doCertsOnlyRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("simpleenroll request failed: %w", err)
+	}
+	return certs, nil
+}
+
+func (c *estClient) operationURL(operation string) string {
+	u := *c.baseURL
+	u.Path = path.Join(u.Path, operation)
+	return u.String()
+}
+
+func (c *estClient) doCertsOnlyRequest(req *http.Request) ([]*x509.Certificate, error) {
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusAccepted:
+		return nil, fmt.Errorf("request deferred by EST server (retry after %q)", resp.Header.Get("Retry-After"))
+	default:
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	der, err := base64.StdEncoding.DecodeString(stripWhitespace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode base64 response: %w", err)
+	}
+
+	return parseCertsOnly(der)
+}
+
+// parseCertsOnly parses the certificates out of a DER encoded "certs-only"
+// CMS SignedData structure, as returned by the cacerts and simpleenroll
+// operations (RFC 7030 section 4.1.3).
+func parseCertsOnly(der []byte) ([]*x509.Certificate, error) {
+	input := cryptobyte.String(der)
+
+	var contentInfo, content, signedData, rawCerts cryptobyte.String
+	var contentType asn1.ObjectIdentifier
+	var hasCerts bool
+	if !input.ReadASN1(&contentInfo, cryptobyte_asn1.SEQUENCE) ||
+		!contentInfo.ReadASN1ObjectIdentifier(&contentType) ||
+		!contentInfo.ReadASN1(&content, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!content.ReadASN1(&signedData, cryptobyte_asn1.SEQUENCE) ||
+		!signedData.SkipASN1(cryptobyte_asn1.INTEGER) ||
+		!signedData.SkipASN1(cryptobyte_asn1.SET) ||
+		!signedData.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!signedData.ReadOptionalASN1(&rawCerts, &hasCerts, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+		return nil, errors.New("malformed PKCS#7 certs-only response")
+	}
+	if !contentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unexpected PKCS#7 content type %s", contentType)
+	}
+
+	var certs []*x509.Certificate
+	for !rawCerts.Empty() {
+		var rawCert cryptobyte.String
+		if !rawCerts.ReadASN1Element(&rawCert, cryptobyte_asn1.SEQUENCE) {
+			return nil, errors.New("malformed certificate in PKCS#7 response")
+		}
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate in PKCS#7 response: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates in PKCS#7 response")
+	}
+	return certs, nil
+}
+
+func stripWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+}
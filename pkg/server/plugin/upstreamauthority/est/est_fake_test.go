@@ -0,0 +1,242 @@
+package est
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiffe/spire/test/testca"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// fakeESTServer is a local stand-in for an EST server. It issues
+// certificates from an intermediate CA chained to a self-signed root.
+type fakeESTServer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	label    string
+	username string
+	password string
+	// clientCAs, when set, are used to verify client certificates
+	clientCAs *x509.CertPool
+
+	mtx              sync.Mutex
+	rootCert         *x509.Certificate
+	intermediateCert *x509.Certificate
+	intermediateKey  crypto.Signer
+	extraCACerts     []*x509.Certificate
+	enrollStatus     int
+	serial           int64
+	// issueNonCA, when set, issues certificates that are not CA certificates
+	issueNonCA bool
+	// omitIssued, when set, leaves the issued certificate out of the response
+	omitIssued bool
+	// prependIssued are returned before the issued certificate
+	prependIssued []*x509.Certificate
+}
+
+var (
+	fakeCAOnce             sync.Once
+	fakeCARootCert         *x509.Certificate
+	fakeCAIntermediateCert *x509.Certificate
+	fakeCAIntermediateKey  crypto.Signer
+)
+
+func newFakeESTServer(t *testing.T, label string) *fakeESTServer {
+	// The CA certificates are shared by every fake server to keep the number
+	// of test keys used by the package low.
+	fakeCAOnce.Do(func() {
+		var rootKey crypto.Signer
+		fakeCARootCert, rootKey = testca.CreateCACertificate(t, nil, nil)
+		fakeCAIntermediateCert, fakeCAIntermediateKey = testca.CreateCACertificate(t, fakeCARootCert, rootKey)
+	})
+	rootCert, intermediateCert, intermediateKey := fakeCARootCert, fakeCAIntermediateCert, fakeCAIntermediateKey
+
+	s := &fakeESTServer{
+		t:                t,
+		label:            label,
+		rootCert:         rootCert,
+		intermediateCert: intermediateCert,
+		intermediateKey:  intermediateKey,
+		enrollStatus:     http.StatusOK,
+	}
+	return s
+}
+
+func (s *fakeESTServer) start() {
+	prefix := wellKnownPath
+	if s.label != "" {
+		prefix += "/" + s.label
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/cacerts", s.handleCACerts)
+	mux.HandleFunc(prefix+"/simpleenroll", s.handleSimpleEnroll)
+
+	s.server = httptest.NewUnstartedServer(mux)
+	s.server.TLS = &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  s.clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	s.server.StartTLS()
+	s.t.Cleanup(s.server.Close)
+}
+
+func (s *fakeESTServer) url() string {
+	return s.server.URL
+}
+
+// serverCert returns the certificate used by the EST server for TLS.
+func (s *fakeESTServer) serverCert() *x509.Certificate {
+	return s.server.Certificate()
+}
+
+func (s *fakeESTServer) appendCACert(cert *x509.Certificate) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.extraCACerts = append(s.extraCACerts, cert)
+}
+
+func (s *fakeESTServer) setEnrollStatus(code int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.enrollStatus = code
+}
+
+func (s *fakeESTServer) setIssued(issueNonCA, omitIssued bool, prependIssued []*x509.Certificate) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.issueNonCA = issueNonCA
+	s.omitIssued = omitIssued
+	s.prependIssued = prependIssued
+}
+
+func (s *fakeESTServer) handleCACerts(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mtx.Lock()
+	certs := append([]*x509.Certificate{s.rootCert, s.intermediateCert}, s.extraCACerts...)
+	s.mtx.Unlock()
+
+	s.writeCertsOnly(w, certs)
+}
+
+func (s *fakeESTServer) handleSimpleEnroll(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.isAuthorized(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if req.Header.Get("Content-Type") != "application/pkcs10" {
+		http.Error(w, "unexpected content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.enrollStatus != http.StatusOK {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(s.enrollStatus)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	der, err := base64.StdEncoding.DecodeString(stripWhitespace(string(body)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.serial++
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(s.serial),
+		Subject:               csr.Subject,
+		URIs:                  csr.URIs,
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  !s.issueNonCA,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	if s.issueNonCA {
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	}
+	cert := testca.CreateCertificate(s.t, tmpl, s.intermediateCert, csr.PublicKey, s.intermediateKey)
+
+	certs := append([]*x509.Certificate{}, s.prependIssued...)
+	if !s.omitIssued {
+		certs = append(certs, cert)
+	}
+	s.writeCertsOnly(w, certs)
+}
+
+func (s *fakeESTServer) isAuthorized(req *http.Request) bool {
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+		return true
+	}
+	username, password, ok := req.BasicAuth()
+	return ok && s.username != "" && username == s.username && password == s.password
+}
+
+func (s *fakeESTServer) writeCertsOnly(w http.ResponseWriter, certs []*x509.Certificate) {
+	der, err := encodeCertsOnly(certs)
+	require.NoError(s.t, err)
+
+	w.Header().Set("Content-Type", "application/pkcs7-mime; smime-type=certs-only")
+	w.Header().Set("Content-Transfer-Encoding", "base64")
+	_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(der)))
+}
+
+// encodeCertsOnly is the inverse of parseCertsOnly.
+func encodeCertsOnly(certs []*x509.Certificate) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oidSignedData)
+		b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(1)
+				b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {})
+				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					b.AddASN1ObjectIdentifier(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1})
+				})
+				b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+					for _, cert := range certs {
+						b.AddBytes(cert.Raw)
+					}
+				})
+				b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {})
+			})
+		})
+	})
+	return b.Bytes()
+}
@@ -0,0 +1,452 @@
+package est
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/spire/pkg/common/catalog"
+	"github.com/spiffe/spire/pkg/common/cryptoutil"
+	"github.com/spiffe/spire/pkg/common/pemutil"
+	"github.com/spiffe/spire/pkg/server/plugin/upstreamauthority"
+	"github.com/spiffe/spire/proto/spire/common"
+	"github.com/spiffe/spire/test/clock"
+	"github.com/spiffe/spire/test/plugintest"
+	"github.com/spiffe/spire/test/spiretest"
+	"github.com/spiffe/spire/test/testca"
+	"github.com/spiffe/spire/test/testkey"
+	"github.com/spiffe/spire/test/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	trustDomain = spiffeid.RequireTrustDomainFromString("example.org")
+)
+
+func TestConfigure(t *testing.T) {
+	dir := spiretest.TempDir(t)
+	caCertPath := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caCertPath, pemutil.EncodeCertificate(testca.New(t, trustDomain).X509Authorities()[0]), 0600))
+
+	for _, tt := range []struct {
+		name            string
+		config          *Configuration
+		expectCode      codes.Code
+		expectMsgPrefix string
+	}{
+		{
+			name: "success with basic auth",
+			config: &Configuration{
+				ServerURL:  "https://est.example.org",
+				CACertPath: caCertPath,
+				Username:   "user",
+				Password:   "pass",
+			},
+		},
+		{
+			name: "success with poll interval",
+			config: &Configuration{
+				ServerURL:           "https://est.example.org",
+				Username:            "user",
+				CACertsPollInterval: "1m",
+			},
+		},
+		{
+			name: "missing server_url",
+			config: &Configuration{
+				Username: "user",
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "server_url is required",
+		},
+		{
+			name: "server_url is not https",
+			config: &Configuration{
+				ServerURL: "http://est.example.org",
+				Username:  "user",
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "unable to create EST client: server_url must use the https scheme",
+		},
+		{
+			name: "no authentication",
+			config: &Configuration{
+				ServerURL: "https://est.example.org",
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "either a client certificate or a username must be configured",
+		},
+		{
+			name: "client cert without key",
+			config: &Configuration{
+				ServerURL:      "https://est.example.org",
+				ClientCertPath: "cert.pem",
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "client_cert_path and client_key_path must be configured together",
+		},
+		{
+			name: "client cert does not exist",
+			config: &Configuration{
+				ServerURL:      "https://est.example.org",
+				ClientCertPath: filepath.Join(dir, "missing-cert.pem"),
+				ClientKeyPath:  filepath.Join(dir, "missing-key.pem"),
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "unable to create EST client: unable to load client certificate",
+		},
+		{
+			name: "CA cert does not exist",
+			config: &Configuration{
+				ServerURL:  "https://est.example.org",
+				Username:   "user",
+				CACertPath: filepath.Join(dir, "missing.pem"),
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "unable to create EST client: unable to load CA certificates",
+		},
+		{
+			name: "invalid poll interval",
+			config: &Configuration{
+				ServerURL:           "https://est.example.org",
+				Username:            "user",
+				CACertsPollInterval: "forever",
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "unable to parse cacerts_poll_interval",
+		},
+		{
+			name: "negative poll interval",
+			config: &Configuration{
+				ServerURL:           "https://est.example.org",
+				Username:            "user",
+				CACertsPollInterval: "-1m",
+			},
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "cacerts_poll_interval must be positive",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugintest.Load(t, BuiltIn(), nil,
+				plugintest.CaptureConfigureError(&err),
+				plugintest.ConfigureJSON(tt.config),
+				plugintest.CoreConfig(catalog.CoreConfig{TrustDomain: trustDomain}),
+			)
+			spiretest.RequireGRPCStatusHasPrefix(t, err, tt.expectCode, tt.expectMsgPrefix)
+		})
+	}
+}
+
+func TestMintX509CA(t *testing.T) {
+	key := testkey.NewEC256(t)
+	csr, err := util.NewCSRTemplateWithKey(trustDomain.IDString(), key)
+	require.NoError(t, err)
+
+	dir := spiretest.TempDir(t)
+
+	// Client credentials used for TLS client authentication
+	clientCACert, clientCAKey := testca.CreateCACertificate(t, nil, nil)
+	clientCert, clientKey := testca.CreateX509Certificate(t, clientCACert, clientCAKey,
+		testca.WithKeyUsage(x509.KeyUsageDigitalSignature))
+	clientCertPath := filepath.Join(dir, "client-cert.pem")
+	clientKeyPath := filepath.Join(dir, "client-key.pem")
+	require.NoError(t, pemutil.SaveCertificate(clientCertPath, clientCert, 0600))
+	clientKeyPEM, err := pemutil.EncodePKCS8PrivateKey(clientKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(clientKeyPath, clientKeyPEM, 0600))
+
+	for _, tt := range []struct {
+		name            string
+		label           string
+		config          Configuration
+		csr             []byte
+		enrollStatus    int
+		expectCode      codes.Code
+		expectMsgPrefix string
+	}{
+		{
+			name: "success with basic auth",
+			config: Configuration{
+				Username: "user",
+				Password: "pass",
+			},
+			csr: csr,
+		},
+		{
+			name: "success with client certificate",
+			config: Configuration{
+				ClientCertPath: clientCertPath,
+				ClientKeyPath:  clientKeyPath,
+			},
+			csr: csr,
+		},
+		{
+			name:  "success with label",
+			label: "spire",
+			config: Configuration{
+				Label:    "spire",
+				Username: "user",
+				Password: "pass",
+			},
+			csr: csr,
+		},
+		{
+			name: "wrong credentials",
+			config: Configuration{
+				Username: "user",
+				Password: "wrong",
+			},
+			csr:             csr,
+			expectCode:      codes.Internal,
+			expectMsgPrefix: "upstreamauthority(est): unable to enroll CSR: simpleenroll request failed: unexpected status code 401",
+		},
+		{
+			name: "unknown label",
+			config: Configuration{
+				Label:    "unknown",
+				Username: "user",
+				Password: "pass",
+			},
+			csr:             csr,
+			expectCode:      codes.Internal,
+			expectMsgPrefix: "upstreamauthority(est): unable to fetch CA certificates: cacerts request failed: unexpected status code 404",
+		},
+		{
+			name: "enrollment deferred",
+			config: Configuration{
+				Username: "user",
+				Password: "pass",
+			},
+			csr:             csr,
+			enrollStatus:    http.StatusAccepted,
+			expectCode:      codes.Internal,
+			expectMsgPrefix: `upstreamauthority(est): unable to enroll CSR: simpleenroll request failed: request deferred by EST server (retry after "60")`,
+		},
+		{
+			name: "malformed CSR",
+			config: Configuration{
+				Username: "user",
+				Password: "pass",
+			},
+			csr:             []byte("MALFORMED"),
+			expectCode:      codes.InvalidArgument,
+			expectMsgPrefix: "upstreamauthority(est): unable to parse CSR",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeESTServer(t, tt.label)
+			server.username = "user"
+			server.password = "pass"
+			server.clientCAs = x509.NewCertPool()
+			server.clientCAs.AddCert(clientCACert)
+			if tt.enrollStatus != 0 {
+				server.setEnrollStatus(tt.enrollStatus)
+			}
+			server.start()
+
+			config := tt.config
+			config.ServerURL = server.url()
+			config.CACertPath = writeServerCert(t, server)
+
+			ua, _ := loadPlugin(t, config)
+
+			x509CA, x509Authorities, stream, err := ua.MintX509CA(context.Background(), tt.csr, 0)
+			spiretest.RequireGRPCStatusHasPrefix(t, err, tt.expectCode, tt.expectMsgPrefix)
+			if tt.expectCode != codes.OK {
+				assert.Nil(t, x509CA)
+				assert.Nil(t, x509Authorities)
+				assert.Nil(t, stream)
+				return
+			}
+			defer stream.Close()
+
+			require.Len(t, x509CA, 2)
+			isEqual, err := cryptoutil.PublicKeyEqual(x509CA[0].PublicKey, key.Public())
+			require.NoError(t, err)
+			assert.True(t, isEqual, "x509CA key does not match expected key")
+			assert.Equal(t, []string{trustDomain.IDString()}, certURIs(x509CA[0]))
+			assert.Equal(t, server.intermediateCert, x509CA[1])
+			assert.Equal(t, []*x509.Certificate{server.rootCert}, x509Authorities)
+		})
+	}
+}
+
+func TestMintX509CAIssuedCertificates(t *testing.T) {
+	csr, publicKey, err := util.NewCSRTemplate(trustDomain.IDString())
+	require.NoError(t, err)
+
+	server := newFakeESTServer(t, "")
+	server.username = "user"
+	server.password = "pass"
+	server.start()
+
+	ua, _ := loadPlugin(t, Configuration{
+		ServerURL:  server.url(),
+		CACertPath: writeServerCert(t, server),
+		Username:   "user",
+		Password:   "pass",
+	})
+
+	for _, tt := range []struct {
+		name string
+		// issueNonCA, omitIssued and prependIntermediate change the
+		// certificates returned by the simpleenroll operation
+		issueNonCA          bool
+		omitIssued          bool
+		prependIntermediate bool
+		expectCode          codes.Code
+		expectMsgPrefix     string
+	}{
+		{
+			name:                "issued certificate is not first",
+			prependIntermediate: true,
+		},
+		{
+			name:            "issued certificate is not a CA",
+			issueNonCA:      true,
+			expectCode:      codes.Internal,
+			expectMsgPrefix: "upstreamauthority(est): issued certificate is not a CA certificate",
+		},
+		{
+			name:                "no issued certificate matches the CSR",
+			omitIssued:          true,
+			prependIntermediate: true,
+			expectCode:          codes.Internal,
+			expectMsgPrefix:     "upstreamauthority(est): no issued certificate public key matches the CSR",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var prependIssued []*x509.Certificate
+			if tt.prependIntermediate {
+				prependIssued = []*x509.Certificate{server.intermediateCert}
+			}
+			server.setIssued(tt.issueNonCA, tt.omitIssued, prependIssued)
+
+			x509CA, _, stream, err := ua.MintX509CA(context.Background(), csr, 0)
+			spiretest.RequireGRPCStatusHasPrefix(t, err, tt.expectCode, tt.expectMsgPrefix)
+			if tt.expectCode != codes.OK {
+				return
+			}
+			defer stream.Close()
+
+			require.Len(t, x509CA, 2)
+			isEqual, err := cryptoutil.PublicKeyEqual(x509CA[0].PublicKey, publicKey)
+			require.NoError(t, err)
+			assert.True(t, isEqual, "x509CA key does not match expected key")
+			assert.Equal(t, server.intermediateCert, x509CA[1])
+		})
+	}
+}
+
+func TestMintX509CAStreamsRootChanges(t *testing.T) {
+	csr, _, err := util.NewCSRTemplate(trustDomain.IDString())
+	require.NoError(t, err)
+
+	server := newFakeESTServer(t, "")
+	server.username = "user"
+	server.password = "pass"
+	server.start()
+
+	ua, mockClock := loadPlugin(t, Configuration{
+		ServerURL:           server.url(),
+		CACertPath:          writeServerCert(t, server),
+		Username:            "user",
+		Password:            "pass",
+		CACertsPollInterval: "1m",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, x509Authorities, stream, err := ua.MintX509CA(ctx, csr, 0)
+	require.NoError(t, err)
+	require.Equal(t, []*x509.Certificate{server.rootCert}, x509Authorities)
+
+	// Add a new root to the EST server and advance the clock so the plugin
+	// polls the CA certificates again.
+	newRoot, _ := testca.CreateCACertificate(t, nil, nil)
+	server.appendCACert(newRoot)
+	mockClock.WaitForTicker(time.Minute, "waiting for the cacerts poll ticker")
+	mockClock.Add(time.Minute)
+
+	x509Authorities, err = stream.RecvUpstreamX509Authorities()
+	require.NoError(t, err)
+	require.Equal(t, []*x509.Certificate{server.rootCert, newRoot}, x509Authorities)
+
+	cancel()
+	_, err = stream.RecvUpstreamX509Authorities()
+	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.Canceled, "upstreamauthority(est): context canceled")
+}
+
+func TestPublishJWTKey(t *testing.T) {
+	ua, _ := loadPlugin(t, Configuration{
+		ServerURL: "https://est.example.org",
+		Username:  "user",
+	})
+
+	pkixBytes, err := x509.MarshalPKIXPublicKey(testkey.NewEC256(t).Public())
+	require.NoError(t, err)
+
+	jwtAuthorities, stream, err := ua.PublishJWTKey(context.Background(), &common.PublicKey{Kid: "ID", PkixBytes: pkixBytes})
+	spiretest.RequireGRPCStatus(t, err, codes.Unimplemented, "upstreamauthority(est): publishing upstream is unsupported")
+	assert.Nil(t, jwtAuthorities)
+	assert.Nil(t, stream)
+}
+
+func TestParseCertsOnly(t *testing.T) {
+	rootCert, rootKey := testca.CreateCACertificate(t, nil, nil)
+	intermediateCert, _ := testca.CreateCACertificate(t, rootCert, rootKey)
+
+	der, err := encodeCertsOnly([]*x509.Certificate{rootCert, intermediateCert})
+	require.NoError(t, err)
+
+	certs, err := parseCertsOnly(der)
+	require.NoError(t, err)
+	require.Equal(t, []*x509.Certificate{rootCert, intermediateCert}, certs)
+
+	_, err = parseCertsOnly([]byte("MALFORMED"))
+	require.EqualError(t, err, "malformed PKCS#7 certs-only response")
+
+	der, err = encodeCertsOnly(nil)
+	require.NoError(t, err)
+	_, err = parseCertsOnly(der)
+	require.EqualError(t, err, "no certificates in PKCS#7 response")
+}
+
+func loadPlugin(t *testing.T, config Configuration) (*upstreamauthority.V1, *clock.Mock) {
+	p := New()
+	mockClock := clock.NewMock(t)
+	p.clock = mockClock
+
+	ua := new(upstreamauthority.V1)
+	plugintest.Load(t, builtin(p), ua,
+		plugintest.ConfigureJSON(config),
+		plugintest.CoreConfig(catalog.CoreConfig{TrustDomain: trustDomain}),
+	)
+	return ua, mockClock
+}
+
+func writeServerCert(t *testing.T, server *fakeESTServer) string {
+	path := filepath.Join(spiretest.TempDir(t), "est-server.pem")
+	require.NoError(t, pemutil.SaveCertificate(path, server.serverCert(), 0600))
+	return path
+}
+
+func certURIs(cert *x509.Certificate) []string {
+	var uris []string
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+	return uris
+}
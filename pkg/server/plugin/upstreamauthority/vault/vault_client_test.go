@@ -1,61 +1,58 @@
 package vault
 
 import (
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"net/http"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
-	vapi "github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/spiffe/spire/pkg/common/pemutil"
+	cvault "github.com/spiffe/spire/pkg/common/plugin/vault"
 	"github.com/spiffe/spire/test/spiretest"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 )
 
 const (
-	testRootCert          = "testdata/keys/EC/root_cert.pem"
-	testInvalidRootCert   = "testdata/keys/EC/invalid_root_cert.pem"
-	testServerCert        = "testdata/keys/EC/server_cert.pem"
-	testServerKey         = "testdata/keys/EC/server_key.pem"
-	testClientCert        = "testdata/keys/EC/client_cert.pem"
-	testClientKey         = "testdata/keys/EC/client_key.pem"
-	testInvalidClientCert = "testdata/keys/EC/invalid_client_cert.pem"
-	testInvalidClientKey  = "testdata/keys/EC/invalid_client_key.pem"
-	testReqCSR            = "testdata/keys/EC/intermediate_csr.pem"
+	testRootCert   = "testdata/keys/EC/root_cert.pem"
+	testServerCert = "testdata/keys/EC/server_cert.pem"
+	testServerKey  = "testdata/keys/EC/server_key.pem"
+	testClientCert = "testdata/keys/EC/client_cert.pem"
+	testClientKey  = "testdata/keys/EC/client_key.pem"
+	testReqCSR     = "testdata/keys/EC/intermediate_csr.pem"
 )
 
 func TestNewClientConfigWithDefaultValues(t *testing.T) {
 	p := &ClientParams{
-		VaultAddr:             "http://example.org:8200/",
-		PKIMountPoint:         "", // Expect the default value to be used.
-		Token:                 "test-token",
-		CertAuthMountPoint:    "", // Expect the default value to be used.
-		AppRoleAuthMountPoint: "", // Expect the default value to be used.
-		K8sAuthMountPoint:     "", // Expect the default value to be used.
+		ClientParams: cvault.ClientParams{
+			VaultAddr:             "http://example.org:8200/",
+			Token:                 "test-token",
+			CertAuthMountPoint:    "", // Expect the default value to be used.
+			AppRoleAuthMountPoint: "", // Expect the default value to be used.
+			K8sAuthMountPoint:     "", // Expect the default value to be used.
+		},
+		PKIMountPoint: "", // Expect the default value to be used.
 	}
 
 	cc, err := NewClientConfig(p, hclog.Default())
 	require.NoError(t, err)
 	require.Equal(t, defaultPKIMountPoint, cc.clientParams.PKIMountPoint)
-	require.Equal(t, defaultCertMountPoint, cc.clientParams.CertAuthMountPoint)
-	require.Equal(t, defaultAppRoleMountPoint, cc.clientParams.AppRoleAuthMountPoint)
-	require.Equal(t, defaultK8sMountPoint, cc.clientParams.K8sAuthMountPoint)
+	require.Equal(t, cvault.DefaultCertMountPoint, cc.clientParams.CertAuthMountPoint)
+	require.Equal(t, cvault.DefaultAppRoleMountPoint, cc.clientParams.AppRoleAuthMountPoint)
+	require.Equal(t, cvault.DefaultK8sMountPoint, cc.clientParams.K8sAuthMountPoint)
 }
 
 func TestNewClientConfigWithGivenValuesInsteadOfDefaults(t *testing.T) {
 	p := &ClientParams{
-		VaultAddr:             "http://example.org:8200/",
-		PKIMountPoint:         "test-pki",
-		Token:                 "test-token",
-		CertAuthMountPoint:    "test-tls-cert",
-		AppRoleAuthMountPoint: "test-approle",
-		K8sAuthMountPoint:     "test-k8s",
+		ClientParams: cvault.ClientParams{
+			VaultAddr:             "http://example.org:8200/",
+			Token:                 "test-token",
+			CertAuthMountPoint:    "test-tls-cert",
+			AppRoleAuthMountPoint: "test-approle",
+			K8sAuthMountPoint:     "test-k8s",
+		},
+		PKIMountPoint: "test-pki",
 	}
 
 	cc, err := NewClientConfig(p, hclog.Default())
@@ -102,17 +99,19 @@ func TestNewAuthenticatedClientCertAuth(t *testing.T) {
 			defer s.Close()
 
 			cp := &ClientParams{
-				VaultAddr:      fmt.Sprintf("https://%v/", addr),
-				Namespace:      tt.namespace,
-				CACertPath:     testRootCert,
-				ClientCertPath: testClientCert,
-				ClientKeyPath:  testClientKey,
+				ClientParams: cvault.ClientParams{
+					VaultAddr:      fmt.Sprintf("https://%v/", addr),
+					Namespace:      tt.namespace,
+					CACertPath:     testRootCert,
+					ClientCertPath: testClientCert,
+					ClientKeyPath:  testClientKey,
+				},
 			}
 			cc, err := NewClientConfig(cp, hclog.Default())
 			require.NoError(t, err)
 
 			renewCh := make(chan struct{})
-			client, err := cc.NewAuthenticatedClient(CERT, renewCh)
+			client, err := cc.NewAuthenticatedClient(cvault.CERT, renewCh)
 			require.NoError(t, err)
 
 			select {
@@ -187,16 +186,18 @@ func TestNewAuthenticatedClientTokenAuth(t *testing.T) {
 			defer s.Close()
 
 			cp := &ClientParams{
-				VaultAddr:  fmt.Sprintf("https://%v/", addr),
-				Namespace:  tt.namespace,
-				CACertPath: testRootCert,
-				Token:      tt.token,
+				ClientParams: cvault.ClientParams{
+					VaultAddr:  fmt.Sprintf("https://%v/", addr),
+					Namespace:  tt.namespace,
+					CACertPath: testRootCert,
+					Token:      tt.token,
+				},
 			}
 			cc, err := NewClientConfig(cp, hclog.Default())
 			require.NoError(t, err)
 
 			renewCh := make(chan struct{})
-			client, err := cc.NewAuthenticatedClient(TOKEN, renewCh)
+			client, err := cc.NewAuthenticatedClient(cvault.TOKEN, renewCh)
 			if tt.expectMsgPrefix != "" {
 				spiretest.RequireGRPCStatusHasPrefix(t, err, tt.expectCode, tt.expectMsgPrefix)
 				return
@@ -255,17 +256,19 @@ func TestNewAuthenticatedClientAppRoleAuth(t *testing.T) {
 			defer s.Close()
 
 			cp := &ClientParams{
-				VaultAddr:       fmt.Sprintf("https://%v/", addr),
-				Namespace:       tt.namespace,
-				CACertPath:      testRootCert,
-				AppRoleID:       "test-approle-id",
-				AppRoleSecretID: "test-approle-secret-id",
+				ClientParams: cvault.ClientParams{
+					VaultAddr:       fmt.Sprintf("https://%v/", addr),
+					Namespace:       tt.namespace,
+					CACertPath:      testRootCert,
+					AppRoleID:       "test-approle-id",
+					AppRoleSecretID: "test-approle-secret-id",
+				},
 			}
 			cc, err := NewClientConfig(cp, hclog.Default())
 			require.NoError(t, err)
 
 			renewCh := make(chan struct{})
-			client, err := cc.NewAuthenticatedClient(APPROLE, renewCh)
+			client, err := cc.NewAuthenticatedClient(cvault.APPROLE, renewCh)
 			require.NoError(t, err)
 
 			select {
@@ -319,17 +322,19 @@ func TestNewAuthenticatedClientK8sAuth(t *testing.T) {
 			defer s.Close()
 
 			cp := &ClientParams{
-				VaultAddr:        fmt.Sprintf("https://%v/", addr),
-				Namespace:        tt.namespace,
-				CACertPath:       testRootCert,
-				K8sAuthRoleName:  "my-role",
-				K8sAuthTokenPath: "testdata/k8s/token",
+				ClientParams: cvault.ClientParams{
+					VaultAddr:        fmt.Sprintf("https://%v/", addr),
+					Namespace:        tt.namespace,
+					CACertPath:       testRootCert,
+					K8sAuthRoleName:  "my-role",
+					K8sAuthTokenPath: "testdata/k8s/token",
+				},
 			}
 			cc, err := NewClientConfig(cp, hclog.Default())
 			require.NoError(t, err)
 
 			renewCh := make(chan struct{})
-			client, err := cc.NewAuthenticatedClient(K8S, renewCh)
+			client, err := cc.NewAuthenticatedClient(cvault.K8S, renewCh)
 			require.NoError(t, err)
 
 			select {
@@ -359,18 +364,20 @@ func TestNewAuthenticatedClientCertAuthFailed(t *testing.T) {
 
 	retry := 0 // Disable retry
 	cp := &ClientParams{
-		MaxRetries:     &retry,
-		VaultAddr:      fmt.Sprintf("https://%v/", addr),
-		CACertPath:     testRootCert,
-		ClientCertPath: testClientCert,
-		ClientKeyPath:  testClientKey,
+		ClientParams: cvault.ClientParams{
+			MaxRetries:     &retry,
+			VaultAddr:      fmt.Sprintf("https://%v/", addr),
+			CACertPath:     testRootCert,
+			ClientCertPath: testClientCert,
+			ClientKeyPath:  testClientKey,
+		},
 	}
 
 	cc, err := NewClientConfig(cp, hclog.Default())
 	require.NoError(t, err)
 
 	renewCh := make(chan struct{})
-	_, err = cc.NewAuthenticatedClient(CERT, renewCh)
+	_, err = cc.NewAuthenticatedClient(cvault.CERT, renewCh)
 	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.Unauthenticated, "authentication failed auth/cert/login: Error making API request.")
 }
 
@@ -386,17 +393,19 @@ func TestNewAuthenticatedClientAppRoleAuthFailed(t *testing.T) {
 
 	retry := 0 // Disable retry
 	cp := &ClientParams{
-		MaxRetries:      &retry,
-		VaultAddr:       fmt.Sprintf("https://%v/", addr),
-		CACertPath:      testRootCert,
-		AppRoleID:       "test-approle-id",
-		AppRoleSecretID: "test-approle-secret-id",
+		ClientParams: cvault.ClientParams{
+			MaxRetries:      &retry,
+			VaultAddr:       fmt.Sprintf("https://%v/", addr),
+			CACertPath:      testRootCert,
+			AppRoleID:       "test-approle-id",
+			AppRoleSecretID: "test-approle-secret-id",
+		},
 	}
 	cc, err := NewClientConfig(cp, hclog.Default())
 	require.NoError(t, err)
 
 	renewCh := make(chan struct{})
-	_, err = cc.NewAuthenticatedClient(APPROLE, renewCh)
+	_, err = cc.NewAuthenticatedClient(cvault.APPROLE, renewCh)
 	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.Unauthenticated, "authentication failed auth/approle/login: Error making API request.")
 }
 
@@ -412,33 +421,37 @@ func TestNewAuthenticatedClientK8sAuthFailed(t *testing.T) {
 
 	retry := 0 // Disable retry
 	cp := &ClientParams{
-		MaxRetries:       &retry,
-		VaultAddr:        fmt.Sprintf("https://%v/", addr),
-		CACertPath:       testRootCert,
-		K8sAuthRoleName:  "my-role",
-		K8sAuthTokenPath: "testdata/k8s/token",
+		ClientParams: cvault.ClientParams{
+			MaxRetries:       &retry,
+			VaultAddr:        fmt.Sprintf("https://%v/", addr),
+			CACertPath:       testRootCert,
+			K8sAuthRoleName:  "my-role",
+			K8sAuthTokenPath: "testdata/k8s/token",
+		},
 	}
 	cc, err := NewClientConfig(cp, hclog.Default())
 	require.NoError(t, err)
 
 	renewCh := make(chan struct{})
-	_, err = cc.NewAuthenticatedClient(K8S, renewCh)
+	_, err = cc.NewAuthenticatedClient(cvault.K8S, renewCh)
 	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.Unauthenticated, "authentication failed auth/kubernetes/login: Error making API request.")
 }
 
 func TestNewAuthenticatedClientK8sAuthInvalidPath(t *testing.T) {
 	retry := 0 // Disable retry
 	cp := &ClientParams{
-		MaxRetries:       &retry,
-		VaultAddr:        "https://example.org:8200",
-		CACertPath:       testRootCert,
-		K8sAuthTokenPath: "invalid/k8s/token",
+		ClientParams: cvault.ClientParams{
+			MaxRetries:       &retry,
+			VaultAddr:        "https://example.org:8200",
+			CACertPath:       testRootCert,
+			K8sAuthTokenPath: "invalid/k8s/token",
+		},
 	}
 	cc, err := NewClientConfig(cp, hclog.Default())
 	require.NoError(t, err)
 
 	renewCh := make(chan struct{})
-	_, err = cc.NewAuthenticatedClient(K8S, renewCh)
+	_, err = cc.NewAuthenticatedClient(cvault.K8S, renewCh)
 	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.Internal, "failed to read k8s service account token:")
 }
 
@@ -457,16 +470,18 @@ func TestRenewTokenFailed(t *testing.T) {
 
 	retry := 0
 	cp := &ClientParams{
-		MaxRetries: &retry,
-		VaultAddr:  fmt.Sprintf("https://%v/", addr),
-		CACertPath: testRootCert,
-		Token:      "test-token",
+		ClientParams: cvault.ClientParams{
+			MaxRetries: &retry,
+			VaultAddr:  fmt.Sprintf("https://%v/", addr),
+			CACertPath: testRootCert,
+			Token:      "test-token",
+		},
 	}
 	cc, err := NewClientConfig(cp, hclog.Default())
 	require.NoError(t, err)
 
 	renewCh := make(chan struct{})
-	_, err = cc.NewAuthenticatedClient(TOKEN, renewCh)
+	_, err = cc.NewAuthenticatedClient(cvault.TOKEN, renewCh)
 	require.NoError(t, err)
 
 	select {
@@ -476,135 +491,6 @@ func TestRenewTokenFailed(t *testing.T) {
 	}
 }
 
-func TestConfigureTLSWithCertAuth(t *testing.T) {
-	cp := &ClientParams{
-		VaultAddr:      "http://example.org:8200",
-		ClientCertPath: testClientCert,
-		ClientKeyPath:  testClientKey,
-		CACertPath:     testRootCert,
-	}
-	cc, err := NewClientConfig(cp, hclog.Default())
-	require.NoError(t, err)
-
-	vc := vapi.DefaultConfig()
-	err = cc.configureTLS(vc)
-	require.NoError(t, err)
-
-	tcc := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
-	cert, err := tcc.GetClientCertificate(&tls.CertificateRequestInfo{})
-	require.NoError(t, err)
-
-	testCert, err := testClientCertificatePair()
-	require.NoError(t, err)
-	require.Equal(t, testCert.Certificate, cert.Certificate)
-
-	testPool, err := testRootCAs()
-	require.NoError(t, err)
-	require.Equal(t, testPool.Subjects(), tcc.RootCAs.Subjects())
-}
-
-func TestConfigureTLSWithTokenAuth(t *testing.T) {
-	cp := &ClientParams{
-		VaultAddr:  "http://example.org:8200",
-		CACertPath: testRootCert,
-		Token:      "test-token",
-	}
-	cc, err := NewClientConfig(cp, hclog.Default())
-	require.NoError(t, err)
-
-	vc := vapi.DefaultConfig()
-	err = cc.configureTLS(vc)
-	require.NoError(t, err)
-
-	tcc := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
-	require.Nil(t, tcc.GetClientCertificate)
-
-	testPool, err := testRootCAs()
-	require.NoError(t, err)
-	require.Equal(t, testPool.Subjects(), tcc.RootCAs.Subjects())
-}
-
-func TestConfigureTLSWithAppRoleAuth(t *testing.T) {
-	cp := &ClientParams{
-		VaultAddr:       "http://example.org:8200",
-		CACertPath:      testRootCert,
-		AppRoleID:       "test-approle-id",
-		AppRoleSecretID: "test-approle-secret",
-	}
-	cc, err := NewClientConfig(cp, hclog.Default())
-	require.NoError(t, err)
-
-	vc := vapi.DefaultConfig()
-	err = cc.configureTLS(vc)
-	require.NoError(t, err)
-
-	tcc := vc.HttpClient.Transport.(*http.Transport).TLSClientConfig
-	require.Nil(t, tcc.GetClientCertificate)
-
-	testPool, err := testRootCAs()
-	require.NoError(t, err)
-	require.Equal(t, testPool.Subjects(), tcc.RootCAs.Subjects())
-}
-
-func TestConfigureTLSInvalidCACert(t *testing.T) {
-	cp := &ClientParams{
-		VaultAddr:      "http://example.org:8200",
-		ClientCertPath: testClientCert,
-		ClientKeyPath:  testClientKey,
-		CACertPath:     testInvalidRootCert,
-	}
-	cc, err := NewClientConfig(cp, hclog.Default())
-	require.NoError(t, err)
-
-	vc := vapi.DefaultConfig()
-	err = cc.configureTLS(vc)
-	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.InvalidArgument, "failed to load CA certificate: no PEM blocks")
-}
-
-func TestConfigureTLSInvalidClientKey(t *testing.T) {
-	cp := &ClientParams{
-		VaultAddr:      "http://example.org:8200",
-		ClientCertPath: testClientCert,
-		ClientKeyPath:  testInvalidClientKey,
-		CACertPath:     testRootCert,
-	}
-	cc, err := NewClientConfig(cp, hclog.Default())
-	require.NoError(t, err)
-
-	vc := vapi.DefaultConfig()
-	err = cc.configureTLS(vc)
-	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.InvalidArgument, "failed to parse client cert and private-key: tls: failed to find any PEM data in key input")
-}
-
-func TestConfigureTLSInvalidClientCert(t *testing.T) {
-	cp := &ClientParams{
-		VaultAddr:      "http://example.org:8200",
-		ClientCertPath: testInvalidClientCert,
-		ClientKeyPath:  testClientKey,
-		CACertPath:     testRootCert,
-	}
-	cc, err := NewClientConfig(cp, hclog.Default())
-	require.NoError(t, err)
-
-	vc := vapi.DefaultConfig()
-	err = cc.configureTLS(vc)
-	spiretest.RequireGRPCStatusHasPrefix(t, err, codes.InvalidArgument, fmt.Sprintf("failed to parse client cert and private-key: open testdata/keys/EC/invalid_client_cert.pem: %s", spiretest.FileNotFound()))
-}
-
-func TestConfigureTLSRequireClientCertAndKey(t *testing.T) {
-	cp := &ClientParams{
-		VaultAddr:      "http://example.org:8200",
-		ClientCertPath: testClientCert,
-		CACertPath:     testRootCert,
-	}
-	cc, err := NewClientConfig(cp, hclog.Default())
-	require.NoError(t, err)
-
-	vc := vapi.DefaultConfig()
-	err = cc.configureTLS(vc)
-	spiretest.RequireGRPCStatus(t, err, codes.InvalidArgument, "both client cert and client key are required")
-}
-
 func TestSignIntermediate(t *testing.T) {
 	fakeVaultServer := newFakeVaultServer()
 	fakeVaultServer.CertAuthResponseCode = 200
@@ -619,17 +505,19 @@ func TestSignIntermediate(t *testing.T) {
 	defer s.Close()
 
 	cp := &ClientParams{
-		VaultAddr:      fmt.Sprintf("https://%v/", addr),
-		CACertPath:     testRootCert,
-		ClientCertPath: testClientCert,
-		ClientKeyPath:  testClientKey,
+		ClientParams: cvault.ClientParams{
+			VaultAddr:      fmt.Sprintf("https://%v/", addr),
+			CACertPath:     testRootCert,
+			ClientCertPath: testClientCert,
+			ClientKeyPath:  testClientKey,
+		},
 	}
 
 	cc, err := NewClientConfig(cp, hclog.Default())
 	require.NoError(t, err)
 
 	renewCh := make(chan struct{})
-	client, err := cc.NewAuthenticatedClient(CERT, renewCh)
+	client, err := cc.NewAuthenticatedClient(cvault.CERT, renewCh)
 	require.NoError(t, err)
 
 	testTTL := "0"
@@ -658,18 +546,20 @@ func TestSignIntermediateErrorFromEndpoint(t *testing.T) {
 
 	retry := 0 // Disable retry
 	cp := &ClientParams{
-		MaxRetries:     &retry,
-		VaultAddr:      fmt.Sprintf("https://%v/", addr),
-		CACertPath:     testRootCert,
-		ClientCertPath: testClientCert,
-		ClientKeyPath:  testClientKey,
+		ClientParams: cvault.ClientParams{
+			MaxRetries:     &retry,
+			VaultAddr:      fmt.Sprintf("https://%v/", addr),
+			CACertPath:     testRootCert,
+			ClientCertPath: testClientCert,
+			ClientKeyPath:  testClientKey,
+		},
 	}
 
 	cc, err := NewClientConfig(cp, hclog.Default())
 	require.NoError(t, err)
 
 	renewCh := make(chan struct{})
-	client, err := cc.NewAuthenticatedClient(CERT, renewCh)
+	client, err := cc.NewAuthenticatedClient(cvault.CERT, renewCh)
 	require.NoError(t, err)
 
 	testTTL := "0"
@@ -688,29 +578,3 @@ func newFakeVaultServer() *FakeVaultServerConfig {
 	fakeVaultServer.RenewResponse = []byte(testRenewResponse)
 	return fakeVaultServer
 }
-
-func testClientCertificatePair() (tls.Certificate, error) {
-	cert, err := os.ReadFile(testClientCert)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-	key, err := os.ReadFile(testClientKey)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-
-	return tls.X509KeyPair(cert, key)
-}
-
-func testRootCAs() (*x509.CertPool, error) {
-	pool := x509.NewCertPool()
-	pem, err := os.ReadFile(testRootCert)
-	if err != nil {
-		return nil, err
-	}
-	ok := pool.AppendCertsFromPEM(pem)
-	if !ok {
-		return nil, err
-	}
-	return pool, nil
-}
@@ -17,6 +17,7 @@ import (
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/spire/pkg/common/catalog"
 	"github.com/spiffe/spire/pkg/common/pemutil"
+	cvault "github.com/spiffe/spire/pkg/common/plugin/vault"
 	"github.com/spiffe/spire/pkg/server/plugin/upstreamauthority"
 	"github.com/spiffe/spire/proto/spire/common"
 	"github.com/spiffe/spire/test/plugintest"
@@ -45,7 +46,7 @@ func TestConfigure(t *testing.T) {
 		plainConfig              string
 		expectMsgPrefix          string
 		expectCode               codes.Code
-		wantAuth                 AuthMethod
+		wantAuth                 cvault.AuthMethod
 		wantNamespaceIsNotNil    bool
 		envKeyVal                map[string]string
 		expectToken              string
@@ -62,22 +63,22 @@ func TestConfigure(t *testing.T) {
 		{
 			name:        "Configure plugin with Client Certificate authentication params given in config file",
 			configTmpl:  testTokenAuthConfigTpl,
-			wantAuth:    TOKEN,
+			wantAuth:    cvault.TOKEN,
 			expectToken: "test-token",
 		},
 		{
 			name:       "Configure plugin with Token authentication params given as environment variables",
 			configTmpl: testTokenAuthConfigWithEnvTpl,
 			envKeyVal: map[string]string{
-				envVaultToken: "test-token",
+				cvault.EnvVaultToken: "test-token",
 			},
-			wantAuth:    TOKEN,
+			wantAuth:    cvault.TOKEN,
 			expectToken: "test-token",
 		},
 		{
 			name:                     "Configure plugin with Client Certificate authentication params given in config file",
 			configTmpl:               testCertAuthConfigTpl,
-			wantAuth:                 CERT,
+			wantAuth:                 cvault.CERT,
 			expectCertAuthMountPoint: "test-cert-auth",
 			expectClientCertPath:     "testdata/keys/EC/client_cert.pem",
 			expectClientKeyPath:      "testdata/keys/EC/client_key.pem",
@@ -86,10 +87,10 @@ func TestConfigure(t *testing.T) {
 			name:       "Configure plugin with Client Certificate authentication params given as environment variables",
 			configTmpl: testCertAuthConfigWithEnvTpl,
 			envKeyVal: map[string]string{
-				envVaultClientCert: "testdata/keys/EC/client_cert.pem",
-				envVaultClientKey:  "testdata/keys/EC/client_key.pem",
+				cvault.EnvVaultClientCert: "testdata/keys/EC/client_cert.pem",
+				cvault.EnvVaultClientKey:  "testdata/keys/EC/client_key.pem",
 			},
-			wantAuth:                 CERT,
+			wantAuth:                 cvault.CERT,
 			expectCertAuthMountPoint: "test-cert-auth",
 			expectClientCertPath:     "testdata/keys/EC/client_cert.pem",
 			expectClientKeyPath:      "testdata/keys/EC/client_key.pem",
@@ -97,7 +98,7 @@ func TestConfigure(t *testing.T) {
 		{
 			name:                  "Configure plugin with AppRole authenticate params given in config file",
 			configTmpl:            testAppRoleAuthConfigTpl,
-			wantAuth:              APPROLE,
+			wantAuth:              cvault.APPROLE,
 			appRoleAuthMountPoint: "test-approle-auth",
 			appRoleID:             "test-approle-id",
 			appRoleSecretID:       "test-approle-secret-id",
@@ -106,10 +107,10 @@ func TestConfigure(t *testing.T) {
 			name:       "Configure plugin with AppRole authentication params given as environment variables",
 			configTmpl: testAppRoleAuthConfigWithEnvTpl,
 			envKeyVal: map[string]string{
-				envVaultAppRoleID:       "test-approle-id",
-				envVaultAppRoleSecretID: "test-approle-secret-id",
+				cvault.EnvVaultAppRoleID:       "test-approle-id",
+				cvault.EnvVaultAppRoleSecretID: "test-approle-secret-id",
 			},
-			wantAuth:              APPROLE,
+			wantAuth:              cvault.APPROLE,
 			appRoleAuthMountPoint: "test-approle-auth",
 			appRoleID:             "test-approle-id",
 			appRoleSecretID:       "test-approle-secret-id",
@@ -117,7 +118,7 @@ func TestConfigure(t *testing.T) {
 		{
 			name:                    "Configure plugin with Kubernetes authentication params given in config file",
 			configTmpl:              testK8sAuthConfigTpl,
-			wantAuth:                K8S,
+			wantAuth:                cvault.K8S,
 			expectK8sAuthMountPoint: "test-k8s-auth",
 			expectK8sAuthTokenPath:  "testdata/k8s/token",
 			expectK8sAuthRoleName:   "my-role",
@@ -132,15 +133,15 @@ func TestConfigure(t *testing.T) {
 			name:       "Pass VaultAddr via the environment variable",
 			configTmpl: testConfigWithVaultAddrEnvTpl,
 			envKeyVal: map[string]string{
-				envVaultAddr: fmt.Sprintf("https://%v/", addr),
+				cvault.EnvVaultAddr: fmt.Sprintf("https://%v/", addr),
 			},
-			wantAuth:    TOKEN,
+			wantAuth:    cvault.TOKEN,
 			expectToken: "test-token",
 		},
 		{
 			name:                  "Configure plugin with given namespace",
 			configTmpl:            testNamespaceConfigTpl,
-			wantAuth:              TOKEN,
+			wantAuth:              cvault.TOKEN,
 			wantNamespaceIsNotNil: true,
 			expectToken:           "test-token",
 		},
@@ -153,14 +154,14 @@ func TestConfigure(t *testing.T) {
 		{
 			name:            "Required parameters are not given / k8s_auth_role_name",
 			configTmpl:      testK8sAuthNoRoleNameTpl,
-			wantAuth:        K8S,
+			wantAuth:        cvault.K8S,
 			expectCode:      codes.InvalidArgument,
 			expectMsgPrefix: "k8s_auth_role_name is required",
 		},
 		{
 			name:            "Required parameters are not given / token_path",
 			configTmpl:      testK8sAuthNoTokenPathTpl,
-			wantAuth:        K8S,
+			wantAuth:        cvault.K8S,
 			expectCode:      codes.InvalidArgument,
 			expectMsgPrefix: "token_path is required",
 		},
@@ -201,17 +202,17 @@ func TestConfigure(t *testing.T) {
 			require.NotNil(t, p.cc.clientParams)
 
 			switch tt.wantAuth {
-			case TOKEN:
+			case cvault.TOKEN:
 				require.Equal(t, tt.expectToken, p.cc.clientParams.Token)
-			case CERT:
+			case cvault.CERT:
 				require.Equal(t, tt.expectCertAuthMountPoint, p.cc.clientParams.CertAuthMountPoint)
 				require.Equal(t, tt.expectClientCertPath, p.cc.clientParams.ClientCertPath)
 				require.Equal(t, tt.expectClientKeyPath, p.cc.clientParams.ClientKeyPath)
-			case APPROLE:
+			case cvault.APPROLE:
 				require.NotNil(t, p.cc.clientParams.AppRoleAuthMountPoint)
 				require.NotNil(t, p.cc.clientParams.AppRoleID)
 				require.NotNil(t, p.cc.clientParams.AppRoleSecretID)
-			case K8S:
+			case cvault.K8S:
 				require.Equal(t, tt.expectK8sAuthMountPoint, p.cc.clientParams.K8sAuthMountPoint)
 				require.Equal(t, tt.expectK8sAuthRoleName, p.cc.clientParams.K8sAuthRoleName)
 				require.Equal(t, tt.expectK8sAuthTokenPath, p.cc.clientParams.K8sAuthTokenPath)
@@ -228,11 +229,13 @@ func TestMintX509CA(t *testing.T) {
 	csr, err := pemutil.LoadCertificateRequest(testReqCSR)
 	require.NoError(t, err)
 	successfulConfig := &Configuration{
-		PKIMountPoint: "test-pki",
-		CACertPath:    "testdata/keys/EC/root_cert.pem",
-		TokenAuth: &TokenAuthConfig{
-			Token: "test-token",
+		Config: cvault.Config{
+			CACertPath: "testdata/keys/EC/root_cert.pem",
+			TokenAuth: &cvault.TokenAuthConfig{
+				Token: "test-token",
+			},
 		},
+		PKIMountPoint: "test-pki",
 	}
 
 	for _, tt := range []struct {
@@ -240,7 +243,7 @@ func TestMintX509CA(t *testing.T) {
 		csr                     []byte
 		config                  *Configuration
 		ttl                     time.Duration
-		authMethod              AuthMethod
+		authMethod              cvault.AuthMethod
 		expectCode              codes.Code
 		expectMsgPrefix         string
 		expectX509CA            []string
@@ -252,13 +255,15 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with Token authentication",
 			csr:  csr.Raw,
 			config: &Configuration{
-				PKIMountPoint: "test-pki",
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				TokenAuth: &TokenAuthConfig{
-					Token: "test-token",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					TokenAuth: &cvault.TokenAuthConfig{
+						Token: "test-token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              TOKEN,
+			authMethod:              cvault.TOKEN,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -276,13 +281,15 @@ func TestMintX509CA(t *testing.T) {
 			csr:  csr.Raw,
 			ttl:  time.Minute,
 			config: &Configuration{
-				PKIMountPoint: "test-pki",
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				TokenAuth: &TokenAuthConfig{
-					Token: "test-token",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					TokenAuth: &cvault.TokenAuthConfig{
+						Token: "test-token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              TOKEN,
+			authMethod:              cvault.TOKEN,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -299,13 +306,15 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with Token authentication / Token is not renewable",
 			csr:  csr.Raw,
 			config: &Configuration{
-				PKIMountPoint: "test-pki",
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				TokenAuth: &TokenAuthConfig{
-					Token: "test-token",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					TokenAuth: &cvault.TokenAuthConfig{
+						Token: "test-token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              TOKEN,
+			authMethod:              cvault.TOKEN,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -322,13 +331,15 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with Token authentication / Token never expire",
 			csr:  csr.Raw,
 			config: &Configuration{
-				PKIMountPoint: "test-pki",
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				TokenAuth: &TokenAuthConfig{
-					Token: "test-token",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					TokenAuth: &cvault.TokenAuthConfig{
+						Token: "test-token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              TOKEN,
+			authMethod:              cvault.TOKEN,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -345,16 +356,18 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with TLS cert authentication",
 			csr:  csr.Raw,
 			config: &Configuration{
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				PKIMountPoint: "test-pki",
-				CertAuth: &CertAuthConfig{
-					CertAuthMountPoint: "test-cert-auth",
-					CertAuthRoleName:   "test",
-					ClientCertPath:     "testdata/keys/EC/client_cert.pem",
-					ClientKeyPath:      "testdata/keys/EC/client_key.pem",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					CertAuth: &cvault.CertAuthConfig{
+						CertAuthMountPoint: "test-cert-auth",
+						CertAuthRoleName:   "test",
+						ClientCertPath:     "testdata/keys/EC/client_cert.pem",
+						ClientKeyPath:      "testdata/keys/EC/client_key.pem",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              CERT,
+			authMethod:              cvault.CERT,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -371,15 +384,17 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with AppRole authentication",
 			csr:  csr.Raw,
 			config: &Configuration{
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				PKIMountPoint: "test-pki",
-				AppRoleAuth: &AppRoleAuthConfig{
-					AppRoleMountPoint: "test-approle-auth",
-					RoleID:            "test-approle-id",
-					SecretID:          "test-approle-secret-id",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					AppRoleAuth: &cvault.AppRoleAuthConfig{
+						AppRoleMountPoint: "test-approle-auth",
+						RoleID:            "test-approle-id",
+						SecretID:          "test-approle-secret-id",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              APPROLE,
+			authMethod:              cvault.APPROLE,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -396,15 +411,17 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with Kubernetes authentication",
 			csr:  csr.Raw,
 			config: &Configuration{
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				PKIMountPoint: "test-pki",
-				K8sAuth: &K8sAuthConfig{
-					K8sAuthMountPoint: "test-k8s-auth",
-					K8sAuthRoleName:   "my-role",
-					TokenPath:         "testdata/k8s/token",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					K8sAuth: &cvault.K8sAuthConfig{
+						K8sAuthMountPoint: "test-k8s-auth",
+						K8sAuthRoleName:   "my-role",
+						TokenPath:         "testdata/k8s/token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              K8S,
+			authMethod:              cvault.K8S,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -420,16 +437,18 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with TLS cert authentication / Token is not renewable",
 			csr:  csr.Raw,
 			config: &Configuration{
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				PKIMountPoint: "test-pki",
-				CertAuth: &CertAuthConfig{
-					CertAuthMountPoint: "test-cert-auth",
-					CertAuthRoleName:   "test",
-					ClientCertPath:     "testdata/keys/EC/client_cert.pem",
-					ClientKeyPath:      "testdata/keys/EC/client_key.pem",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					CertAuth: &cvault.CertAuthConfig{
+						CertAuthMountPoint: "test-cert-auth",
+						CertAuthRoleName:   "test",
+						ClientCertPath:     "testdata/keys/EC/client_cert.pem",
+						ClientKeyPath:      "testdata/keys/EC/client_key.pem",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              CERT,
+			authMethod:              cvault.CERT,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -446,15 +465,17 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with AppRole authentication / Token is not renewable",
 			csr:  csr.Raw,
 			config: &Configuration{
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				PKIMountPoint: "test-pki",
-				AppRoleAuth: &AppRoleAuthConfig{
-					AppRoleMountPoint: "test-approle-auth",
-					RoleID:            "test-approle-id",
-					SecretID:          "test-approle-secret-id",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					AppRoleAuth: &cvault.AppRoleAuthConfig{
+						AppRoleMountPoint: "test-approle-auth",
+						RoleID:            "test-approle-id",
+						SecretID:          "test-approle-secret-id",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              APPROLE,
+			authMethod:              cvault.APPROLE,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -471,15 +492,17 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with Kubernetes authentication / Token is not renewable",
 			csr:  csr.Raw,
 			config: &Configuration{
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				PKIMountPoint: "test-pki",
-				K8sAuth: &K8sAuthConfig{
-					K8sAuthMountPoint: "test-k8s-auth",
-					K8sAuthRoleName:   "my-role",
-					TokenPath:         "testdata/k8s/token",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					K8sAuth: &cvault.K8sAuthConfig{
+						K8sAuthMountPoint: "test-k8s-auth",
+						K8sAuthRoleName:   "my-role",
+						TokenPath:         "testdata/k8s/token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              K8S,
+			authMethod:              cvault.K8S,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -495,14 +518,16 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID with Namespace",
 			csr:  csr.Raw,
 			config: &Configuration{
-				Namespace:     "test-ns",
-				PKIMountPoint: "test-pki",
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				TokenAuth: &TokenAuthConfig{
-					Token: "test-token",
+				Config: cvault.Config{
+					Namespace:  "test-ns",
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					TokenAuth: &cvault.TokenAuthConfig{
+						Token: "test-token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              TOKEN,
+			authMethod:              cvault.TOKEN,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -519,13 +544,15 @@ func TestMintX509CA(t *testing.T) {
 			name: "Mint X509CA SVID against the RootCA Vault",
 			csr:  csr.Raw,
 			config: &Configuration{
-				PKIMountPoint: "test-pki",
-				CACertPath:    "testdata/keys/EC/root_cert.pem",
-				TokenAuth: &TokenAuthConfig{
-					Token: "test-token",
+				Config: cvault.Config{
+					CACertPath: "testdata/keys/EC/root_cert.pem",
+					TokenAuth: &cvault.TokenAuthConfig{
+						Token: "test-token",
+					},
 				},
+				PKIMountPoint: "test-pki",
 			},
-			authMethod:              TOKEN,
+			authMethod:              cvault.TOKEN,
 			expectX509CA:            []string{"spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer: func() *FakeVaultServerConfig {
@@ -541,7 +568,7 @@ func TestMintX509CA(t *testing.T) {
 		{
 			name:                    "Plugin is not configured",
 			csr:                     csr.Raw,
-			authMethod:              TOKEN,
+			authMethod:              cvault.TOKEN,
 			expectX509CA:            []string{"spiffe://intermediate-vault", "spiffe://intermediate"},
 			expectedX509Authorities: []string{"spiffe://root"},
 			fakeServer:              setupSuccessFakeVaultServer,
@@ -552,7 +579,7 @@ func TestMintX509CA(t *testing.T) {
 			name:       "Authenticate client fails",
 			csr:        csr.Raw,
 			config:     successfulConfig,
-			authMethod: TOKEN,
+			authMethod: cvault.TOKEN,
 			fakeServer: func() *FakeVaultServerConfig {
 				fakeServer := setupSuccessFakeVaultServer()
 				// Expect error
@@ -569,7 +596,7 @@ func TestMintX509CA(t *testing.T) {
 			name:       "Signin fails",
 			csr:        csr.Raw,
 			config:     successfulConfig,
-			authMethod: TOKEN,
+			authMethod: cvault.TOKEN,
 			fakeServer: func() *FakeVaultServerConfig {
 				fakeServer := setupSuccessFakeVaultServer()
 				// Expect error
@@ -586,7 +613,7 @@ func TestMintX509CA(t *testing.T) {
 			name:       "Invalid signing response",
 			csr:        csr.Raw,
 			config:     successfulConfig,
-			authMethod: TOKEN,
+			authMethod: cvault.TOKEN,
 			fakeServer: func() *FakeVaultServerConfig {
 				fakeServer := setupSuccessFakeVaultServer()
 				// Expect error
@@ -603,7 +630,7 @@ func TestMintX509CA(t *testing.T) {
 			name:       "Signing response malformed certificate",
 			csr:        csr.Raw,
 			config:     successfulConfig,
-			authMethod: TOKEN,
+			authMethod: cvault.TOKEN,
 			fakeServer: func() *FakeVaultServerConfig {
 				fakeServer := setupSuccessFakeVaultServer()
 				// Expect error
@@ -620,7 +647,7 @@ func TestMintX509CA(t *testing.T) {
 			name:       "Signing response malformed certificate",
 			csr:        csr.Raw,
 			config:     successfulConfig,
-			authMethod: TOKEN,
+			authMethod: cvault.TOKEN,
 			fakeServer: func() *FakeVaultServerConfig {
 				fakeServer := setupSuccessFakeVaultServer()
 				// Expect error
@@ -637,7 +664,7 @@ func TestMintX509CA(t *testing.T) {
 			name:            "Invalid CSR",
 			csr:             []byte("malformed-csr"),
 			config:          successfulConfig,
-			authMethod:      TOKEN,
+			authMethod:      cvault.TOKEN,
 			fakeServer:      setupSuccessFakeVaultServer,
 			expectCode:      codes.InvalidArgument,
 			expectMsgPrefix: "upstreamauthority(vault): failed to parse CSR data:",
@@ -717,12 +744,14 @@ func TestMintX509CA_InvalidCSR(t *testing.T) {
 	v1 := new(upstreamauthority.V1)
 	plugintest.Load(t, builtin(p), v1,
 		plugintest.ConfigureJSON(&Configuration{
-			VaultAddr:     fmt.Sprintf("https://%v/", addr),
-			CACertPath:    testRootCert,
-			PKIMountPoint: "test-pki",
-			TokenAuth: &TokenAuthConfig{
-				Token: "test-token",
+			Config: cvault.Config{
+				VaultAddr:  fmt.Sprintf("https://%v/", addr),
+				CACertPath: testRootCert,
+				TokenAuth: &cvault.TokenAuthConfig{
+					Token: "test-token",
+				},
 			},
+			PKIMountPoint: "test-pki",
 		}),
 		plugintest.CoreConfig(catalog.CoreConfig{TrustDomain: spiffeid.RequireTrustDomainFromString("example.org")}),
 	)
@@ -749,12 +778,14 @@ func TestPublishJWTKey(t *testing.T) {
 	ua := new(upstreamauthority.V1)
 	plugintest.Load(t, BuiltIn(), ua,
 		plugintest.ConfigureJSON(Configuration{
-			VaultAddr:     fmt.Sprintf("https://%v/", addr),
-			CACertPath:    testRootCert,
-			PKIMountPoint: "test-pki",
-			TokenAuth: &TokenAuthConfig{
-				Token: "test-token",
+			Config: cvault.Config{
+				VaultAddr:  fmt.Sprintf("https://%v/", addr),
+				CACertPath: testRootCert,
+				TokenAuth: &cvault.TokenAuthConfig{
+					Token: "test-token",
+				},
 			},
+			PKIMountPoint: "test-pki",
 		}),
 		plugintest.CoreConfig(catalog.CoreConfig{
 			TrustDomain: spiffeid.RequireTrustDomainFromString("example.org"),
@@ -19,6 +19,7 @@ import (
 	"github.com/spiffe/spire/pkg/common/telemetry"
 	"github.com/spiffe/spire/pkg/common/uptime"
 	"github.com/spiffe/spire/pkg/common/util"
+	"github.com/spiffe/spire/pkg/server/attestpolicy"
 	"github.com/spiffe/spire/pkg/server/authpolicy"
 	bundle_client "github.com/spiffe/spire/pkg/server/bundle/client"
 	"github.com/spiffe/spire/pkg/server/ca"
@@ -128,9 +129,14 @@ func (s *Server) run(ctx context.Context) (err error) {
 		return fmt.Errorf("unable to obtain authpolicy engine: %w", err)
 	}
 
+	attestationPolicyEngine, err := attestpolicy.NewEngineFromConfig(ctx, s.config.AttestationOpaPolicyEngineConfig)
+	if err != nil {
+		return fmt.Errorf("unable to obtain attestation policy engine: %w", err)
+	}
+
 	bundleManager := s.newBundleManager(cat, metrics)
 
-	endpointsServer, err := s.newEndpointsServer(ctx, cat, svidRotator, serverCA, metrics, caManager, authPolicyEngine, bundleManager)
+	endpointsServer, err := s.newEndpointsServer(ctx, cat, svidRotator, serverCA, metrics, caManager, authPolicyEngine, attestationPolicyEngine, bundleManager)
 	if err != nil {
 		return err
 	}
@@ -303,25 +309,27 @@ func (s *Server) newSVIDRotator(ctx context.Context, serverCA ca.ServerCA, metri
 	return svidRotator, nil
 }
 
-func (s *Server) newEndpointsServer(ctx context.Context, catalog catalog.Catalog, svidObserver svid.Observer, serverCA ca.ServerCA, metrics telemetry.Metrics, caManager *ca.Manager, authPolicyEngine *authpolicy.Engine, bundleManager *bundle_client.Manager) (endpoints.Server, error) {
+func (s *Server) newEndpointsServer(ctx context.Context, catalog catalog.Catalog, svidObserver svid.Observer, serverCA ca.ServerCA, metrics telemetry.Metrics, caManager *ca.Manager, authPolicyEngine *authpolicy.Engine, attestationPolicyEngine *attestpolicy.Engine, bundleManager *bundle_client.Manager) (endpoints.Server, error) {
 	config := endpoints.Config{
-		TCPAddr:             s.config.BindAddress,
-		UDSAddr:             s.config.BindUDSAddress,
-		SVIDObserver:        svidObserver,
-		TrustDomain:         s.config.TrustDomain,
-		Catalog:             catalog,
-		ServerCA:            serverCA,
-		AgentTTL:            s.config.AgentTTL,
-		Log:                 s.config.Log.WithField(telemetry.SubsystemName, telemetry.Endpoints),
-		Metrics:             metrics,
-		Manager:             caManager,
-		RateLimit:           s.config.RateLimit,
-		Uptime:              uptime.Uptime,
-		Clock:               clock.New(),
-		CacheReloadInterval: s.config.CacheReloadInterval,
-		AuditLogEnabled:     s.config.AuditLogEnabled,
-		AuthPolicyEngine:    authPolicyEngine,
-		BundleManager:       bundleManager,
+		TCPAddr:                   s.config.BindAddress,
+		UDSAddr:                   s.config.BindUDSAddress,
+		SVIDObserver:              svidObserver,
+		TrustDomain:               s.config.TrustDomain,
+		Catalog:                   catalog,
+		ServerCA:                  serverCA,
+		AgentTTL:                  s.config.AgentTTL,
+		ReattestableNodeAttestors: s.config.ReattestableNodeAttestors,
+		Log:                       s.config.Log.WithField(telemetry.SubsystemName, telemetry.Endpoints),
+		Metrics:                   metrics,
+		Manager:                   caManager,
+		RateLimit:                 s.config.RateLimit,
+		Uptime:                    uptime.Uptime,
+		Clock:                     clock.New(),
+		CacheReloadInterval:       s.config.CacheReloadInterval,
+		AuditLogEnabled:           s.config.AuditLogEnabled,
+		AuthPolicyEngine:          authPolicyEngine,
+		AttestationPolicyEngine:   attestationPolicyEngine,
+		BundleManager:             bundleManager,
 	}
 	if s.config.Federation.BundleEndpoint != nil {
 		config.BundleEndpoint.Address = s.config.Federation.BundleEndpoint.Address
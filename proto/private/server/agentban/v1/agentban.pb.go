@@ -0,0 +1,168 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.14.0
+// source: private/server/agentban/v1/agentban.proto
+
+package agentbanv1
+
+import (
+	types "github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type UnbanAgentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Required. The SPIFFE ID of the agent to unban.
+	Id *types.SPIFFEID `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *UnbanAgentRequest) Reset() {
+	*x = UnbanAgentRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_server_agentban_v1_agentban_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UnbanAgentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnbanAgentRequest) ProtoMessage() {}
+
+func (x *UnbanAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_server_agentban_v1_agentban_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnbanAgentRequest.ProtoReflect.Descriptor instead.
+func (*UnbanAgentRequest) Descriptor() ([]byte, []int) {
+	return file_private_server_agentban_v1_agentban_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UnbanAgentRequest) GetId() *types.SPIFFEID {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+var File_private_server_agentban_v1_agentban_proto protoreflect.FileDescriptor
+
+var file_private_server_agentban_v1_agentban_proto_rawDesc = []byte{
+	0x0a, 0x29, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x62, 0x61, 0x6e, 0x2f, 0x76, 0x31, 0x2f, 0x61, 0x67, 0x65,
+	0x6e, 0x74, 0x62, 0x61, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x20, 0x73, 0x70, 0x69,
+	0x72, 0x65, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x62, 0x61, 0x6e, 0x2e, 0x76, 0x31, 0x1a, 0x1b, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65,
+	0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1e, 0x73, 0x70, 0x69, 0x72,
+	0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2f, 0x73, 0x70, 0x69, 0x66,
+	0x66, 0x65, 0x69, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x3e, 0x0a, 0x11, 0x55, 0x6e,
+	0x62, 0x61, 0x6e, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x29, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x73, 0x70,
+	0x69, 0x72, 0x65, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x53, 0x50,
+	0x49, 0x46, 0x46, 0x45, 0x49, 0x44, 0x52, 0x02, 0x69, 0x64, 0x32, 0x65, 0x0a, 0x08, 0x41, 0x67,
+	0x65, 0x6e, 0x74, 0x42, 0x61, 0x6e, 0x12, 0x59, 0x0a, 0x0a, 0x55, 0x6e, 0x62, 0x61, 0x6e, 0x41,
+	0x67, 0x65, 0x6e, 0x74, 0x12, 0x33, 0x2e, 0x73, 0x70, 0x69, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x69,
+	0x76, 0x61, 0x74, 0x65, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x61, 0x67, 0x65, 0x6e,
+	0x74, 0x62, 0x61, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x6e, 0x62, 0x61, 0x6e, 0x41, 0x67, 0x65,
+	0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x42, 0x45, 0x5a, 0x43, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x73, 0x70, 0x69, 0x66, 0x66, 0x65, 0x2f, 0x73, 0x70, 0x69, 0x72, 0x65, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2f, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x62, 0x61, 0x6e, 0x2f, 0x76, 0x31, 0x3b, 0x61, 0x67,
+	0x65, 0x6e, 0x74, 0x62, 0x61, 0x6e, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_private_server_agentban_v1_agentban_proto_rawDescOnce sync.Once
+	file_private_server_agentban_v1_agentban_proto_rawDescData = file_private_server_agentban_v1_agentban_proto_rawDesc
+)
+
+func file_private_server_agentban_v1_agentban_proto_rawDescGZIP() []byte {
+	file_private_server_agentban_v1_agentban_proto_rawDescOnce.Do(func() {
+		file_private_server_agentban_v1_agentban_proto_rawDescData = protoimpl.X.CompressGZIP(file_private_server_agentban_v1_agentban_proto_rawDescData)
+	})
+	return file_private_server_agentban_v1_agentban_proto_rawDescData
+}
+
+var file_private_server_agentban_v1_agentban_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_private_server_agentban_v1_agentban_proto_goTypes = []interface{}{
+	(*UnbanAgentRequest)(nil), // 0: spire.private.server.agentban.v1.UnbanAgentRequest
+	(*types.SPIFFEID)(nil),    // 1: spire.api.types.SPIFFEID
+	(*emptypb.Empty)(nil),     // 2: google.protobuf.Empty
+}
+var file_private_server_agentban_v1_agentban_proto_depIdxs = []int32{
+	1, // 0: spire.private.server.agentban.v1.UnbanAgentRequest.id:type_name -> spire.api.types.SPIFFEID
+	0, // 1: spire.private.server.agentban.v1.AgentBan.UnbanAgent:input_type -> spire.private.server.agentban.v1.UnbanAgentRequest
+	2, // 2: spire.private.server.agentban.v1.AgentBan.UnbanAgent:output_type -> google.protobuf.Empty
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_private_server_agentban_v1_agentban_proto_init() }
+func file_private_server_agentban_v1_agentban_proto_init() {
+	if File_private_server_agentban_v1_agentban_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_private_server_agentban_v1_agentban_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UnbanAgentRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_private_server_agentban_v1_agentban_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_private_server_agentban_v1_agentban_proto_goTypes,
+		DependencyIndexes: file_private_server_agentban_v1_agentban_proto_depIdxs,
+		MessageInfos:      file_private_server_agentban_v1_agentban_proto_msgTypes,
+	}.Build()
+	File_private_server_agentban_v1_agentban_proto = out.File
+	file_private_server_agentban_v1_agentban_proto_rawDesc = nil
+	file_private_server_agentban_v1_agentban_proto_goTypes = nil
+	file_private_server_agentban_v1_agentban_proto_depIdxs = nil
+}
@@ -152,6 +152,13 @@ func (s *DataStore) UpdateAttestedNode(ctx context.Context, node *common.Atteste
 	return s.ds.UpdateAttestedNode(ctx, node, mask)
 }
 
+func (s *DataStore) UpdateAttestedNodeAndSelectors(ctx context.Context, node *common.AttestedNode, mask *common.AttestedNodeMask, selectors []*common.Selector) (*common.AttestedNode, error) {
+	if err := s.getNextError(); err != nil {
+		return nil, err
+	}
+	return s.ds.UpdateAttestedNodeAndSelectors(ctx, node, mask, selectors)
+}
+
 func (s *DataStore) DeleteAttestedNode(ctx context.Context, spiffeID string) (*common.AttestedNode, error) {
 	if err := s.getNextError(); err != nil {
 		return nil, err
@@ -260,6 +267,20 @@ func (s *DataStore) FetchJoinToken(ctx context.Context, token string) (*datastor
 	return s.ds.FetchJoinToken(ctx, token)
 }
 
+func (s *DataStore) ListJoinTokens(ctx context.Context) ([]*datastore.JoinToken, error) {
+	if err := s.getNextError(); err != nil {
+		return nil, err
+	}
+	return s.ds.ListJoinTokens(ctx)
+}
+
+func (s *DataStore) UseJoinToken(ctx context.Context, token string) (*datastore.JoinToken, error) {
+	if err := s.getNextError(); err != nil {
+		return nil, err
+	}
+	return s.ds.UseJoinToken(ctx, token)
+}
+
 func (s *DataStore) DeleteJoinToken(ctx context.Context, token string) error {
 	if err := s.getNextError(); err != nil {
 		return err
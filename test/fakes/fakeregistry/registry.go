@@ -0,0 +1,155 @@
+package fakeregistry
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	signatureMediaType  = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+// Registry is an in-memory OCI registry that can hold cosign-style image
+// signatures.
+type Registry struct {
+	host string
+}
+
+// New starts a new registry that is shut down when the test finishes.
+func New(t *testing.T) *Registry {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return &Registry{host: u.Host}
+}
+
+// Host returns the host (and port) of the registry.
+func (r *Registry) Host() string {
+	return r.host
+}
+
+// PushImage pushes a random image to the given repository and returns its
+// digest reference.
+func (r *Registry) PushImage(t *testing.T, repo string) string {
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref, err := name.NewDigest(fmt.Sprintf("%s/%s@%s", r.host, repo, digest))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+	return ref.String()
+}
+
+// Sign signs the image with the given digest reference using the signer,
+// vouching for the given subject.
+func (r *Registry) Sign(t *testing.T, imageRef string, signer crypto.Signer, subject string) {
+	payload := Payload(t, imageRef, subject)
+	r.AddSignature(t, imageRef, payload, SignPayload(t, signer, payload))
+}
+
+// AddSignature adds the payload and signature to the signatures of the image
+// with the given digest reference.
+func (r *Registry) AddSignature(t *testing.T, imageRef string, payload, sig []byte) {
+	ref, err := name.NewDigest(imageRef)
+	require.NoError(t, err)
+	tag := ref.Context().Tag(strings.Replace(ref.DigestStr(), ":", "-", 1) + ".sig")
+
+	var base v1.Image = empty.Image
+	existing, err := remote.Image(tag)
+	var transportErr *transport.Error
+	switch {
+	case errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound:
+	case err != nil:
+		require.NoError(t, err)
+	default:
+		base = existing
+	}
+
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer: static.NewLayer(payload, types.MediaType(signatureMediaType)),
+		Annotations: map[string]string{
+			signatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, img))
+}
+
+// Payload returns a cosign signature payload for the image with the given
+// digest reference.
+func Payload(t *testing.T, imageRef string, subject string) []byte {
+	ref, err := name.NewDigest(imageRef)
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"critical": map[string]interface{}{
+			"identity": map[string]string{"docker-reference": subject},
+			"image":    map[string]string{"docker-manifest-digest": ref.DigestStr()},
+			"type":     "cosign container image signature",
+		},
+		"optional": nil,
+	})
+	require.NoError(t, err)
+	return payload
+}
+
+// SignPayload signs the payload the way cosign does for the type of signer.
+func SignPayload(t *testing.T, signer crypto.Signer, payload []byte) []byte {
+	var (
+		sig []byte
+		err error
+	)
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		sig, err = signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		hash := sha256.Sum256(payload)
+		sig, err = signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+	require.NoError(t, err)
+	return sig
+}
+
+// WritePublicKey writes the public key of the signer as PEM into the directory
+// and returns the path to the file.
+func WritePublicKey(t *testing.T, dir, keyID string, signer crypto.Signer) string {
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, keyID+".pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
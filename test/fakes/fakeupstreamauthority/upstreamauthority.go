@@ -55,7 +55,7 @@ type UpstreamAuthority struct {
 	jwtKeys    []*common.PublicKey
 
 	streamsMtx           sync.Mutex
-	mintX509CAStreams    map[chan struct{}]struct{}
+	mintX509CAStreams    map[chan struct{}]chan struct{}
 	publishJWTKeyStreams map[chan struct{}]struct{}
 }
 
@@ -63,7 +63,7 @@ func New(t *testing.T, config Config) *UpstreamAuthority {
 	ua := &UpstreamAuthority{
 		t:                    t,
 		config:               config,
-		mintX509CAStreams:    make(map[chan struct{}]struct{}),
+		mintX509CAStreams:    make(map[chan struct{}]chan struct{}),
 		publishJWTKeyStreams: make(map[chan struct{}]struct{}),
 	}
 	ua.RotateX509CA()
@@ -71,7 +71,7 @@ func New(t *testing.T, config Config) *UpstreamAuthority {
 }
 
 func (ua *UpstreamAuthority) MintX509CAAndSubscribe(request *upstreamauthorityv1.MintX509CARequest, stream upstreamauthorityv1.UpstreamAuthority_MintX509CAAndSubscribeServer) error {
-	streamCh := ua.newMintX509CAStream()
+	streamCh, abortCh := ua.newMintX509CAStream()
 	defer ua.removeMintX509CAStream(streamCh)
 
 	ctx := stream.Context()
@@ -92,6 +92,8 @@ func (ua *UpstreamAuthority) MintX509CAAndSubscribe(request *upstreamauthorityv1
 		select {
 		case <-ctx.Done():
 			return nil
+		case <-abortCh:
+			return status.Error(codes.Aborted, "upstream CA certificate changed")
 		case <-streamCh:
 			if err := ua.sendMintX509CAResponse(stream, &upstreamauthorityv1.MintX509CAResponse{
 				UpstreamX509Roots: x509certificate.RequireToPluginProtos(ua.X509Roots()),
@@ -194,6 +196,19 @@ func (ua *UpstreamAuthority) TriggerX509RootsChanged() {
 	}
 }
 
+// TriggerUpstreamX509CAChanged ends the open MintX509CA streams with an
+// Aborted status, which signals that the upstream CA changed.
+func (ua *UpstreamAuthority) TriggerUpstreamX509CAChanged() {
+	ua.streamsMtx.Lock()
+	defer ua.streamsMtx.Unlock()
+	for _, abortCh := range ua.mintX509CAStreams {
+		select {
+		case abortCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
 func (ua *UpstreamAuthority) TriggerJWTKeysChanged() {
 	ua.streamsMtx.Lock()
 	defer ua.streamsMtx.Unlock()
@@ -205,12 +220,13 @@ func (ua *UpstreamAuthority) TriggerJWTKeysChanged() {
 	}
 }
 
-func (ua *UpstreamAuthority) newMintX509CAStream() chan struct{} {
+func (ua *UpstreamAuthority) newMintX509CAStream() (chan struct{}, chan struct{}) {
 	streamCh := make(chan struct{}, 1)
+	abortCh := make(chan struct{}, 1)
 	ua.streamsMtx.Lock()
-	ua.mintX509CAStreams[streamCh] = struct{}{}
+	ua.mintX509CAStreams[streamCh] = abortCh
 	ua.streamsMtx.Unlock()
-	return streamCh
+	return streamCh, abortCh
 }
 
 func (ua *UpstreamAuthority) removeMintX509CAStream(streamCh chan struct{}) {
@@ -3,6 +3,7 @@ package plugintest
 import (
 	"context"
 	"io"
+	"sync"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -41,7 +42,8 @@ func Load(t *testing.T, builtIn catalog.BuiltIn, pluginFacade catalog.Facade, op
 		}
 	}
 	require.NoError(t, err)
-	t.Cleanup(func() { assert.NoError(t, conn.Close()) })
+	closer := &onceCloser{closer: conn}
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
 
 	var facades []catalog.Facade
 	if pluginFacade != nil {
@@ -66,10 +68,25 @@ func Load(t *testing.T, builtIn catalog.BuiltIn, pluginFacade catalog.Facade, op
 		io.Closer
 	}{
 		Configurer: configurer,
-		Closer:     conn,
+		Closer:     closer,
 	}
 }
 
+// onceCloser closes the plugin only once, since it is closed when the test
+// is over even if it was unloaded before.
+type onceCloser struct {
+	closer io.Closer
+	once   sync.Once
+	err    error
+}
+
+func (c *onceCloser) Close() error {
+	c.once.Do(func() {
+		c.err = c.closer.Close()
+	})
+	return c.err
+}
+
 func nullLogger() logrus.FieldLogger {
 	log, _ := test.NewNullLogger()
 	return log